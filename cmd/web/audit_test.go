@@ -0,0 +1,124 @@
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/stackninja.pro/goth/internals/models"
+)
+
+// auditEvents returns the events recorded about email, newest first
+func auditEvents(t *testing.T, email string, action models.AuditAction) []models.AuditEvent {
+	t.Helper()
+	events, _, err := testRepo.GetAuditEvents(context.Background(), models.AuditQuery{Search: email, Action: action, PerPage: models.MaxPerPage})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return events
+}
+
+func TestAuditLoginsAndLogout(t *testing.T) {
+	user := createTestUser(t, "Audited", "audited@audit.test", "secret123")
+
+	tryLogin(t, "audited@audit.test", "wrong")
+	tryLogin(t, "ghost@audit.test", "wrong")
+
+	c := newTestClient(t)
+	c.login("audited@audit.test", "secret123")
+	c.postForm("/logout", nil)
+
+	failed := auditEvents(t, "audited@audit.test", models.AuditLoginFailed)
+	if len(failed) != 1 || failed[0].TargetID != user.ID || failed[0].ActorID != user.ID {
+		t.Fatalf("expected one failed login against the account, got %+v", failed)
+	}
+	if ghost := auditEvents(t, "ghost@audit.test", models.AuditLoginFailed); len(ghost) != 1 || ghost[0].TargetID != "" {
+		t.Errorf("expected a failed login for the unknown address, got %+v", ghost)
+	}
+
+	login := auditEvents(t, "audited@audit.test", models.AuditLogin)
+	if len(login) != 1 || login[0].IP == "" {
+		t.Fatalf("expected one login with an IP, got %+v", login)
+	}
+	if logout := auditEvents(t, "audited@audit.test", models.AuditLogout); len(logout) != 1 {
+		t.Errorf("expected one logout, got %+v", logout)
+	}
+}
+
+func TestAuditProfileUpdateDiff(t *testing.T) {
+	createTestUser(t, "Before Name", "diff@audit.test", "secret123")
+	c := newTestClient(t)
+	c.login("diff@audit.test", "secret123")
+
+	c.postForm("/profile/update", url.Values{
+		"name":  {"After Name"},
+		"email": {"diff@audit.test"},
+		"dob":   {"2000-01-02"},
+		"bio":   {""},
+	})
+
+	events := auditEvents(t, "diff@audit.test", models.AuditProfileUpdate)
+	if len(events) != 1 {
+		t.Fatalf("expected one profile update, got %+v", events)
+	}
+	found := false
+	for _, ch := range events[0].Changes {
+		if ch.Field == "name" && ch.Before == "Before Name" && ch.After == "After Name" {
+			found = true
+		}
+		if ch.Field == "email" {
+			t.Errorf("expected unchanged fields to be left out, got %+v", ch)
+		}
+	}
+	if !found {
+		t.Errorf("expected the name change in the diff, got %+v", events[0].Changes)
+	}
+}
+
+func TestAuditRoleChangeAndViewer(t *testing.T) {
+	c, admin := adminClient(t, "admin@viewer.audit.test")
+	target := createTestUser(t, "Promoted", "promoted@viewer.audit.test", "secret123")
+
+	c.postForm("/users/"+target.ID+"/role", url.Values{"role": {string(models.RoleInstructor)}})
+
+	events := auditEvents(t, "promoted@viewer.audit.test", models.AuditRoleChange)
+	if len(events) != 1 {
+		t.Fatalf("expected one role change, got %+v", events)
+	}
+	e := events[0]
+	if e.ActorID != admin.ID || e.TargetID != target.ID || len(e.Changes) != 1 || e.Changes[0] != (models.AuditChange{Field: "role", Before: "student", After: "instructor"}) {
+		t.Errorf("unexpected event %+v", e)
+	}
+
+	rr := c.get("/users/audit?action=role_change&q=promoted%40viewer.audit.test")
+	if rr.Code != http.StatusOK || !strings.Contains(rr.Body.String(), "Role changed") || !strings.Contains(rr.Body.String(), "promoted@viewer.audit.test") {
+		t.Fatalf("expected the viewer to list the change, got %d %q", rr.Code, rr.Body.String())
+	}
+
+	rr = c.get("/users/audit/export?q=viewer.audit.test&action=role_change")
+	if ct := rr.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/csv") {
+		t.Fatalf("expected CSV, got %q", ct)
+	}
+	rows, err := csv.NewReader(rr.Body).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 2 || rows[1][2] != "role_change" || !strings.Contains(rows[1][9], `role: "student" → "instructor"`) {
+		t.Errorf("unexpected export %q", rows)
+	}
+}
+
+func TestAuditViewerRequiresPermission(t *testing.T) {
+	createTestUser(t, "Snoop", "snoop@audit.test", "secret123")
+	c := newTestClient(t)
+	c.login("snoop@audit.test", "secret123")
+
+	for _, path := range []string{"/users/audit", "/users/audit/export"} {
+		if rr := c.get(path); rr.Code != http.StatusForbidden {
+			t.Errorf("%s: expected 403 for a student, got %d", path, rr.Code)
+		}
+	}
+}
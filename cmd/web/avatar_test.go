@@ -0,0 +1,88 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"image"
+	"image/color"
+	"image/png"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stackninja.pro/goth/internals/storage"
+)
+
+// testPNG returns a tiny valid PNG
+func testPNG(t *testing.T) []byte {
+	t.Helper()
+
+	img := image.NewRGBA(image.Rect(0, 0, 2, 2))
+	img.Set(0, 0, color.RGBA{R: 255, A: 255})
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestUploadAvatarReplacesOldFile(t *testing.T) {
+	user := createTestUser(t, "Picture User", "picture@example.com", "secret123")
+	ctx := context.Background()
+
+	c := newTestClient(t)
+	c.login("picture@example.com", "secret123")
+
+	rr := c.postFile("/upload-avatar", "avatar", "me.png", testPNG(t))
+	if rr.Header().Get("HX-Location") != "/" {
+		t.Fatalf("upload failed: %d %q", rr.Code, rr.Body.String())
+	}
+
+	first, err := testRepo.GetUserByID(ctx, user.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(first.AvatarKey, "avatars/") || first.Avatar != testBlob.URL(first.AvatarKey) {
+		t.Fatalf("avatar not recorded: url %q key %q", first.Avatar, first.AvatarKey)
+	}
+
+	// the stored file is served at its URL
+	if rr := c.get(first.Avatar); rr.Code != http.StatusOK {
+		t.Errorf("GET %s: expected %d, got %d", first.Avatar, http.StatusOK, rr.Code)
+	}
+
+	rr = c.postFile("/upload-avatar", "avatar", "again.png", testPNG(t))
+	if rr.Header().Get("HX-Location") != "/" {
+		t.Fatalf("second upload failed: %d %q", rr.Code, rr.Body.String())
+	}
+
+	second, err := testRepo.GetUserByID(ctx, user.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if second.AvatarKey == first.AvatarKey {
+		t.Fatal("expected a new key for the new avatar")
+	}
+	if _, err := testBlob.Get(ctx, first.AvatarKey); !errors.Is(err, storage.ErrNotFound) {
+		t.Errorf("expected the old avatar to be deleted, got %v", err)
+	}
+	if f, err := testBlob.Get(ctx, second.AvatarKey); err != nil {
+		t.Errorf("new avatar missing: %v", err)
+	} else {
+		f.Close()
+	}
+}
+
+func TestUploadAvatarRejectsNonImages(t *testing.T) {
+	createTestUser(t, "Script User", "script@example.com", "secret123")
+
+	c := newTestClient(t)
+	c.login("script@example.com", "secret123")
+
+	rr := c.postFile("/upload-avatar", "avatar", "evil.png", []byte("<script>alert(1)</script>"))
+	if !strings.Contains(rr.Body.String(), "Please upload a JPEG, PNG, GIF or WebP image") {
+		t.Errorf("expected a file type error, got %q", rr.Body.String())
+	}
+}
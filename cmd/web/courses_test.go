@@ -0,0 +1,146 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/stackninja.pro/goth/internals/handlers"
+	"github.com/stackninja.pro/goth/internals/models"
+)
+
+// instructorClient signs in a new instructor
+func instructorClient(t *testing.T, name, email string) (*testClient, *models.User) {
+	t.Helper()
+
+	user := createTestUser(t, name, email, "secret123")
+	setRole(t, user, models.RoleInstructor)
+
+	c := newTestClient(t)
+	c.login(email, "secret123")
+	return c, user
+}
+
+// courseForm is a valid course form for code
+func courseForm(code string) url.Values {
+	return url.Values{
+		"code":         {code},
+		"title":        {"Course " + code},
+		"description":  {"All about " + code},
+		"department":   {"Catalog Tests"},
+		"credit_units": {"3"},
+		"capacity":     {"40"},
+	}
+}
+
+// createCourse posts the new course form and returns the course's ID
+func createCourse(t *testing.T, c *testClient, form url.Values) string {
+	t.Helper()
+
+	rr := c.postForm("/courses/new", form)
+	loc := rr.Header().Get("HX-Location")
+	if rr.Code != http.StatusNoContent || !strings.HasPrefix(loc, "/courses/") {
+		t.Fatalf("expected to be sent to the new course, got %d %q", rr.Code, rr.Body.String())
+	}
+	return strings.TrimPrefix(loc, "/courses/")
+}
+
+func TestInstructorManagesCourses(t *testing.T) {
+	c, teacher := instructorClient(t, "Tayo Teacher", "tayo@courses.test")
+
+	id := createCourse(t, c, courseForm("csc101"))
+	course, err := handlers.Repo.Courses.GetCourse(context.Background(), id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if course.Code != "CSC 101" || course.Status != models.CourseDraft || !course.TaughtBy(teacher.ID) {
+		t.Errorf("expected a draft CSC 101 taught by its creator, got %+v", course)
+	}
+
+	rr := c.postForm("/courses/new", courseForm("CSC 101"))
+	if !strings.Contains(rr.Body.String(), "already uses the code CSC 101") {
+		t.Errorf("expected a duplicate code to be refused, got %q", rr.Body.String())
+	}
+	bad := courseForm("nope")
+	bad.Set("credit_units", "20")
+	bad.Set("title", "")
+	rr = c.postForm("/courses/new", bad)
+	for _, msg := range []string{"must look like CSC 101", "Title is required", "Credit units must be between 1 and 10"} {
+		if !strings.Contains(rr.Body.String(), msg) {
+			t.Errorf("expected %q, got %q", msg, rr.Body.String())
+		}
+	}
+
+	edit := courseForm("CSC 101")
+	edit.Set("title", "Computing Basics")
+	if rr := c.postForm("/courses/"+id+"/edit", edit); rr.Code != http.StatusNoContent {
+		t.Fatalf("expected the edit to save, got %d %q", rr.Code, rr.Body.String())
+	}
+	if body := c.get("/courses/manage").Body.String(); !strings.Contains(body, "Computing Basics") || !strings.Contains(body, "Draft") {
+		t.Error("expected the instructor's list to show the edited draft")
+	}
+
+	if rr := c.postForm("/courses/"+id+"/status", url.Values{"status": {"archived"}}); rr.Code != http.StatusNoContent {
+		t.Fatalf("expected the course to be archived, got %d", rr.Code)
+	}
+	if course, _ := handlers.Repo.Courses.GetCourse(context.Background(), id); course.Status != models.CourseArchived {
+		t.Errorf("expected the course to be archived, got %s", course.Status)
+	}
+}
+
+func TestCourseOwnership(t *testing.T) {
+	owner, _ := instructorClient(t, "Owner Teacher", "owner@courses.test")
+	other, _ := instructorClient(t, "Other Teacher", "other@courses.test")
+	id := createCourse(t, owner, courseForm("OWN 101"))
+
+	if rr := other.get("/courses/" + id + "/edit"); rr.Code != http.StatusForbidden {
+		t.Errorf("expected another instructor to be refused, got %d", rr.Code)
+	}
+	if rr := other.postForm("/courses/"+id+"/status", url.Values{"status": {"published"}}); rr.Code != http.StatusForbidden {
+		t.Errorf("expected another instructor's status change to be refused, got %d", rr.Code)
+	}
+	if body := other.get("/courses/manage").Body.String(); strings.Contains(body, "OWN 101") {
+		t.Error("expected other instructors' courses to be left out of the teaching list")
+	}
+
+	admin, _ := adminClient(t, "admin@courses.test")
+	if rr := admin.get("/courses/" + id + "/edit"); rr.Code != http.StatusOK {
+		t.Errorf("expected an admin to edit any course, got %d", rr.Code)
+	}
+	if rr := admin.get("/courses/not-an-id"); rr.Code != http.StatusNotFound {
+		t.Errorf("expected a bad id to be a 404, got %d", rr.Code)
+	}
+}
+
+func TestStudentCatalog(t *testing.T) {
+	teacher, _ := instructorClient(t, "Catalog Teacher", "catalog@courses.test")
+	published := createCourse(t, teacher, courseForm("CAT 101"))
+	draft := createCourse(t, teacher, courseForm("CAT 102"))
+	teacher.postForm("/courses/"+published+"/status", url.Values{"status": {"published"}})
+
+	createTestUser(t, "Browsing Student", "student@courses.test", "secret123")
+	c := newTestClient(t)
+	c.login("student@courses.test", "secret123")
+
+	body := c.get("/courses?department=Catalog+Tests").Body.String()
+	if !strings.Contains(body, "CAT 101") || strings.Contains(body, "CAT 102") {
+		t.Error("expected the catalog to list only published courses")
+	}
+	if body := c.get("/courses?q=cat+101").Body.String(); !strings.Contains(body, "Catalog Teacher") {
+		t.Error("expected searching by code to find the course and its instructor")
+	}
+
+	if rr := c.get("/courses/" + published); rr.Code != http.StatusOK || strings.Contains(rr.Body.String(), "/edit") {
+		t.Errorf("expected the student to read the course without editing it, got %d", rr.Code)
+	}
+	if rr := c.get("/courses/" + draft); rr.Code != http.StatusNotFound {
+		t.Errorf("expected drafts to be hidden from students, got %d", rr.Code)
+	}
+	for _, path := range []string{"/courses/manage", "/courses/new"} {
+		if rr := c.get(path); rr.Code != http.StatusForbidden {
+			t.Errorf("GET %s: expected %d, got %d", path, http.StatusForbidden, rr.Code)
+		}
+	}
+}
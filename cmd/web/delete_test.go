@@ -0,0 +1,214 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stackninja.pro/goth/internals/handlers"
+	"github.com/stackninja.pro/goth/internals/models"
+	"github.com/stackninja.pro/goth/internals/repository"
+)
+
+func TestAdminDeleteAndRestore(t *testing.T) {
+	c, _ := adminClient(t, "admin@softdelete.test")
+	gone := createTestUser(t, "Soon Gone", "gone@softdelete.test", "secret123")
+
+	signedIn := newTestClient(t)
+	signedIn.login("gone@softdelete.test", "secret123")
+
+	rr := c.postForm("/users/bulk", url.Values{"ids": {gone.ID}, "action": {"delete"}})
+	if !strings.Contains(rr.Body.String(), "1 users deleted") {
+		t.Fatalf("expected a summary of the delete, got %q", rr.Body.String())
+	}
+	if rr := signedIn.get("/"); rr.Code != http.StatusSeeOther {
+		t.Errorf("expected a deleted user's session to end, got %d", rr.Code)
+	}
+
+	if body := c.get("/users?q=softdelete.test").Body.String(); strings.Contains(body, "gone@softdelete.test") {
+		t.Error("expected deleted users to be left out of the directory")
+	}
+	if body := c.get("/users?q=softdelete.test&deleted=1").Body.String(); !strings.Contains(body, "gone@softdelete.test") || !strings.Contains(body, "/users/"+gone.ID+"/restore") {
+		t.Error("expected the deleted view to list the user with a restore button")
+	}
+
+	if body := tryLogin(t, "gone@softdelete.test", "wrong"); !strings.Contains(body, "Invalid email or password") {
+		t.Errorf("expected a wrong password not to reveal the deletion, got %q", body)
+	}
+	if body := tryLogin(t, "gone@softdelete.test", "secret123"); !strings.Contains(body, "has been deleted") {
+		t.Errorf("expected a deleted user to be refused, got %q", body)
+	}
+
+	rr = c.postForm("/users/"+gone.ID+"/restore", nil)
+	if !strings.Contains(rr.Body.String(), `name="status"`) {
+		t.Fatalf("expected the restored row with its editors, got %q", rr.Body.String())
+	}
+	if got, _ := testRepo.GetUserByID(context.Background(), gone.ID); got.Deleted() {
+		t.Error("expected the user to be restored")
+	}
+	if body := tryLogin(t, "gone@softdelete.test", "secret123"); body != "redirect /" {
+		t.Errorf("expected a restored user to sign in, got %q", body)
+	}
+
+	if rr := c.postForm("/users/"+gone.ID+"/restore", nil); !strings.Contains(rr.Body.String(), "This account isn") {
+		t.Errorf("expected restoring an active user to be refused, got %q", rr.Body.String())
+	}
+	for _, action := range []models.AuditAction{models.AuditUserDelete, models.AuditUserRestore} {
+		if len(auditEvents(t, "gone@softdelete.test", action)) != 1 {
+			t.Errorf("expected one %s event", action)
+		}
+	}
+}
+
+func TestPurgeDeletedUsers(t *testing.T) {
+	ctx := context.Background()
+	user := createTestUser(t, "Purged", "purged@softdelete.test", "secret123")
+	if err := testRepo.DeleteUser(ctx, user.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := handlers.Repo.PurgeDeletedUsers(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := testRepo.GetUserByID(ctx, user.ID); err != nil {
+		t.Fatalf("expected the user to be kept during the retention period, got %v", err)
+	}
+
+	saved := testApp.Accounts
+	testApp.Accounts.DeletedRetention = 0
+	t.Cleanup(func() { testApp.Accounts = saved })
+
+	if n, err := handlers.Repo.PurgeDeletedUsers(ctx); err != nil || n == 0 {
+		t.Fatalf("expected the user to be purged, got %d, %v", n, err)
+	}
+	if _, err := testRepo.GetUserByID(ctx, user.ID); !errors.Is(err, repository.ErrNotFound) {
+		t.Errorf("expected ErrNotFound after purge, got %v", err)
+	}
+	if events := auditEvents(t, "purged@softdelete.test", models.AuditUserPurge); len(events) != 1 || events[0].ActorEmail != "system" {
+		t.Errorf("expected one purge event by the system, got %+v", events)
+	}
+}
+
+func TestPurgeKeepsGradedStudents(t *testing.T) {
+	ctx := context.Background()
+	admin, _ := adminClient(t, "admin@keepgrades.test")
+	week := 7 * 24 * time.Hour
+	term := makeCurrentTerm(t, admin, "Kept Grades Term", time.Now().Add(week), time.Now().Add(2*week))
+
+	teacher, _ := instructorClient(t, "Kept Teacher", "teacher@keepgrades.test")
+	id := createCourse(t, teacher, courseForm("KPT 101"))
+	teacher.postForm("/courses/"+id+"/status", url.Values{"status": {"published"}})
+
+	studentClient(t, "Graded Leaver", "leaver@keepgrades.test").postForm("/courses/"+id+"/enroll", nil)
+	user, _ := testRepo.GetUserByEmail(ctx, "leaver@keepgrades.test")
+	publishFinal(t, teacher, id, term.ID, user.ID, "64")
+
+	if err := testRepo.DeleteUser(ctx, user.ID); err != nil {
+		t.Fatal(err)
+	}
+	saved := testApp.Accounts
+	testApp.Accounts.DeletedRetention = 0
+	t.Cleanup(func() { testApp.Accounts = saved })
+
+	if n, err := handlers.Repo.PurgeDeletedUsers(ctx); err != nil || n == 0 {
+		t.Fatalf("expected the student to be purged, got %d, %v", n, err)
+	}
+	if events := auditEvents(t, "leaver@keepgrades.test", models.AuditUserPurge); len(events) != 1 {
+		t.Errorf("expected one purge event under the old address, got %+v", events)
+	}
+
+	// the row stays for the grades, with nothing personal left on it
+	kept, err := testRepo.GetUserByID(ctx, user.ID)
+	if err != nil {
+		t.Fatalf("expected the graded student's row to be kept, got %v", err)
+	}
+	if !kept.Anonymized() || kept.Name != models.AnonymizedName || kept.Email == "leaver@keepgrades.test" {
+		t.Errorf("expected the account to be anonymised, got %+v", kept)
+	}
+	if _, err := testRepo.GetUserByEmail(ctx, "leaver@keepgrades.test"); !errors.Is(err, repository.ErrNotFound) {
+		t.Errorf("expected the old address to be free, got %v", err)
+	}
+	if err := testRepo.RestoreUser(ctx, user.ID); !errors.Is(err, repository.ErrNotFound) {
+		t.Errorf("expected an anonymised account not to be restorable, got %v", err)
+	}
+
+	scores, _ := handlers.Repo.Grades.GetScores(ctx, id, term.ID)
+	if len(scores[user.ID]) != 1 {
+		t.Errorf("expected the gradebook to keep the student's score, got %v", scores)
+	}
+	if history, _ := handlers.Repo.Grades.GetScoreHistory(ctx, id, term.ID, user.ID); len(history) == 0 {
+		t.Error("expected the score history to be kept")
+	}
+
+	// purging again leaves the kept row alone
+	if n, err := handlers.Repo.PurgeDeletedUsers(ctx); err != nil || n != 0 {
+		t.Errorf("expected nothing left to purge, got %d, %v", n, err)
+	}
+}
+
+func TestPurgeKeepsEnrollments(t *testing.T) {
+	ctx := context.Background()
+	admin, _ := adminClient(t, "admin@keeproster.test")
+	week := 7 * 24 * time.Hour
+	term := makeCurrentTerm(t, admin, "Kept Roster Term", time.Now().Add(week), time.Now().Add(2*week))
+
+	teacher, _ := instructorClient(t, "Roster Keeper", "teacher@keeproster.test")
+	id := createCourse(t, teacher, courseForm("KRS 101"))
+	teacher.postForm("/courses/"+id+"/status", url.Values{"status": {"published"}})
+
+	studentClient(t, "Enrolled Leaver", "leaver@keeproster.test").postForm("/courses/"+id+"/enroll", nil)
+	user, _ := testRepo.GetUserByEmail(ctx, "leaver@keeproster.test")
+	if err := testRepo.DeleteUser(ctx, user.ID); err != nil {
+		t.Fatal(err)
+	}
+	saved := testApp.Accounts
+	testApp.Accounts.DeletedRetention = 0
+	t.Cleanup(func() { testApp.Accounts = saved })
+
+	if n, err := handlers.Repo.PurgeDeletedUsers(ctx); err != nil || n == 0 {
+		t.Fatalf("expected the student to be purged, got %d, %v", n, err)
+	}
+	if kept, err := testRepo.GetUserByID(ctx, user.ID); err != nil || !kept.Anonymized() {
+		t.Fatalf("expected the enrolled student's row to be kept anonymised, got %+v, %v", kept, err)
+	}
+
+	roster, _ := handlers.Repo.Courses.GetRoster(ctx, id, term.ID)
+	if len(roster) != 1 || roster[0].StudentID != user.ID || roster[0].StudentName != models.AnonymizedName {
+		t.Errorf("expected the roster to keep the enrollment, got %+v", roster)
+	}
+}
+
+func TestRunPurgerPurgesAtStartup(t *testing.T) {
+	user := createTestUser(t, "Purged Early", "early@softdelete.test", "secret123")
+	if err := testRepo.DeleteUser(context.Background(), user.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	saved := testApp.Accounts
+	testApp.Accounts.DeletedRetention = 0
+	t.Cleanup(func() { testApp.Accounts = saved })
+
+	// an interval far longer than the test, so only the startup run can purge
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		handlers.Repo.RunPurger(ctx, time.Hour)
+		close(done)
+	}()
+	defer func() { cancel(); <-done }()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		if _, err := testRepo.GetUserByID(context.Background(), user.ID); errors.Is(err, repository.ErrNotFound) {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("expected the purger to remove the account at startup")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
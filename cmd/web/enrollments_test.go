@@ -0,0 +1,199 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stackninja.pro/goth/internals/handlers"
+	"github.com/stackninja.pro/goth/internals/models"
+)
+
+// makeSession has an admin add an academic session running from start to end
+func makeSession(t *testing.T, admin *testClient, name string, start, end time.Time) *models.AcademicSession {
+	t.Helper()
+
+	rr := admin.postForm("/terms/sessions", url.Values{"name": {name}, "start_date": {start.Format("2006-01-02")}, "end_date": {end.Format("2006-01-02")}})
+	if !strings.Contains(rr.Body.String(), name+" added") {
+		t.Fatalf("expected the session to be added, got %q", rr.Body.String())
+	}
+
+	sessions, err := handlers.Repo.Terms.GetSessions(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range sessions {
+		if s.Name == name {
+			return &s
+		}
+	}
+	t.Fatalf("session %s not found", name)
+	return nil
+}
+
+// termForm is a term in the session with the given deadlines. Registration
+// opens a month before the add deadline, teaching runs from two weeks before
+// it to three months after the drop deadline, and exams take the last two
+// weeks.
+func termForm(name, sessionID string, add, drop time.Time) url.Values {
+	day := func(d time.Time) []string { return []string{d.Format("2006-01-02")} }
+	end := drop.AddDate(0, 3, 0)
+	return url.Values{
+		"name": {name}, "session": {sessionID},
+		"start_date": day(add.AddDate(0, 0, -14)), "end_date": day(end),
+		"registration_opens": day(add.AddDate(0, -1, 0)), "add_deadline": day(add), "drop_deadline": day(drop),
+		"exam_start": day(end.AddDate(0, 0, -14)), "exam_end": day(end),
+	}
+}
+
+// makeCurrentTerm has an admin add a term with the given deadlines, in a
+// session of its own, and make it the current one
+func makeCurrentTerm(t *testing.T, admin *testClient, name string, add, drop time.Time) *models.Term {
+	t.Helper()
+
+	session := makeSession(t, admin, name+" Session", add.AddDate(-1, 0, 0), drop.AddDate(1, 0, 0))
+	rr := admin.postForm("/terms", termForm(name, session.ID, add, drop))
+	if !strings.Contains(rr.Body.String(), name+" added") {
+		t.Fatalf("expected the term to be added, got %q", rr.Body.String())
+	}
+
+	terms, err := handlers.Repo.Terms.GetTerms(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, term := range terms {
+		if term.Name == name {
+			if rr := admin.postForm("/terms/"+term.ID+"/current", nil); !strings.Contains(rr.Body.String(), "Current term changed") {
+				t.Fatalf("expected the term to become current, got %q", rr.Body.String())
+			}
+			return &term
+		}
+	}
+	t.Fatalf("term %s not found", name)
+	return nil
+}
+
+// studentClient signs in a new student
+func studentClient(t *testing.T, name, email string) *testClient {
+	t.Helper()
+
+	createTestUser(t, name, email, "secret123")
+	c := newTestClient(t)
+	c.login(email, "secret123")
+	return c
+}
+
+func TestEnrollmentWaitlistAndRoster(t *testing.T) {
+	admin, _ := adminClient(t, "admin@enroll.test")
+	week := 7 * 24 * time.Hour
+	term := makeCurrentTerm(t, admin, "Enrollment Test Term", time.Now().Add(week), time.Now().Add(2*week))
+
+	teacher, _ := instructorClient(t, "Roster Teacher", "teacher@enroll.test")
+	form := courseForm("ENR 101")
+	form.Set("capacity", "1")
+	id := createCourse(t, teacher, form)
+
+	first := studentClient(t, "First Student", "first@enroll.test")
+	second := studentClient(t, "Second Student", "second@enroll.test")
+
+	if rr := first.postForm("/courses/"+id+"/enroll", nil); rr.Code != http.StatusNotFound {
+		t.Errorf("expected drafts to refuse enrollment, got %d", rr.Code)
+	}
+	teacher.postForm("/courses/"+id+"/status", url.Values{"status": {"published"}})
+
+	if body := first.postForm("/courses/"+id+"/enroll", nil).Body.String(); !strings.Contains(body, "enrolled in ENR 101") {
+		t.Errorf("expected a seat, got %q", body)
+	}
+	if body := second.postForm("/courses/"+id+"/enroll", nil).Body.String(); !strings.Contains(body, "number 1 on the waitlist") {
+		t.Errorf("expected a waitlist place, got %q", body)
+	}
+	if body := second.postForm("/courses/"+id+"/enroll", nil).Body.String(); !strings.Contains(body, "already enrolled") {
+		t.Errorf("expected enrolling twice to be refused, got %q", body)
+	}
+
+	body := teacher.get("/courses/" + id + "/roster").Body.String()
+	if !strings.Contains(body, "first@enroll.test") || !strings.Contains(body, "Waitlist #1") {
+		t.Error("expected the roster to list the enrolled student and the waitlist")
+	}
+	if rr := first.get("/courses/" + id + "/roster"); rr.Code != http.StatusForbidden {
+		t.Errorf("expected students to be kept off the roster, got %d", rr.Code)
+	}
+
+	if body := first.postForm("/courses/"+id+"/drop", nil).Body.String(); !strings.Contains(body, "You dropped ENR 101") {
+		t.Errorf("expected the drop to succeed, got %q", body)
+	}
+	msg, ok := testMail.LastTo("second@enroll.test")
+	if !ok || !strings.Contains(msg.Subject, "You're enrolled in ENR 101") {
+		t.Errorf("expected the promoted student to be emailed, got %+v", msg)
+	}
+	if body := second.get("/courses/enrolled").Body.String(); !strings.Contains(body, "ENR 101") || !strings.Contains(body, term.Name) {
+		t.Error("expected the promoted student's courses to list ENR 101")
+	}
+}
+
+func TestRaisedCapacityEmailsPromotedStudents(t *testing.T) {
+	admin, _ := adminClient(t, "admin@capacity.test")
+	week := 7 * 24 * time.Hour
+	makeCurrentTerm(t, admin, "Capacity Term", time.Now().Add(week), time.Now().Add(2*week))
+
+	teacher, _ := instructorClient(t, "Capacity Teacher", "teacher@capacity.test")
+	form := courseForm("CAP 101")
+	form.Set("capacity", "1")
+	id := createCourse(t, teacher, form)
+	teacher.postForm("/courses/"+id+"/status", url.Values{"status": {"published"}})
+
+	studentClient(t, "Seated Student", "seated@capacity.test").postForm("/courses/"+id+"/enroll", nil)
+	studentClient(t, "Waiting Student", "waiting@capacity.test").postForm("/courses/"+id+"/enroll", nil)
+
+	form.Set("capacity", "2")
+	if rr := teacher.postForm("/courses/"+id+"/edit", form); rr.Code != http.StatusNoContent {
+		t.Fatalf("expected the course to be saved, got %d %q", rr.Code, rr.Body.String())
+	}
+	msg, ok := testMail.LastTo("waiting@capacity.test")
+	if !ok || !strings.Contains(msg.Subject, "You're enrolled in CAP 101") || !strings.Contains(msg.Text, "Capacity Term") {
+		t.Errorf("expected the promoted student to be emailed, got %+v", msg)
+	}
+}
+
+func TestEnrollmentDeadlines(t *testing.T) {
+	admin, _ := adminClient(t, "admin@deadline.test")
+	yesterday := time.Now().AddDate(0, 0, -1)
+	makeCurrentTerm(t, admin, "Closed Term", yesterday, yesterday)
+
+	teacher, _ := instructorClient(t, "Deadline Teacher", "teacher@deadline.test")
+	id := createCourse(t, teacher, courseForm("DDL 101"))
+	teacher.postForm("/courses/"+id+"/status", url.Values{"status": {"published"}})
+
+	student := studentClient(t, "Late Student", "late@deadline.test")
+	if body := student.postForm("/courses/"+id+"/enroll", nil).Body.String(); !strings.Contains(body, "add deadline for Closed Term has passed") {
+		t.Errorf("expected a late enrollment to be refused, got %q", body)
+	}
+
+	// enrolled before the deadline, through the repository
+	term, _ := handlers.Repo.Terms.GetCurrentTerm(context.Background())
+	user, _ := testRepo.GetUserByEmail(context.Background(), "late@deadline.test")
+	if _, _, err := handlers.Repo.Courses.Enroll(context.Background(), id, term.ID, user.ID); err != nil {
+		t.Fatal(err)
+	}
+	if body := student.postForm("/courses/"+id+"/drop", nil).Body.String(); !strings.Contains(body, "drop deadline for Closed Term has passed") {
+		t.Errorf("expected a late drop to be refused, got %q", body)
+	}
+}
+
+func TestTermsRequireAdmin(t *testing.T) {
+	student := studentClient(t, "Term Student", "student@terms.test")
+	if rr := student.get("/terms"); rr.Code != http.StatusForbidden {
+		t.Errorf("expected %d, got %d", http.StatusForbidden, rr.Code)
+	}
+
+	admin, _ := adminClient(t, "admin@terms.test")
+	if body := admin.postForm("/terms", url.Values{"name": {""}, "add_deadline": {"soon"}}).Body.String(); !strings.Contains(body, "Term name is required") || !strings.Contains(body, "Add deadline must be a date") {
+		t.Errorf("expected validation errors, got %q", body)
+	}
+	if rr := admin.postForm("/terms/not-an-id/current", nil); rr.Code != http.StatusNotFound {
+		t.Errorf("expected a bad id to be a 404, got %d", rr.Code)
+	}
+}
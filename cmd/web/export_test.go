@@ -0,0 +1,119 @@
+package main
+
+import (
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stackninja.pro/goth/internals/models"
+)
+
+func TestExportUsesFiltersAndColumns(t *testing.T) {
+	c, _ := adminClient(t, "admin@filters.export.test")
+	createTestUser(t, "Zed Student", "zed@filters.export.test", "secret123")
+	amy := createTestUser(t, "Amy Instructor", "amy@filters.export.test", "secret123")
+	setRole(t, amy, models.RoleInstructor)
+	createTestUser(t, "Outsider", "outsider@example.com", "secret123")
+
+	rr := c.get("/users/export?q=filters.export.test&role=student&sort=name&dir=asc&columns=email&columns=name&columns=password")
+	if rr.Code != http.StatusOK || !strings.HasPrefix(rr.Header().Get("Content-Type"), "text/csv") {
+		t.Fatalf("expected a CSV download, got %d %q", rr.Code, rr.Header().Get("Content-Type"))
+	}
+	records, err := csv.NewReader(rr.Body).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// columns come out in file order, unknown ones are ignored, and the
+	// student filter leaves out the admin and the instructor
+	want := [][]string{{"name", "email"}, {"Zed Student", "zed@filters.export.test"}}
+	if len(records) != len(want) || strings.Join(records[0], ",") != "name,email" || strings.Join(records[1], ",") != strings.Join(want[1], ",") {
+		t.Errorf("unexpected export %q", records)
+	}
+
+	events := auditEvents(t, "admin@filters.export.test", models.AuditUserExport)
+	if len(events) != 1 {
+		t.Fatalf("expected the export to be audited, got %+v", events)
+	}
+	if rows := events[0].Changes[len(events[0].Changes)-1]; rows.Field != "rows" || rows.After != "1" {
+		t.Errorf("expected the row count in the audit event, got %+v", events[0].Changes)
+	}
+	if filter := events[0].Changes[2]; filter.Field != "filter" || strings.Contains(filter.After, "page") || !strings.Contains(filter.After, "role=student") {
+		t.Errorf("expected only the chosen filters in the audit event, got %+v", filter)
+	}
+}
+
+func TestExportNDJSON(t *testing.T) {
+	c, _ := adminClient(t, "admin@ndjson.export.test")
+	createTestUser(t, "Line One", "one@ndjson.export.test", "secret123")
+	createTestUser(t, "Line Two", "two@ndjson.export.test", "secret123")
+
+	rr := c.get("/users/export?format=ndjson&q=ndjson.export.test&sort=email&columns=email&columns=two_factor")
+	if ct := rr.Header().Get("Content-Type"); ct != "application/x-ndjson" {
+		t.Fatalf("expected NDJSON, got %q", ct)
+	}
+	if strings.Contains(rr.Body.String(), "$2a$") || strings.Contains(rr.Body.String(), "password") {
+		t.Fatal("export contains a password hash")
+	}
+
+	var emails []string
+	sc := bufio.NewScanner(rr.Body)
+	for sc.Scan() {
+		var row map[string]string
+		if err := json.Unmarshal(sc.Bytes(), &row); err != nil {
+			t.Fatalf("bad line %q: %v", sc.Text(), err)
+		}
+		if len(row) != 2 || row["two_factor"] != "false" {
+			t.Errorf("unexpected row %v", row)
+		}
+		emails = append(emails, row["email"])
+	}
+	if strings.Join(emails, " ") != "admin@ndjson.export.test one@ndjson.export.test two@ndjson.export.test" {
+		t.Errorf("expected every matching user in email order, got %v", emails)
+	}
+}
+
+func TestExportXLSX(t *testing.T) {
+	c, _ := adminClient(t, "admin@xlsx.export.test")
+	createTestUser(t, "=HYPERLINK(\"x\")", "sheet@xlsx.export.test", "secret123")
+
+	rr := c.get("/users/export?format=xlsx&q=sheet%40xlsx.export.test")
+	if !strings.Contains(rr.Header().Get("Content-Disposition"), ".xlsx") {
+		t.Fatalf("expected an xlsx file name, got %q", rr.Header().Get("Content-Disposition"))
+	}
+
+	data := rr.Body.Bytes()
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatalf("not a zip file: %v", err)
+	}
+	var sheet string
+	for _, f := range zr.File {
+		if f.Name == "xl/worksheets/sheet1.xml" {
+			rc, _ := f.Open()
+			b, _ := io.ReadAll(rc)
+			rc.Close()
+			sheet = string(b)
+		}
+	}
+	for _, want := range []string{">Email<", ">sheet@xlsx.export.test<", `t="inlineStr"`} {
+		if !strings.Contains(sheet, want) {
+			t.Errorf("expected %q in the sheet, got %q", want, sheet)
+		}
+	}
+	if strings.Contains(sheet, "<f>") {
+		t.Error("expected no formulas in the sheet")
+	}
+}
+
+func TestExportRejectsUnknownFormat(t *testing.T) {
+	c, _ := adminClient(t, "admin@format.export.test")
+	if rr := c.get("/users/export?format=pdf"); rr.Code != http.StatusBadRequest {
+		t.Errorf("expected 400, got %d", rr.Code)
+	}
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stackninja.pro/goth/internals/handlers"
+)
+
+func TestGradebook(t *testing.T) {
+	ctx := context.Background()
+	admin, _ := adminClient(t, "admin@grades.test")
+	week := 7 * 24 * time.Hour
+	term := makeCurrentTerm(t, admin, "Grading Term", time.Now().Add(week), time.Now().Add(2*week))
+
+	teacher, _ := instructorClient(t, "Grading Teacher", "teacher@grades.test")
+	id := createCourse(t, teacher, courseForm("GRD 101"))
+	teacher.postForm("/courses/"+id+"/status", url.Values{"status": {"published"}})
+
+	student := studentClient(t, "Graded Student", "student@grades.test")
+	student.postForm("/courses/"+id+"/enroll", nil)
+	user, _ := testRepo.GetUserByEmail(ctx, "student@grades.test")
+
+	assessment := func(name, category, weight, max string) string {
+		return teacher.postForm("/courses/"+id+"/assessments", url.Values{
+			"term": {term.ID}, "name": {name}, "category": {category}, "weight": {weight}, "max_score": {max},
+		}).Body.String()
+	}
+	if body := assessment("First test", "test", "30", "20"); !strings.Contains(body, "First test added") || !strings.Contains(body, "Weights add up to 30%") {
+		t.Errorf("expected the test to be added with a weight warning, got %q", body)
+	}
+	if body := assessment("Exam", "exam", "70", "100"); !strings.Contains(body, "Exam added") || strings.Contains(body, "Weights add up to") {
+		t.Errorf("expected the exam to be added, got %q", body)
+	}
+	if body := assessment("Extra", "assignment", "10", "10"); !strings.Contains(body, "more than 100%") {
+		t.Errorf("expected weights over 100%% to be refused, got %q", body)
+	}
+
+	assessments, err := handlers.Repo.Grades.GetAssessments(ctx, id, term.ID)
+	if err != nil || len(assessments) != 2 {
+		t.Fatalf("expected two assessments, got %v, %v", assessments, err)
+	}
+	test, exam := assessments[0], assessments[1]
+
+	score := func(assessmentID, value string) string {
+		return teacher.postForm("/courses/"+id+"/gradebook/scores", url.Values{
+			"assessment": {assessmentID}, "student": {user.ID}, "score": {value},
+		}).Body.String()
+	}
+	if body := score(test.ID, "25"); !strings.Contains(body, "between 0 and 20") {
+		t.Errorf("expected a score above the maximum to be refused, got %q", body)
+	}
+	score(test.ID, "15")
+	score(test.ID, "16")
+	// 16/20 of 30 plus 60/100 of 70 is 66
+	if body := score(exam.ID, "60"); !strings.Contains(body, "66.0") || !strings.Contains(body, ">B<") {
+		t.Errorf("expected the row to show a total of 66 and a B, got %q", body)
+	}
+
+	body := teacher.get("/courses/" + id + "/gradebook/history?term=" + term.ID + "&student=" + user.ID).Body.String()
+	if !strings.Contains(body, "15 → 16") || !strings.Contains(body, "— → 15") || !strings.Contains(body, "teacher@grades.test") {
+		t.Errorf("expected the score history, got %q", body)
+	}
+
+	if body := teacher.postForm("/courses/"+id+"/assessments/"+test.ID, url.Values{"name": {"First test"}, "category": {"test"}, "weight": {"30"}, "max_score": {"10"}}).Body.String(); !strings.Contains(body, "above the new maximum") {
+		t.Errorf("expected the maximum to stay above given scores, got %q", body)
+	}
+
+	if body := student.get("/").Body.String(); strings.Contains(body, "GRD 101") {
+		t.Error("expected unpublished grades to stay hidden")
+	}
+	if body := teacher.postForm("/courses/"+id+"/gradebook/publish", url.Values{"term": {term.ID}, "publish": {"true"}}).Body.String(); !strings.Contains(body, "Grades published") {
+		t.Errorf("expected the grades to be published, got %q", body)
+	}
+	if body := student.get("/").Body.String(); !strings.Contains(body, "GRD 101") || !strings.Contains(body, "66.0") {
+		t.Error("expected the student to see their published grade")
+	}
+	teacher.postForm("/courses/"+id+"/gradebook/publish", url.Values{"term": {term.ID}, "publish": {"false"}})
+	if body := student.get("/").Body.String(); strings.Contains(body, "GRD 101") {
+		t.Error("expected unpublished grades to be hidden again")
+	}
+}
+
+func TestGradebookAccess(t *testing.T) {
+	admin, _ := adminClient(t, "admin@gradeaccess.test")
+	week := 7 * 24 * time.Hour
+	makeCurrentTerm(t, admin, "Access Term", time.Now().Add(week), time.Now().Add(2*week))
+
+	owner, _ := instructorClient(t, "Owner Teacher", "owner@gradeaccess.test")
+	id := createCourse(t, owner, courseForm("ACC 101"))
+	other, _ := instructorClient(t, "Other Teacher", "other@gradeaccess.test")
+	student := studentClient(t, "Curious Student", "student@gradeaccess.test")
+
+	if rr := other.get("/courses/" + id + "/gradebook"); rr.Code != http.StatusForbidden {
+		t.Errorf("expected other instructors to be kept out, got %d", rr.Code)
+	}
+	if rr := student.get("/courses/" + id + "/gradebook"); rr.Code != http.StatusForbidden {
+		t.Errorf("expected students to be kept out, got %d", rr.Code)
+	}
+	if rr := owner.get("/courses/" + id + "/gradebook"); rr.Code != http.StatusOK || !strings.Contains(rr.Body.String(), "ACC 101 Gradebook") {
+		t.Errorf("expected the owner to see the gradebook, got %d", rr.Code)
+	}
+	if rr := owner.postForm("/courses/"+id+"/gradebook/scores", url.Values{"assessment": {"nope"}}); rr.Code != http.StatusNotFound {
+		t.Errorf("expected a bad assessment to be a 404, got %d", rr.Code)
+	}
+}
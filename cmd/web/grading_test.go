@@ -0,0 +1,159 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stackninja.pro/goth/internals/handlers"
+	"github.com/stackninja.pro/goth/internals/models"
+)
+
+// publishFinal gives the student total out of 100 in a course graded on a
+// single final exam, and publishes the course's grades
+func publishFinal(t *testing.T, teacher *testClient, courseID, termID, studentID, total string) {
+	t.Helper()
+
+	teacher.postForm("/courses/"+courseID+"/assessments", url.Values{
+		"term": {termID}, "name": {"Final"}, "category": {"exam"}, "weight": {"100"}, "max_score": {"100"},
+	})
+	assessments, err := handlers.Repo.Grades.GetAssessments(context.Background(), courseID, termID)
+	if err != nil || len(assessments) != 1 {
+		t.Fatalf("expected the final exam, got %v, %v", assessments, err)
+	}
+	teacher.postForm("/courses/"+courseID+"/gradebook/scores", url.Values{"assessment": {assessments[0].ID}, "student": {studentID}, "score": {total}})
+	teacher.postForm("/courses/"+courseID+"/gradebook/publish", url.Values{"term": {termID}, "publish": {"true"}})
+}
+
+func TestGradingScalesAndCGPA(t *testing.T) {
+	ctx := context.Background()
+	admin, _ := adminClient(t, "admin@scales.test")
+	week := 7 * 24 * time.Hour
+	term := makeCurrentTerm(t, admin, "Scales Term", time.Now().Add(week), time.Now().Add(2*week))
+
+	teacher, _ := instructorClient(t, "Scales Teacher", "teacher@scales.test")
+	three := createCourse(t, teacher, courseForm("SCL 301"))
+	form := courseForm("SCL 101")
+	form.Set("credit_units", "1")
+	one := createCourse(t, teacher, form)
+
+	student := studentClient(t, "Scaled Student", "student@scales.test")
+	user, _ := testRepo.GetUserByEmail(ctx, "student@scales.test")
+	for _, id := range []string{three, one} {
+		teacher.postForm("/courses/"+id+"/status", url.Values{"status": {"published"}})
+		student.postForm("/courses/"+id+"/enroll", nil)
+	}
+	publishFinal(t, teacher, three, term.ID, user.ID, "75")
+	publishFinal(t, teacher, one, term.ID, user.ID, "55")
+
+	// on the NUC scale an A (5) over 3 units and a C (3) over 1 unit is 4.50
+	body := student.get("/").Body.String()
+	if !strings.Contains(body, "4.50") || !strings.Contains(body, "First Class") || !strings.Contains(body, "NUC 5-point") {
+		t.Errorf("expected a 4.50 CGPA and a First Class on the default scale, got %q", body)
+	}
+
+	if body := admin.postForm("/grading/scales", url.Values{"name": {"Broken"}, "bands": {"70 A 5\n50 B 4"}}).Body.String(); !strings.Contains(body, "lowest band must start at 0") {
+		t.Errorf("expected bands without a 0 band to be refused, got %q", body)
+	}
+	if body := admin.postForm("/grading/scales", url.Values{"name": {"US 4.0"}, "bands": {"0 F 0"}}).Body.String(); !strings.Contains(body, "already called US 4.0") {
+		t.Errorf("expected a duplicate scale name to be refused, got %q", body)
+	}
+
+	scales, _ := handlers.Repo.Scales.GetScales(ctx)
+	byName := map[string]string{}
+	for _, s := range scales {
+		byName[s.Name] = s.ID
+	}
+	if body := admin.postForm("/grading/scales/"+byName["NUC 5-point"]+"/delete", nil).Body.String(); !strings.Contains(body, "is the default scale") {
+		t.Errorf("expected the default scale to be kept, got %q", body)
+	}
+
+	if body := admin.postForm("/grading/programmes", url.Values{"name": {"Exchange Programme"}, "scale": {byName["US 4.0"]}}).Body.String(); !strings.Contains(body, "Exchange Programme added") {
+		t.Fatalf("expected the programme to be added, got %q", body)
+	}
+	programmes, _ := handlers.Repo.Scales.GetProgrammes(ctx)
+	var programmeID string
+	for _, p := range programmes {
+		if p.Name == "Exchange Programme" {
+			programmeID = p.ID
+		}
+	}
+	if body := admin.postForm("/grading/programmes/"+programmeID+"/students", url.Values{"email": {"teacher@scales.test"}}).Body.String(); !strings.Contains(body, "a student</p>") {
+		t.Errorf("expected instructors to be kept out of programmes, got %q", body)
+	}
+	if body := admin.postForm("/grading/programmes/"+programmeID+"/students", url.Values{"email": {"student@scales.test"}}).Body.String(); !strings.Contains(body, "Scaled Student added") {
+		t.Errorf("expected the student to join the programme, got %q", body)
+	}
+	if body := admin.postForm("/grading/scales/"+byName["US 4.0"]+"/delete", nil).Body.String(); !strings.Contains(body, "a programme still uses it") {
+		t.Errorf("expected a scale in use to be kept, got %q", body)
+	}
+
+	// on the US scale the same totals are a C (2) and an F (0): 1.50
+	body = student.get("/").Body.String()
+	if !strings.Contains(body, "1.50") || !strings.Contains(body, "US 4.0") || strings.Contains(body, "First Class") {
+		t.Errorf("expected a 1.50 CGPA on the programme's scale, got %q", body)
+	}
+	if body := teacher.get("/courses/" + three + "/gradebook").Body.String(); !strings.Contains(body, ">C<") {
+		t.Error("expected the gradebook to grade the student on their programme's scale")
+	}
+}
+
+func TestGradingRequiresAdmin(t *testing.T) {
+	teacher, _ := instructorClient(t, "Curious Teacher", "teacher@grading.test")
+	if rr := teacher.get("/grading"); rr.Code != http.StatusForbidden {
+		t.Errorf("expected %d, got %d", http.StatusForbidden, rr.Code)
+	}
+
+	admin, _ := adminClient(t, "admin@grading.test")
+	if rr := admin.get("/grading"); rr.Code != http.StatusOK || !strings.Contains(rr.Body.String(), "NUC 5-point") {
+		t.Errorf("expected the standard scales, got %d", rr.Code)
+	}
+	if rr := admin.get("/grading/programmes/not-an-id"); rr.Code != http.StatusNotFound {
+		t.Errorf("expected a bad id to be a 404, got %d", rr.Code)
+	}
+}
+
+func TestGradeBoundary(t *testing.T) {
+	ctx := context.Background()
+	admin, _ := adminClient(t, "admin@boundary.test")
+	week := 7 * 24 * time.Hour
+	term := makeCurrentTerm(t, admin, "Boundary Term", time.Now().Add(week), time.Now().Add(2*week))
+
+	teacher, _ := instructorClient(t, "Boundary Teacher", "teacher@boundary.test")
+	id := createCourse(t, teacher, courseForm("BND 101"))
+	teacher.postForm("/courses/"+id+"/status", url.Values{"status": {"published"}})
+
+	student := studentClient(t, "Boundary Student", "student@boundary.test")
+	student.postForm("/courses/"+id+"/enroll", nil)
+	user, _ := testRepo.GetUserByEmail(ctx, "student@boundary.test")
+
+	for _, a := range []url.Values{
+		{"name": {"CA"}, "category": {"test"}, "weight": {"20"}, "max_score": {"30"}},
+		{"name": {"Exam"}, "category": {"exam"}, "weight": {"80"}, "max_score": {"30"}},
+	} {
+		a.Set("term", term.ID)
+		teacher.postForm("/courses/"+id+"/assessments", a)
+	}
+	assessments, err := handlers.Repo.Grades.GetAssessments(ctx, id, term.ID)
+	if err != nil || len(assessments) != 2 {
+		t.Fatalf("expected two assessments, got %v, %v", assessments, err)
+	}
+
+	// 10/30 at 20% and 20/30 at 80% is exactly 60, a B on the NUC scale,
+	// though the unrounded sum comes out a hair under it
+	for i, score := range []string{"10", "20"} {
+		teacher.postForm("/courses/"+id+"/gradebook/scores", url.Values{"assessment": {assessments[i].ID}, "student": {user.ID}, "score": {score}})
+	}
+	scores, _ := handlers.Repo.Grades.GetScores(ctx, id, term.ID)
+	if total := models.WeightedTotal(assessments, scores[user.ID]); total != 60 {
+		t.Errorf("expected a total of exactly 60, got %v", total)
+	}
+	teacher.postForm("/courses/"+id+"/gradebook/publish", url.Values{"term": {term.ID}, "publish": {"true"}})
+
+	if body := student.get("/").Body.String(); !strings.Contains(body, "4.00") || !strings.Contains(body, "Second Class Upper") {
+		t.Errorf("expected a B worth 4 points, got %q", body)
+	}
+}
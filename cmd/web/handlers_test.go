@@ -0,0 +1,279 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestProtectedPagesRedirectToLogin(t *testing.T) {
+	c := newTestClient(t)
+
+	for _, path := range []string{"/", "/about", "/profile/edit", "/profile/edit/avatar"} {
+		rr := c.get(path)
+		if rr.Code != http.StatusSeeOther {
+			t.Errorf("GET %s: expected %d, got %d", path, http.StatusSeeOther, rr.Code)
+		}
+		if loc := rr.Header().Get("Location"); loc != "/login" {
+			t.Errorf("GET %s: expected redirect to /login, got %q", path, loc)
+		}
+	}
+}
+
+func TestPublicPages(t *testing.T) {
+	c := newTestClient(t)
+
+	for _, path := range []string{"/login", "/register"} {
+		if rr := c.get(path); rr.Code != http.StatusOK {
+			t.Errorf("GET %s: expected %d, got %d", path, http.StatusOK, rr.Code)
+		}
+	}
+}
+
+func TestRegisterUser(t *testing.T) {
+	createTestUser(t, "Taken", "taken@example.com", "secret123")
+
+	tests := []struct {
+		name     string
+		form     url.Values
+		location string
+		errors   []string
+	}{
+		{
+			name:     "valid",
+			form:     url.Values{"name": {"Ada Obi"}, "email": {"ada@example.com"}, "password": {"secret123"}, "role": {"student"}},
+			location: "/login",
+		},
+		{
+			name:   "missing fields",
+			form:   url.Values{},
+			errors: []string{"Name is required", "Email is required", "Password is required", "Role is required"},
+		},
+		{
+			name:   "admin is not self service",
+			form:   url.Values{"name": {"Sneaky"}, "email": {"sneaky@example.com"}, "password": {"secret123"}, "role": {"admin"}},
+			errors: []string{"Please choose Student or Instructor"},
+		},
+		{
+			name:   "duplicate email",
+			form:   url.Values{"name": {"Again"}, "email": {"taken@example.com"}, "password": {"secret123"}, "role": {"instructor"}},
+			errors: []string{"An account with that email already exists"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rr := newTestClient(t).postForm("/register", tt.form)
+
+			if got := rr.Header().Get("HX-Location"); got != tt.location {
+				t.Errorf("expected HX-Location %q, got %q", tt.location, got)
+			}
+			for _, msg := range tt.errors {
+				if !strings.Contains(rr.Body.String(), msg) {
+					t.Errorf("expected body to contain %q, got %q", msg, rr.Body.String())
+				}
+			}
+		})
+	}
+
+	user, err := testRepo.GetUserByEmail(context.Background(), "ada@example.com")
+	if err != nil {
+		t.Fatalf("registered user not stored: %v", err)
+	}
+	if user.Password == "secret123" {
+		t.Error("password stored in plain text")
+	}
+}
+
+func TestLoginUser(t *testing.T) {
+	createTestUser(t, "Login User", "login@example.com", "correct-horse")
+
+	tests := []struct {
+		name     string
+		form     url.Values
+		location string
+		errorMsg string
+	}{
+		{"valid", url.Values{"email": {"login@example.com"}, "password": {"correct-horse"}}, "/", ""},
+		{"wrong password", url.Values{"email": {"login@example.com"}, "password": {"nope"}}, "", "Invalid email or password"},
+		{"unknown email", url.Values{"email": {"ghost@example.com"}, "password": {"nope"}}, "", "Invalid email or password"},
+		{"missing fields", url.Values{}, "", "Email is required"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rr := newTestClient(t).postForm("/login", tt.form)
+
+			if got := rr.Header().Get("HX-Location"); got != tt.location {
+				t.Errorf("expected HX-Location %q, got %q", tt.location, got)
+			}
+			if tt.errorMsg != "" && !strings.Contains(rr.Body.String(), tt.errorMsg) {
+				t.Errorf("expected body to contain %q, got %q", tt.errorMsg, rr.Body.String())
+			}
+		})
+	}
+}
+
+func TestLoginThenLogout(t *testing.T) {
+	createTestUser(t, "Session User", "session@example.com", "secret123")
+
+	c := newTestClient(t)
+	c.login("session@example.com", "secret123")
+
+	rr := c.get("/")
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected home page after login, got %d", rr.Code)
+	}
+	if !strings.Contains(rr.Body.String(), "Session User") {
+		t.Error("expected home page to show the logged in user")
+	}
+
+	rr = c.postForm("/logout", nil)
+	if rr.Header().Get("HX-Redirect") != "/login" {
+		t.Fatalf("expected logout to redirect to /login, got %d %q", rr.Code, rr.Header().Get("HX-Redirect"))
+	}
+
+	if rr := c.get("/"); rr.Code != http.StatusSeeOther {
+		t.Errorf("expected home page to require login after logout, got %d", rr.Code)
+	}
+}
+
+func TestUpdateProfile(t *testing.T) {
+	user := createTestUser(t, "Before Name", "profile@example.com", "secret123")
+	createTestUser(t, "Other", "other-profile@example.com", "secret123")
+
+	c := newTestClient(t)
+	c.login("profile@example.com", "secret123")
+
+	tests := []struct {
+		name     string
+		form     url.Values
+		location string
+		errorMsg string
+	}{
+		{"missing fields", url.Values{}, "", "Name is required"},
+		{"bad date", url.Values{"name": {"X"}, "email": {"profile@example.com"}, "dob": {"17/05/2000"}}, "", "Invalid Date of Birth format"},
+		{"email taken", url.Values{"name": {"X"}, "email": {"other-profile@example.com"}, "dob": {"2000-05-17"}}, "", "An account with that email already exists"},
+		{"valid", url.Values{"name": {"After Name"}, "email": {"profile@example.com"}, "dob": {"1999-12-31"}, "bio": {"Hello"}}, "/", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rr := c.postForm("/profile/update", tt.form)
+
+			if got := rr.Header().Get("HX-Location"); got != tt.location {
+				t.Errorf("expected HX-Location %q, got %q", tt.location, got)
+			}
+			if tt.errorMsg != "" && !strings.Contains(rr.Body.String(), tt.errorMsg) {
+				t.Errorf("expected body to contain %q, got %q", tt.errorMsg, rr.Body.String())
+			}
+		})
+	}
+
+	updated, err := testRepo.GetUserByID(context.Background(), user.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if updated.Name != "After Name" || updated.Bio != "Hello" || updated.DOB.Format("2006-01-02") != "1999-12-31" {
+		t.Errorf("profile not updated: %+v", updated)
+	}
+}
+
+func TestUploadAvatarRequiresFile(t *testing.T) {
+	createTestUser(t, "Avatar User", "avatar@example.com", "secret123")
+
+	c := newTestClient(t)
+	c.login("avatar@example.com", "secret123")
+
+	rr := c.postFile("/upload-avatar", "avatar", "", nil)
+	if !strings.Contains(rr.Body.String(), "No file uploaded") {
+		t.Errorf("expected missing file error, got %q", rr.Body.String())
+	}
+}
+
+func TestHTMXRequestsGetHXRedirect(t *testing.T) {
+	c := newTestClient(t)
+
+	rr := c.postFile("/upload-avatar", "avatar", "me.png", []byte("png"))
+	if got := rr.Header().Get("HX-Redirect"); got != "/login" {
+		t.Errorf("expected HX-Redirect to /login, got %q", got)
+	}
+	if rr.Header().Get("Location") != "" {
+		t.Error("htmx requests must not get a plain redirect")
+	}
+
+	rr = c.postForm("/profile/update", url.Values{"name": {"x"}})
+	if got := rr.Header().Get("HX-Redirect"); got != "/login" {
+		t.Errorf("expected HX-Redirect to /login, got %q", got)
+	}
+}
+
+func TestStaleSessionIsCleared(t *testing.T) {
+	user := createTestUser(t, "Gone Soon", "gone@example.com", "secret123")
+
+	c := newTestClient(t)
+	c.login("gone@example.com", "secret123")
+
+	if err := testRepo.DeleteUser(context.Background(), user.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	rr := c.get("/")
+	if rr.Code != http.StatusSeeOther || rr.Header().Get("Location") != "/login" {
+		t.Errorf("expected redirect to /login for a deleted user, got %d %q", rr.Code, rr.Header().Get("Location"))
+	}
+}
+
+func TestLogoutRequiresPost(t *testing.T) {
+	createTestUser(t, "Get Logout", "get-logout@example.com", "secret123")
+
+	c := newTestClient(t)
+	c.login("get-logout@example.com", "secret123")
+
+	if rr := c.get("/logout"); rr.Code != http.StatusMethodNotAllowed {
+		t.Errorf("expected GET /logout to be rejected, got %d", rr.Code)
+	}
+	if rr := c.get("/"); rr.Code != http.StatusOK {
+		t.Errorf("expected to still be logged in, got %d", rr.Code)
+	}
+}
+
+func TestCSRFTokenRequired(t *testing.T) {
+	createTestUser(t, "CSRF User", "csrf@example.com", "secret123")
+
+	c := newTestClient(t)
+	c.login("csrf@example.com", "secret123")
+
+	tests := []struct {
+		name  string
+		token string
+	}{
+		{"missing", ""},
+		{"wrong", "not-the-token"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			saved := c.csrf
+			c.csrf = tt.token
+			defer func() { c.csrf = saved }()
+
+			rr := c.postForm("/profile/update", url.Values{"name": {"Forged"}, "email": {"csrf@example.com"}, "dob": {"2000-01-01"}})
+			if rr.Code != http.StatusForbidden {
+				t.Errorf("expected %d, got %d", http.StatusForbidden, rr.Code)
+			}
+		})
+	}
+
+	// the form field works as well as the header
+	form := url.Values{"csrf_token": {c.csrf}}
+	saved := c.csrf
+	c.csrf = ""
+	rr := c.postForm("/logout", form)
+	c.csrf = saved
+	if rr.Header().Get("HX-Redirect") != "/login" {
+		t.Errorf("expected logout with form token to succeed, got %d", rr.Code)
+	}
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"context"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/stackninja.pro/goth/internals/models"
+)
+
+// importCSV uploads content to the import page in the given mode
+func importCSV(c *testClient, mode, content string) string {
+	c.t.Helper()
+	return c.postFileWith("/users/import", "file", "users.csv", []byte(content), url.Values{"mode": {mode}}).Body.String()
+}
+
+func TestImportPreviewShowsRowErrors(t *testing.T) {
+	c, _ := adminClient(t, "admin@preview.import.test")
+	createTestUser(t, "Taken", "taken@preview.import.test", "secret123")
+
+	body := importCSV(c, "preview", "name,email,role,dob\n"+
+		"Good,good@preview.import.test,Student,2001-02-03\n"+
+		",bad-email,admin,03/02/2001\n"+
+		"Again,GOOD@preview.import.test,student,2001-02-03\n"+
+		"Taken,taken@preview.import.test,instructor,1990-01-01\n")
+
+	for _, want := range []string{
+		"4 rows: 1 ready, 3 with problems",
+		"Name is required",
+		"Please enter a valid email address",
+		"Please choose Student or Instructor",
+		"Date of Birth must look like 2001-12-31",
+		"This email is already on line 2",
+		"An account with that email already exists",
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("expected %q in the preview, got %q", want, body)
+		}
+	}
+
+	if _, err := testRepo.GetUserByEmail(context.Background(), "good@preview.import.test"); err == nil {
+		t.Error("expected a preview not to create anyone")
+	}
+
+	// importing the same file is refused as a whole
+	body = importCSV(c, "import", "name,email,role,dob\nGood,good@preview.import.test,student,2001-02-03\nBad,,student,2001-02-03\n")
+	if !strings.Contains(body, "Nothing was imported") {
+		t.Errorf("expected the import to be refused, got %q", body)
+	}
+	if _, err := testRepo.GetUserByEmail(context.Background(), "good@preview.import.test"); err == nil {
+		t.Error("expected no users from a file with errors")
+	}
+}
+
+func TestImportCreatesUsersAndInvites(t *testing.T) {
+	c, _ := adminClient(t, "admin@create.import.test")
+
+	body := importCSV(c, "import", "\ufeffEmail,Name,Role,DOB,Password\n"+
+		"pw@create.import.test,With Password,student,2002-04-05,secret123\n"+
+		"\n"+
+		"invite@create.import.test,Invited,instructor,1985-06-07,\n")
+	if !strings.Contains(body, "2 users imported. 1 were emailed an invitation") {
+		t.Fatalf("expected a summary, got %q", body)
+	}
+
+	ctx := context.Background()
+	invited, err := testRepo.GetUserByEmail(ctx, "invite@create.import.test")
+	if err != nil || invited.Role != models.RoleInstructor || invited.DOB.Format("2006-01-02") != "1985-06-07" {
+		t.Fatalf("expected the invited instructor, got %+v %v", invited, err)
+	}
+
+	// the password row can log in straight away
+	if msg := tryLogin(t, "pw@create.import.test", "secret123"); msg != "redirect /" {
+		t.Errorf("expected the imported password to work, got %q", msg)
+	}
+
+	msg, ok := testMail.LastTo("invite@create.import.test")
+	if !ok || !strings.Contains(msg.Text, "choose your password") {
+		t.Fatalf("expected an invitation, got %+v", msg)
+	}
+	link := resetLinkPattern.FindStringSubmatch(msg.Text)
+	if link == nil {
+		t.Fatalf("no link in invitation %q", msg.Text)
+	}
+
+	rr := newTestClient(t).postForm("/reset-password", url.Values{"token": {link[1]}, "password": {"chosen123"}, "confirm_password": {"chosen123"}})
+	if rr.Header().Get("HX-Location") != "/login?reset=done" {
+		t.Fatalf("expected the invitation to set a password, got %d %q", rr.Code, rr.Body.String())
+	}
+	if got, _ := testRepo.GetUserByID(ctx, invited.ID); !got.EmailVerified() {
+		t.Error("expected accepting the invitation to verify the address")
+	}
+
+	if events := auditEvents(t, "invite@create.import.test", models.AuditUserCreate); len(events) != 1 || events[0].ActorEmail != "admin@create.import.test" {
+		t.Errorf("expected the import in the audit log, got %+v", events)
+	}
+}
+
+func TestImportRejectsBadFiles(t *testing.T) {
+	c, _ := adminClient(t, "admin@files.import.test")
+
+	for content, want := range map[string]string{
+		"":                            "The file is empty",
+		"name,email,role\n":           `The file needs a &#34;dob&#34; column`,
+		"name,email,role,dob,age\n":   `Unknown column &#34;age&#34;`,
+		"name,email,role,dob,Email\n": `The &#34;email&#34; column appears more than once`,
+		"name,email,role,dob\n":       "The file has no users in it",
+	} {
+		if body := importCSV(c, "preview", content); !strings.Contains(body, want) {
+			t.Errorf("%q: expected %q, got %q", content, want, body)
+		}
+	}
+}
@@ -0,0 +1,186 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stackninja.pro/goth/internals/handlers"
+	"github.com/stackninja.pro/goth/internals/models"
+	"github.com/stackninja.pro/goth/internals/ratelimit"
+	"github.com/stackninja.pro/goth/internals/totp"
+)
+
+var unlockLinkPattern = regexp.MustCompile(`/unlock-account\?token=[A-Za-z0-9_.%-]+`)
+
+// tryLogin submits the login form and returns the message shown
+func tryLogin(t *testing.T, email, password string) string {
+	t.Helper()
+	rr := newTestClient(t).postForm("/login", url.Values{"email": {email}, "password": {password}})
+	if loc := rr.Header().Get("HX-Location"); loc != "" {
+		return "redirect " + loc
+	}
+	return rr.Body.String()
+}
+
+// setLoginPolicy changes the failure limits for one test
+func setLoginPolicy(t *testing.T, free, threshold int) {
+	t.Helper()
+	saved := testApp.Login
+	testApp.Login.FreeFailures = free
+	testApp.Login.LockoutThreshold = threshold
+	t.Cleanup(func() { testApp.Login = saved })
+}
+
+// setAccountLimit swaps the per-account login bucket for one test
+func setAccountLimit(t *testing.T, burst int) {
+	t.Helper()
+	saved := handlers.Repo.AccountLimiter
+	handlers.Repo.AccountLimiter = ratelimit.New(burst, time.Minute)
+	t.Cleanup(func() { handlers.Repo.AccountLimiter = saved })
+}
+
+func TestLoginAccountRateLimit(t *testing.T) {
+	setAccountLimit(t, 2)
+
+	createTestUser(t, "Bucket", "bucket@example.com", "secret123")
+
+	tryLogin(t, "bucket@example.com", "secret123")
+	tryLogin(t, "BUCKET@example.com", "secret123")
+
+	c := newTestClient(t)
+	rr := c.postForm("/login", url.Values{"email": {"bucket@example.com"}, "password": {"secret123"}})
+	if !strings.Contains(rr.Body.String(), "Too many login attempts") || rr.Header().Get("Retry-After") == "" {
+		t.Fatalf("expected the third attempt to be throttled, got %q", rr.Body.String())
+	}
+
+	// unknown addresses are limited the same way
+	tryLogin(t, "nobody@example.com", "x")
+	tryLogin(t, "nobody@example.com", "x")
+	if msg := tryLogin(t, "nobody@example.com", "x"); !strings.Contains(msg, "Too many login attempts") {
+		t.Errorf("expected unknown emails to be throttled too, got %q", msg)
+	}
+}
+
+func TestLoginProgressiveDelay(t *testing.T) {
+	setLoginPolicy(t, 2, 10)
+	createTestUser(t, "Slow", "slow@example.com", "secret123")
+
+	for range 2 {
+		if msg := tryLogin(t, "slow@example.com", "wrong"); !strings.Contains(msg, "Invalid email or password") {
+			t.Fatalf("expected a plain failure, got %q", msg)
+		}
+	}
+
+	// even the right password waits out the delay
+	if msg := tryLogin(t, "slow@example.com", "secret123"); !strings.Contains(msg, "Please wait 1 second") {
+		t.Fatalf("expected a delay, got %q", msg)
+	}
+}
+
+func TestLoginLockoutAndUnlockEmail(t *testing.T) {
+	setLoginPolicy(t, 5, 3)
+	user := createTestUser(t, "Locked", "locked@example.com", "secret123")
+
+	tryLogin(t, "locked@example.com", "wrong")
+	tryLogin(t, "locked@example.com", "wrong")
+	if msg := tryLogin(t, "locked@example.com", "wrong"); !strings.Contains(msg, "This account is locked for 15 minutes") {
+		t.Fatalf("expected a lockout, got %q", msg)
+	}
+	if msg := tryLogin(t, "locked@example.com", "secret123"); !strings.Contains(msg, "This account is locked") {
+		t.Fatalf("expected the right password to be refused while locked, got %q", msg)
+	}
+
+	msg, ok := testMail.LastTo("locked@example.com")
+	if !ok {
+		t.Fatal("no unlock email sent")
+	}
+	link := unlockLinkPattern.FindString(msg.Text)
+	if link == "" {
+		t.Fatalf("no unlock link in %q", msg.Text)
+	}
+
+	if rr := newTestClient(t).get(link); !strings.Contains(rr.Body.String(), "Your account is unlocked") {
+		t.Fatalf("expected the link to unlock, got %q", rr.Body.String())
+	}
+	if rr := newTestClient(t).get(link); !strings.Contains(rr.Body.String(), "already ended") {
+		t.Error("expected the link to work only once")
+	}
+	if msg := tryLogin(t, "locked@example.com", "secret123"); msg != "redirect /" {
+		t.Errorf("expected to log in after unlocking, got %q", msg)
+	}
+
+	// a second lockout lasts twice as long
+	tryLogin(t, "locked@example.com", "wrong")
+	tryLogin(t, "locked@example.com", "wrong")
+	tryLogin(t, "locked@example.com", "wrong")
+	tryLogin(t, "locked@example.com", "wrong")
+	tryLogin(t, "locked@example.com", "wrong")
+	f, _ := testRepo.GetLoginFailures(context.Background(), user.ID)
+	if !f.Locked(time.Now()) || f.Lockouts != 1 {
+		t.Fatalf("expected a fresh lockout after the successful login, got %+v", f)
+	}
+}
+
+func TestTwoFactorPasswordStepKeepsFailures(t *testing.T) {
+	setLoginPolicy(t, 5, 10)
+	user := createTestUser(t, "Half Way", "half-way@example.com", "secret123")
+	c := newTestClient(t)
+	c.login("half-way@example.com", "secret123")
+	secret, _ := enableTwoFactor(t, c)
+
+	tryLogin(t, "half-way@example.com", "wrong")
+	tryLogin(t, "half-way@example.com", "wrong")
+
+	// the right password alone doesn't wipe the failures
+	c = startLogin(t, "half-way@example.com", "secret123")
+	if f, _ := testRepo.GetLoginFailures(context.Background(), user.ID); f.Failures != 2 {
+		t.Fatalf("expected 2 failures after the password step, got %+v", f)
+	}
+
+	code, _ := totp.Code(secret, totp.Counter(time.Now())+1)
+	if rr := c.postForm("/login/2fa", url.Values{"code": {code}}); rr.Header().Get("HX-Location") != "/" {
+		t.Fatalf("expected the code to finish the login, got %q", rr.Body.String())
+	}
+	if f, _ := testRepo.GetLoginFailures(context.Background(), user.ID); f.Failures != 0 {
+		t.Errorf("expected the finished login to clear the failures, got %+v", f)
+	}
+}
+
+func TestAdminClearsLockout(t *testing.T) {
+	setLoginPolicy(t, 5, 2)
+	admin := createTestUser(t, "Lock Admin", "lock-admin@example.com", "secret123")
+	setRole(t, admin, models.RoleAdmin)
+	createTestUser(t, "Victim", "victim@example.com", "secret123")
+
+	tryLogin(t, "victim@example.com", "wrong")
+	tryLogin(t, "victim@example.com", "wrong")
+
+	a := newTestClient(t)
+	a.login("lock-admin@example.com", "secret123")
+	rr := a.get("/users/lockouts")
+	if rr.Code != http.StatusOK || !strings.Contains(rr.Body.String(), "victim@example.com") {
+		t.Fatalf("expected the victim to be listed, got %d", rr.Code)
+	}
+
+	victim, _ := testRepo.GetUserByEmail(context.Background(), "victim@example.com")
+	rr = a.postForm("/users/"+victim.ID+"/unlock", nil)
+	if !strings.Contains(rr.Body.String(), "victim@example.com is unlocked") || strings.Contains(rr.Body.String(), "victim@example.com</span>") {
+		t.Fatalf("expected the lockout to be cleared, got %q", rr.Body.String())
+	}
+	if msg := tryLogin(t, "victim@example.com", "secret123"); msg != "redirect /" {
+		t.Errorf("expected the victim to log in, got %q", msg)
+	}
+
+	// only admins see the page
+	createTestUser(t, "Lock Student", "lock-student@example.com", "secret123")
+	s := newTestClient(t)
+	s.login("lock-student@example.com", "secret123")
+	if rr := s.get("/users/lockouts"); rr.Code != http.StatusForbidden {
+		t.Errorf("expected 403, got %d", rr.Code)
+	}
+}
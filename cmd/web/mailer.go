@@ -0,0 +1,23 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/stackninja.pro/goth/internals/mail"
+	"github.com/stackninja.pro/goth/src/config"
+)
+
+// openMailer builds the mail driver named in the config
+func openMailer(app *config.AppConfig) (mail.Sender, error) {
+	m := app.Mail
+	switch m.Driver {
+	case config.MailSMTP:
+		return mail.NewSMTP(m.SMTPHost, m.SMTPPort, m.SMTPUsername, m.SMTPPassword, m.From), nil
+	case config.MailFile:
+		return mail.NewFileDrop(m.FileDir, m.From), nil
+	case config.MailMemory:
+		return mail.NewMemory(), nil
+	default:
+		return nil, fmt.Errorf("unknown mail driver %q", m.Driver)
+	}
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+
+	"github.com/stackninja.pro/goth/internals/driver"
+	"github.com/stackninja.pro/goth/internals/migrations"
+	"github.com/stackninja.pro/goth/src/config"
+)
+
+const migrateUsage = `usage: web migrate <command>
+
+commands:
+  up              apply all pending migrations
+  down [steps]    roll back the last steps migrations (default 1)
+  status          list migrations and when they were applied
+  create <name>   write a new empty up/down pair into -dir`
+
+// runMigrate implements the "migrate" subcommand
+func runMigrate(ctx context.Context, configPath string, args []string) error {
+	fs := flag.NewFlagSet("migrate", flag.ContinueOnError)
+	dir := fs.String("dir", "internals/migrations/sql", "directory new migrations are written to")
+	fs.Usage = func() { fmt.Fprintln(fs.Output(), migrateUsage); fs.PrintDefaults() }
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return errors.New("migrate: missing command")
+	}
+
+	cmd, rest := fs.Arg(0), fs.Args()[1:]
+
+	// create only touches the filesystem, so it needs no database
+	if cmd == "create" {
+		if len(rest) != 1 {
+			return errors.New("migrate create: expected exactly one name")
+		}
+		up, down, err := migrations.Create(*dir, rest[0])
+		if err != nil {
+			return err
+		}
+		log.Println("📝 Created", up)
+		log.Println("📝 Created", down)
+		return nil
+	}
+
+	app, err := config.Read(configPath)
+	if err != nil {
+		return err
+	}
+	if err := app.ValidateDatabase(); err != nil {
+		return err
+	}
+
+	conn, err := driver.ConnectToDB(ctx, app.Database)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	migrator, err := migrations.New(conn.Pool)
+	if err != nil {
+		return err
+	}
+
+	switch cmd {
+	case "up":
+		ran, err := migrator.Up(ctx)
+		for _, m := range ran {
+			log.Printf("⬆️ Applied %04d_%s", m.Version, m.Name)
+		}
+		if err == nil && len(ran) == 0 {
+			log.Println("✅ Database is up to date")
+		}
+		return err
+
+	case "down":
+		steps := 1
+		if len(rest) > 0 {
+			if steps, err = strconv.Atoi(rest[0]); err != nil || steps < 1 {
+				return fmt.Errorf("migrate down: steps must be a positive number, got %q", rest[0])
+			}
+		}
+		ran, err := migrator.Down(ctx, steps)
+		for _, m := range ran {
+			log.Printf("⬇️ Reverted %04d_%s", m.Version, m.Name)
+		}
+		return err
+
+	case "status":
+		statuses, err := migrator.Status(ctx)
+		if err != nil {
+			return err
+		}
+		for _, s := range statuses {
+			applied := "pending"
+			if s.AppliedAt != nil {
+				applied = s.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+			fmt.Fprintf(os.Stdout, "%04d  %-40s  %s\n", s.Version, s.Name, applied)
+		}
+		return nil
+	}
+
+	fs.Usage()
+	return fmt.Errorf("migrate: unknown command %q", cmd)
+}
+
+// autoMigrate applies pending migrations during startup
+func autoMigrate(ctx context.Context, conn *driver.DB) error {
+	migrator, err := migrations.New(conn.Pool)
+	if err != nil {
+		return err
+	}
+
+	ran, err := migrator.Up(ctx)
+	for _, m := range ran {
+		log.Printf("⬆️ Applied %04d_%s", m.Version, m.Name)
+	}
+	return err
+}
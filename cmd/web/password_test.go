@@ -0,0 +1,153 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stackninja.pro/goth/internals/handlers"
+	"github.com/stackninja.pro/goth/internals/models"
+	"github.com/stackninja.pro/goth/internals/tokens"
+)
+
+var resetLinkPattern = regexp.MustCompile(`/reset-password\?token=([A-Za-z0-9_-]+)`)
+
+// requestReset submits the forgot password form and returns the token
+// emailed to the address, if any
+func requestReset(t *testing.T, email string) string {
+	t.Helper()
+
+	before := len(testMail.Sent())
+	rr := newTestClient(t).postForm("/forgot-password", url.Values{"email": {email}})
+	if !strings.Contains(rr.Body.String(), "If an account exists for that address") {
+		t.Fatalf("expected the generic confirmation, got %d %q", rr.Code, rr.Body.String())
+	}
+	handlers.Repo.WaitForMail()
+
+	sent := testMail.Sent()
+	if len(sent) == before {
+		return ""
+	}
+	m := resetLinkPattern.FindStringSubmatch(sent[len(sent)-1].Text)
+	if m == nil {
+		t.Fatalf("no reset link in %q", sent[len(sent)-1].Text)
+	}
+	return m[1]
+}
+
+func TestPasswordReset(t *testing.T) {
+	createTestUser(t, "Forgetful", "forgetful@example.com", "secret123")
+
+	signedIn := newTestClient(t)
+	signedIn.login("forgetful@example.com", "secret123")
+
+	token := requestReset(t, "forgetful@example.com")
+	if token == "" {
+		t.Fatal("expected a reset email")
+	}
+	if msg, _ := testMail.LastTo("forgetful@example.com"); !strings.HasPrefix(msg.Text, "Hi Forgetful") {
+		t.Errorf("unexpected email body %q", msg.Text)
+	}
+
+	c := newTestClient(t)
+	if rr := c.get("/reset-password?token=" + token); strings.Contains(rr.Body.String(), "invalid or has expired") {
+		t.Fatal("expected a fresh token to be accepted")
+	}
+
+	rr := c.postForm("/reset-password", url.Values{"token": {token}, "password": {"new-secret"}, "confirm_password": {"typo"}})
+	if !strings.Contains(rr.Body.String(), "Passwords do not match") {
+		t.Errorf("expected a mismatch error, got %q", rr.Body.String())
+	}
+
+	rr = c.postForm("/reset-password", url.Values{"token": {token}, "password": {"new-secret"}, "confirm_password": {"new-secret"}})
+	if rr.Header().Get("HX-Location") != "/login?reset=done" {
+		t.Fatalf("expected a redirect to login, got %d %q", rr.Code, rr.Body.String())
+	}
+
+	if rr := signedIn.get("/"); rr.Code != http.StatusSeeOther {
+		t.Errorf("expected other sessions to be signed out, got %d", rr.Code)
+	}
+
+	newTestClient(t).login("forgetful@example.com", "new-secret")
+	if rr := newTestClient(t).postForm("/login", url.Values{"email": {"forgetful@example.com"}, "password": {"secret123"}}); rr.Header().Get("HX-Location") != "" {
+		t.Error("expected the old password to stop working")
+	}
+
+	// the token is single use
+	rr = c.postForm("/reset-password", url.Values{"token": {token}, "password": {"again"}, "confirm_password": {"again"}})
+	if !strings.Contains(rr.Body.String(), "invalid or has expired") {
+		t.Errorf("expected a used token to be rejected, got %q", rr.Body.String())
+	}
+}
+
+func TestPasswordResetNewTokenSpendsOlder(t *testing.T) {
+	createTestUser(t, "Twice", "twice@example.com", "secret123")
+
+	first := requestReset(t, "twice@example.com")
+	second := requestReset(t, "twice@example.com")
+
+	c := newTestClient(t)
+	rr := c.postForm("/reset-password", url.Values{"token": {second}, "password": {"new-secret"}, "confirm_password": {"new-secret"}})
+	if rr.Header().Get("HX-Location") == "" {
+		t.Fatalf("expected the reset to succeed, got %q", rr.Body.String())
+	}
+
+	rr = c.postForm("/reset-password", url.Values{"token": {first}, "password": {"other"}, "confirm_password": {"other"}})
+	if !strings.Contains(rr.Body.String(), "invalid or has expired") {
+		t.Errorf("expected the older token to be spent, got %q", rr.Body.String())
+	}
+}
+
+func TestPasswordResetExpired(t *testing.T) {
+	user := createTestUser(t, "Late", "late@example.com", "secret123")
+
+	raw, hash := tokens.New()
+	if err := testRepo.CreatePasswordReset(context.Background(), user.ID, hash, time.Now().Add(-time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+
+	c := newTestClient(t)
+	if rr := c.get("/reset-password?token=" + raw); !strings.Contains(rr.Body.String(), "invalid or has expired") {
+		t.Error("expected the reset page to reject an expired token")
+	}
+	rr := c.postForm("/reset-password", url.Values{"token": {raw}, "password": {"new-secret"}, "confirm_password": {"new-secret"}})
+	if !strings.Contains(rr.Body.String(), "invalid or has expired") {
+		t.Errorf("expected an expired token to be rejected, got %q", rr.Body.String())
+	}
+}
+
+func TestPasswordResetSendsNothingForUnknownOrInactive(t *testing.T) {
+	user := createTestUser(t, "Suspended", "suspended-reset@example.com", "secret123")
+	if _, err := testRepo.UpdateUsersStatus(context.Background(), []string{user.ID}, models.StatusSuspended); err != nil {
+		t.Fatal(err)
+	}
+
+	if token := requestReset(t, "nobody@example.com"); token != "" {
+		t.Error("expected no email for an unknown address")
+	}
+	if token := requestReset(t, "suspended-reset@example.com"); token != "" {
+		t.Error("expected no email for a suspended account")
+	}
+}
+
+func TestPasswordResetIsThrottled(t *testing.T) {
+	setAccountLimit(t, 2)
+	createTestUser(t, "Flooded", "flooded@example.com", "secret123")
+
+	requestReset(t, "flooded@example.com")
+	requestReset(t, "FLOODED@example.com")
+
+	before := len(testMail.Sent())
+	rr := newTestClient(t).postForm("/forgot-password", url.Values{"email": {"flooded@example.com"}})
+	handlers.Repo.WaitForMail()
+	if !strings.Contains(rr.Body.String(), "Too many reset requests") || rr.Header().Get("Retry-After") == "" {
+		t.Fatalf("expected the third request to be throttled, got %q", rr.Body.String())
+	}
+	if len(testMail.Sent()) != before {
+		t.Error("expected no email for a throttled request")
+	}
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+
+	"github.com/stackninja.pro/goth/internals/driver"
+	"github.com/stackninja.pro/goth/internals/models"
+	"github.com/stackninja.pro/goth/internals/repository/dbrepo"
+	"github.com/stackninja.pro/goth/internals/sessionstore"
+	"github.com/stackninja.pro/goth/src/config"
+)
+
+// runSetRole implements "role <email> <role>", which is how the first admin is created
+func runSetRole(ctx context.Context, configPath string, args []string) error {
+	if len(args) != 2 {
+		return errors.New("usage: web role <email> <admin|instructor|student>")
+	}
+
+	role, err := models.ParseRole(args[1])
+	if err != nil {
+		return err
+	}
+
+	app, err := config.Read(configPath)
+	if err != nil {
+		return err
+	}
+	if err := app.ValidateDatabase(); err != nil {
+		return err
+	}
+
+	conn, err := driver.ConnectToDB(ctx, app.Database)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	repo := dbrepo.NewPostgresRepo(app, conn.Pool)
+	user, err := repo.GetUserByEmail(ctx, args[0])
+	if err != nil {
+		return fmt.Errorf("find %s: %w", args[0], err)
+	}
+
+	if err := repo.UpdateUserRole(ctx, user.ID, role); err != nil {
+		return err
+	}
+
+	// a privilege change ends every session, so the user signs in again
+	// with a fresh one
+	revoked, err := sessionstore.NewPGStore(conn.Pool).RevokeAll(ctx, user.ID, "")
+	if err != nil {
+		return fmt.Errorf("revoke sessions: %w", err)
+	}
+
+	log.Printf("✅ %s is now %s (was %s), %d sessions signed out", user.Email, role.Label(), user.Role.Label(), revoked)
+	return nil
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stackninja.pro/goth/internals/handlers"
+	"github.com/stackninja.pro/goth/internals/models"
+)
+
+func TestRolePermissionMatrix(t *testing.T) {
+	tests := []struct {
+		role models.Role
+		perm models.Permission
+		want bool
+	}{
+		{models.RoleAdmin, models.PermManageUsers, true},
+		{models.RoleAdmin, models.PermViewDiagnostics, true},
+		{models.RoleInstructor, models.PermManageUsers, false},
+		{models.RoleStudent, models.PermManageUsers, false},
+		{models.RoleStudent, models.PermEditOwnProfile, true},
+		{models.RoleStudent, models.PermBrowseCourses, true},
+		{models.RoleStudent, models.PermTeachCourses, false},
+		{models.RoleInstructor, models.PermTeachCourses, true},
+		{models.RoleInstructor, models.PermManageCourses, false},
+		{models.RoleAdmin, models.PermManageCourses, true},
+		{models.RoleStudent, models.PermEnrollCourses, true},
+		{models.RoleInstructor, models.PermEnrollCourses, false},
+		{models.RoleAdmin, models.PermManageTerms, true},
+		{models.RoleInstructor, models.PermManageTerms, false},
+		{models.RoleAdmin, models.PermManageGrading, true},
+		{models.RoleInstructor, models.PermManageGrading, false},
+		{models.Role("bogus"), models.PermEditOwnProfile, false},
+	}
+
+	for _, tt := range tests {
+		if got := tt.role.Can(tt.perm); got != tt.want {
+			t.Errorf("%s.Can(%s) = %t, want %t", tt.role, tt.perm, got, tt.want)
+		}
+	}
+}
+
+func TestRequirePermission(t *testing.T) {
+	student := createTestUser(t, "Plain Student", "perm-student@example.com", "secret123")
+	admin := createTestUser(t, "The Admin", "perm-admin@example.com", "secret123")
+	setRole(t, admin, models.RoleAdmin)
+
+	c := newTestClient(t)
+	c.login(student.Email, "secret123")
+	if rr := c.get("/debug/db"); rr.Code != http.StatusForbidden {
+		t.Errorf("student: expected %d, got %d", http.StatusForbidden, rr.Code)
+	}
+
+	c = newTestClient(t)
+	c.login(admin.Email, "secret123")
+	// the in-memory setup has no pool, so getting past the check means a 503
+	if rr := c.get("/debug/db"); rr.Code != http.StatusServiceUnavailable {
+		t.Errorf("admin: expected %d, got %d", http.StatusServiceUnavailable, rr.Code)
+	}
+
+	// a role without PermEditOwnProfile can't reach the profile forms
+	nobody := createTestUser(t, "No Role", "perm-nobody@example.com", "secret123")
+	setRole(t, nobody, models.Role("bogus"))
+	c = newTestClient(t)
+	c.login(nobody.Email, "secret123")
+	if rr := c.get("/profile/edit"); rr.Code != http.StatusForbidden {
+		t.Errorf("no role: expected %d, got %d", http.StatusForbidden, rr.Code)
+	}
+}
+
+func TestRequireRole(t *testing.T) {
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})
+	h := handlers.Repo.RequireRole(models.RoleAdmin, models.RoleInstructor)(next)
+
+	tests := []struct {
+		user *models.User
+		want int
+	}{
+		{&models.User{Email: "admin@example.com", Role: models.RoleAdmin}, http.StatusNoContent},
+		{&models.User{Email: "teacher@example.com", Role: models.RoleInstructor}, http.StatusNoContent},
+		{&models.User{Email: "student@example.com", Role: models.RoleStudent}, http.StatusForbidden},
+		{nil, http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		if tt.user != nil {
+			r = r.WithContext(handlers.WithUser(r.Context(), tt.user))
+		}
+		rr := httptest.NewRecorder()
+		h.ServeHTTP(rr, r)
+		if rr.Code != tt.want {
+			t.Errorf("%+v: expected %d, got %d", tt.user, tt.want, rr.Code)
+		}
+	}
+}
+
+func TestProfileShowsRoleLabel(t *testing.T) {
+	user := createTestUser(t, "Role Label", "role-label@example.com", "secret123")
+	setRole(t, user, models.RoleInstructor)
+
+	c := newTestClient(t)
+	c.login(user.Email, "secret123")
+
+	if body := c.get("/").Body.String(); !strings.Contains(body, "Instructor") {
+		t.Error("expected the profile to show the Instructor role")
+	}
+}
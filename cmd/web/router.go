@@ -6,6 +6,8 @@ import (
 
 	"github.com/go-chi/chi/v5"
 	"github.com/stackninja.pro/goth/internals/handlers"
+	"github.com/stackninja.pro/goth/internals/models"
+	"github.com/stackninja.pro/goth/internals/storage"
 )
 
 func Route() chi.Router {
@@ -16,28 +18,173 @@ func Route() chi.Router {
 	staticDir := http.Dir(filepath.Join(workDir, "web/static"))
 	r.Handle("/static/*", http.StripPrefix("/static/", http.FileServer(staticDir)))
 
-	// in your router setup
-	fs := http.FileServer(http.Dir("./uploads"))
-	r.Handle("/uploads/*", http.StripPrefix("/uploads", fs))
+	// uploads are only ours to serve when they are kept on local disk
+	if local, ok := handlers.Repo.App.Blob.(*storage.Local); ok {
+		r.Handle(local.BaseURL+"/*", http.StripPrefix(local.BaseURL, local.Handler()))
+	}
 
-	// pages
-	r.Get("/", handlers.Repo.HomePage)
-	r.Get("/about", handlers.Repo.AboutPage)
-	r.Get("/profile/edit", handlers.Repo.EditProfilePage)
-	r.Post("/profile/update", handlers.Repo.UpdateProfile)
-	r.Get("/profile/edit/avatar", handlers.Repo.ChangeAvatarPage)
-	r.Post("/upload-avatar", handlers.Repo.UploadAvatar)
+	// everything below is a page or form post and needs a CSRF token
+	pages := r.With(handlers.Repo.CSRF)
+
+	// pages that need a logged in user
+	pages.Group(func(r chi.Router) {
+		r.Use(handlers.Repo.RequireAuth)
+
+		// unverified users can still fix a mistyped address and ask for a new link
+		r.Get("/verify-email/pending", handlers.Repo.VerifyEmailNotice)
+		r.Post("/verify-email/resend", handlers.Repo.ResendVerification)
+		r.Group(func(r chi.Router) {
+			r.Use(handlers.Repo.RequirePermission(models.PermEditOwnProfile))
+
+			r.Get("/profile/edit", handlers.Repo.EditProfilePage)
+			r.Post("/profile/update", handlers.Repo.UpdateProfile)
+		})
+
+		r.Group(func(r chi.Router) {
+			r.Use(handlers.Repo.RequireVerified)
+
+			// users whose role requires two-factor authentication can set it up
+			r.Get("/settings/two-factor", handlers.Repo.TwoFactorSettingsPage)
+			r.Post("/settings/two-factor/enable", handlers.Repo.EnableTwoFactor)
+			r.Post("/settings/two-factor/disable", handlers.Repo.DisableTwoFactor)
+			r.Post("/settings/two-factor/recovery-codes", handlers.Repo.RegenerateRecoveryCodes)
+
+			r.Group(func(r chi.Router) {
+				r.Use(handlers.Repo.RequireTwoFactor)
+
+				r.Get("/", handlers.Repo.HomePage)
+				r.Get("/about", handlers.Repo.AboutPage)
+				r.Group(func(r chi.Router) {
+					r.Use(handlers.Repo.RequirePermission(models.PermEditOwnProfile))
+
+					r.Get("/profile/edit/avatar", handlers.Repo.ChangeAvatarPage)
+					r.Post("/upload-avatar", handlers.Repo.UploadAvatar)
+				})
+
+				// account settings
+				r.Get("/settings", handlers.Repo.SettingsPage)
+				r.Post("/settings/password", handlers.Repo.ChangePassword)
+				r.Post("/settings/preferences", handlers.Repo.SavePreferences)
+				r.Post("/settings/delete", handlers.Repo.DeleteAccount)
+
+				// signed in devices
+				r.Get("/settings/sessions", handlers.Repo.SessionsPage)
+				r.Post("/settings/sessions/revoke-others", handlers.Repo.RevokeOtherSessions)
+				r.Post("/settings/sessions/{id}/revoke", handlers.Repo.RevokeSession)
+
+				// course catalog, and the courses instructors teach
+				r.Group(func(r chi.Router) {
+					r.Use(handlers.Repo.RequirePermission(models.PermBrowseCourses))
+
+					r.Get("/courses", handlers.Repo.CoursesPage)
+					r.Get("/courses/{id}", handlers.Repo.CoursePage)
+				})
+				r.Group(func(r chi.Router) {
+					r.Use(handlers.Repo.RequirePermission(models.PermEnrollCourses))
+
+					r.Get("/courses/enrolled", handlers.Repo.MyCoursesPage)
+					r.Post("/courses/{id}/enroll", handlers.Repo.EnrollCourse)
+					r.Post("/courses/{id}/drop", handlers.Repo.DropCourse)
+				})
+				r.Group(func(r chi.Router) {
+					r.Use(handlers.Repo.RequirePermission(models.PermTeachCourses))
+
+					r.Get("/courses/manage", handlers.Repo.TeachingPage)
+					r.Get("/courses/new", handlers.Repo.NewCoursePage)
+					r.Post("/courses/new", handlers.Repo.CreateCourse)
+					r.Get("/courses/{id}/edit", handlers.Repo.EditCoursePage)
+					r.Post("/courses/{id}/edit", handlers.Repo.UpdateCourse)
+					r.Post("/courses/{id}/status", handlers.Repo.ChangeCourseStatus)
+					r.Get("/courses/{id}/roster", handlers.Repo.RosterPage)
+
+					// gradebooks
+					r.Get("/courses/{id}/gradebook", handlers.Repo.GradebookPage)
+					r.Post("/courses/{id}/gradebook/scores", handlers.Repo.SaveScore)
+					r.Get("/courses/{id}/gradebook/history", handlers.Repo.ScoreHistoryPage)
+					r.Post("/courses/{id}/gradebook/publish", handlers.Repo.PublishGrades)
+					r.Post("/courses/{id}/assessments", handlers.Repo.CreateAssessment)
+					r.Post("/courses/{id}/assessments/{aid}", handlers.Repo.UpdateAssessment)
+					r.Post("/courses/{id}/assessments/{aid}/delete", handlers.Repo.DeleteAssessment)
+				})
+
+				// academic sessions, their terms and the terms' dates
+				r.Group(func(r chi.Router) {
+					r.Use(handlers.Repo.RequirePermission(models.PermManageTerms))
+
+					r.Get("/terms", handlers.Repo.TermsPage)
+					r.Post("/terms", handlers.Repo.CreateTerm)
+					r.Post("/terms/{id}", handlers.Repo.UpdateTerm)
+					r.Post("/terms/{id}/current", handlers.Repo.SetCurrentTerm)
+					r.Post("/terms/sessions", handlers.Repo.CreateSession)
+					r.Post("/terms/sessions/{id}", handlers.Repo.UpdateSession)
+					r.Post("/terms/sessions/{id}/delete", handlers.Repo.DeleteSession)
+				})
+
+				// grading scales and the programmes graded on them
+				r.Group(func(r chi.Router) {
+					r.Use(handlers.Repo.RequirePermission(models.PermManageGrading))
+
+					r.Get("/grading", handlers.Repo.GradingPage)
+					r.Post("/grading/scales", handlers.Repo.CreateScale)
+					r.Post("/grading/scales/{id}", handlers.Repo.UpdateScale)
+					r.Post("/grading/scales/{id}/default", handlers.Repo.SetDefaultScale)
+					r.Post("/grading/scales/{id}/delete", handlers.Repo.DeleteScale)
+					r.Post("/grading/programmes", handlers.Repo.CreateProgramme)
+					r.Get("/grading/programmes/{id}", handlers.Repo.ProgrammePage)
+					r.Post("/grading/programmes/{id}", handlers.Repo.UpdateProgramme)
+					r.Post("/grading/programmes/{id}/delete", handlers.Repo.DeleteProgramme)
+					r.Post("/grading/programmes/{id}/students", handlers.Repo.AddProgrammeStudent)
+					r.Post("/grading/programmes/{id}/students/{sid}/remove", handlers.Repo.RemoveProgrammeStudent)
+				})
+
+				// user management
+				r.Group(func(r chi.Router) {
+					r.Use(handlers.Repo.RequirePermission(models.PermManageUsers))
+
+					r.Get("/users", handlers.Repo.UsersPage)
+					r.Get("/users/export", handlers.Repo.ExportUsers)
+					r.Post("/users/bulk", handlers.Repo.BulkUpdateUsers)
+					r.Get("/users/import", handlers.Repo.ImportUsersPage)
+					r.Post("/users/import", handlers.Repo.ImportUsers)
+					r.Post("/users/{id}/role", handlers.Repo.ChangeUserRole)
+					r.Post("/users/{id}/status", handlers.Repo.ChangeUserStatus)
+					r.Post("/users/{id}/restore", handlers.Repo.RestoreUser)
+					r.Get("/users/policy", handlers.Repo.TwoFactorPolicyPage)
+					r.Post("/users/policy", handlers.Repo.SaveTwoFactorPolicy)
+					r.Get("/users/lockouts", handlers.Repo.LockoutsPage)
+					r.Post("/users/{id}/unlock", handlers.Repo.UnlockUser)
+					r.Get("/users/audit", handlers.Repo.AuditPage)
+					r.Get("/users/audit/export", handlers.Repo.ExportAudit)
+				})
+
+				// diagnostics
+				r.With(handlers.Repo.RequirePermission(models.PermViewDiagnostics)).Get("/debug/db", handlers.Repo.DBStats)
+			})
+		})
+	})
 
 	// registration routes
-	r.Get("/register", handlers.Repo.ShowRegisterPage)
-	r.Post("/register", handlers.Repo.RegisterUser)
+	pages.Get("/register", handlers.Repo.ShowRegisterPage)
+	pages.Post("/register", handlers.Repo.RegisterUser)
 
 	// login routes
-	r.Get("/login", handlers.Repo.LoginPage)
-	r.Post("/login", handlers.Repo.LoginUser)
+	pages.Get("/login", handlers.Repo.LoginPage)
+	pages.Post("/login", handlers.Repo.LoginUser)
+	pages.Get("/login/2fa", handlers.Repo.TwoFactorLoginPage)
+	pages.Post("/login/2fa", handlers.Repo.TwoFactorLogin)
+
+	// password reset routes
+	pages.Get("/forgot-password", handlers.Repo.ForgotPasswordPage)
+	pages.Post("/forgot-password", handlers.Repo.ForgotPassword)
+	pages.Get("/reset-password", handlers.Repo.ResetPasswordPage)
+	pages.Post("/reset-password", handlers.Repo.ResetPassword)
+
+	// links from verification emails, which may be opened signed out
+	pages.Get("/verify-email", handlers.Repo.VerifyEmail)
+	pages.Get("/unlock-account", handlers.Repo.UnlockAccount)
 
 	// logout route
-	r.Get("/logout", handlers.Repo.LogoutUser)
+	pages.Post("/logout", handlers.Repo.LogoutUser)
 
 	return r
 }
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"net/http"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+var revokePattern = regexp.MustCompile(`/settings/sessions/([0-9a-f]{64})/revoke`)
+
+func TestLogoutRevokesSessionServerSide(t *testing.T) {
+	createTestUser(t, "Replay User", "replay@example.com", "secret123")
+
+	c := newTestClient(t)
+	c.login("replay@example.com", "secret123")
+
+	stolen := newTestClient(t)
+	stolen.cookies = map[string]*http.Cookie{}
+	for name, ck := range c.cookies {
+		stolen.cookies[name] = ck
+	}
+
+	c.postForm("/logout", nil)
+
+	if rr := stolen.get("/"); rr.Code != http.StatusSeeOther {
+		t.Errorf("expected a copied cookie to stop working after logout, got %d", rr.Code)
+	}
+}
+
+func TestLoginRotatesSession(t *testing.T) {
+	createTestUser(t, "Rotate User", "rotate@example.com", "secret123")
+
+	c := newTestClient(t)
+	before := c.cookies[sessionCookie].Value
+
+	planted := newTestClient(t)
+	planted.cookies = map[string]*http.Cookie{sessionCookie: c.cookies[sessionCookie]}
+
+	c.login("rotate@example.com", "secret123")
+	if c.cookies[sessionCookie].Value == before {
+		t.Fatal("expected a new session cookie after login")
+	}
+
+	if rr := planted.get("/"); rr.Code != http.StatusSeeOther {
+		t.Errorf("expected the pre-login session to stay anonymous, got %d", rr.Code)
+	}
+}
+
+func TestSessionsPageAndRevoke(t *testing.T) {
+	createTestUser(t, "Many Devices", "devices@example.com", "secret123")
+
+	laptop := newTestClient(t)
+	laptop.login("devices@example.com", "secret123")
+	phone := newTestClient(t)
+	phone.login("devices@example.com", "secret123")
+	tablet := newTestClient(t)
+	tablet.login("devices@example.com", "secret123")
+
+	rr := laptop.get("/settings/sessions")
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected sessions page, got %d", rr.Code)
+	}
+	if !strings.Contains(rr.Body.String(), "This device") {
+		t.Error("expected the current session to be marked")
+	}
+	ids := revokePattern.FindAllStringSubmatch(rr.Body.String(), -1)
+	if len(ids) != 2 {
+		t.Fatalf("expected 2 other sessions listed, got %d", len(ids))
+	}
+
+	// revoke one device
+	rr = laptop.postForm("/settings/sessions/"+ids[0][1]+"/revoke", nil)
+	if rr.Header().Get("HX-Location") != "/settings/sessions" {
+		t.Fatalf("revoke failed: %d %q", rr.Code, rr.Body.String())
+	}
+	if got := len(revokePattern.FindAllString(laptop.get("/settings/sessions").Body.String(), -1)); got != 1 {
+		t.Errorf("expected 1 other session after revoking, got %d", got)
+	}
+
+	// someone else can't revoke our sessions
+	other := newTestClient(t)
+	createTestUser(t, "Intruder", "intruder@example.com", "secret123")
+	other.login("intruder@example.com", "secret123")
+	if rr := other.postForm("/settings/sessions/"+ids[1][1]+"/revoke", nil); rr.Code != http.StatusNotFound {
+		t.Errorf("expected 404 revoking another user's session, got %d", rr.Code)
+	}
+
+	// and the rest
+	rr = laptop.postForm("/settings/sessions/revoke-others", nil)
+	if rr.Header().Get("HX-Location") != "/settings/sessions" {
+		t.Fatalf("revoke others failed: %d %q", rr.Code, rr.Body.String())
+	}
+
+	for name, c := range map[string]*testClient{"phone": phone, "tablet": tablet} {
+		if rr := c.get("/"); rr.Code != http.StatusSeeOther {
+			t.Errorf("expected %s to be signed out, got %d", name, rr.Code)
+		}
+	}
+	if rr := laptop.get("/"); rr.Code != http.StatusOK {
+		t.Errorf("expected the current device to stay signed in, got %d", rr.Code)
+	}
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/stackninja.pro/goth/internals/models"
+)
+
+func TestChangePassword(t *testing.T) {
+	createTestUser(t, "Changer", "changer@example.com", "secret123")
+
+	c := newTestClient(t)
+	c.login("changer@example.com", "secret123")
+	other := newTestClient(t)
+	other.login("changer@example.com", "secret123")
+
+	if rr := c.get("/settings"); rr.Code != http.StatusOK || !strings.Contains(rr.Body.String(), "Change password") {
+		t.Fatalf("expected the settings page, got %d", rr.Code)
+	}
+
+	tests := []struct {
+		name string
+		form url.Values
+		msg  string
+	}{
+		{"missing", url.Values{}, "Current password is required"},
+		{"mismatch", url.Values{"current_password": {"secret123"}, "new_password": {"a"}, "confirm_password": {"b"}}, "Passwords do not match"},
+		{"wrong current", url.Values{"current_password": {"guess"}, "new_password": {"new-secret"}, "confirm_password": {"new-secret"}}, "Current password is incorrect"},
+		{"valid", url.Values{"current_password": {"secret123"}, "new_password": {"new-secret"}, "confirm_password": {"new-secret"}}, "Password changed"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if rr := c.postForm("/settings/password", tt.form); !strings.Contains(rr.Body.String(), tt.msg) {
+				t.Errorf("expected %q, got %q", tt.msg, rr.Body.String())
+			}
+		})
+	}
+
+	if rr := c.get("/settings"); rr.Code != http.StatusOK {
+		t.Errorf("expected this device to stay signed in, got %d", rr.Code)
+	}
+	if rr := other.get("/settings"); rr.Code != http.StatusSeeOther {
+		t.Errorf("expected other devices to be signed out, got %d", rr.Code)
+	}
+	newTestClient(t).login("changer@example.com", "new-secret")
+}
+
+func TestPreferencesChangeDateDisplay(t *testing.T) {
+	user := createTestUser(t, "Prefers ISO", "prefs@example.com", "secret123")
+
+	c := newTestClient(t)
+	c.login("prefs@example.com", "secret123")
+
+	if rr := c.get("/"); !strings.Contains(rr.Body.String(), "May 17, 2000") {
+		t.Error("expected the default long date format")
+	}
+
+	rr := c.postForm("/settings/preferences", url.Values{"timezone": {"Mars/Olympus"}, "date_format": {"iso"}})
+	if !strings.Contains(rr.Body.String(), "Please choose a timezone") {
+		t.Errorf("expected an unknown timezone to be rejected, got %q", rr.Body.String())
+	}
+
+	rr = c.postForm("/settings/preferences", url.Values{"timezone": {"Africa/Lagos"}, "date_format": {"iso"}, "notify_news": {"1"}})
+	if !strings.Contains(rr.Body.String(), "Preferences saved") {
+		t.Fatalf("expected preferences to save, got %q", rr.Body.String())
+	}
+
+	prefs, err := testRepo.GetPreferences(context.Background(), user.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := models.Preferences{UserID: user.ID, Timezone: "Africa/Lagos", DateFormat: models.DateISO, NotifyNews: true}
+	if prefs != want {
+		t.Errorf("expected %+v, got %+v", want, prefs)
+	}
+
+	if rr := c.get("/"); !strings.Contains(rr.Body.String(), "2000-05-17") {
+		t.Error("expected the date of birth in ISO format")
+	}
+}
+
+func TestDeleteAccount(t *testing.T) {
+	user := createTestUser(t, "Leaving", "leaving@example.com", "secret123")
+
+	c := newTestClient(t)
+	c.login("leaving@example.com", "secret123")
+
+	if rr := c.postForm("/settings/delete", url.Values{"password": {"wrong"}}); !strings.Contains(rr.Body.String(), "Password is incorrect") {
+		t.Fatalf("expected the wrong password to be refused, got %q", rr.Body.String())
+	}
+
+	rr := c.postForm("/settings/delete", url.Values{"password": {"secret123"}})
+	if rr.Header().Get("HX-Redirect") != "/login?deleted=1" {
+		t.Fatalf("expected a redirect to login, got %d %q", rr.Code, rr.Body.String())
+	}
+
+	if got, err := testRepo.GetUserByID(context.Background(), user.ID); err != nil || !got.Deleted() {
+		t.Errorf("expected the account to be marked deleted, got %+v, %v", got, err)
+	}
+	if rr := c.get("/"); rr.Code != http.StatusSeeOther {
+		t.Errorf("expected the session to end, got %d", rr.Code)
+	}
+}
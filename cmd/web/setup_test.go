@@ -0,0 +1,217 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"log"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/sessions"
+	"github.com/stackninja.pro/goth/internals/handlers"
+	"github.com/stackninja.pro/goth/internals/mail"
+	"github.com/stackninja.pro/goth/internals/models"
+	"github.com/stackninja.pro/goth/internals/repository"
+	"github.com/stackninja.pro/goth/internals/repository/dbrepo"
+	"github.com/stackninja.pro/goth/internals/sessionstore"
+	"github.com/stackninja.pro/goth/internals/storage"
+	"github.com/stackninja.pro/goth/src/config"
+	"golang.org/x/crypto/bcrypt"
+)
+
+var (
+	testApp  *config.AppConfig
+	testRepo repository.DatabaseRepo
+	testBlob *storage.Local
+	testMail *mail.Memory
+)
+
+func TestMain(m *testing.M) {
+	// the handlers log every request; keep test output readable
+	log.SetOutput(io.Discard)
+
+	testApp = config.Default()
+	testApp.SessionConfig.Secret = "test-session-secret-0123456789"
+	testApp.Session = sessionstore.NewMemoryStore([]byte(testApp.SessionConfig.Secret))
+	testApp.Session.Options = &sessions.Options{Path: "/", MaxAge: 3600, HttpOnly: true}
+
+	// every test logs in from the same address
+	testApp.Login.IPBurst = 10000
+
+	uploads, err := os.MkdirTemp("", "uploads-*")
+	if err != nil {
+		log.Fatal(err)
+	}
+	testBlob = storage.NewLocal(uploads, "/uploads")
+	testApp.Blob = testBlob
+
+	testMail = mail.NewMemory()
+	testApp.Mailer = testMail
+
+	testRepo = dbrepo.NewMemoryRepo(testApp)
+	handlers.NewHandlers(handlers.NewRepositoryWithDB(testApp, testRepo))
+
+	code := m.Run()
+	os.RemoveAll(uploads)
+	os.Exit(code)
+}
+
+// testClient drives the router and carries cookies between requests
+type testClient struct {
+	t       *testing.T
+	handler http.Handler
+	cookies map[string]*http.Cookie
+	csrf    string
+}
+
+// sessionCookie is the name of the cookie the handlers keep the session in
+const sessionCookie = "logged-in-user"
+
+// csrfPattern pulls the token out of the hx-headers attribute pages render
+var csrfPattern = regexp.MustCompile(`X-CSRF-Token&#34;:&#34;([^&]+)&#34;`)
+
+// newTestClient returns a client that has loaded the login page, so it holds
+// a session cookie and the CSRF token that goes with it
+func newTestClient(t *testing.T) *testClient {
+	t.Helper()
+
+	c := &testClient{t: t, handler: Route(), cookies: map[string]*http.Cookie{}}
+	if rr := c.get("/login"); c.csrf == "" {
+		t.Fatalf("no CSRF token on the login page: status %d", rr.Code)
+	}
+	return c
+}
+
+// do sends a request through the router and remembers any cookies it sets
+func (c *testClient) do(req *http.Request) *httptest.ResponseRecorder {
+	c.t.Helper()
+
+	for _, ck := range c.cookies {
+		req.AddCookie(ck)
+	}
+
+	if c.csrf != "" && req.Header.Get(handlers.CSRFHeader) == "" {
+		req.Header.Set(handlers.CSRFHeader, c.csrf)
+	}
+
+	rr := httptest.NewRecorder()
+	c.handler.ServeHTTP(rr, req)
+
+	for _, ck := range rr.Result().Cookies() {
+		if ck.MaxAge < 0 {
+			delete(c.cookies, ck.Name)
+			continue
+		}
+		c.cookies[ck.Name] = ck
+	}
+	if m := csrfPattern.FindStringSubmatch(rr.Body.String()); m != nil {
+		c.csrf = m[1]
+	}
+	return rr
+}
+
+func (c *testClient) get(path string) *httptest.ResponseRecorder {
+	c.t.Helper()
+	return c.do(httptest.NewRequest(http.MethodGet, path, nil))
+}
+
+// postForm submits an HTMX style urlencoded form
+func (c *testClient) postForm(path string, form url.Values) *httptest.ResponseRecorder {
+	c.t.Helper()
+	req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("HX-Request", "true")
+	return c.do(req)
+}
+
+// postFile submits a multipart form with a single file field
+func (c *testClient) postFile(path, field, filename string, content []byte) *httptest.ResponseRecorder {
+	c.t.Helper()
+	return c.postFileWith(path, field, filename, content, nil)
+}
+
+// postFileWith submits a multipart form with a file field and other values
+func (c *testClient) postFileWith(path, field, filename string, content []byte, values url.Values) *httptest.ResponseRecorder {
+	c.t.Helper()
+
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	for name, vs := range values {
+		for _, v := range vs {
+			mw.WriteField(name, v)
+		}
+	}
+	if filename != "" {
+		fw, err := mw.CreateFormFile(field, filename)
+		if err != nil {
+			c.t.Fatal(err)
+		}
+		fw.Write(content)
+	}
+	mw.Close()
+
+	req := httptest.NewRequest(http.MethodPost, path, &body)
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	req.Header.Set("HX-Request", "true")
+	return c.do(req)
+}
+
+// login signs the client in as email/password and fails the test if it can't
+func (c *testClient) login(email, password string) {
+	c.t.Helper()
+
+	rr := c.postForm("/login", url.Values{"email": {email}, "password": {password}})
+	if rr.Header().Get("HX-Location") != "/" {
+		c.t.Fatalf("login as %s failed: status %d, body %q", email, rr.Code, rr.Body.String())
+	}
+
+	// logging in rotates the token; pick up the new one
+	c.get("/")
+}
+
+// createTestUser stores a verified user with a bcrypt hashed password and returns it
+func createTestUser(t *testing.T, name, email, password string) *models.User {
+	t.Helper()
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	verified := time.Now()
+	err = testRepo.CreateUser(context.Background(), models.User{
+		Name:            name,
+		Email:           email,
+		EmailVerifiedAt: &verified,
+		Password:        string(hash),
+		Role:            models.RoleStudent,
+		DOB:             time.Date(2000, 5, 17, 0, 0, 0, 0, time.UTC),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	user, err := testRepo.GetUserByEmail(context.Background(), email)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return user
+}
+
+// setRole changes a test user's role
+func setRole(t *testing.T, user *models.User, role models.Role) {
+	t.Helper()
+
+	if err := testRepo.UpdateUserRole(context.Background(), user.ID, role); err != nil {
+		t.Fatal(err)
+	}
+	user.Role = role
+}
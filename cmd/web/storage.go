@@ -0,0 +1,31 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/stackninja.pro/goth/internals/storage"
+	"github.com/stackninja.pro/goth/src/config"
+)
+
+// openBlob builds the storage backend named in the config
+func openBlob(app *config.AppConfig) (storage.Blob, error) {
+	switch app.Storage.Backend {
+	case config.StorageLocal:
+		return storage.NewLocal(app.Storage.LocalDir, app.Storage.LocalURL), nil
+	case config.StorageCloudinary:
+		return storage.NewCloudinary(app.Cloudinary.CloudName, app.Cloudinary.APIKey, app.Cloudinary.APISecret)
+	case config.StorageS3:
+		s3 := app.Storage.S3
+		return storage.NewS3(storage.S3Options{
+			Endpoint:  s3.Endpoint,
+			Region:    s3.Region,
+			Bucket:    s3.Bucket,
+			AccessKey: s3.AccessKey,
+			SecretKey: s3.SecretKey,
+			PathStyle: s3.PathStyle,
+			PublicURL: s3.PublicURL,
+		})
+	default:
+		return nil, fmt.Errorf("unknown storage backend %q", app.Storage.Backend)
+	}
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"context"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stackninja.pro/goth/internals/handlers"
+)
+
+func TestSessionsAndTermDates(t *testing.T) {
+	admin, _ := adminClient(t, "admin@sessions.test")
+	start := time.Date(2031, time.September, 1, 0, 0, 0, 0, time.UTC)
+	session := makeSession(t, admin, "2031/2032", start, start.AddDate(1, 0, -1))
+
+	if body := admin.postForm("/terms/sessions", url.Values{"name": {"2031/2032"}, "start_date": {"2031-09-01"}, "end_date": {"2032-08-31"}}).Body.String(); !strings.Contains(body, "Another session is already called 2031/2032") {
+		t.Errorf("expected a duplicate session name to be refused, got %q", body)
+	}
+	if body := admin.postForm("/terms/sessions", url.Values{"name": {"Backwards"}, "start_date": {"2031-09-01"}, "end_date": {"2031-08-01"}}).Body.String(); !strings.Contains(body, "end before it starts") {
+		t.Errorf("expected a session ending before it starts to be refused, got %q", body)
+	}
+
+	// a term running past the end of its session
+	form := termForm("Late Semester", session.ID, start.AddDate(0, 10, 0), start.AddDate(0, 10, 14))
+	if body := admin.postForm("/terms", form).Body.String(); !strings.Contains(body, "The term must fall within 2031/2032") {
+		t.Errorf("expected a term outside its session to be refused, got %q", body)
+	}
+
+	form = termForm("First Semester 2031", session.ID, start.AddDate(0, 0, 21), start.AddDate(0, 0, 42))
+	form.Set("exam_start", form.Get("start_date"))
+	form.Set("exam_end", start.AddDate(1, 0, 0).Format("2006-01-02"))
+	if body := admin.postForm("/terms", form).Body.String(); !strings.Contains(body, "Exams must fall within the term") {
+		t.Errorf("expected exams outside the term to be refused, got %q", body)
+	}
+	form = termForm("First Semester 2031", session.ID, start.AddDate(0, 0, 21), start.AddDate(0, 0, 42))
+	form.Set("registration_opens", start.AddDate(0, 1, 0).Format("2006-01-02"))
+	if body := admin.postForm("/terms", form).Body.String(); !strings.Contains(body, "open after the add deadline") {
+		t.Errorf("expected registration opening after the add deadline to be refused, got %q", body)
+	}
+	form.Del("session")
+	if body := admin.postForm("/terms", form).Body.String(); !strings.Contains(body, "Session is required") {
+		t.Errorf("expected a term without a session to be refused, got %q", body)
+	}
+
+	form = termForm("First Semester 2031", session.ID, start.AddDate(0, 0, 21), start.AddDate(0, 0, 42))
+	if body := admin.postForm("/terms", form).Body.String(); !strings.Contains(body, "First Semester 2031 added") {
+		t.Fatalf("expected the term to be added, got %q", body)
+	}
+
+	// filtering by session only shows its terms
+	other := makeCurrentTerm(t, admin, "Other Session Term", time.Now().AddDate(0, 0, 7), time.Now().AddDate(0, 0, 14))
+	body := admin.get("/terms?session=" + session.ID).Body.String()
+	if !strings.Contains(body, `value="First Semester 2031"`) || strings.Contains(body, `value="`+other.Name+`"`) {
+		t.Errorf("expected only the session's terms, got %q", body)
+	}
+
+	// the session can't shrink past its terms or be deleted while it has them
+	if body := admin.postForm("/terms/sessions/"+session.ID, url.Values{"name": {"2031/2032"}, "start_date": {"2031-09-01"}, "end_date": {"2031-10-01"}}).Body.String(); !strings.Contains(body, "First Semester 2031 would fall outside 2031/2032") {
+		t.Errorf("expected the session to keep its terms inside it, got %q", body)
+	}
+	if body := admin.postForm("/terms/sessions/"+session.ID+"/delete", nil).Body.String(); !strings.Contains(body, "2031/2032 still has terms") {
+		t.Errorf("expected a session with terms to be kept, got %q", body)
+	}
+
+	empty := makeSession(t, admin, "2040/2041", start.AddDate(9, 0, 0), start.AddDate(10, 0, -1))
+	if body := admin.postForm("/terms/sessions/"+empty.ID+"/delete", nil).Body.String(); !strings.Contains(body, "2040/2041 deleted") {
+		t.Errorf("expected the empty session to be deleted, got %q", body)
+	}
+}
+
+func TestRegistrationWindowAndTermFilter(t *testing.T) {
+	admin, _ := adminClient(t, "admin@window.test")
+	week := 7 * 24 * time.Hour
+	past := makeCurrentTerm(t, admin, "Window Past Term", time.Now().Add(week), time.Now().Add(2*week))
+
+	teacher, _ := instructorClient(t, "Window Teacher", "teacher@window.test")
+	id := createCourse(t, teacher, courseForm("WIN 101"))
+	teacher.postForm("/courses/"+id+"/status", url.Values{"status": {"published"}})
+
+	student := studentClient(t, "Window Student", "student@window.test")
+	if body := student.postForm("/courses/"+id+"/enroll", nil).Body.String(); !strings.Contains(body, "enrolled in WIN 101") {
+		t.Fatalf("expected the student to enroll, got %q", body)
+	}
+
+	// a term whose registration opens next month
+	future := makeCurrentTerm(t, admin, "Window Future Term", time.Now().AddDate(0, 2, 0), time.Now().AddDate(0, 2, 14))
+	if body := student.postForm("/courses/"+id+"/enroll", nil).Body.String(); !strings.Contains(body, "Registration for Window Future Term opens on") {
+		t.Errorf("expected enrollment before registration opens to be refused, got %q", body)
+	}
+
+	// My courses shows the current term unless another is picked
+	if body := student.get("/courses/enrolled").Body.String(); strings.Contains(body, "WIN 101") || !strings.Contains(body, future.Name) {
+		t.Errorf("expected the current term to have no courses, got %q", body)
+	}
+	if body := student.get("/courses/enrolled?term=" + past.ID).Body.String(); !strings.Contains(body, "WIN 101") || !strings.Contains(body, "Window Past Term Session") {
+		t.Errorf("expected the earlier term's courses grouped under its session, got %q", body)
+	}
+
+	term, err := handlers.Repo.Terms.GetTerm(context.Background(), past.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if term.SessionName != "Window Past Term Session" || term.ExamEnd.Before(term.ExamStart) {
+		t.Errorf("expected the term's session and exam period to be stored, got %+v", term)
+	}
+}
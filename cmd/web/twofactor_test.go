@@ -0,0 +1,238 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stackninja.pro/goth/internals/models"
+	"github.com/stackninja.pro/goth/internals/totp"
+)
+
+var (
+	totpSecretPattern   = regexp.MustCompile(`>([A-Z2-7]{32})</code>`)
+	recoveryCodePattern = regexp.MustCompile(`<li>([a-z2-7]{5}-[a-z2-7]{5})</li>`)
+)
+
+// enableTwoFactor turns on 2FA for the signed in client and returns the
+// secret and recovery codes
+func enableTwoFactor(t *testing.T, c *testClient) (string, []string) {
+	t.Helper()
+
+	rr := c.get("/settings/two-factor")
+	if !strings.Contains(rr.Body.String(), "<svg") {
+		t.Fatalf("expected a QR code, got %q", rr.Body.String())
+	}
+	m := totpSecretPattern.FindStringSubmatch(rr.Body.String())
+	if m == nil {
+		t.Fatal("no secret on the setup page")
+	}
+	secret := m[1]
+
+	code, _ := totp.Code(secret, totp.Counter(time.Now()))
+	rr = c.postForm("/settings/two-factor/enable", url.Values{"code": {code}})
+	if !strings.Contains(rr.Body.String(), "Two-factor authentication is on") {
+		t.Fatalf("enabling failed: %q", rr.Body.String())
+	}
+
+	var codes []string
+	for _, m := range recoveryCodePattern.FindAllStringSubmatch(rr.Body.String(), -1) {
+		codes = append(codes, m[1])
+	}
+	if len(codes) != totp.RecoveryCodeCount {
+		t.Fatalf("expected %d recovery codes, got %d", totp.RecoveryCodeCount, len(codes))
+	}
+	return secret, codes
+}
+
+// startLogin submits the password step and expects to be asked for a code
+func startLogin(t *testing.T, email, password string) *testClient {
+	t.Helper()
+
+	c := newTestClient(t)
+	rr := c.postForm("/login", url.Values{"email": {email}, "password": {password}})
+	if rr.Header().Get("HX-Location") != "/login/2fa" {
+		t.Fatalf("expected the second step, got %d %q", rr.Code, rr.Header().Get("HX-Location"))
+	}
+	c.get("/login/2fa")
+	return c
+}
+
+func TestTwoFactorLogin(t *testing.T) {
+	// both login steps take from the account's bucket
+	setAccountLimit(t, 10)
+	user := createTestUser(t, "Two Factor", "2fa@example.com", "secret123")
+
+	c := newTestClient(t)
+	c.login("2fa@example.com", "secret123")
+	secret, codes := enableTwoFactor(t, c)
+
+	// the password alone only gets a half authenticated session
+	c = startLogin(t, "2fa@example.com", "secret123")
+	if rr := c.get("/"); rr.Code != http.StatusSeeOther || rr.Header().Get("Location") != "/login" {
+		t.Fatalf("expected the half authenticated session to be refused, got %d", rr.Code)
+	}
+
+	// the code used to enable 2FA can't be replayed
+	_, used, _ := testRepo.GetTOTPSecret(context.Background(), user.ID)
+	code, _ := totp.Code(secret, used)
+	if rr := c.postForm("/login/2fa", url.Values{"code": {code}}); !strings.Contains(rr.Body.String(), "incorrect or has already been used") {
+		t.Fatalf("expected a replayed code to be refused, got %q", rr.Body.String())
+	}
+
+	code, _ = totp.Code(secret, totp.Counter(time.Now())+1)
+	if rr := c.postForm("/login/2fa", url.Values{"code": {code}}); rr.Header().Get("HX-Location") != "/" {
+		t.Fatalf("expected the code to finish the login, got %q", rr.Body.String())
+	}
+	if rr := c.get("/"); rr.Code != http.StatusOK {
+		t.Errorf("expected full access, got %d", rr.Code)
+	}
+
+	// a recovery code works once, in any case and without the dash
+	typed := strings.ToUpper(strings.Replace(codes[0], "-", "", 1))
+	c = startLogin(t, "2fa@example.com", "secret123")
+	if rr := c.postForm("/login/2fa", url.Values{"code": {typed}}); rr.Header().Get("HX-Location") != "/" {
+		t.Fatalf("expected the recovery code to finish the login, got %q", rr.Body.String())
+	}
+	c = startLogin(t, "2fa@example.com", "secret123")
+	if rr := c.postForm("/login/2fa", url.Values{"code": {codes[0]}}); rr.Header().Get("HX-Location") != "" {
+		t.Error("expected a used recovery code to be refused")
+	}
+}
+
+func TestTwoFactorLoginLimitsAttempts(t *testing.T) {
+	// leave the per-account limits to the other tests
+	setAccountLimit(t, 10)
+	setLoginPolicy(t, 10, 20)
+	createTestUser(t, "Guesser", "2fa-guess@example.com", "secret123")
+
+	c := newTestClient(t)
+	c.login("2fa-guess@example.com", "secret123")
+	secret, _ := enableTwoFactor(t, c)
+
+	c = startLogin(t, "2fa-guess@example.com", "secret123")
+	rr := c.postForm("/login/2fa", url.Values{"code": {"000000"}})
+	for range 4 {
+		rr = c.postForm("/login/2fa", url.Values{"code": {"000000"}})
+	}
+	if !strings.Contains(rr.Body.String(), "Too many incorrect codes") {
+		t.Fatalf("expected the login to end, got %q", rr.Body.String())
+	}
+
+	// even the right code doesn't help now
+	code, _ := totp.Code(secret, totp.Counter(time.Now())+1)
+	if rr := c.postForm("/login/2fa", url.Values{"code": {code}}); !strings.Contains(rr.Body.String(), "timed out") {
+		t.Errorf("expected the second step to be over, got %q", rr.Body.String())
+	}
+}
+
+func TestTwoFactorFailuresLockTheAccount(t *testing.T) {
+	setAccountLimit(t, 20)
+	setLoginPolicy(t, 10, 3)
+	createTestUser(t, "Restarter", "2fa-restart@example.com", "secret123")
+
+	c := newTestClient(t)
+	c.login("2fa-restart@example.com", "secret123")
+	enableTwoFactor(t, c)
+
+	// starting the login over between guesses doesn't reset the count
+	var rr *httptest.ResponseRecorder
+	for range 3 {
+		c = startLogin(t, "2fa-restart@example.com", "secret123")
+		rr = c.postForm("/login/2fa", url.Values{"code": {"000000"}})
+	}
+	if !strings.Contains(rr.Body.String(), "This account is locked for 15 minutes") {
+		t.Fatalf("expected the third wrong code to lock the account, got %q", rr.Body.String())
+	}
+	if _, ok := testMail.LastTo("2fa-restart@example.com"); !ok {
+		t.Error("expected an unlock email")
+	}
+	if msg := tryLogin(t, "2fa-restart@example.com", "secret123"); !strings.Contains(msg, "This account is locked") {
+		t.Errorf("expected the password step to be refused while locked, got %q", msg)
+	}
+}
+
+func TestTwoFactorEnableRejectsWrongCode(t *testing.T) {
+	createTestUser(t, "Typo", "2fa-typo@example.com", "secret123")
+
+	c := newTestClient(t)
+	c.login("2fa-typo@example.com", "secret123")
+	c.get("/settings/two-factor")
+
+	if rr := c.postForm("/settings/two-factor/enable", url.Values{"code": {"123"}}); !strings.Contains(rr.Body.String(), "That code is incorrect") {
+		t.Errorf("expected the code to be rejected, got %q", rr.Body.String())
+	}
+}
+
+func TestTwoFactorDisableAndRegenerate(t *testing.T) {
+	user := createTestUser(t, "Toggler", "2fa-off@example.com", "secret123")
+
+	c := newTestClient(t)
+	c.login("2fa-off@example.com", "secret123")
+	_, old := enableTwoFactor(t, c)
+
+	if rr := c.postForm("/settings/two-factor/recovery-codes", url.Values{"password": {"wrong"}}); !strings.Contains(rr.Body.String(), "Password is incorrect") {
+		t.Errorf("expected the password to be checked, got %q", rr.Body.String())
+	}
+	rr := c.postForm("/settings/two-factor/recovery-codes", url.Values{"password": {"secret123"}})
+	if m := recoveryCodePattern.FindStringSubmatch(rr.Body.String()); m == nil || m[1] == old[0] {
+		t.Errorf("expected new recovery codes, got %q", rr.Body.String())
+	}
+
+	if rr := c.postForm("/settings/two-factor/disable", url.Values{"password": {"secret123"}}); !strings.Contains(rr.Body.String(), "Two-factor authentication is off") {
+		t.Fatalf("disabling failed: %q", rr.Body.String())
+	}
+	if got, _ := testRepo.GetUserByID(context.Background(), user.ID); got.TwoFactorEnabled() {
+		t.Error("expected 2FA to be off")
+	}
+
+	// back to a single step
+	newTestClient(t).login("2fa-off@example.com", "secret123")
+}
+
+func TestTwoFactorRolePolicy(t *testing.T) {
+	admin := createTestUser(t, "Policy Admin", "2fa-admin@example.com", "secret123")
+	setRole(t, admin, models.RoleAdmin)
+	user := createTestUser(t, "Policy Instructor", "2fa-instructor@example.com", "secret123")
+	setRole(t, user, models.RoleInstructor)
+	t.Cleanup(func() {
+		testRepo.SetTwoFactorRequired(context.Background(), models.RoleInstructor, false)
+	})
+
+	a := newTestClient(t)
+	a.login("2fa-admin@example.com", "secret123")
+	if rr := a.get("/users/policy"); !strings.Contains(rr.Body.String(), `name="require_instructor"`) {
+		t.Fatalf("expected the policy form, got %d", rr.Code)
+	}
+	if rr := a.postForm("/users/policy", url.Values{"require_instructor": {"1"}}); !strings.Contains(rr.Body.String(), "Policy saved") {
+		t.Fatalf("saving the policy failed: %q", rr.Body.String())
+	}
+
+	// instructors may only set up 2FA until they have it
+	c := newTestClient(t)
+	c.login("2fa-instructor@example.com", "secret123")
+	if rr := c.get("/"); rr.Header().Get("Location") != "/settings/two-factor" {
+		t.Fatalf("expected to be sent to the 2FA setup, got %d %q", rr.Code, rr.Header().Get("Location"))
+	}
+	enableTwoFactor(t, c)
+	if rr := c.get("/"); rr.Code != http.StatusOK {
+		t.Errorf("expected access once 2FA is on, got %d", rr.Code)
+	}
+
+	if rr := c.postForm("/settings/two-factor/disable", url.Values{"password": {"secret123"}}); !strings.Contains(rr.Body.String(), "requires two-factor") {
+		t.Errorf("expected disabling to be refused, got %q", rr.Body.String())
+	}
+
+	// students can't change the policy
+	s := newTestClient(t)
+	createTestUser(t, "Policy Student", "2fa-student@example.com", "secret123")
+	s.login("2fa-student@example.com", "secret123")
+	if rr := s.postForm("/users/policy", url.Values{"require_student": {"1"}}); rr.Code != http.StatusForbidden {
+		t.Errorf("expected 403, got %d", rr.Code)
+	}
+}
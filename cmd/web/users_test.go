@@ -0,0 +1,175 @@
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/stackninja.pro/goth/internals/models"
+)
+
+// adminClient returns a client signed in as a fresh admin
+func adminClient(t *testing.T, email string) (*testClient, *models.User) {
+	t.Helper()
+
+	admin := createTestUser(t, "Admin "+email, email, "secret123")
+	setRole(t, admin, models.RoleAdmin)
+
+	c := newTestClient(t)
+	c.login(email, "secret123")
+	return c, admin
+}
+
+func TestUsersPageRequiresPermission(t *testing.T) {
+	createTestUser(t, "Nosy Student", "nosy@example.com", "secret123")
+
+	c := newTestClient(t)
+	c.login("nosy@example.com", "secret123")
+
+	for _, path := range []string{"/users", "/users/export?ids=x"} {
+		if rr := c.get(path); rr.Code != http.StatusForbidden {
+			t.Errorf("GET %s: expected %d, got %d", path, http.StatusForbidden, rr.Code)
+		}
+	}
+	if rr := c.postForm("/users/bulk", url.Values{"ids": {"x"}, "action": {"deactivate"}}); rr.Code != http.StatusForbidden {
+		t.Errorf("POST /users/bulk: expected %d, got %d", http.StatusForbidden, rr.Code)
+	}
+}
+
+func TestUsersDirectoryFilters(t *testing.T) {
+	c, _ := adminClient(t, "dir-admin@directory.test")
+	createTestUser(t, "Zara Student", "zara@directory.test", "secret123")
+	teacher := createTestUser(t, "Yemi Teacher", "yemi@directory.test", "secret123")
+	setRole(t, teacher, models.RoleInstructor)
+
+	rr := c.get("/users?q=directory.test&role=instructor")
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected directory page, got %d", rr.Code)
+	}
+	body := rr.Body.String()
+	if !strings.Contains(body, "yemi@directory.test") || strings.Contains(body, "zara@directory.test") {
+		t.Error("expected only the instructor to be listed")
+	}
+	if !strings.Contains(body, "<html") {
+		t.Error("expected a full page for a normal request")
+	}
+	if strings.Contains(body, `selected="false"`) || !strings.Contains(body, `value="instructor" selected>`) {
+		t.Error("expected only the chosen options to be marked selected")
+	}
+
+	// htmx requests for the table get only the table
+	req, _ := http.NewRequest(http.MethodGet, "/users?q=directory.test&sort=name&dir=asc", nil)
+	req.Header.Set("HX-Request", "true")
+	req.Header.Set("HX-Target", "user-table")
+	rr = c.do(req)
+	body = rr.Body.String()
+	if strings.Contains(body, "<html") {
+		t.Error("expected just the table fragment")
+	}
+	if strings.Index(body, "Yemi Teacher") > strings.Index(body, "Zara Student") {
+		t.Error("expected users sorted by name")
+	}
+}
+
+func TestInlineRoleAndStatus(t *testing.T) {
+	c, admin := adminClient(t, "inline-admin@example.com")
+	user := createTestUser(t, "Inline Target", "inline-target@example.com", "secret123")
+
+	target := newTestClient(t)
+	target.login("inline-target@example.com", "secret123")
+
+	rr := c.postForm("/users/"+user.ID+"/role", url.Values{"role": {"instructor"}})
+	if rr.Code != http.StatusOK || !strings.Contains(rr.Body.String(), `id="user-`+user.ID+`"`) {
+		t.Fatalf("expected the updated row, got %d %q", rr.Code, rr.Body.String())
+	}
+	got, _ := testRepo.GetUserByID(context.Background(), user.ID)
+	if got.Role != models.RoleInstructor {
+		t.Errorf("expected role instructor, got %s", got.Role)
+	}
+
+	// a privilege change signs the user out everywhere
+	if rr := target.get("/"); rr.Code != http.StatusSeeOther {
+		t.Errorf("expected the target to be signed out after a role change, got %d", rr.Code)
+	}
+
+	rr = c.postForm("/users/"+admin.ID+"/role", url.Values{"role": {"student"}})
+	if !strings.Contains(rr.Body.String(), "You can&#39;t change your own role") {
+		t.Errorf("expected self demotion to be refused, got %q", rr.Body.String())
+	}
+
+	rr = c.postForm("/users/"+user.ID+"/status", url.Values{"status": {"suspended"}})
+	if rr.Code != http.StatusOK {
+		t.Fatalf("status change failed: %d", rr.Code)
+	}
+	if rr := newTestClient(t).postForm("/login", url.Values{"email": {"inline-target@example.com"}, "password": {"secret123"}}); !strings.Contains(rr.Body.String(), "This account is suspended") {
+		t.Errorf("expected a suspended user to be refused, got %q", rr.Body.String())
+	}
+
+	if rr := c.postForm("/users/00000000-0000-0000-0000-000000000000/status", url.Values{"status": {"active"}}); rr.Code != http.StatusNotFound {
+		t.Errorf("expected 404 for an unknown user, got %d", rr.Code)
+	}
+}
+
+func TestBulkDeactivateAndExport(t *testing.T) {
+	c, admin := adminClient(t, "bulk-admin@bulk.test")
+	a := createTestUser(t, "Bulk A", "a@bulk.test", "secret123")
+	b := createTestUser(t, "=cmd|' /C calc'!A0", "b@bulk.test", "secret123")
+	keep := createTestUser(t, "Bulk Keep", "keep@bulk.test", "secret123")
+
+	signedIn := newTestClient(t)
+	signedIn.login("a@bulk.test", "secret123")
+
+	form := url.Values{"ids": {a.ID, b.ID, admin.ID}, "action": {"deactivate"}}
+	req, _ := http.NewRequest(http.MethodPost, "/users/bulk", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("HX-Request", "true")
+	req.Header.Set("HX-Current-URL", "http://localhost/users?q=bulk.test")
+	rr := c.do(req)
+
+	body := rr.Body.String()
+	if !strings.Contains(body, "2 users set to Deactivated") || !strings.Contains(body, "Your own account was skipped") {
+		t.Errorf("expected a summary of the bulk action, got %q", body)
+	}
+	if !strings.Contains(body, "keep@bulk.test") {
+		t.Error("expected the table to keep the current filter")
+	}
+
+	for _, u := range []*models.User{a, b} {
+		got, _ := testRepo.GetUserByID(context.Background(), u.ID)
+		if got.Status != models.StatusDeactivated {
+			t.Errorf("%s: expected deactivated, got %s", u.Email, got.Status)
+		}
+	}
+	if got, _ := testRepo.GetUserByID(context.Background(), keep.ID); got.Status != models.StatusActive {
+		t.Error("unselected user was changed")
+	}
+	if got, _ := testRepo.GetUserByID(context.Background(), admin.ID); got.Status != models.StatusActive {
+		t.Error("admin deactivated their own account")
+	}
+	if rr := signedIn.get("/"); rr.Code != http.StatusSeeOther {
+		t.Errorf("expected a deactivated user's session to end, got %d", rr.Code)
+	}
+
+	rr = c.get("/users/export?ids=" + a.ID + "&ids=" + b.ID)
+	if ct := rr.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/csv") {
+		t.Fatalf("expected CSV, got %q", ct)
+	}
+	records, err := csv.NewReader(rr.Body).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 3 {
+		t.Fatalf("expected a header and 2 rows, got %d", len(records))
+	}
+	for _, rec := range records[1:] {
+		if strings.HasPrefix(rec[1], "=") {
+			t.Errorf("formula not neutralised: %q", rec[1])
+		}
+	}
+	if strings.Contains(rr.Body.String(), "$2a$") {
+		t.Error("export contains a password hash")
+	}
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+var verifyLinkPattern = regexp.MustCompile(`/verify-email\?token=([A-Za-z0-9_.%-]+)`)
+
+// verifyLink returns the path of the latest verification link sent to address
+func verifyLink(t *testing.T, address string) string {
+	t.Helper()
+
+	msg, ok := testMail.LastTo(address)
+	if !ok {
+		t.Fatalf("no email sent to %s", address)
+	}
+	m := verifyLinkPattern.FindString(msg.Text)
+	if m == "" {
+		t.Fatalf("no verification link in %q", msg.Text)
+	}
+	return m
+}
+
+func TestRegistrationRequiresVerification(t *testing.T) {
+	form := url.Values{"name": {"New Student"}, "email": {"verify-me@example.com"}, "password": {"secret123"}, "role": {"student"}}
+	if rr := newTestClient(t).postForm("/register", form); rr.Header().Get("HX-Location") != "/login" {
+		t.Fatalf("registration failed: %q", rr.Body.String())
+	}
+	link := verifyLink(t, "verify-me@example.com")
+
+	c := newTestClient(t)
+	c.login("verify-me@example.com", "secret123")
+
+	rr := c.get("/")
+	if rr.Code != http.StatusSeeOther || rr.Header().Get("Location") != "/verify-email/pending" {
+		t.Fatalf("expected unverified users to be sent to the notice, got %d %q", rr.Code, rr.Header().Get("Location"))
+	}
+	if rr := c.get("/verify-email/pending"); !strings.Contains(rr.Body.String(), "verify-me@example.com") {
+		t.Errorf("expected the notice to name the address, got %d", rr.Code)
+	}
+
+	before := len(testMail.Sent())
+	if rr := c.postForm("/verify-email/resend", nil); !strings.Contains(rr.Body.String(), "sent a new link") {
+		t.Errorf("expected a resend confirmation, got %q", rr.Body.String())
+	}
+	if len(testMail.Sent()) != before+1 {
+		t.Error("expected resend to send another email")
+	}
+
+	// the link works from any browser
+	if rr := newTestClient(t).get(link); !strings.Contains(rr.Body.String(), "is confirmed") {
+		t.Fatalf("expected the link to confirm the address, got %q", rr.Body.String())
+	}
+	if rr := c.get("/"); rr.Code != http.StatusOK {
+		t.Errorf("expected full access once verified, got %d", rr.Code)
+	}
+}
+
+func TestRegistrationRejectsInvalidEmail(t *testing.T) {
+	form := url.Values{"name": {"Typo"}, "email": {"not-an-email"}, "password": {"secret123"}, "role": {"student"}}
+	rr := newTestClient(t).postForm("/register", form)
+	if !strings.Contains(rr.Body.String(), "Please enter a valid email address") {
+		t.Errorf("expected an invalid email error, got %q", rr.Body.String())
+	}
+}
+
+func TestVerifyEmailRejectsBadLinks(t *testing.T) {
+	for _, path := range []string{"/verify-email", "/verify-email?token=forged.token"} {
+		if rr := newTestClient(t).get(path); !strings.Contains(rr.Body.String(), "invalid or has expired") {
+			t.Errorf("%s: expected the link to be rejected, got %q", path, rr.Body.String())
+		}
+	}
+}
+
+func TestEmailChangeNeedsConfirmation(t *testing.T) {
+	user := createTestUser(t, "Mover", "mover@old.example.com", "secret123")
+
+	c := newTestClient(t)
+	c.login("mover@old.example.com", "secret123")
+
+	form := url.Values{"name": {"Mover"}, "email": {"mover@new.example.com"}, "dob": {"2000-05-17"}}
+	if rr := c.postForm("/profile/update", form); rr.Header().Get("HX-Location") != "/" {
+		t.Fatalf("profile update failed: %q", rr.Body.String())
+	}
+
+	got, _ := testRepo.GetUserByID(context.Background(), user.ID)
+	if got.Email != "mover@old.example.com" || got.PendingEmail != "mover@new.example.com" {
+		t.Fatalf("expected the new address to be pending, got %q pending %q", got.Email, got.PendingEmail)
+	}
+	if notice, ok := testMail.LastTo("mover@old.example.com"); !ok || !strings.Contains(notice.Text, "mover@new.example.com") {
+		t.Error("expected a notice at the old address")
+	}
+
+	if rr := c.get(verifyLink(t, "mover@new.example.com")); !strings.Contains(rr.Body.String(), "is confirmed") {
+		t.Fatalf("expected the change to be confirmed, got %q", rr.Body.String())
+	}
+
+	got, _ = testRepo.GetUserByID(context.Background(), user.ID)
+	if got.Email != "mover@new.example.com" || got.PendingEmail != "" {
+		t.Errorf("expected the new address to take over, got %q pending %q", got.Email, got.PendingEmail)
+	}
+	c.postForm("/logout", nil)
+	newTestClient(t).login("mover@new.example.com", "secret123")
+}
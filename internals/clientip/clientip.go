@@ -0,0 +1,24 @@
+// Package clientip works out the address a request came from, so that login
+// throttling, the audit log and the sessions page all agree on it.
+package clientip
+
+import (
+	"net"
+	"net/http"
+	"strings"
+)
+
+// FromRequest is the client's address. X-Forwarded-For is only believed when
+// trustProxy is set, and then only its last entry: the one the proxy
+// appended. Earlier entries come from the client and can be anything.
+func FromRequest(r *http.Request, trustProxy bool) string {
+	if fwd := r.Header.Get("X-Forwarded-For"); fwd != "" && trustProxy {
+		parts := strings.Split(fwd, ",")
+		return strings.TrimSpace(parts[len(parts)-1])
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
@@ -0,0 +1,25 @@
+package clientip
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+func TestFromRequest(t *testing.T) {
+	r := httptest.NewRequest("GET", "/", nil)
+	r.RemoteAddr = "10.0.0.1:5000"
+	r.Header.Set("X-Forwarded-For", "6.6.6.6, 203.0.113.7")
+
+	if ip := FromRequest(r, false); ip != "10.0.0.1" {
+		t.Errorf("without a trusted proxy: expected the peer address, got %q", ip)
+	}
+	if ip := FromRequest(r, true); ip != "203.0.113.7" {
+		t.Errorf("behind a trusted proxy: expected the entry it appended, got %q", ip)
+	}
+
+	r.RemoteAddr = "pipe"
+	r.Header.Del("X-Forwarded-For")
+	if ip := FromRequest(r, true); ip != "pipe" {
+		t.Errorf("expected an address without a port as is, got %q", ip)
+	}
+}
@@ -2,52 +2,118 @@ package driver
 
 import (
 	"context"
+	"fmt"
+	"log"
 	"time"
 
-	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/stackninja.pro/goth/src/config"
 )
 
 // DB holds the database connection pool
 type DB struct {
-	Conn *pgx.Conn
+	Pool *pgxpool.Pool
 }
 
-var dbConn = &DB{}
+// PoolStats is a snapshot of the connection pool used for diagnostics
+type PoolStats struct {
+	MaxConns             int32         `json:"max_conns"`
+	TotalConns           int32         `json:"total_conns"`
+	AcquiredConns        int32         `json:"acquired_conns"`
+	IdleConns            int32         `json:"idle_conns"`
+	ConstructingConns    int32         `json:"constructing_conns"`
+	AcquireCount         int64         `json:"acquire_count"`
+	EmptyAcquireCount    int64         `json:"empty_acquire_count"`
+	CanceledAcquireCount int64         `json:"canceled_acquire_count"`
+	AcquireDuration      time.Duration `json:"acquire_duration_ns"`
+	NewConnsCount        int64         `json:"new_conns_count"`
+}
+
+// maxBackoff caps the delay between connection attempts
+const maxBackoff = 30 * time.Second
 
-// ConnectToDB establishes a connection to the database (postgres)
-func ConnectToDB(dsn string) (*DB, error) {
-	conn, err := NewDatabase(dsn)
+// ConnectToDB creates the connection pool, retrying with exponential backoff
+// until the database answers a ping or the retries run out
+func ConnectToDB(ctx context.Context, cfg config.DatabaseConfig) (*DB, error) {
+	poolConfig, err := NewPoolConfig(cfg)
 	if err != nil {
 		return nil, err
 	}
 
-	dbConn.Conn = conn
+	backoff := cfg.ConnectBackoff
+	for attempt := 1; ; attempt++ {
+		pool, err := pgxpool.NewWithConfig(ctx, poolConfig)
+		if err == nil {
+			err = testDBConnection(ctx, pool)
+			if err == nil {
+				return &DB{Pool: pool}, nil
+			}
+			pool.Close()
+		}
+
+		if attempt > cfg.ConnectRetries {
+			return nil, fmt.Errorf("connect to database after %d attempts: %w", attempt, err)
+		}
 
-	err = testDBConnection(conn)
+		log.Printf("⚠️ Database not ready (attempt %d/%d): %v, retrying in %s", attempt, cfg.ConnectRetries+1, err, backoff)
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(backoff):
+		}
+
+		backoff = min(backoff*2, maxBackoff)
+	}
+}
+
+// NewPoolConfig parses the DSN and applies the pool limits from the configuration
+func NewPoolConfig(cfg config.DatabaseConfig) (*pgxpool.Config, error) {
+	poolConfig, err := pgxpool.ParseConfig(cfg.DSN)
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("parse database DSN: %w", err)
+	}
+
+	if cfg.MaxConns > 0 {
+		poolConfig.MaxConns = cfg.MaxConns
+	}
+	if cfg.MinConns > 0 {
+		poolConfig.MinConns = cfg.MinConns
 	}
-	return dbConn, nil
+	if cfg.MaxConnLifetime > 0 {
+		poolConfig.MaxConnLifetime = cfg.MaxConnLifetime
+	}
+	if cfg.HealthCheckPeriod > 0 {
+		poolConfig.HealthCheckPeriod = cfg.HealthCheckPeriod
+	}
+
+	return poolConfig, nil
 }
 
 // testDBConnection tests the database connection
-func testDBConnection(d *pgx.Conn) error {
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+func testDBConnection(ctx context.Context, p *pgxpool.Pool) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
 	defer cancel()
-	return d.Ping(ctx)
-
+	return p.Ping(ctx)
 }
 
-// NewDatabase creates a new database connection
-func NewDatabase(dsn string) (*pgx.Conn, error) {
-	conn, err := pgx.Connect(context.Background(), dsn)
-	if err != nil {
-		return nil, err
-	}
+// Close releases every connection in the pool
+func (d *DB) Close() {
+	d.Pool.Close()
+}
 
-	if err := conn.Ping(context.Background()); err != nil {
-		return nil, err
+// Stats returns a snapshot of the pool counters
+func (d *DB) Stats() PoolStats {
+	s := d.Pool.Stat()
+	return PoolStats{
+		MaxConns:             s.MaxConns(),
+		TotalConns:           s.TotalConns(),
+		AcquiredConns:        s.AcquiredConns(),
+		IdleConns:            s.IdleConns(),
+		ConstructingConns:    s.ConstructingConns(),
+		AcquireCount:         s.AcquireCount(),
+		EmptyAcquireCount:    s.EmptyAcquireCount(),
+		CanceledAcquireCount: s.CanceledAcquireCount(),
+		AcquireDuration:      s.AcquireDuration(),
+		NewConnsCount:        s.NewConnsCount(),
 	}
-
-	return conn, nil
 }
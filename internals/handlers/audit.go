@@ -0,0 +1,118 @@
+package handlers
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/a-h/templ"
+	"github.com/stackninja.pro/goth/internals/models"
+	"github.com/stackninja.pro/goth/web/templates"
+)
+
+// maxUserAgent caps the user agent kept with each audit event
+const maxUserAgent = 512
+
+// audit records action on target, done by the signed in user or, when nobody
+// is signed in yet, by target themselves
+func (m *Repository) audit(r *http.Request, action models.AuditAction, target *models.User, changes []models.AuditChange) {
+	e := models.AuditEvent{Action: action, Changes: changes}
+	if target != nil {
+		e.TargetID, e.TargetEmail = target.ID, target.Email
+	}
+
+	actor := CurrentUser(r.Context())
+	if actor == nil {
+		actor = target
+	}
+	if actor != nil {
+		e.ActorID, e.ActorEmail = actor.ID, actor.Email
+	}
+
+	m.recordAudit(r, e)
+}
+
+// auditChanges records that each of users had field set to value, skipping
+// those it already had
+func (m *Repository) auditChanges(r *http.Request, action models.AuditAction, users []models.User, field, value string) {
+	for _, u := range users {
+		before := u.AuditFields()[field]
+		if before == value {
+			continue
+		}
+		m.audit(r, action, &u, []models.AuditChange{{Field: field, Before: before, After: value}})
+	}
+}
+
+// recordAudit stores e with the request's IP and user agent. A failure is
+// logged rather than shown, since whatever e describes has already happened.
+func (m *Repository) recordAudit(r *http.Request, e models.AuditEvent) {
+	e.IP = m.clientIP(r)
+	e.UserAgent = r.UserAgent()
+	if len(e.UserAgent) > maxUserAgent {
+		e.UserAgent = strings.ToValidUTF8(e.UserAgent[:maxUserAgent], "")
+	}
+
+	// a client that hangs up now must not lose the event
+	if err := m.DB.RecordAuditEvent(context.WithoutCancel(r.Context()), e); err != nil {
+		log.Println("❌ Failed to record audit event", string(e.Action)+":", err)
+	}
+}
+
+// AuditPage is the filterable audit log. HTMX requests aimed at the table
+// only get the table back.
+func (m *Repository) AuditPage(w http.ResponseWriter, r *http.Request) {
+	q := models.ParseAuditQuery(r.URL.Query())
+	events, total, err := m.DB.GetAuditEvents(r.Context(), q)
+	if err != nil {
+		dbError(w, err)
+		return
+	}
+
+	page := templates.AuditPage(m.AddDefaultData(&models.TemplateData{
+		Data:   map[string]interface{}{"title": "Audit log", "events": events, "query": q},
+		IntMap: map[string]int{"total": total, "pages": q.Pages(total)},
+	}, r))
+
+	if isHTMX(r) && r.Header.Get("HX-Target") == "audit-table" {
+		templ.Handler(page, templ.WithFragments("audit-table")).ServeHTTP(w, r)
+		return
+	}
+	if err := page.Render(r.Context(), w); err != nil {
+		log.Println("❌ Template render error:", err)
+	}
+}
+
+// ExportAudit downloads every event matching the viewer's filters as CSV
+func (m *Repository) ExportAudit(w http.ResponseWriter, r *http.Request) {
+	q := models.ParseAuditQuery(r.URL.Query())
+
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.Header().Set("Content-Disposition", `attachment; filename="audit-`+time.Now().Format("20060102")+`.csv"`)
+
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"id", "created_at", "action", "actor_id", "actor_email", "target_id", "target_email", "ip", "user_agent", "changes"})
+	err := m.DB.ForEachAuditEvent(r.Context(), q, func(e models.AuditEvent) error {
+		changes := make([]string, len(e.Changes))
+		for i, c := range e.Changes {
+			changes[i] = c.String()
+		}
+		cw.Write([]string{
+			fmt.Sprint(e.ID), e.CreatedAt.UTC().Format(time.RFC3339), string(e.Action),
+			e.ActorID, csvCell(e.ActorEmail), e.TargetID, csvCell(e.TargetEmail),
+			e.IP, csvCell(e.UserAgent), csvCell(strings.Join(changes, "; ")),
+		})
+		return cw.Error()
+	})
+	cw.Flush()
+	if err == nil {
+		err = cw.Error()
+	}
+	if err != nil {
+		log.Println("❌ Failed to write audit export:", err)
+	}
+}
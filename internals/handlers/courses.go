@@ -0,0 +1,320 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/a-h/templ"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/stackninja.pro/goth/internals/models"
+	"github.com/stackninja.pro/goth/internals/repository"
+	"github.com/stackninja.pro/goth/web/templates"
+)
+
+// CoursesPage is the catalog of published courses students browse. HTMX
+// requests aimed at the list only get the list back.
+func (m *Repository) CoursesPage(w http.ResponseWriter, r *http.Request) {
+	q := models.ParseCourseQuery(r.URL.Query())
+	q.Status = models.CoursePublished
+	m.renderCourses(w, r, templates.CoursesPage, q)
+}
+
+// TeachingPage lists the courses the instructor teaches, in any status.
+// Admins see every course.
+func (m *Repository) TeachingPage(w http.ResponseWriter, r *http.Request) {
+	user := CurrentUser(r.Context())
+
+	q := models.ParseCourseQuery(r.URL.Query())
+	if !user.Can(models.PermManageCourses) {
+		q.InstructorID = user.ID
+	}
+	m.renderCourses(w, r, templates.TeachingPage, q)
+}
+
+// renderCourses shows the page of courses q selects using one of the course
+// list pages
+func (m *Repository) renderCourses(w http.ResponseWriter, r *http.Request, page func(*models.TemplateData) templ.Component, q models.CourseQuery) {
+	courses, total, err := m.Courses.GetCourses(r.Context(), q)
+	if err != nil {
+		dbError(w, err)
+		return
+	}
+	departments, err := m.Courses.GetDepartments(r.Context())
+	if err != nil {
+		dbError(w, err)
+		return
+	}
+
+	c := page(m.AddDefaultData(&models.TemplateData{
+		Data:   map[string]interface{}{"title": "Courses", "courses": courses, "departments": departments, "query": q},
+		IntMap: map[string]int{"total": total, "pages": q.Pages(total)},
+	}, r))
+
+	if isHTMX(r) && r.Header.Get("HX-Target") == "course-list" {
+		templ.Handler(c, templ.WithFragments("course-list")).ServeHTTP(w, r)
+		return
+	}
+	if err := c.Render(r.Context(), w); err != nil {
+		log.Println("❌ Template render error:", err)
+	}
+}
+
+// CoursePage shows one course. Drafts and archived courses are only visible
+// to the people who may edit them.
+func (m *Repository) CoursePage(w http.ResponseWriter, r *http.Request) {
+	course, ok := m.loadCourse(w, r)
+	if !ok {
+		return
+	}
+	if course.Status != models.CoursePublished && !canEditCourse(CurrentUser(r.Context()), course) {
+		http.NotFound(w, r)
+		return
+	}
+
+	td, err := m.coursePageData(r, course, "", nil)
+	if err != nil {
+		dbError(w, err)
+		return
+	}
+	if err := templates.CoursePage(td).Render(r.Context(), w); err != nil {
+		log.Println("❌ Template render error:", err)
+	}
+}
+
+// NewCoursePage is the form for creating a course
+func (m *Repository) NewCoursePage(w http.ResponseWriter, r *http.Request) {
+	user := CurrentUser(r.Context())
+
+	course := &models.Course{CreditUnits: 3, Capacity: 50}
+	if user.Role == models.RoleInstructor {
+		course.InstructorIDs = []string{user.ID}
+	}
+	m.renderCourseForm(w, r, course, nil)
+}
+
+// CreateCourse saves a new course as a draft and opens it
+func (m *Repository) CreateCourse(w http.ResponseWriter, r *http.Request) {
+	user := CurrentUser(r.Context())
+
+	course, errs := parseCourseForm(r, user)
+	if len(errs) > 0 {
+		m.renderCourseForm(w, r, &course, errs)
+		return
+	}
+
+	id, err := m.Courses.CreateCourse(r.Context(), course)
+	if err != nil {
+		m.courseSaveError(w, r, &course, err)
+		return
+	}
+
+	log.Printf("📚 %s created course %s", user.Email, course.Code)
+	w.Header().Set("HX-Location", "/courses/"+id)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// EditCoursePage is the form for changing a course
+func (m *Repository) EditCoursePage(w http.ResponseWriter, r *http.Request) {
+	course, ok := m.loadEditableCourse(w, r)
+	if !ok {
+		return
+	}
+	m.renderCourseForm(w, r, course, nil)
+}
+
+// UpdateCourse saves the changes made on the edit form
+func (m *Repository) UpdateCourse(w http.ResponseWriter, r *http.Request) {
+	user := CurrentUser(r.Context())
+
+	old, ok := m.loadEditableCourse(w, r)
+	if !ok {
+		return
+	}
+
+	course, errs := parseCourseForm(r, user)
+	course.ID = old.ID
+	course.Status = old.Status
+	if len(errs) > 0 {
+		m.renderCourseForm(w, r, &course, errs)
+		return
+	}
+
+	promoted, err := m.Courses.UpdateCourse(r.Context(), course)
+	if err != nil {
+		m.courseSaveError(w, r, &course, err)
+		return
+	}
+
+	log.Printf("📚 %s updated course %s", user.Email, course.Code)
+	m.notifyPromoted(r.Context(), &course, promoted)
+	w.Header().Set("HX-Location", "/courses/"+course.ID)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ChangeCourseStatus publishes, archives or returns a course to draft
+func (m *Repository) ChangeCourseStatus(w http.ResponseWriter, r *http.Request) {
+	user := CurrentUser(r.Context())
+
+	course, ok := m.loadEditableCourse(w, r)
+	if !ok {
+		return
+	}
+	status, err := models.ParseCourseStatus(r.FormValue("status"))
+	if err != nil {
+		http.Error(w, "Unknown course status", http.StatusBadRequest)
+		return
+	}
+
+	if err := m.Courses.SetCourseStatus(r.Context(), course.ID, status); err != nil {
+		dbError(w, err)
+		return
+	}
+
+	log.Printf("📚 %s set course %s to %s", user.Email, course.Code, status.Label())
+	redirect(w, r, "/courses/"+course.ID)
+}
+
+// loadCourse fetches the course named in the URL, answering 404 for ids
+// that don't exist or aren't ids at all
+func (m *Repository) loadCourse(w http.ResponseWriter, r *http.Request) (*models.Course, bool) {
+	id := chi.URLParam(r, "id")
+	if _, err := uuid.Parse(id); err != nil {
+		http.NotFound(w, r)
+		return nil, false
+	}
+
+	course, err := m.Courses.GetCourse(r.Context(), id)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			http.NotFound(w, r)
+			return nil, false
+		}
+		dbError(w, err)
+		return nil, false
+	}
+	return course, true
+}
+
+// loadEditableCourse is loadCourse for pages that change the course, which
+// only its instructors and course managers may use
+func (m *Repository) loadEditableCourse(w http.ResponseWriter, r *http.Request) (*models.Course, bool) {
+	course, ok := m.loadCourse(w, r)
+	if !ok {
+		return nil, false
+	}
+	if !canEditCourse(CurrentUser(r.Context()), course) {
+		forbidden(w, r)
+		return nil, false
+	}
+	return course, true
+}
+
+// canEditCourse reports whether the user teaches the course or manages all
+// courses
+func canEditCourse(user *models.User, course *models.Course) bool {
+	return course.TaughtBy(user.ID) || user.Can(models.PermManageCourses)
+}
+
+// courseSaveError turns a failed create or update into a message on the form
+func (m *Repository) courseSaveError(w http.ResponseWriter, r *http.Request, course *models.Course, err error) {
+	switch {
+	case errors.Is(err, repository.ErrDuplicateCourseCode):
+		m.renderCourseForm(w, r, course, []string{"Another course already uses the code " + course.Code})
+	case errors.Is(err, repository.ErrNotFound):
+		m.renderCourseForm(w, r, course, []string{"One of the chosen instructors no longer exists"})
+	default:
+		dbError(w, err)
+	}
+}
+
+// renderCourseForm shows the create or edit form. HTMX submissions only get
+// the error messages back.
+func (m *Repository) renderCourseForm(w http.ResponseWriter, r *http.Request, course *models.Course, errs []string) {
+	instructors, _, err := m.DB.GetAllUsers(r.Context(), models.UserQuery{Role: models.RoleInstructor, Status: models.StatusActive, Sort: "name", PerPage: models.MaxPerPage})
+	if err != nil {
+		dbError(w, err)
+		return
+	}
+
+	title := "New course"
+	if course.ID != "" {
+		title = "Edit " + course.Code
+	}
+	page := templates.CourseFormPage(m.AddDefaultData(&models.TemplateData{
+		Data:   map[string]interface{}{"title": title, "course": course, "instructors": instructors},
+		Errors: errs,
+	}, r))
+
+	if isHTMX(r) && r.Method == http.MethodPost {
+		templ.Handler(page, templ.WithFragments("course-errors")).ServeHTTP(w, r)
+		return
+	}
+	if err := page.Render(r.Context(), w); err != nil {
+		log.Println("❌ Template render error:", err)
+	}
+}
+
+// parseCourseForm reads and validates the course form. Instructors always
+// stay on the courses they save, so they can't lock themselves out.
+func parseCourseForm(r *http.Request, user *models.User) (models.Course, []string) {
+	var errs []string
+	if err := r.ParseForm(); err != nil {
+		return models.Course{}, []string{"Failed to parse form data"}
+	}
+
+	c := models.Course{
+		Code:        models.NormalizeCourseCode(r.PostFormValue("code")),
+		Title:       strings.TrimSpace(r.PostFormValue("title")),
+		Description: strings.TrimSpace(r.PostFormValue("description")),
+		Department:  strings.TrimSpace(r.PostFormValue("department")),
+	}
+
+	if c.Code == "" {
+		errs = append(errs, "Course code is required")
+	} else if !models.ValidCourseCode(c.Code) {
+		errs = append(errs, "Course code must look like CSC 101")
+	}
+	if c.Title == "" {
+		errs = append(errs, "Title is required")
+	} else if utf8.RuneCountInString(c.Title) > models.MaxCourseTitle {
+		errs = append(errs, "Title must be at most "+strconv.Itoa(models.MaxCourseTitle)+" characters")
+	}
+	if utf8.RuneCountInString(c.Description) > models.MaxCourseDescription {
+		errs = append(errs, "Description must be at most "+strconv.Itoa(models.MaxCourseDescription)+" characters")
+	}
+	if c.Department == "" {
+		errs = append(errs, "Department is required")
+	}
+
+	var err error
+	if c.CreditUnits, err = strconv.Atoi(r.PostFormValue("credit_units")); err != nil || c.CreditUnits < 1 || c.CreditUnits > models.MaxCreditUnits {
+		errs = append(errs, "Credit units must be between 1 and "+strconv.Itoa(models.MaxCreditUnits))
+	}
+	if c.Capacity, err = strconv.Atoi(r.PostFormValue("capacity")); err != nil || c.Capacity < 1 || c.Capacity > models.MaxCourseCapacity {
+		errs = append(errs, "Capacity must be between 1 and "+strconv.Itoa(models.MaxCourseCapacity))
+	}
+
+	if user.Role == models.RoleInstructor {
+		c.InstructorIDs = append(c.InstructorIDs, user.ID)
+	}
+	for _, id := range r.PostForm["instructors"] {
+		if _, err := uuid.Parse(id); err != nil {
+			errs = append(errs, "Unknown instructor")
+			break
+		}
+		if !slices.Contains(c.InstructorIDs, id) {
+			c.InstructorIDs = append(c.InstructorIDs, id)
+		}
+	}
+	if len(c.InstructorIDs) == 0 {
+		errs = append(errs, "Choose at least one instructor")
+	}
+
+	return c, errs
+}
@@ -0,0 +1,87 @@
+package handlers
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"log"
+	"net/http"
+)
+
+const (
+	// CSRFHeader is the header htmx sends the token in, see Layout
+	CSRFHeader = "X-CSRF-Token"
+
+	// CSRFField is the form field plain HTML forms send the token in
+	CSRFField = "csrf_token"
+
+	csrfSessionKey             = "csrf_token"
+	csrfContextKey  contextKey = "csrf"
+	csrfTokenLength            = 32
+)
+
+// CSRF issues every session a random token and rejects unsafe requests that
+// don't echo it back in the X-CSRF-Token header or the csrf_token form field
+func (m *Repository) CSRF(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		session, err := m.App.Session.Get(r, sessionName)
+		if session == nil {
+			log.Println("❌ Failed to get session:", err)
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+
+		token, _ := session.Values[csrfSessionKey].(string)
+		if token == "" {
+			token = newCSRFToken()
+			session.Values[csrfSessionKey] = token
+			if err := session.Save(r, w); err != nil {
+				log.Println("❌ Failed to save session:", err)
+				http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+				return
+			}
+		}
+
+		if !isSafeMethod(r.Method) && !validCSRFToken(r, token) {
+			log.Printf("🛡️ CSRF check failed for %s %s", r.Method, r.URL.Path)
+			http.Error(w, "Invalid or missing CSRF token, please reload the page", http.StatusForbidden)
+			return
+		}
+
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), csrfContextKey, token)))
+	})
+}
+
+// CSRFToken returns the token the CSRF middleware stored for this request
+func CSRFToken(ctx context.Context) string {
+	token, _ := ctx.Value(csrfContextKey).(string)
+	return token
+}
+
+// isSafeMethod reports whether the method must not change state (RFC 9110)
+func isSafeMethod(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace:
+		return true
+	}
+	return false
+}
+
+// validCSRFToken compares the submitted token with the session one in constant time
+func validCSRFToken(r *http.Request, want string) bool {
+	got := r.Header.Get(CSRFHeader)
+	if got == "" {
+		got = r.PostFormValue(CSRFField)
+	}
+	return got != "" && subtle.ConstantTimeCompare([]byte(got), []byte(want)) == 1
+}
+
+// newCSRFToken returns a random URL-safe token
+func newCSRFToken() string {
+	b := make([]byte, csrfTokenLength)
+	if _, err := rand.Read(b); err != nil {
+		panic("csrf: crypto/rand failed: " + err.Error())
+	}
+	return base64.RawURLEncoding.EncodeToString(b)
+}
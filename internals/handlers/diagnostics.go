@@ -0,0 +1,20 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+)
+
+// DBStats reports the connection pool counters as JSON
+func (m *Repository) DBStats(w http.ResponseWriter, r *http.Request) {
+	if m.Conn == nil {
+		http.Error(w, "Database pool not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(m.Conn.Stats()); err != nil {
+		log.Println("❌ Failed to encode pool stats:", err)
+	}
+}
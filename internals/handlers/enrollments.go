@@ -0,0 +1,302 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/a-h/templ"
+	"github.com/google/uuid"
+	"github.com/stackninja.pro/goth/internals/mail"
+	"github.com/stackninja.pro/goth/internals/models"
+	"github.com/stackninja.pro/goth/internals/repository"
+	"github.com/stackninja.pro/goth/web/templates"
+)
+
+// coursePageData is the template data for a course page: the course, and
+// for students the current term, the seats left and their own enrollment
+func (m *Repository) coursePageData(r *http.Request, course *models.Course, flash string, errs []string) (*models.TemplateData, error) {
+	data := map[string]interface{}{"title": course.Code, "course": course}
+
+	term, err := m.Terms.GetCurrentTerm(r.Context())
+	switch {
+	case errors.Is(err, repository.ErrNotFound):
+	case err != nil:
+		return nil, err
+	default:
+		seats, err := m.Courses.GetSeats(r.Context(), course.ID, term.ID)
+		if err != nil {
+			return nil, err
+		}
+		data["term"], data["seats"] = term, seats
+
+		e, err := m.Courses.GetEnrollment(r.Context(), course.ID, term.ID, CurrentUser(r.Context()).ID)
+		if err != nil && !errors.Is(err, repository.ErrNotFound) {
+			return nil, err
+		}
+		if e.Active() {
+			data["enrollment"] = e
+		}
+	}
+
+	return m.AddDefaultData(&models.TemplateData{Data: data, Flash: flash, Errors: errs}, r), nil
+}
+
+// renderEnrollment answers an enroll or drop with the course page's
+// enrollment panel
+func (m *Repository) renderEnrollment(w http.ResponseWriter, r *http.Request, course *models.Course, flash string, errs []string) {
+	td, err := m.coursePageData(r, course, flash, errs)
+	if err != nil {
+		dbError(w, err)
+		return
+	}
+	templ.Handler(templates.CoursePage(td), templ.WithFragments("enrollment")).ServeHTTP(w, r)
+}
+
+// EnrollCourse takes a seat in a published course for the current term, or
+// a place on its waitlist when it is full
+func (m *Repository) EnrollCourse(w http.ResponseWriter, r *http.Request) {
+	user := CurrentUser(r.Context())
+
+	course, ok := m.loadCourse(w, r)
+	if !ok {
+		return
+	}
+	if course.Status != models.CoursePublished {
+		http.NotFound(w, r)
+		return
+	}
+
+	term, ok := m.enrollmentTerm(w, r, course)
+	if !ok {
+		return
+	}
+	if !term.RegistrationStarted(time.Now()) {
+		m.renderEnrollment(w, r, course, "", []string{"Registration for " + term.Name + " opens on " + term.RegistrationOpens.Format("2 January 2006")})
+		return
+	}
+	if !term.AddOpen(time.Now()) {
+		m.renderEnrollment(w, r, course, "", []string{"The add deadline for " + term.Name + " has passed"})
+		return
+	}
+
+	e, promoted, err := m.Courses.Enroll(r.Context(), course.ID, term.ID, user.ID)
+	if err != nil {
+		if errors.Is(err, repository.ErrAlreadyEnrolled) {
+			m.renderEnrollment(w, r, course, "", []string{"You're already enrolled in " + course.Code})
+			return
+		}
+		dbError(w, err)
+		return
+	}
+
+	log.Printf("🎓 %s %s for %s in %s", user.Email, e.Status, course.Code, term.Name)
+	m.notifyPromoted(r.Context(), course, promoted)
+
+	flash := "You're enrolled in " + course.Code
+	if e.Status == models.EnrollmentWaitlisted {
+		flash = fmt.Sprintf("%s is full, so you're number %d on the waitlist", course.Code, e.Position)
+	}
+	m.renderEnrollment(w, r, course, flash, nil)
+}
+
+// DropCourse gives up a seat before the drop deadline. Leaving a waitlist is
+// allowed at any time. A freed seat goes to the next student waiting.
+func (m *Repository) DropCourse(w http.ResponseWriter, r *http.Request) {
+	user := CurrentUser(r.Context())
+
+	course, ok := m.loadCourse(w, r)
+	if !ok {
+		return
+	}
+	term, ok := m.enrollmentTerm(w, r, course)
+	if !ok {
+		return
+	}
+
+	e, err := m.Courses.GetEnrollment(r.Context(), course.ID, term.ID, user.ID)
+	if err != nil && !errors.Is(err, repository.ErrNotFound) {
+		dbError(w, err)
+		return
+	}
+	if !e.Active() {
+		m.renderEnrollment(w, r, course, "", []string{"You aren't enrolled in " + course.Code})
+		return
+	}
+	if e.Status == models.EnrollmentEnrolled && !term.DropOpen(time.Now()) {
+		m.renderEnrollment(w, r, course, "", []string{"The drop deadline for " + term.Name + " has passed"})
+		return
+	}
+
+	promoted, err := m.Courses.DropEnrollment(r.Context(), course.ID, term.ID, user.ID)
+	if err != nil {
+		dbError(w, err)
+		return
+	}
+
+	log.Printf("🎓 %s dropped %s in %s", user.Email, course.Code, term.Name)
+	m.notifyPromoted(r.Context(), course, promoted)
+
+	flash := "You dropped " + course.Code
+	if e.Status == models.EnrollmentWaitlisted {
+		flash = "You left the waitlist for " + course.Code
+	}
+	m.renderEnrollment(w, r, course, flash, nil)
+}
+
+// enrollmentTerm is the current term, or an error on the enrollment panel
+// when none has been chosen
+func (m *Repository) enrollmentTerm(w http.ResponseWriter, r *http.Request, course *models.Course) (*models.Term, bool) {
+	term, err := m.Terms.GetCurrentTerm(r.Context())
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			m.renderEnrollment(w, r, course, "", []string{"Enrollment isn't open yet"})
+			return nil, false
+		}
+		dbError(w, err)
+		return nil, false
+	}
+	return term, true
+}
+
+// notifyPromoted emails the students given a seat in the course off a
+// waitlist, in whichever term they were waiting for
+func (m *Repository) notifyPromoted(ctx context.Context, course *models.Course, promoted []models.Enrollment) {
+	terms := map[string]*models.Term{}
+	for _, p := range promoted {
+		term, ok := terms[p.TermID]
+		if !ok {
+			t, err := m.Terms.GetTerm(ctx, p.TermID)
+			if err != nil {
+				log.Println("❌ Failed to load term for waitlist promotion email:", err)
+				continue
+			}
+			term, terms[p.TermID] = t, t
+		}
+		if err := m.sendPromotion(ctx, p.StudentID, course, term); err != nil {
+			log.Println("❌ Failed to send waitlist promotion email:", err)
+		}
+	}
+}
+
+// sendPromotion tells a waitlisted student they have been given a seat
+func (m *Repository) sendPromotion(ctx context.Context, studentID string, course *models.Course, term *models.Term) error {
+	student, err := m.DB.GetUserByID(ctx, studentID)
+	if err != nil {
+		return err
+	}
+
+	link := m.App.Server.BaseURL + "/courses/" + course.ID
+	return m.App.Mailer.Send(ctx, mail.Message{
+		To:      []string{student.Email},
+		Subject: "You're enrolled in " + course.Code,
+		Text: "Hi " + student.Name + ",\n\n" +
+			"A seat opened up in " + course.Code + " " + course.Title + " for " + term.Name + ", " +
+			"and as you were next on the waitlist you are now enrolled.\n\n" +
+			link + "\n\n" +
+			"If you no longer want the seat, drop the course before " + term.DropDeadline.Format("2 January 2006") + ".\n",
+	})
+}
+
+// MyCoursesPage lists the courses the student takes or waits for in a term,
+// the current one unless another is picked
+func (m *Repository) MyCoursesPage(w http.ResponseWriter, r *http.Request) {
+	user := CurrentUser(r.Context())
+
+	terms, err := m.Terms.GetTerms(r.Context())
+	if err != nil {
+		dbError(w, err)
+		return
+	}
+	data := map[string]interface{}{"title": "My courses", "terms": terms}
+
+	units := 0
+	term, err := m.selectedTerm(r.Context(), r.URL.Query().Get("term"))
+	if err != nil {
+		dbError(w, err)
+		return
+	}
+	if term != nil {
+		enrollments, err := m.Courses.GetStudentEnrollments(r.Context(), user.ID, term.ID)
+		if err != nil {
+			dbError(w, err)
+			return
+		}
+		for _, e := range enrollments {
+			if e.Status == models.EnrollmentEnrolled {
+				units += e.CreditUnits
+			}
+		}
+		data["term"], data["enrollments"] = term, enrollments
+	}
+
+	err = templates.MyCoursesPage(m.AddDefaultData(&models.TemplateData{
+		Data:   data,
+		IntMap: map[string]int{"units": units},
+	}, r)).Render(r.Context(), w)
+	if err != nil {
+		log.Println("❌ Template render error:", err)
+	}
+}
+
+// RosterPage lists a course's students and waitlist for a term, the current
+// one unless another is picked
+func (m *Repository) RosterPage(w http.ResponseWriter, r *http.Request) {
+	course, ok := m.loadEditableCourse(w, r)
+	if !ok {
+		return
+	}
+
+	terms, err := m.Terms.GetTerms(r.Context())
+	if err != nil {
+		dbError(w, err)
+		return
+	}
+
+	term, err := m.selectedTerm(r.Context(), r.URL.Query().Get("term"))
+	if err != nil {
+		dbError(w, err)
+		return
+	}
+
+	data := map[string]interface{}{"title": course.Code + " roster", "course": course, "terms": terms}
+	if term != nil {
+		roster, err := m.Courses.GetRoster(r.Context(), course.ID, term.ID)
+		if err != nil {
+			dbError(w, err)
+			return
+		}
+		seats, err := m.Courses.GetSeats(r.Context(), course.ID, term.ID)
+		if err != nil {
+			dbError(w, err)
+			return
+		}
+		data["term"], data["roster"], data["seats"] = term, roster, seats
+	}
+
+	err = templates.RosterPage(m.AddDefaultData(&models.TemplateData{Data: data}, r)).Render(r.Context(), w)
+	if err != nil {
+		log.Println("❌ Template render error:", err)
+	}
+}
+
+// selectedTerm is the term with the given id, or the current term when id is
+// empty. It is nil when there is no such term.
+func (m *Repository) selectedTerm(ctx context.Context, id string) (*models.Term, error) {
+	var term *models.Term
+	var err error
+	if id == "" {
+		term, err = m.Terms.GetCurrentTerm(ctx)
+	} else if _, perr := uuid.Parse(id); perr != nil {
+		return nil, nil
+	} else {
+		term, err = m.Terms.GetTerm(ctx, id)
+	}
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, nil
+	}
+	return term, err
+}
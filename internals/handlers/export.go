@@ -0,0 +1,112 @@
+package handlers
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+
+	"github.com/stackninja.pro/goth/internals/models"
+	"github.com/stackninja.pro/goth/internals/xlsx"
+)
+
+// userExporter writes users one at a time in some file format. Close
+// finishes the file and must be called even when a Write fails.
+type userExporter interface {
+	Write(u *models.User) error
+	Close() error
+}
+
+// newUserExporter starts a file of the given format with a header row of
+// cols, when the format has one
+func newUserExporter(w io.Writer, format models.ExportFormat, cols []models.ExportColumn) (userExporter, error) {
+	switch format {
+	case models.ExportNDJSON:
+		return &ndjsonExporter{bw: bufio.NewWriter(w), cols: cols}, nil
+	case models.ExportXLSX:
+		xw, err := xlsx.NewWriter(w, "Users")
+		if err != nil {
+			return nil, err
+		}
+		header := make([]string, len(cols))
+		for i, c := range cols {
+			header[i] = c.Label
+		}
+		return &xlsxExporter{xw: xw, cols: cols}, xw.WriteRow(header)
+	}
+
+	cw := csv.NewWriter(w)
+	header := make([]string, len(cols))
+	for i, c := range cols {
+		header[i] = c.Key
+	}
+	return &csvExporter{cw: cw, cols: cols}, cw.Write(header)
+}
+
+// exportRow is u's value for each of cols
+func exportRow(u *models.User, cols []models.ExportColumn) []string {
+	row := make([]string, len(cols))
+	for i, c := range cols {
+		row[i] = c.Value(u)
+	}
+	return row
+}
+
+type csvExporter struct {
+	cw   *csv.Writer
+	cols []models.ExportColumn
+}
+
+func (e *csvExporter) Write(u *models.User) error {
+	row := exportRow(u, e.cols)
+	for i := range row {
+		row[i] = csvCell(row[i])
+	}
+	return e.cw.Write(row)
+}
+
+func (e *csvExporter) Close() error {
+	e.cw.Flush()
+	return e.cw.Error()
+}
+
+// ndjsonExporter writes one JSON object per line, keyed by column, with the
+// keys in column order
+type ndjsonExporter struct {
+	bw   *bufio.Writer
+	cols []models.ExportColumn
+}
+
+func (e *ndjsonExporter) Write(u *models.User) error {
+	e.bw.WriteByte('{')
+	for i, c := range e.cols {
+		if i > 0 {
+			e.bw.WriteByte(',')
+		}
+		key, _ := json.Marshal(c.Key)
+		value, _ := json.Marshal(c.Value(u))
+		e.bw.Write(key)
+		e.bw.WriteByte(':')
+		e.bw.Write(value)
+	}
+	_, err := e.bw.WriteString("}\n")
+	return err
+}
+
+func (e *ndjsonExporter) Close() error {
+	return e.bw.Flush()
+}
+
+// xlsxExporter needs no formula escaping: every cell is written as text
+type xlsxExporter struct {
+	xw   *xlsx.Writer
+	cols []models.ExportColumn
+}
+
+func (e *xlsxExporter) Write(u *models.User) error {
+	return e.xw.WriteRow(exportRow(u, e.cols))
+}
+
+func (e *xlsxExporter) Close() error {
+	return e.xw.Close()
+}
@@ -0,0 +1,463 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/a-h/templ"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/stackninja.pro/goth/internals/models"
+	"github.com/stackninja.pro/goth/internals/repository"
+	"github.com/stackninja.pro/goth/web/templates"
+)
+
+// GradebookPage shows a course's assessments and the score grid of its
+// enrolled students for a term, the current one unless another is picked
+func (m *Repository) GradebookPage(w http.ResponseWriter, r *http.Request) {
+	course, ok := m.loadEditableCourse(w, r)
+	if !ok {
+		return
+	}
+	term, err := m.selectedTerm(r.Context(), r.URL.Query().Get("term"))
+	if err != nil {
+		dbError(w, err)
+		return
+	}
+	m.renderGradebook(w, r, course, term, "", nil)
+}
+
+// renderGradebook shows the gradebook with an optional message. HTMX
+// requests only get the gradebook itself back.
+func (m *Repository) renderGradebook(w http.ResponseWriter, r *http.Request, course *models.Course, term *models.Term, flash string, errs []string) {
+	terms, err := m.Terms.GetTerms(r.Context())
+	if err != nil {
+		dbError(w, err)
+		return
+	}
+
+	data := map[string]interface{}{"title": course.Code + " gradebook", "course": course, "terms": terms}
+	if term != nil {
+		if err := m.gradebookData(r, course.ID, term, data); err != nil {
+			dbError(w, err)
+			return
+		}
+	}
+
+	page := templates.GradebookPage(m.AddDefaultData(&models.TemplateData{Data: data, Flash: flash, Errors: errs}, r))
+	if isHTMX(r) {
+		templ.Handler(page, templ.WithFragments("gradebook")).ServeHTTP(w, r)
+		return
+	}
+	if err := page.Render(r.Context(), w); err != nil {
+		log.Println("❌ Template render error:", err)
+	}
+}
+
+// gradebookData adds the term's assessments, enrolled students with the
+// scales they are graded on, scores and publish state to data
+func (m *Repository) gradebookData(r *http.Request, courseID string, term *models.Term, data map[string]interface{}) error {
+	assessments, err := m.Grades.GetAssessments(r.Context(), courseID, term.ID)
+	if err != nil {
+		return err
+	}
+	roster, err := m.Courses.GetRoster(r.Context(), courseID, term.ID)
+	if err != nil {
+		return err
+	}
+	scores, err := m.Grades.GetScores(r.Context(), courseID, term.ID)
+	if err != nil {
+		return err
+	}
+	published, err := m.Grades.GetGradesPublished(r.Context(), courseID, term.ID)
+	if err != nil {
+		return err
+	}
+
+	var students []models.Enrollment
+	var ids []string
+	for _, e := range roster {
+		if e.Status == models.EnrollmentEnrolled {
+			students = append(students, e)
+			ids = append(ids, e.StudentID)
+		}
+	}
+	scales, err := m.Scales.GetStudentScales(r.Context(), ids)
+	if err != nil {
+		return err
+	}
+	data["term"], data["assessments"], data["students"], data["scores"], data["scales"] = term, assessments, students, scores, scales
+	if published != nil {
+		data["published"] = *published
+	}
+	return nil
+}
+
+// CreateAssessment adds an assessment to the course's gradebook for a term
+func (m *Repository) CreateAssessment(w http.ResponseWriter, r *http.Request) {
+	user := CurrentUser(r.Context())
+
+	course, ok := m.loadEditableCourse(w, r)
+	if !ok {
+		return
+	}
+	term, ok := m.formTerm(w, r)
+	if !ok {
+		return
+	}
+
+	a, errs := parseAssessmentForm(r)
+	a.CourseID, a.TermID = course.ID, term.ID
+	if len(errs) == 0 {
+		var err error
+		if errs, err = m.checkAssessment(r, a); err != nil {
+			dbError(w, err)
+			return
+		}
+	}
+	if len(errs) > 0 {
+		m.renderGradebook(w, r, course, term, "", errs)
+		return
+	}
+
+	if _, err := m.Grades.CreateAssessment(r.Context(), a); err != nil {
+		dbError(w, err)
+		return
+	}
+
+	log.Printf("📊 %s added %s to %s in %s", user.Email, a.Name, course.Code, term.Name)
+	m.renderGradebook(w, r, course, term, a.Name+" added", nil)
+}
+
+// UpdateAssessment saves an assessment's name, category, weight and maximum
+func (m *Repository) UpdateAssessment(w http.ResponseWriter, r *http.Request) {
+	user := CurrentUser(r.Context())
+
+	course, old, term, ok := m.loadAssessment(w, r)
+	if !ok {
+		return
+	}
+
+	a, errs := parseAssessmentForm(r)
+	a.ID, a.CourseID, a.TermID = old.ID, old.CourseID, old.TermID
+	if len(errs) == 0 {
+		var err error
+		if errs, err = m.checkAssessment(r, a); err != nil {
+			dbError(w, err)
+			return
+		}
+	}
+	if len(errs) > 0 {
+		m.renderGradebook(w, r, course, term, "", errs)
+		return
+	}
+
+	if err := m.Grades.UpdateAssessment(r.Context(), a); err != nil {
+		dbError(w, err)
+		return
+	}
+
+	log.Printf("📊 %s updated %s in %s for %s", user.Email, a.Name, course.Code, term.Name)
+	m.renderGradebook(w, r, course, term, a.Name+" saved", nil)
+}
+
+// DeleteAssessment removes an assessment along with its scores
+func (m *Repository) DeleteAssessment(w http.ResponseWriter, r *http.Request) {
+	user := CurrentUser(r.Context())
+
+	course, a, term, ok := m.loadAssessment(w, r)
+	if !ok {
+		return
+	}
+	if err := m.Grades.DeleteAssessment(r.Context(), a.ID); err != nil {
+		dbError(w, err)
+		return
+	}
+
+	log.Printf("📊 %s deleted %s from %s in %s", user.Email, a.Name, course.Code, term.Name)
+	m.renderGradebook(w, r, course, term, a.Name+" deleted", nil)
+}
+
+// SaveScore records, changes or clears one student's score and answers with
+// their updated row of the grid
+func (m *Repository) SaveScore(w http.ResponseWriter, r *http.Request) {
+	user := CurrentUser(r.Context())
+
+	course, ok := m.loadEditableCourse(w, r)
+	if !ok {
+		return
+	}
+	id := r.FormValue("assessment")
+	if _, err := uuid.Parse(id); err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	a, err := m.Grades.GetAssessment(r.Context(), id)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			http.NotFound(w, r)
+			return
+		}
+		dbError(w, err)
+		return
+	}
+	if a.CourseID != course.ID {
+		http.NotFound(w, r)
+		return
+	}
+	student, ok := m.loadStudent(w, r, course.ID, a.TermID, r.FormValue("student"))
+	if !ok {
+		return
+	}
+
+	var errs []string
+	score, err := parseScore(r.FormValue("score"), a.MaxScore)
+	if err != nil {
+		errs = append(errs, err.Error())
+	} else if err := m.Grades.SetScore(r.Context(), a.ID, student.StudentID, score, user.Email); err != nil {
+		dbError(w, err)
+		return
+	} else {
+		log.Printf("📊 %s graded %s on %s in %s", user.Email, student.StudentEmail, a.Name, course.Code)
+	}
+
+	m.renderGradebookRow(w, r, course, a.TermID, student, errs)
+}
+
+// renderGradebookRow answers with one student's row of the score grid
+func (m *Repository) renderGradebookRow(w http.ResponseWriter, r *http.Request, course *models.Course, termID string, student *models.Enrollment, errs []string) {
+	assessments, err := m.Grades.GetAssessments(r.Context(), course.ID, termID)
+	if err != nil {
+		dbError(w, err)
+		return
+	}
+	scores, err := m.Grades.GetScores(r.Context(), course.ID, termID)
+	if err != nil {
+		dbError(w, err)
+		return
+	}
+	scales, err := m.Scales.GetStudentScales(r.Context(), []string{student.StudentID})
+	if err != nil {
+		dbError(w, err)
+		return
+	}
+
+	td := m.AddDefaultData(&models.TemplateData{
+		Data:   map[string]interface{}{"course": course, "assessments": assessments, "scores": scores, "scales": scales},
+		Errors: errs,
+	}, r)
+	if err := templates.GradebookRow(td, termID, *student).Render(r.Context(), w); err != nil {
+		log.Println("❌ Template render error:", err)
+	}
+}
+
+// ScoreHistoryPage lists every change made to one student's scores in the
+// course for a term
+func (m *Repository) ScoreHistoryPage(w http.ResponseWriter, r *http.Request) {
+	course, ok := m.loadEditableCourse(w, r)
+	if !ok {
+		return
+	}
+	termID := r.URL.Query().Get("term")
+	if _, err := uuid.Parse(termID); err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	student, ok := m.loadStudent(w, r, course.ID, termID, r.URL.Query().Get("student"))
+	if !ok {
+		return
+	}
+
+	changes, err := m.Grades.GetScoreHistory(r.Context(), course.ID, termID, student.StudentID)
+	if err != nil {
+		dbError(w, err)
+		return
+	}
+
+	td := m.AddDefaultData(&models.TemplateData{Data: map[string]interface{}{"student": student, "changes": changes}}, r)
+	if err := templates.ScoreHistory(td).Render(r.Context(), w); err != nil {
+		log.Println("❌ Template render error:", err)
+	}
+}
+
+// PublishGrades shows the course's grades for a term to its students, or
+// hides them again
+func (m *Repository) PublishGrades(w http.ResponseWriter, r *http.Request) {
+	user := CurrentUser(r.Context())
+
+	course, ok := m.loadEditableCourse(w, r)
+	if !ok {
+		return
+	}
+	term, ok := m.formTerm(w, r)
+	if !ok {
+		return
+	}
+
+	publish := r.FormValue("publish") == "true"
+	if err := m.Grades.SetGradesPublished(r.Context(), course.ID, term.ID, publish); err != nil {
+		dbError(w, err)
+		return
+	}
+
+	verb, flash := "unpublished", "Grades hidden from students"
+	if publish {
+		verb, flash = "published", "Grades published to students"
+	}
+	log.Printf("📊 %s %s grades for %s in %s", user.Email, verb, course.Code, term.Name)
+	m.renderGradebook(w, r, course, term, flash, nil)
+}
+
+// formTerm fetches the term named by the submitted form, answering 404 when
+// there is none
+func (m *Repository) formTerm(w http.ResponseWriter, r *http.Request) (*models.Term, bool) {
+	id := r.FormValue("term")
+	if id == "" {
+		http.NotFound(w, r)
+		return nil, false
+	}
+	term, err := m.selectedTerm(r.Context(), id)
+	if err != nil {
+		dbError(w, err)
+		return nil, false
+	}
+	if term == nil {
+		http.NotFound(w, r)
+		return nil, false
+	}
+	return term, true
+}
+
+// loadAssessment fetches the editable course and the assessment of it named
+// in the URL, with the assessment's term
+func (m *Repository) loadAssessment(w http.ResponseWriter, r *http.Request) (*models.Course, *models.Assessment, *models.Term, bool) {
+	course, ok := m.loadEditableCourse(w, r)
+	if !ok {
+		return nil, nil, nil, false
+	}
+	id := chi.URLParam(r, "aid")
+	if _, err := uuid.Parse(id); err != nil {
+		http.NotFound(w, r)
+		return nil, nil, nil, false
+	}
+
+	a, err := m.Grades.GetAssessment(r.Context(), id)
+	if err == nil && a.CourseID != course.ID {
+		err = repository.ErrNotFound
+	}
+	var term *models.Term
+	if err == nil {
+		term, err = m.Terms.GetTerm(r.Context(), a.TermID)
+	}
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			http.NotFound(w, r)
+			return nil, nil, nil, false
+		}
+		dbError(w, err)
+		return nil, nil, nil, false
+	}
+	return course, a, term, true
+}
+
+// loadStudent fetches the enrollment of a student taking the course in the
+// term, answering 404 for anyone else
+func (m *Repository) loadStudent(w http.ResponseWriter, r *http.Request, courseID, termID, studentID string) (*models.Enrollment, bool) {
+	if _, err := uuid.Parse(studentID); err != nil {
+		http.NotFound(w, r)
+		return nil, false
+	}
+	e, err := m.Courses.GetEnrollment(r.Context(), courseID, termID, studentID)
+	if err != nil && !errors.Is(err, repository.ErrNotFound) {
+		dbError(w, err)
+		return nil, false
+	}
+	if err != nil || e.Status != models.EnrollmentEnrolled {
+		http.NotFound(w, r)
+		return nil, false
+	}
+	return e, true
+}
+
+// checkAssessment compares an otherwise valid assessment with the rest of
+// the gradebook: weights may not add up to more than 100%, and the maximum
+// may not drop below a score already given
+func (m *Repository) checkAssessment(r *http.Request, a models.Assessment) ([]string, error) {
+	var errs []string
+	assessments, err := m.Grades.GetAssessments(r.Context(), a.CourseID, a.TermID)
+	if err != nil {
+		return nil, err
+	}
+
+	weight := a.Weight
+	for _, other := range assessments {
+		if other.ID != a.ID {
+			weight += other.Weight
+		}
+	}
+	if weight > 100.005 {
+		errs = append(errs, fmt.Sprintf("Weights would add up to %s%%, more than 100%%", strconv.FormatFloat(weight, 'f', -1, 64)))
+	}
+
+	if a.ID != "" {
+		scores, err := m.Grades.GetScores(r.Context(), a.CourseID, a.TermID)
+		if err != nil {
+			return nil, err
+		}
+		for _, s := range scores {
+			if score, ok := s[a.ID]; ok && score > a.MaxScore {
+				errs = append(errs, "Some scores are above the new maximum of "+strconv.FormatFloat(a.MaxScore, 'f', -1, 64))
+				break
+			}
+		}
+	}
+	return errs, nil
+}
+
+// parseAssessmentForm reads and validates an assessment's name, category,
+// weight and maximum score
+func parseAssessmentForm(r *http.Request) (models.Assessment, []string) {
+	var errs []string
+	a := models.Assessment{Name: strings.TrimSpace(r.FormValue("name"))}
+
+	if a.Name == "" {
+		errs = append(errs, "Assessment name is required")
+	} else if utf8.RuneCountInString(a.Name) > models.MaxAssessmentName {
+		errs = append(errs, "Assessment name must be at most "+strconv.Itoa(models.MaxAssessmentName)+" characters")
+	}
+
+	var err error
+	if a.Category, err = models.ParseAssessmentCategory(r.FormValue("category")); err != nil {
+		errs = append(errs, "Choose a category")
+	}
+	if a.Weight, err = strconv.ParseFloat(r.FormValue("weight"), 64); err != nil || math.IsNaN(a.Weight) || a.Weight <= 0 || a.Weight > 100 {
+		errs = append(errs, "Weight must be more than 0 and at most 100")
+	}
+	a.Weight = math.Round(a.Weight*100) / 100
+	if a.MaxScore, err = strconv.ParseFloat(r.FormValue("max_score"), 64); err != nil || math.IsNaN(a.MaxScore) || a.MaxScore <= 0 || a.MaxScore > models.MaxAssessmentScore {
+		errs = append(errs, "Maximum score must be more than 0 and at most "+strconv.Itoa(models.MaxAssessmentScore))
+	}
+	a.MaxScore = math.Round(a.MaxScore*100) / 100
+	return a, errs
+}
+
+// parseScore reads a submitted score, rounded to hundredths. A blank score
+// clears the one given before.
+func parseScore(s string, maxScore float64) (*float64, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return nil, nil
+	}
+	score, err := strconv.ParseFloat(s, 64)
+	if err != nil || math.IsNaN(score) || score < 0 || score > maxScore {
+		return nil, errors.New("Scores must be between 0 and " + strconv.FormatFloat(maxScore, 'f', -1, 64))
+	}
+	score = math.Round(score*100) / 100
+	return &score, nil
+}
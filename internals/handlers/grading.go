@@ -0,0 +1,373 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/a-h/templ"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/stackninja.pro/goth/internals/models"
+	"github.com/stackninja.pro/goth/internals/repository"
+	"github.com/stackninja.pro/goth/web/templates"
+)
+
+// GradingPage lists the grading scales and the programmes graded on them
+func (m *Repository) GradingPage(w http.ResponseWriter, r *http.Request) {
+	m.renderGrading(w, r, "", nil)
+}
+
+// renderGrading shows the scales and programmes with an optional message.
+// HTMX requests only get the lists back.
+func (m *Repository) renderGrading(w http.ResponseWriter, r *http.Request, flash string, errs []string) {
+	scales, err := m.Scales.GetScales(r.Context())
+	if err != nil {
+		dbError(w, err)
+		return
+	}
+	programmes, err := m.Scales.GetProgrammes(r.Context())
+	if err != nil {
+		dbError(w, err)
+		return
+	}
+
+	page := templates.GradingPage(m.AddDefaultData(&models.TemplateData{
+		Data:   map[string]interface{}{"title": "Grading", "scales": scales, "programmes": programmes},
+		Flash:  flash,
+		Errors: errs,
+	}, r))
+
+	if isHTMX(r) {
+		templ.Handler(page, templ.WithFragments("grading")).ServeHTTP(w, r)
+		return
+	}
+	if err := page.Render(r.Context(), w); err != nil {
+		log.Println("❌ Template render error:", err)
+	}
+}
+
+// CreateScale adds a grading scale
+func (m *Repository) CreateScale(w http.ResponseWriter, r *http.Request) {
+	admin := CurrentUser(r.Context())
+
+	scale, errs := parseScaleForm(r)
+	if len(errs) > 0 {
+		m.renderGrading(w, r, "", errs)
+		return
+	}
+
+	if _, err := m.Scales.CreateScale(r.Context(), scale); err != nil {
+		m.gradingSaveError(w, r, scale.Name, err)
+		return
+	}
+
+	log.Printf("📐 %s added grading scale %s", admin.Email, scale.Name)
+	m.renderGrading(w, r, scale.Name+" added", nil)
+}
+
+// UpdateScale saves a grading scale's name, bands and degree classes
+func (m *Repository) UpdateScale(w http.ResponseWriter, r *http.Request) {
+	admin := CurrentUser(r.Context())
+
+	scale, errs := parseScaleForm(r)
+	scale.ID = chi.URLParam(r, "id")
+	if _, err := uuid.Parse(scale.ID); err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	if len(errs) > 0 {
+		m.renderGrading(w, r, "", errs)
+		return
+	}
+
+	if err := m.Scales.UpdateScale(r.Context(), scale); err != nil {
+		m.gradingSaveError(w, r, scale.Name, err)
+		return
+	}
+
+	log.Printf("📐 %s updated grading scale %s", admin.Email, scale.Name)
+	m.renderGrading(w, r, scale.Name+" saved", nil)
+}
+
+// SetDefaultScale makes a scale the one students outside any programme are
+// graded on
+func (m *Repository) SetDefaultScale(w http.ResponseWriter, r *http.Request) {
+	admin := CurrentUser(r.Context())
+
+	id := chi.URLParam(r, "id")
+	if _, err := uuid.Parse(id); err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	if err := m.Scales.SetDefaultScale(r.Context(), id); err != nil {
+		m.gradingSaveError(w, r, "", err)
+		return
+	}
+
+	log.Printf("📐 %s made grading scale %s the default", admin.Email, id)
+	m.renderGrading(w, r, "Default scale changed", nil)
+}
+
+// DeleteScale removes a scale that isn't the default and no programme uses
+func (m *Repository) DeleteScale(w http.ResponseWriter, r *http.Request) {
+	admin := CurrentUser(r.Context())
+
+	id := chi.URLParam(r, "id")
+	if _, err := uuid.Parse(id); err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	scale, err := m.Scales.GetScale(r.Context(), id)
+	if err != nil {
+		m.gradingSaveError(w, r, "", err)
+		return
+	}
+	if err := m.Scales.DeleteScale(r.Context(), id); err != nil {
+		m.gradingSaveError(w, r, scale.Name, err)
+		return
+	}
+
+	log.Printf("📐 %s deleted grading scale %s", admin.Email, scale.Name)
+	m.renderGrading(w, r, scale.Name+" deleted", nil)
+}
+
+// CreateProgramme adds a programme graded on one of the scales
+func (m *Repository) CreateProgramme(w http.ResponseWriter, r *http.Request) {
+	admin := CurrentUser(r.Context())
+
+	p, errs := parseProgrammeForm(r)
+	if len(errs) > 0 {
+		m.renderGrading(w, r, "", errs)
+		return
+	}
+
+	if _, err := m.Scales.CreateProgramme(r.Context(), p); err != nil {
+		m.gradingSaveError(w, r, p.Name, err)
+		return
+	}
+
+	log.Printf("📐 %s added programme %s", admin.Email, p.Name)
+	m.renderGrading(w, r, p.Name+" added", nil)
+}
+
+// UpdateProgramme saves a programme's name and scale
+func (m *Repository) UpdateProgramme(w http.ResponseWriter, r *http.Request) {
+	admin := CurrentUser(r.Context())
+
+	p, errs := parseProgrammeForm(r)
+	p.ID = chi.URLParam(r, "id")
+	if _, err := uuid.Parse(p.ID); err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	if len(errs) > 0 {
+		m.renderGrading(w, r, "", errs)
+		return
+	}
+
+	if err := m.Scales.UpdateProgramme(r.Context(), p); err != nil {
+		m.gradingSaveError(w, r, p.Name, err)
+		return
+	}
+
+	log.Printf("📐 %s updated programme %s", admin.Email, p.Name)
+	m.renderGrading(w, r, p.Name+" saved", nil)
+}
+
+// DeleteProgramme removes a programme; its students go back to the default
+// scale
+func (m *Repository) DeleteProgramme(w http.ResponseWriter, r *http.Request) {
+	admin := CurrentUser(r.Context())
+
+	p, ok := m.loadProgramme(w, r)
+	if !ok {
+		return
+	}
+	if err := m.Scales.DeleteProgramme(r.Context(), p.ID); err != nil {
+		m.gradingSaveError(w, r, p.Name, err)
+		return
+	}
+
+	log.Printf("📐 %s deleted programme %s", admin.Email, p.Name)
+	m.renderGrading(w, r, p.Name+" deleted", nil)
+}
+
+// gradingSaveError turns a failed save into a message above the scales
+func (m *Repository) gradingSaveError(w http.ResponseWriter, r *http.Request, name string, err error) {
+	switch {
+	case errors.Is(err, repository.ErrDuplicateScaleName):
+		m.renderGrading(w, r, "", []string{"Another grading scale is already called " + name})
+	case errors.Is(err, repository.ErrDuplicateProgrammeName):
+		m.renderGrading(w, r, "", []string{"Another programme is already called " + name})
+	case errors.Is(err, repository.ErrScaleInUse):
+		m.renderGrading(w, r, "", []string{name + " is the default scale or a programme still uses it"})
+	case errors.Is(err, repository.ErrNotFound):
+		http.NotFound(w, r)
+	default:
+		dbError(w, err)
+	}
+}
+
+// ProgrammePage lists a programme's students, for admins to add and remove
+// them
+func (m *Repository) ProgrammePage(w http.ResponseWriter, r *http.Request) {
+	p, ok := m.loadProgramme(w, r)
+	if !ok {
+		return
+	}
+	m.renderProgramme(w, r, p, "", nil)
+}
+
+// renderProgramme shows the programme's students with an optional message.
+// HTMX requests only get the list back.
+func (m *Repository) renderProgramme(w http.ResponseWriter, r *http.Request, p *models.Programme, flash string, errs []string) {
+	students, err := m.Scales.GetProgrammeStudents(r.Context(), p.ID)
+	if err != nil {
+		dbError(w, err)
+		return
+	}
+
+	page := templates.ProgrammePage(m.AddDefaultData(&models.TemplateData{
+		Data:   map[string]interface{}{"title": p.Name, "programme": p, "students": students},
+		Flash:  flash,
+		Errors: errs,
+	}, r))
+
+	if isHTMX(r) {
+		templ.Handler(page, templ.WithFragments("programme-students")).ServeHTTP(w, r)
+		return
+	}
+	if err := page.Render(r.Context(), w); err != nil {
+		log.Println("❌ Template render error:", err)
+	}
+}
+
+// AddProgrammeStudent moves a student, found by email, into the programme
+func (m *Repository) AddProgrammeStudent(w http.ResponseWriter, r *http.Request) {
+	admin := CurrentUser(r.Context())
+
+	p, ok := m.loadProgramme(w, r)
+	if !ok {
+		return
+	}
+
+	email := strings.TrimSpace(r.FormValue("email"))
+	student, err := m.DB.GetUserByEmail(r.Context(), email)
+	if err != nil && !errors.Is(err, repository.ErrNotFound) {
+		dbError(w, err)
+		return
+	}
+	if err != nil || student.DeletedAt != nil {
+		m.renderProgramme(w, r, p, "", []string{"No account uses " + email})
+		return
+	}
+	if student.Role != models.RoleStudent {
+		m.renderProgramme(w, r, p, "", []string{student.Name + " isn't a student"})
+		return
+	}
+
+	if err := m.Scales.SetStudentProgramme(r.Context(), student.ID, p.ID); err != nil {
+		dbError(w, err)
+		return
+	}
+
+	log.Printf("📐 %s added %s to programme %s", admin.Email, student.Email, p.Name)
+	m.renderProgramme(w, r, p, student.Name+" added", nil)
+}
+
+// RemoveProgrammeStudent takes a student out of the programme, back onto
+// the default scale
+func (m *Repository) RemoveProgrammeStudent(w http.ResponseWriter, r *http.Request) {
+	admin := CurrentUser(r.Context())
+
+	p, ok := m.loadProgramme(w, r)
+	if !ok {
+		return
+	}
+	id := chi.URLParam(r, "sid")
+	if _, err := uuid.Parse(id); err != nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	students, err := m.Scales.GetProgrammeStudents(r.Context(), p.ID)
+	if err != nil {
+		dbError(w, err)
+		return
+	}
+	for _, s := range students {
+		if s.ID != id {
+			continue
+		}
+		if err := m.Scales.SetStudentProgramme(r.Context(), id, ""); err != nil {
+			dbError(w, err)
+			return
+		}
+		log.Printf("📐 %s removed %s from programme %s", admin.Email, s.Email, p.Name)
+		m.renderProgramme(w, r, p, s.Name+" removed", nil)
+		return
+	}
+	http.NotFound(w, r)
+}
+
+// loadProgramme fetches the programme named in the URL, answering 404 for
+// ids that don't exist or aren't ids at all
+func (m *Repository) loadProgramme(w http.ResponseWriter, r *http.Request) (*models.Programme, bool) {
+	id := chi.URLParam(r, "id")
+	if _, err := uuid.Parse(id); err != nil {
+		http.NotFound(w, r)
+		return nil, false
+	}
+
+	p, err := m.Scales.GetProgramme(r.Context(), id)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			http.NotFound(w, r)
+			return nil, false
+		}
+		dbError(w, err)
+		return nil, false
+	}
+	return p, true
+}
+
+// parseScaleForm reads and validates a scale's name, grade bands and degree
+// classes
+func parseScaleForm(r *http.Request) (models.GradingScale, []string) {
+	var errs []string
+	s := models.GradingScale{Name: strings.TrimSpace(r.FormValue("name"))}
+
+	if s.Name == "" {
+		errs = append(errs, "Scale name is required")
+	} else if utf8.RuneCountInString(s.Name) > models.MaxScaleName {
+		errs = append(errs, "Scale name must be at most "+strconv.Itoa(models.MaxScaleName)+" characters")
+	}
+
+	var err error
+	if s.Bands, err = models.ParseBands(r.FormValue("bands")); err != nil {
+		errs = append(errs, "Grade bands: "+err.Error())
+	} else if s.Classes, err = models.ParseClasses(r.FormValue("classes"), s.MaxPoints()); err != nil {
+		errs = append(errs, "Degree classes: "+err.Error())
+	}
+	return s, errs
+}
+
+// parseProgrammeForm reads and validates a programme's name and scale
+func parseProgrammeForm(r *http.Request) (models.Programme, []string) {
+	var errs []string
+	p := models.Programme{Name: strings.TrimSpace(r.FormValue("name")), ScaleID: r.FormValue("scale")}
+
+	if p.Name == "" {
+		errs = append(errs, "Programme name is required")
+	} else if utf8.RuneCountInString(p.Name) > models.MaxProgrammeName {
+		errs = append(errs, "Programme name must be at most "+strconv.Itoa(models.MaxProgrammeName)+" characters")
+	}
+	if _, err := uuid.Parse(p.ScaleID); err != nil {
+		errs = append(errs, "Choose a grading scale")
+	}
+	return p, errs
+}
@@ -1,21 +1,22 @@
 package handlers
 
 import (
-	"context"
-	"database/sql"
+	"errors"
 	"fmt"
+	"io"
 	"log"
 	"net/http"
-	"path/filepath"
-	"strconv"
+	"slices"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/a-h/templ"
-	"github.com/cloudinary/cloudinary-go/v2"
-	"github.com/cloudinary/cloudinary-go/v2/api/uploader"
 	"github.com/google/uuid"
 	"github.com/stackninja.pro/goth/internals/driver"
+	"github.com/stackninja.pro/goth/internals/mail"
 	"github.com/stackninja.pro/goth/internals/models"
+	"github.com/stackninja.pro/goth/internals/ratelimit"
 	"github.com/stackninja.pro/goth/internals/repository"
 	"github.com/stackninja.pro/goth/internals/repository/dbrepo"
 	"github.com/stackninja.pro/goth/src/config"
@@ -29,15 +30,47 @@ var Repo *Repository
 
 // Repository holds the application data
 type Repository struct {
-	App *config.AppConfig
-	DB  repository.DatabaseRepo
+	App     *config.AppConfig
+	DB      repository.DatabaseRepo
+	Courses repository.CourseRepo
+	Terms   repository.TermRepo
+	Grades  repository.GradeRepo
+	Scales  repository.ScaleRepo
+	Conn    *driver.DB
+
+	// login attempts per client IP and per email address
+	IPLimiter      *ratelimit.Limiter
+	AccountLimiter *ratelimit.Limiter
+
+	// mail still being sent after its response went out
+	mailing sync.WaitGroup
 }
 
 // NewRepository creates a new Repository
 func NewRepository(a *config.AppConfig, db *driver.DB) *Repository {
+	r := NewRepositoryWithDB(a, dbrepo.NewPostgresRepo(a, db.Pool))
+	r.Conn = db
+	return r
+}
+
+// NewRepositoryWithDB creates a Repository around any DatabaseRepo, such as
+// the in-memory one used in tests. Courses, terms, grades and grading scales
+// are kept in the same store when it is also a CourseRepo, TermRepo, GradeRepo
+// and ScaleRepo, as both of ours are.
+func NewRepositoryWithDB(a *config.AppConfig, db repository.DatabaseRepo) *Repository {
+	courses, _ := db.(repository.CourseRepo)
+	terms, _ := db.(repository.TermRepo)
+	grades, _ := db.(repository.GradeRepo)
+	scales, _ := db.(repository.ScaleRepo)
 	return &Repository{
-		App: a,
-		DB:  dbrepo.NewPostgresRepo(a, db.Conn),
+		App:            a,
+		DB:             db,
+		Courses:        courses,
+		Terms:          terms,
+		Grades:         grades,
+		Scales:         scales,
+		IPLimiter:      ratelimit.New(a.Login.IPBurst, a.Login.IPRefill),
+		AccountLimiter: ratelimit.New(a.Login.AccountBurst, a.Login.AccountRefill),
 	}
 }
 
@@ -50,16 +83,11 @@ func (m *Repository) HomePage(w http.ResponseWriter, r *http.Request) {
 	log.Println("➡️ HomePage handler called")
 
 	users := []models.User{
-		{ID: "1", Name: "John Doe", Email: "john@example.com", Avatar: "/static/avatars/1.jpg", Category: 1, CreatedAt: time.Now()},
-		{ID: "2", Name: "Jane Smith", Email: "jane@example.com", Avatar: "/static/avatars/2.jpg", Category: 2, CreatedAt: time.Now()},
+		{ID: "1", Name: "John Doe", Email: "john@example.com", Avatar: "/static/avatars/1.jpg", Role: models.RoleAdmin, CreatedAt: time.Now()},
+		{ID: "2", Name: "Jane Smith", Email: "jane@example.com", Avatar: "/static/avatars/2.jpg", Role: models.RoleStudent, CreatedAt: time.Now()},
 	}
 
-	// Check user session
-	user, _, err := m.CheckUserAuthentication(w, r)
-	if err != nil {
-		log.Println("⛔ User not authenticated → redirect triggered")
-		return
-	}
+	user := CurrentUser(r.Context())
 
 	log.Println("✅ Logged in user passed to template:", user.Email)
 
@@ -68,24 +96,35 @@ func (m *Repository) HomePage(w http.ResponseWriter, r *http.Request) {
 		"userSession": user,
 	}
 
-	err = templates.HomePage(&models.TemplateData{
+	// students see their results once their instructors publish them,
+	// graded on their programme's scale
+	if user.Can(models.PermEnrollCourses) {
+		grades, err := m.Grades.GetPublishedGrades(r.Context(), user.ID)
+		if err != nil {
+			dbError(w, err)
+			return
+		}
+		scales, err := m.Scales.GetStudentScales(r.Context(), []string{user.ID})
+		if err != nil {
+			dbError(w, err)
+			return
+		}
+		userMap["transcript"] = models.NewTranscript(scales[user.ID], grades)
+	}
+
+	err := templates.HomePage(m.AddDefaultData(&models.TemplateData{
 		Data: userMap,
-	}).Render(r.Context(), w)
+	}, r)).Render(r.Context(), w)
 	if err != nil {
 		log.Println("❌ Template render error:", err)
 	}
 }
 
 func (m *Repository) AboutPage(w http.ResponseWriter, r *http.Request) {
-	// check if the user is authenticated
-	user, _, err := m.CheckUserAuthentication(w, r)
-	if err != nil {
-		log.Println("⛔ User not authenticated → redirect triggered")
-		return
-	}
+	user := CurrentUser(r.Context())
 
 	// Render the About page
-	templates.AboutPage(&models.TemplateData{Data: map[string]interface{}{"title": "Goth Stack Demo", "userSession": user}}).Render(r.Context(), w)
+	templates.AboutPage(m.AddDefaultData(&models.TemplateData{Data: map[string]interface{}{"title": "Goth Stack Demo", "userSession": user}}, r)).Render(r.Context(), w)
 }
 
 func (m *Repository) ShowRegisterPage(w http.ResponseWriter, r *http.Request) {
@@ -93,14 +132,14 @@ func (m *Repository) ShowRegisterPage(w http.ResponseWriter, r *http.Request) {
 	user := &models.User{}
 
 	// Prepare a template
-	td := &models.TemplateData{
+	td := m.AddDefaultData(&models.TemplateData{
 		Data: map[string]any{
 			"user": user,
 		},
 		StringMap: map[string]string{
 			"title": "Create an Account",
 		},
-	}
+	}, r)
 
 	registrationPage := templates.RegistrationPage(td)
 	registrationPage.Render(r.Context(), w)
@@ -118,24 +157,17 @@ func (m *Repository) RegisterUser(w http.ResponseWriter, r *http.Request) {
 	user.Name = r.FormValue("name")
 	user.Email = r.FormValue("email")
 	user.Password = r.FormValue("password")
-	user.Category, _ = strconv.Atoi(r.FormValue("category"))
 
 	// Validation
-	if user.Name == "" {
-		errorMessages = append(errorMessages, "Name is required")
-	}
-	if user.Email == "" {
-		errorMessages = append(errorMessages, "Email is required")
-	}
+	role, errs := registrationErrors(user.Name, user.Email, r.FormValue("role"))
+	errorMessages = append(errorMessages, errs...)
 	if user.Password == "" {
 		errorMessages = append(errorMessages, "Password is required")
 	}
-	if user.Category == 0 {
-		errorMessages = append(errorMessages, "Category is required")
-	}
+	user.Role = role
 
 	// Prepare template data
-	td := &models.TemplateData{
+	td := m.AddDefaultData(&models.TemplateData{
 		Data: map[string]interface{}{
 			"user": &user,
 		},
@@ -144,7 +176,7 @@ func (m *Repository) RegisterUser(w http.ResponseWriter, r *http.Request) {
 		},
 
 		Errors: errorMessages,
-	}
+	}, r)
 
 	registrationPage := templates.RegistrationPage(td)
 
@@ -169,19 +201,63 @@ func (m *Repository) RegisterUser(w http.ResponseWriter, r *http.Request) {
 	user.Avatar = "/static/avatars/default.jpg"
 
 	// Save user
-	if err := m.DB.CreateUser(user); err != nil {
-		td.Errors = append(td.Errors, "Failed to create user")
+	if err := m.DB.CreateUser(r.Context(), user); err != nil {
+		if errors.Is(err, repository.ErrDuplicateEmail) {
+			td.Errors = append(td.Errors, "An account with that email already exists")
+		} else {
+			td.Errors = append(td.Errors, "Failed to create user")
+		}
 		templ.Handler(registrationPage, templ.WithFragments("error-messages")).ServeHTTP(w, r)
 		return
 	}
 
+	// the account works without this; the user can resend from the notice page
+	if created, err := m.DB.GetUserByEmail(r.Context(), user.Email); err != nil {
+		log.Println("❌ Failed to load new user for verification:", err)
+	} else {
+		m.audit(r, models.AuditUserCreate, created, nil)
+		if err := m.sendVerification(r.Context(), created, created.Email); err != nil {
+			log.Println("❌ Failed to send verification email:", err)
+		}
+	}
+
 	// ✅ Success: redirect via HTMX
 	w.Header().Set("HX-Location", "/login")
 	w.WriteHeader(http.StatusNoContent)
 }
 
+// registrationErrors checks the details every new account needs, whether it
+// registers itself or is imported. Passwords are left to the caller, since
+// imported accounts may be invited to choose their own.
+func registrationErrors(name, email, roleValue string) (models.Role, []string) {
+	var errs []string
+	if name == "" {
+		errs = append(errs, "Name is required")
+	}
+	if email == "" {
+		errs = append(errs, "Email is required")
+	} else if !mail.ValidAddress(email) {
+		errs = append(errs, "Please enter a valid email address")
+	}
+
+	role, err := models.ParseRole(roleValue)
+	if roleValue == "" {
+		errs = append(errs, "Role is required")
+	} else if err != nil || !slices.Contains(models.SelfServiceRoles, role) {
+		errs = append(errs, "Please choose Student or Instructor")
+	}
+	return role, errs
+}
+
 func (m *Repository) LoginPage(w http.ResponseWriter, r *http.Request) {
-	templates.LoginPage(&models.TemplateData{}).Render(r.Context(), w)
+	td := &models.TemplateData{}
+	switch {
+	case r.URL.Query().Get("reset") == "done":
+		td.Flash = "Your password has been changed. Please log in with the new one."
+	case r.URL.Query().Has("deleted"):
+		td.Flash = fmt.Sprintf("Your account has been deleted. It will be removed for good in %d days.", m.retentionDays())
+	}
+	templates.LoginPage(m.AddDefaultData(td, r)).Render(r.Context(), w)
 }
 
 func (m *Repository) LoginUser(w http.ResponseWriter, r *http.Request) {
@@ -204,14 +280,14 @@ func (m *Repository) LoginUser(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Prepare template data
-	td := &models.TemplateData{
+	td := m.AddDefaultData(&models.TemplateData{
 
 		StringMap: map[string]string{
 			"title": "Login",
 		},
 
 		Errors: errorMessages,
-	}
+	}, r)
 
 	loginPage := templates.LoginPage(td)
 
@@ -221,86 +297,138 @@ func (m *Repository) LoginUser(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Rate limit by IP and by email before touching the database or bcrypt
+	if msg := m.throttleLogin(w, r, email); msg != "" {
+		td.Errors = append(td.Errors, msg)
+		templ.Handler(loginPage, templ.WithFragments("error-messages")).ServeHTTP(w, r)
+		return
+	}
+
 	// Retrieve user by email
-	user, err := m.DB.GetUserByEmail(email)
+	user, err := m.DB.GetUserByEmail(r.Context(), email)
+	if errors.Is(err, repository.ErrInactive) {
+		td.Errors = append(td.Errors, m.inactiveLogin(r, email, password))
+		templ.Handler(loginPage, templ.WithFragments("error-messages")).ServeHTTP(w, r)
+		return
+	}
 	if err != nil {
+		m.recordAudit(r, models.AuditEvent{Action: models.AuditLoginFailed, ActorEmail: email, TargetEmail: email})
 		td.Errors = append(td.Errors, "Invalid email or password")
 		templ.Handler(loginPage, templ.WithFragments("error-messages")).ServeHTTP(w, r)
 		return
 	}
 
+	// A locked account, or one inside its delay, isn't even checked
+	failures, err := m.DB.GetLoginFailures(r.Context(), user.ID)
+	if err != nil {
+		dbError(w, err)
+		return
+	}
+	if msg := m.lockedOut(failures); msg != "" {
+		td.Errors = append(td.Errors, msg)
+		templ.Handler(loginPage, templ.WithFragments("error-messages")).ServeHTTP(w, r)
+		return
+	}
+
 	// Check password and compare password
 	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(password)); err != nil {
-		td.Errors = append(td.Errors, "Invalid email or password")
+		msg, _ := m.loginFailed(r, user, "Invalid email or password", nil)
+		td.Errors = append(td.Errors, msg)
 		templ.Handler(loginPage, templ.WithFragments("error-messages")).ServeHTTP(w, r)
 		return
 	}
 
+	// the session stays half authenticated until the second step is done
+	if user.TwoFactorEnabled() {
+		if err := m.startTwoFactor(w, r, user); err != nil {
+			td.Errors = append(td.Errors, "Failed to create session")
+			templ.Handler(loginPage, templ.WithFragments("error-messages")).ServeHTTP(w, r)
+		}
+		return
+	}
+
 	// Create a session and authenticate user
-	session, err := m.App.Session.Get(r, "logged-in-user")
+	session, err := m.App.Session.Get(r, sessionName)
 	if err != nil {
 		td.Errors = append(td.Errors, "Failed to create session")
 		templ.Handler(loginPage, templ.WithFragments("error-messages")).ServeHTTP(w, r)
 		return
 	}
 
+	clearTwoFactor(session.Values)
 	session.Values["user_id"] = user.ID
-	if err := session.Save(r, w); err != nil {
+	// a fresh CSRF token stops a token planted before login from being reused
+	session.Values[csrfSessionKey] = newCSRFToken()
+
+	// a new session ID for the same reason
+	if err := m.App.Session.Rotate(r, w, session); err != nil {
 		td.Errors = append(td.Errors, "Failed to save session")
 		templ.Handler(loginPage, templ.WithFragments("error-messages")).ServeHTTP(w, r)
 		return
 	}
+	m.audit(r, models.AuditLogin, user, nil)
+	m.loginSucceeded(r, user, failures)
 
 	// ✅ Success: redirect via HTMX to home page
 	w.Header().Set("HX-Location", "/")
 	w.WriteHeader(http.StatusNoContent)
 }
 
+// inactiveLogin answers a sign-in to a suspended, deactivated or deleted
+// account. Only someone who knows the password is told why, so the status
+// doesn't leak which emails exist.
+func (m *Repository) inactiveLogin(r *http.Request, email, password string) string {
+	user, err := m.DB.AuthenticateUser(r.Context(), email, password)
+	if err != nil {
+		m.recordAudit(r, models.AuditEvent{Action: models.AuditLoginFailed, ActorEmail: email, TargetEmail: email})
+		return "Invalid email or password"
+	}
+
+	m.audit(r, models.AuditLoginFailed, user, nil)
+	if user.Deleted() {
+		return "This account has been deleted. Please contact an administrator if you'd like it restored."
+	}
+	return "This account is " + strings.ToLower(user.Status.Label()) + ". Please contact an administrator."
+}
+
 func (m *Repository) LogoutUser(w http.ResponseWriter, r *http.Request) {
-	session, err := m.App.Session.Get(r, "logged-in-user")
+	session, err := m.App.Session.Get(r, sessionName)
 	if err != nil {
 		log.Println("❌ Failed to get session:", err)
 		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
 		return
 	}
 
-	// remove the user from the session
-	delete(session.Values, "user_id")
+	if id, ok := session.Values["user_id"].(string); ok {
+		if user, err := m.DB.GetUserByID(r.Context(), id); err == nil {
+			m.audit(r, models.AuditLogout, user, nil)
+		}
+	}
+
+	// drop everything in the session and expire the cookie
+	session.Values = map[interface{}]interface{}{}
+	session.Options.MaxAge = -1
 
-	// save the change to database
 	if err := session.Save(r, w); err != nil {
 		log.Println("❌ Failed to save session:", err)
 		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
 		return
 	}
 
-	// Clear session
-	session.Options.MaxAge = -1
-	session.Values["user_id"] = nil
-
 	//redirect
-	http.Redirect(w, r, "/login", http.StatusSeeOther)
+	redirect(w, r, "/login")
 }
 
 func (m *Repository) EditProfilePage(w http.ResponseWriter, r *http.Request) {
-	// check if the user is authenticated
-	user, _, err := m.CheckUserAuthentication(w, r)
-	if err != nil {
-		log.Println("⛔ User not authenticated → redirect triggered")
-		return
-	}
+	user := CurrentUser(r.Context())
 
 	// Render the Edit Profile page
-	templates.EditProfile(&models.TemplateData{Data: map[string]interface{}{"title": "Goth Stack Demo", "userSession": user}}).Render(r.Context(), w)
+	templates.EditProfile(m.AddDefaultData(&models.TemplateData{Data: map[string]interface{}{"title": "Goth Stack Demo", "userSession": user}}, r)).Render(r.Context(), w)
 }
 
 func (m *Repository) UpdateProfile(w http.ResponseWriter, r *http.Request) {
-	// check if the user is authenticated
-	currentUserProfile, userID, err := m.CheckUserAuthentication(w, r)
-	if err != nil {
-		log.Println("⛔ User not authenticated → redirect triggered")
-		return
-	}
+	currentUserProfile := CurrentUser(r.Context())
+	userID := currentUserProfile.ID
 
 	var errorMessages []string
 
@@ -320,13 +448,15 @@ func (m *Repository) UpdateProfile(w http.ResponseWriter, r *http.Request) {
 	}
 	if email == "" {
 		errorMessages = append(errorMessages, "Email is required")
+	} else if !mail.ValidAddress(email) {
+		errorMessages = append(errorMessages, "Please enter a valid email address")
 	}
 	if dobStr == "" {
 		errorMessages = append(errorMessages, "Date of Birth is required")
 	}
 
 	// Prepare template data
-	td := &models.TemplateData{
+	td := m.AddDefaultData(&models.TemplateData{
 		Data: map[string]interface{}{
 			"userSession": currentUserProfile,
 		},
@@ -335,7 +465,7 @@ func (m *Repository) UpdateProfile(w http.ResponseWriter, r *http.Request) {
 		},
 
 		Errors: errorMessages,
-	}
+	}, r)
 
 	editProfilePage := components.EditProfileForm(td)
 
@@ -353,167 +483,189 @@ func (m *Repository) UpdateProfile(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// A new address only takes over once it is confirmed
+	if email != currentUserProfile.Email && email != currentUserProfile.PendingEmail {
+		if err := m.DB.SetPendingEmail(r.Context(), userID, email); err != nil {
+			if errors.Is(err, repository.ErrDuplicateEmail) {
+				td.Errors = append(td.Errors, "An account with that email already exists")
+			} else {
+				log.Println("❌ DB update error:", err)
+				td.Errors = append(td.Errors, "Failed to update profile")
+			}
+			templ.Handler(editProfilePage, templ.WithFragments("error-messages")).ServeHTTP(w, r)
+			return
+		}
+		if err := m.sendVerification(r.Context(), currentUserProfile, email); err != nil {
+			log.Println("❌ Failed to send verification email:", err)
+		}
+		if err := m.sendEmailChangeNotice(r.Context(), currentUserProfile, email); err != nil {
+			log.Println("❌ Failed to send email change notice:", err)
+		}
+	} else if email == currentUserProfile.Email && currentUserProfile.PendingEmail != "" {
+		// putting the old address back cancels the change
+		if err := m.DB.SetPendingEmail(r.Context(), userID, ""); err != nil {
+			log.Println("❌ DB update error:", err)
+		}
+	}
+
 	// Update user fields
 	user := &models.User{
-		ID:       userID,
-		Name:     name,
-		Email:    email,
-		DOB:      dob,
-		Bio:      bio,
-		Category: currentUserProfile.Category,
+		ID:    userID,
+		Name:  name,
+		Email: currentUserProfile.Email,
+		DOB:   dob,
+		Bio:   bio,
+		Role:  currentUserProfile.Role,
 	}
 
 	// Update user in DB
-	if err := m.DB.UpdateUser(userID, *user); err != nil {
+	if err := m.DB.UpdateUser(r.Context(), userID, *user); err != nil {
 		log.Println("❌ DB update error:", err) // <-- log actual error
-		td.Errors = append(td.Errors, "Failed to update profile")
+		if errors.Is(err, repository.ErrDuplicateEmail) {
+			td.Errors = append(td.Errors, "An account with that email already exists")
+		} else {
+			td.Errors = append(td.Errors, "Failed to update profile")
+		}
 		templ.Handler(editProfilePage, templ.WithFragments("error-messages")).ServeHTTP(w, r)
 		return
 	}
 
+	if updated, err := m.DB.GetUserByID(r.Context(), userID); err == nil {
+		if changes := models.Diff(currentUserProfile.AuditFields(), updated.AuditFields()); len(changes) > 0 {
+			m.audit(r, models.AuditProfileUpdate, updated, changes)
+		}
+	}
+
 	// ✅ Success: redirect via HTMX to home page
 	w.Header().Set("HX-Location", "/")
 	w.WriteHeader(http.StatusNoContent)
 }
 
 func (m *Repository) ChangeAvatarPage(w http.ResponseWriter, r *http.Request) {
-	// check if the user is authenticated
-	user, _, err := m.CheckUserAuthentication(w, r)
-	if err != nil {
-		log.Println("⛔ User not authenticated → redirect triggered")
-		return
-	}
+	user := CurrentUser(r.Context())
 
 	// Render the Change Avatar page
-	templates.ChangeAvatar(&models.TemplateData{Data: map[string]interface{}{"title": "Change Avatar", "userSession": user}}).Render(r.Context(), w)
+	templates.ChangeAvatar(m.AddDefaultData(&models.TemplateData{Data: map[string]interface{}{"title": "Change Avatar", "userSession": user}}, r)).Render(r.Context(), w)
+}
+
+// avatarTypes are the image formats accepted as avatars, by sniffed content type
+var avatarTypes = map[string]string{
+	"image/jpeg": ".jpg",
+	"image/png":  ".png",
+	"image/gif":  ".gif",
+	"image/webp": ".webp",
 }
 
 func (m *Repository) UploadAvatar(w http.ResponseWriter, r *http.Request) {
-	user, _, err := m.CheckUserAuthentication(w, r)
-	if err != nil {
-		log.Println("⛔ User not authenticated → redirect triggered")
-		return
-	}
+	user := CurrentUser(r.Context())
 
-	var errorMessages []string
+	// fail re-renders the page with msg
+	fail := func(msg string) {
+		templates.ChangeAvatar(m.AddDefaultData(&models.TemplateData{
+			Data:   map[string]interface{}{"title": "Change Avatar", "userSession": user},
+			Errors: []string{msg},
+		}, r)).Render(r.Context(), w)
+	}
 
 	// Parse multipart form (10MB max)
-	err = r.ParseMultipartForm(10 << 20)
+	err := r.ParseMultipartForm(10 << 20)
 	if err != nil {
 		log.Println("❌ Failed to parse multipart form:", err)
-		errorMessages = append(errorMessages, "Failed to parse form data")
-		templates.ChangeAvatar(&models.TemplateData{
-			Data:   map[string]interface{}{"title": "Change Avatar", "userSession": user},
-			Errors: errorMessages,
-		}).Render(r.Context(), w)
+		fail("Failed to parse form data")
 		return
 	}
 
-	file, handler, err := r.FormFile("avatar")
+	file, _, err := r.FormFile("avatar")
 	if err != nil {
-		errorMessages = append(errorMessages, "No file uploaded")
-		templates.ChangeAvatar(&models.TemplateData{
-			Data:   map[string]interface{}{"title": "Change Avatar", "userSession": user},
-			Errors: errorMessages,
-		}).Render(r.Context(), w)
+		fail("No file uploaded")
 		return
 	}
 	defer file.Close()
 
-	// ✅ Generate unique public ID
-	uuid, _ := uuid.NewRandom()
-	publicID := "avatars/" + uuid.String() + filepath.Ext(handler.Filename)
-
-	// ✅ Init Cloudinary
-	cld, err := cloudinary.NewFromParams(
-		"dvmoamnui",
-		"225745419446694",
-		"40bN_Rd4TBJKQEzj4d-AoXb2AMs",
-	)
-	if err != nil {
-		log.Println("❌ Cloudinary init failed:", err)
-		errorMessages = append(errorMessages, "Cloud upload setup failed")
-		templates.ChangeAvatar(&models.TemplateData{
-			Data:   map[string]interface{}{"title": "Change Avatar", "userSession": user},
-			Errors: errorMessages,
-		}).Render(r.Context(), w)
+	// trust the bytes, not the file name, for the type
+	head := make([]byte, 512)
+	n, _ := io.ReadFull(file, head)
+	contentType := http.DetectContentType(head[:n])
+	ext, ok := avatarTypes[contentType]
+	if !ok {
+		fail("Please upload a JPEG, PNG, GIF or WebP image")
+		return
+	}
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		log.Println("❌ Failed to rewind upload:", err)
+		fail("Failed to upload image")
 		return
 	}
 
-	// ✅ Upload directly
-	uploadResult, err := cld.Upload.Upload(context.Background(), file, uploader.UploadParams{
-		PublicID: publicID,
-		Folder:   "avatars",
-	})
-	if err != nil {
-		log.Println("❌ Cloudinary upload failed:", err)
-		errorMessages = append(errorMessages, "Failed to upload image")
-		templates.ChangeAvatar(&models.TemplateData{
-			Data:   map[string]interface{}{"title": "Change Avatar", "userSession": user},
-			Errors: errorMessages,
-		}).Render(r.Context(), w)
+	// ✅ Generate unique key
+	id, _ := uuid.NewRandom()
+	key := "avatars/" + id.String() + ext
+
+	if err := m.App.Blob.Put(r.Context(), key, file, contentType); err != nil {
+		log.Println("❌ Avatar upload failed:", err)
+		fail("Failed to upload image")
 		return
 	}
 
-	// ✅ Save Cloudinary URL in DB
-	if err := m.DB.UpdateUserAvatar(user.ID, uploadResult.SecureURL); err != nil {
+	if err := m.DB.UpdateUserAvatar(r.Context(), user.ID, m.App.Blob.URL(key), key); err != nil {
 		log.Println("❌ Failed to update user avatar in DB:", err)
-		errorMessages = append(errorMessages, "Failed to update avatar")
-		templates.ChangeAvatar(&models.TemplateData{
-			Data:   map[string]interface{}{"title": "Change Avatar", "userSession": user},
-			Errors: errorMessages,
-		}).Render(r.Context(), w)
+		if err := m.App.Blob.Delete(r.Context(), key); err != nil {
+			log.Println("⚠️ Failed to remove unused avatar", key+":", err)
+		}
+		fail("Failed to update avatar")
 		return
 	}
 
-	// ✅ Optional: delete old Cloudinary avatar
-	 if user.Avatar != "" {
-	     _, _ = cld.Upload.Destroy(context.Background(), uploader.DestroyParams{
-	         PublicID: user.Avatar,
-	     })
-	 }
+	m.audit(r, models.AuditAvatarChange, user, []models.AuditChange{{Field: "avatar", Before: user.Avatar, After: m.App.Blob.URL(key)}})
+
+	// ✅ Delete the old avatar; ones uploaded before keys were recorded are left alone
+	if user.AvatarKey != "" {
+		if err := m.App.Blob.Delete(r.Context(), user.AvatarKey); err != nil {
+			log.Println("⚠️ Failed to delete old avatar", user.AvatarKey+":", err)
+		}
+	}
 
 	// ✅ Success: redirect to profile page
 	w.Header().Set("HX-Location", "/")
 	w.WriteHeader(http.StatusNoContent)
 }
 
-func (m *Repository) CheckUserAuthentication(w http.ResponseWriter, r *http.Request) (*models.User, string, error) {
-	session, err := m.App.Session.Get(r, "logged-in-user")
+// CheckUserAuthentication loads the user named by the session. It never writes
+// a response; a session pointing at a missing or inactive user is cleared and
+// reported as ErrNotAuthenticated so the caller can send the visitor to the login page.
+func (m *Repository) CheckUserAuthentication(w http.ResponseWriter, r *http.Request) (*models.User, error) {
+	session, err := m.App.Session.Get(r, sessionName)
 	if err != nil {
-		log.Println("❌ Failed to get session:", err)
-		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
-		return nil, "", err
+		return nil, fmt.Errorf("get session: %w", err)
 	}
 
-	userID, ok := session.Values["user_id"]
-	if !ok {
-		log.Println("⚠️ No user_id in session → redirecting to login")
-		http.Redirect(w, r, "/login", http.StatusSeeOther)
-		return nil, "", fmt.Errorf("user not authenticated")
+	userID, ok := session.Values["user_id"].(string)
+	if !ok || userID == "" {
+		return nil, ErrNotAuthenticated
 	}
 
-	log.Println("✅ Found user_id in session:", userID)
-
-	user, err := m.DB.GetUserByID(userID.(string))
+	user, err := m.DB.GetUserByID(r.Context(), userID)
 	if err != nil {
-		if err == sql.ErrNoRows {
+		if errors.Is(err, repository.ErrNotFound) {
 			log.Println("⚠️ No user found in DB for ID:", userID)
 
 			// clear session
+			delete(session.Values, "user_id")
 			session.Options.MaxAge = -1
-			session.Values["user_id"] = nil
 			_ = session.Save(r, w)
 
-			http.Redirect(w, r, "/login", http.StatusSeeOther)
-			return nil, "", fmt.Errorf("user not found")
+			return nil, ErrNotAuthenticated
 		}
+		return nil, err
+	}
 
-		log.Println("❌ Error fetching user from DB:", err)
-		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
-		return nil, "", err
+	// a suspended, deactivated or deleted account loses its session
+	if !user.Active() {
+		log.Println("⚠️ Session for inactive user:", userID)
+		session.Options.MaxAge = -1
+		_ = session.Save(r, w)
+		return nil, ErrNotAuthenticated
 	}
 
-	log.Println("✅ Authenticated user:", user.Email)
-	return user, user.ID, nil
+	return user, nil
 }
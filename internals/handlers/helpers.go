@@ -0,0 +1,35 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/stackninja.pro/goth/internals/models"
+	"github.com/stackninja.pro/goth/internals/repository"
+)
+
+// AddDefaultData fills in the template data every page needs: the CSRF
+// token, the logged in user and the current path
+func (m *Repository) AddDefaultData(td *models.TemplateData, r *http.Request) *models.TemplateData {
+	td.CSRFToken = CSRFToken(r.Context())
+	td.UserData = CurrentUser(r.Context())
+	td.Path = r.URL.Path
+	return td
+}
+
+// dbError logs a repository failure and answers with a status that matches
+// its cause: a timeout is a 504, a cancelled request gets nothing because the
+// client has already gone, and anything else is a 500
+func dbError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, repository.ErrCanceled):
+		log.Println("⚠️ Request cancelled by client:", err)
+	case errors.Is(err, repository.ErrTimeout):
+		log.Println("⏱️ Database query timed out:", err)
+		http.Error(w, "The request took too long, please try again", http.StatusGatewayTimeout)
+	default:
+		log.Println("❌ Database error:", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+	}
+}
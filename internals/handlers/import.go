@@ -0,0 +1,277 @@
+package handlers
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/a-h/templ"
+	"github.com/stackninja.pro/goth/internals/mail"
+	"github.com/stackninja.pro/goth/internals/models"
+	"github.com/stackninja.pro/goth/internals/repository"
+	"github.com/stackninja.pro/goth/internals/tokens"
+	"github.com/stackninja.pro/goth/web/templates"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	// maxImportSize and maxImportRows bound one uploaded file
+	maxImportSize = 2 << 20
+	maxImportRows = 2000
+
+	// inviteTTL is how long an imported user has to choose a password
+	inviteTTL = 7 * 24 * time.Hour
+)
+
+// ImportUsersPage shows the CSV upload form
+func (m *Repository) ImportUsersPage(w http.ResponseWriter, r *http.Request) {
+	m.renderImport(w, r, nil, "", nil)
+}
+
+// renderImport draws the import page, or just its result for htmx requests
+func (m *Repository) renderImport(w http.ResponseWriter, r *http.Request, rows []models.ImportRow, flash string, errs []string) {
+	page := templates.ImportUsersPage(m.AddDefaultData(&models.TemplateData{
+		Data:   map[string]interface{}{"title": "Import users", "rows": rows, "summary": models.SummarizeImport(rows)},
+		Flash:  flash,
+		Errors: errs,
+	}, r))
+
+	if isHTMX(r) {
+		templ.Handler(page, templ.WithFragments("import-result")).ServeHTTP(w, r)
+		return
+	}
+	if err := page.Render(r.Context(), w); err != nil {
+		log.Println("❌ Template render error:", err)
+	}
+}
+
+// ImportUsers checks an uploaded CSV row by row. A preview stops there; an
+// import of a file without errors creates every account in one transaction.
+func (m *Repository) ImportUsers(w http.ResponseWriter, r *http.Request) {
+	fail := func(msg string) {
+		m.renderImport(w, r, nil, "", []string{msg})
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxImportSize)
+	if err := r.ParseMultipartForm(maxImportSize); err != nil {
+		fail("The file must be a CSV of at most 2 MB")
+		return
+	}
+	file, _, err := r.FormFile("file")
+	if err != nil {
+		fail("Choose a CSV file to import")
+		return
+	}
+	defer file.Close()
+
+	rows, err := parseUserImport(file)
+	if err != nil {
+		fail(err.Error())
+		return
+	}
+	if err := m.checkImportEmails(r.Context(), rows); err != nil {
+		dbError(w, err)
+		return
+	}
+
+	summary := models.SummarizeImport(rows)
+	if r.FormValue("mode") != "import" {
+		m.renderImport(w, r, rows, "", nil)
+		return
+	}
+	if summary.Invalid > 0 {
+		m.renderImport(w, r, rows, "", []string{"Nothing was imported. Fix the rows marked below and upload the file again."})
+		return
+	}
+
+	users := make([]models.User, len(rows))
+	for i, row := range rows {
+		users[i] = row.User
+		if row.Invite {
+			continue
+		}
+		hashed, err := bcrypt.GenerateFromPassword([]byte(row.User.Password), bcrypt.DefaultCost)
+		if err != nil {
+			fail("Failed to hash password")
+			return
+		}
+		users[i].Password = string(hashed)
+	}
+
+	created, err := m.DB.CreateUsers(r.Context(), users)
+	if err != nil {
+		if errors.Is(err, repository.ErrDuplicateEmail) {
+			fail("One of these addresses was registered while you were importing, so nothing was imported. Preview the file again.")
+			return
+		}
+		dbError(w, err)
+		return
+	}
+
+	// the accounts exist now; a mail failure only means the link has to be resent
+	for i := range created {
+		user := &created[i]
+		m.audit(r, models.AuditUserCreate, user, nil)
+		if rows[i].Invite {
+			err = m.sendInvite(r.Context(), user)
+		} else {
+			err = m.sendVerification(r.Context(), user, user.Email)
+		}
+		if err != nil {
+			log.Println("❌ Failed to email imported user", user.Email+":", err)
+		}
+	}
+
+	log.Printf("👥 %s imported %d users", CurrentUser(r.Context()).Email, len(created))
+	m.renderImport(w, r, nil, fmt.Sprintf("%d users imported. %d were emailed an invitation to choose a password.", len(created), summary.Invites), nil)
+}
+
+// parseUserImport reads and validates an import file. Problems with single
+// rows are recorded on the row; an error means the file as a whole is unusable.
+func parseUserImport(f io.Reader) ([]models.ImportRow, error) {
+	cr := csv.NewReader(f)
+	cr.FieldsPerRecord = -1
+	cr.TrimLeadingSpace = true
+
+	header, err := cr.Read()
+	if err == io.EOF {
+		return nil, errors.New("The file is empty")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("The file isn't valid CSV: %v", err)
+	}
+
+	cols := map[string]int{}
+	for i, name := range header {
+		// spreadsheets often start UTF-8 files with a byte order mark
+		name = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))
+		if !slices.Contains(models.ImportColumns, name) {
+			return nil, fmt.Errorf("Unknown column %q. The columns are: %s", name, strings.Join(models.ImportColumns, ", "))
+		}
+		if _, ok := cols[name]; ok {
+			return nil, fmt.Errorf("The %q column appears more than once", name)
+		}
+		cols[name] = i
+	}
+	for _, name := range models.ImportColumns {
+		if _, ok := cols[name]; !ok && name != "password" {
+			return nil, fmt.Errorf("The file needs a %q column", name)
+		}
+	}
+
+	var rows []models.ImportRow
+	firstLine := map[string]int{}
+	for {
+		record, err := cr.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("The file isn't valid CSV: %v", err)
+		}
+		if len(rows) == maxImportRows {
+			return nil, fmt.Errorf("Import at most %d users at a time", maxImportRows)
+		}
+
+		get := func(name string) string {
+			if i, ok := cols[name]; ok && i < len(record) {
+				return strings.TrimSpace(record[i])
+			}
+			return ""
+		}
+		if strings.Join(record, "") == "" {
+			continue
+		}
+
+		line, _ := cr.FieldPos(0)
+		row := validateImportRow(line, get("name"), get("email"), get("role"), get("dob"), get("password"))
+
+		key := strings.ToLower(row.User.Email)
+		if first, dup := firstLine[key]; dup && key != "" {
+			row.Errors = append(row.Errors, fmt.Sprintf("This email is already on line %d", first))
+		} else {
+			firstLine[key] = line
+		}
+		rows = append(rows, row)
+	}
+
+	if len(rows) == 0 {
+		return nil, errors.New("The file has no users in it")
+	}
+	return rows, nil
+}
+
+// validateImportRow applies the registration rules to one row
+func validateImportRow(line int, name, email, roleValue, dob, password string) models.ImportRow {
+	row := models.ImportRow{Line: line, Invite: password == ""}
+
+	role, errs := registrationErrors(name, email, strings.ToLower(roleValue))
+	row.Errors = errs
+	row.User = models.User{
+		Name:     name,
+		Email:    email,
+		Password: password,
+		Role:     role,
+		Bio:      "This is a sample bio.",
+		Avatar:   "/static/avatars/default.jpg",
+	}
+
+	switch d, err := time.Parse("2006-01-02", dob); {
+	case dob == "":
+		row.Errors = append(row.Errors, "Date of Birth is required")
+	case err != nil:
+		row.Errors = append(row.Errors, "Date of Birth must look like 2001-12-31")
+	case d.After(time.Now()):
+		row.Errors = append(row.Errors, "Date of Birth can't be in the future")
+	default:
+		row.User.DOB = d
+	}
+	return row
+}
+
+// checkImportEmails marks rows whose address already has an account
+func (m *Repository) checkImportEmails(ctx context.Context, rows []models.ImportRow) error {
+	for i := range rows {
+		row := &rows[i]
+		if row.User.Email == "" {
+			continue
+		}
+		// an inactive or deleted account still holds its address
+		_, err := m.DB.GetUserByEmail(ctx, row.User.Email)
+		switch {
+		case err == nil, errors.Is(err, repository.ErrInactive):
+			row.Errors = append(row.Errors, "An account with that email already exists")
+		case !errors.Is(err, repository.ErrNotFound):
+			return err
+		}
+	}
+	return nil
+}
+
+// sendInvite emails an imported user without a password a link to choose
+// one. It is a password reset token that lasts longer.
+func (m *Repository) sendInvite(ctx context.Context, user *models.User) error {
+	raw, hash := tokens.New()
+	if err := m.DB.CreatePasswordReset(ctx, user.ID, hash, time.Now().Add(inviteTTL)); err != nil {
+		return err
+	}
+
+	link := m.App.Server.BaseURL + "/reset-password?" + url.Values{"token": {raw}}.Encode()
+	return m.App.Mailer.Send(ctx, mail.Message{
+		To:      []string{user.Email},
+		Subject: "You've been invited to GoTH",
+		Text: "Hi " + user.Name + ",\n\n" +
+			"An account has been created for you as a " + strings.ToLower(user.Role.Label()) + ". " +
+			"Use the link below to choose your password:\n\n" +
+			link + "\n\n" +
+			"The link works once and expires in 7 days. If you weren't expecting this, you can ignore this email.\n",
+	})
+}
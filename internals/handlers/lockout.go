@@ -0,0 +1,254 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/a-h/templ"
+	"github.com/go-chi/chi/v5"
+	"github.com/stackninja.pro/goth/internals/clientip"
+	"github.com/stackninja.pro/goth/internals/mail"
+	"github.com/stackninja.pro/goth/internals/models"
+	"github.com/stackninja.pro/goth/internals/repository"
+	"github.com/stackninja.pro/goth/internals/tokens"
+	"github.com/stackninja.pro/goth/web/templates"
+)
+
+// unlockPurpose keeps unlock links from being valid as any other signed token
+const unlockPurpose = "unlock-account"
+
+// clientIP is the address login attempts are limited by and audit entries
+// record
+func (m *Repository) clientIP(r *http.Request) string {
+	return clientip.FromRequest(r, m.App.Server.TrustProxy)
+}
+
+// throttle takes a token from the client's and the email's buckets. When
+// either is empty it sets Retry-After and returns how long to wait.
+func (m *Repository) throttle(w http.ResponseWriter, r *http.Request, email string) time.Duration {
+	okIP, waitIP := m.IPLimiter.Allow(m.clientIP(r))
+	okAccount, waitAccount := m.AccountLimiter.Allow(strings.ToLower(email))
+	if okIP && okAccount {
+		return 0
+	}
+
+	wait := max(waitIP, waitAccount)
+	w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+	return wait
+}
+
+// throttleLogin returns a message for the user when the client or the email
+// has made too many login attempts
+func (m *Repository) throttleLogin(w http.ResponseWriter, r *http.Request, email string) string {
+	wait := m.throttle(w, r, email)
+	if wait == 0 {
+		return ""
+	}
+	log.Printf("🔒 Login throttled for %s from %s", email, m.clientIP(r))
+	return "Too many login attempts. Please try again in " + humanDuration(wait) + "."
+}
+
+// lockedOut returns a message for the user when the account is locked or
+// still waiting out the delay after its last failure
+func (m *Repository) lockedOut(f models.LoginFailures) string {
+	now := time.Now()
+	if f.Locked(now) {
+		return "This account is locked after too many failed attempts. Use the link we emailed you, or try again in " + humanDuration(f.LockedUntil.Sub(now)) + "."
+	}
+	if retry := f.RetryAt(m.App.Login.FreeFailures); now.Before(retry) {
+		return "Too many failed attempts. Please wait " + humanDuration(retry.Sub(now)) + " and try again."
+	}
+	return ""
+}
+
+// loginFailed counts a wrong password or second factor code and locks the
+// account once there have been too many, emailing the owner a link to unlock
+// it. It returns invalid, or the lockout message and true when the account
+// has just been locked.
+func (m *Repository) loginFailed(r *http.Request, user *models.User, invalid string, changes []models.AuditChange) (string, bool) {
+	ctx := r.Context()
+	m.audit(r, models.AuditLoginFailed, user, changes)
+
+	f, err := m.DB.RecordLoginFailure(ctx, user.ID)
+	if err != nil {
+		log.Println("❌ Failed to record login failure:", err)
+		return invalid, false
+	}
+	if f.Failures < m.App.Login.LockoutThreshold {
+		return invalid, false
+	}
+
+	until := time.Now().Add(f.LockoutFor(m.App.Login.LockoutDuration)).Truncate(time.Second)
+	if err := m.DB.LockAccount(ctx, user.ID, until); err != nil {
+		log.Println("❌ Failed to lock account:", err)
+		return invalid, false
+	}
+	log.Printf("🔒 %s locked until %s after %d failed logins", user.Email, until.Format(time.RFC3339), f.Failures)
+	m.audit(r, models.AuditLockout, user, []models.AuditChange{{Field: "locked_until", After: until.UTC().Format(time.RFC3339)}})
+
+	if err := m.sendUnlockEmail(ctx, user, until); err != nil {
+		log.Println("❌ Failed to send unlock email:", err)
+	}
+	return "Too many failed attempts. This account is locked for " + humanDuration(time.Until(until)) + ". We've emailed you a link to unlock it.", true
+}
+
+// loginSucceeded forgets the account's failed attempts. It only runs once the
+// whole login is done, so passing the password step of a two-factor account
+// doesn't reset its delay or lockout.
+func (m *Repository) loginSucceeded(r *http.Request, user *models.User, f models.LoginFailures) {
+	if f.Failures == 0 && f.Lockouts == 0 {
+		return
+	}
+	if err := m.DB.ClearLoginFailures(r.Context(), user.ID); err != nil {
+		log.Println("⚠️ Failed to clear login failures for", user.ID+":", err)
+	}
+}
+
+// sendUnlockEmail sends a link that lifts this lockout. It stops working when
+// the lockout ends or is replaced by another.
+func (m *Repository) sendUnlockEmail(ctx context.Context, user *models.User, until time.Time) error {
+	value := user.ID + "|" + strconv.FormatInt(until.Unix(), 10)
+	token := tokens.Sign([]byte(m.App.SessionConfig.Secret), unlockPurpose, value, until)
+	link := m.App.Server.BaseURL + "/unlock-account?" + url.Values{"token": {token}}.Encode()
+
+	return m.App.Mailer.Send(ctx, mail.Message{
+		To:      []string{user.Email},
+		Subject: "Your account has been locked",
+		Text: "Hi " + user.Name + ",\n\n" +
+			"There have been too many failed attempts to log in to your account, so we've locked it for now.\n\n" +
+			"If that was you, open the link below to unlock it:\n\n" +
+			link + "\n\n" +
+			"If it wasn't you, someone may be guessing your password. Unlocking is safe, but consider changing your password afterwards.\n",
+	})
+}
+
+// UnlockAccount follows the link from an unlock email
+func (m *Repository) UnlockAccount(w http.ResponseWriter, r *http.Request) {
+	td := m.AddDefaultData(&models.TemplateData{}, r)
+	render := func() {
+		if err := templates.UnlockAccountPage(td).Render(r.Context(), w); err != nil {
+			log.Println("❌ Template render error:", err)
+		}
+	}
+	invalid := func() {
+		td.Errors = append(td.Errors, "This link is invalid, or the lockout has already ended.")
+		render()
+	}
+
+	value, err := tokens.Verify([]byte(m.App.SessionConfig.Secret), unlockPurpose, r.URL.Query().Get("token"))
+	if err != nil {
+		invalid()
+		return
+	}
+	userID, until, _ := strings.Cut(value, "|")
+
+	f, err := m.DB.GetLoginFailures(r.Context(), userID)
+	if err != nil {
+		dbError(w, err)
+		return
+	}
+	if !f.Locked(time.Now()) || strconv.FormatInt(f.LockedUntil.Unix(), 10) != until {
+		invalid()
+		return
+	}
+
+	user, err := m.DB.GetUserByID(r.Context(), userID)
+	if err != nil {
+		invalid()
+		return
+	}
+	if err := m.unlock(r, user); err != nil {
+		dbError(w, err)
+		return
+	}
+
+	log.Println("🔓 Account unlocked from email by user", userID)
+	td.Flash = "Your account is unlocked. You can log in again."
+	render()
+}
+
+// unlock lifts a lockout and refills the account's rate limit bucket
+func (m *Repository) unlock(r *http.Request, user *models.User) error {
+	if err := m.DB.ClearLoginFailures(r.Context(), user.ID); err != nil {
+		return err
+	}
+	m.AccountLimiter.Reset(strings.ToLower(user.Email))
+	m.audit(r, models.AuditUnlock, user, nil)
+	return nil
+}
+
+// LockoutsPage lists the accounts that are locked now
+func (m *Repository) LockoutsPage(w http.ResponseWriter, r *http.Request) {
+	m.renderLockouts(w, r, "", nil)
+}
+
+// renderLockouts draws the lockouts page, or just its list for htmx requests
+func (m *Repository) renderLockouts(w http.ResponseWriter, r *http.Request, flash string, errs []string) {
+	locked, err := m.DB.GetLockedAccounts(r.Context())
+	if err != nil {
+		dbError(w, err)
+		return
+	}
+
+	td := m.AddDefaultData(&models.TemplateData{
+		Data:   map[string]interface{}{"title": "Locked accounts", "locked": locked},
+		Flash:  flash,
+		Errors: errs,
+	}, r)
+	page := templates.LockoutsPage(td)
+
+	if isHTMX(r) {
+		templ.Handler(page, templ.WithFragments("lockouts")).ServeHTTP(w, r)
+		return
+	}
+	if err := page.Render(r.Context(), w); err != nil {
+		log.Println("❌ Template render error:", err)
+	}
+}
+
+// UnlockUser lets an admin lift a lockout
+func (m *Repository) UnlockUser(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+
+	user, err := m.DB.GetUserByID(r.Context(), id)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			m.renderLockouts(w, r, "", []string{"That user no longer exists"})
+			return
+		}
+		dbError(w, err)
+		return
+	}
+
+	if err := m.unlock(r, user); err != nil {
+		dbError(w, err)
+		return
+	}
+
+	log.Printf("🔓 %s unlocked %s", CurrentUser(r.Context()).Email, user.Email)
+	m.renderLockouts(w, r, user.Email+" is unlocked", nil)
+}
+
+// humanDuration rounds d up to whole seconds or minutes for messages
+func humanDuration(d time.Duration) string {
+	if d < time.Minute {
+		s := int((d + time.Second - 1) / time.Second)
+		if s <= 1 {
+			return "1 second"
+		}
+		return fmt.Sprintf("%d seconds", s)
+	}
+	mins := int((d + time.Minute - 1) / time.Minute)
+	if mins == 1 {
+		return "1 minute"
+	}
+	return fmt.Sprintf("%d minutes", mins)
+}
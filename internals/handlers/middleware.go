@@ -0,0 +1,116 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"slices"
+
+	"github.com/stackninja.pro/goth/internals/models"
+)
+
+// ErrNotAuthenticated is returned when the request has no logged in user
+var ErrNotAuthenticated = errors.New("user not authenticated")
+
+// contextKey keeps our context values from colliding with other packages
+type contextKey string
+
+const userContextKey contextKey = "user"
+
+// WithUser returns a copy of ctx carrying the authenticated user
+func WithUser(ctx context.Context, user *models.User) context.Context {
+	return context.WithValue(ctx, userContextKey, user)
+}
+
+// CurrentUser returns the user stored by RequireAuth, or nil outside a
+// protected route
+func CurrentUser(ctx context.Context) *models.User {
+	user, _ := ctx.Value(userContextKey).(*models.User)
+	return user
+}
+
+// RequireAuth loads the session user once and stores it in the request
+// context. Anonymous visitors are sent to the login page.
+func (m *Repository) RequireAuth(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, err := m.CheckUserAuthentication(w, r)
+		if err != nil {
+			if errors.Is(err, ErrNotAuthenticated) {
+				log.Println("⛔ User not authenticated → redirecting to login")
+				redirect(w, r, "/login")
+				return
+			}
+			dbError(w, err)
+			return
+		}
+
+		next.ServeHTTP(w, r.WithContext(WithUser(r.Context(), user)))
+	})
+}
+
+// RequireVerified keeps users who haven't confirmed their email address on
+// the page that explains how to. It must run after RequireAuth.
+func (m *Repository) RequireVerified(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !CurrentUser(r.Context()).EmailVerified() {
+			redirect(w, r, "/verify-email/pending")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// isHTMX reports whether the request was made by htmx
+func isHTMX(r *http.Request) bool {
+	return r.Header.Get("HX-Request") == "true"
+}
+
+// redirect sends the browser to url. htmx requests would otherwise swap the
+// target page into the current element, so they get an HX-Redirect instead.
+func redirect(w http.ResponseWriter, r *http.Request, url string) {
+	if isHTMX(r) {
+		w.Header().Set("HX-Redirect", url)
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	http.Redirect(w, r, url, http.StatusSeeOther)
+}
+
+// RequireRole only lets users with one of the given roles through. It must be
+// mounted after RequireAuth.
+func (m *Repository) RequireRole(roles ...models.Role) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			user := CurrentUser(r.Context())
+			if user == nil || !slices.Contains(roles, user.Role) {
+				forbidden(w, r)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// RequirePermission only lets users whose role has been granted p through. It
+// must be mounted after RequireAuth.
+func (m *Repository) RequirePermission(p models.Permission) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !CurrentUser(r.Context()).Can(p) {
+				forbidden(w, r)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// forbidden answers a request the current user is not allowed to make
+func forbidden(w http.ResponseWriter, r *http.Request) {
+	user := CurrentUser(r.Context())
+	if user != nil {
+		log.Printf("🚫 %s (%s) denied %s %s", user.Email, user.Role, r.Method, r.URL.Path)
+	}
+	http.Error(w, "You do not have permission to do that", http.StatusForbidden)
+}
@@ -0,0 +1,189 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/a-h/templ"
+	"github.com/stackninja.pro/goth/internals/mail"
+	"github.com/stackninja.pro/goth/internals/models"
+	"github.com/stackninja.pro/goth/internals/repository"
+	"github.com/stackninja.pro/goth/internals/tokens"
+	"github.com/stackninja.pro/goth/web/templates"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// passwordResetTTL is how long an emailed reset link stays valid
+const passwordResetTTL = time.Hour
+
+// resetRequested is shown whether or not the address has an account, so the
+// form can't be used to find out who is registered
+const resetRequested = "If an account exists for that address, we've emailed a link to reset your password. It expires in 1 hour."
+
+// ForgotPasswordPage asks for the email address to send a reset link to
+func (m *Repository) ForgotPasswordPage(w http.ResponseWriter, r *http.Request) {
+	templates.ForgotPasswordPage(m.AddDefaultData(&models.TemplateData{}, r)).Render(r.Context(), w)
+}
+
+// ForgotPassword emails a one-time reset link to the address if it belongs
+// to an active account
+func (m *Repository) ForgotPassword(w http.ResponseWriter, r *http.Request) {
+	td := m.AddDefaultData(&models.TemplateData{}, r)
+	page := templates.ForgotPasswordPage(td)
+
+	email := r.FormValue("email")
+	if email == "" {
+		td.Errors = append(td.Errors, "Email is required")
+		templ.Handler(page, templ.WithFragments("error-messages")).ServeHTTP(w, r)
+		return
+	}
+
+	// the same buckets as logins keep the form from flooding an inbox
+	if wait := m.throttle(w, r, email); wait > 0 {
+		log.Printf("🔒 Password reset throttled for %s from %s", email, m.clientIP(r))
+		td.Errors = append(td.Errors, "Too many reset requests. Please try again in "+humanDuration(wait)+".")
+		templ.Handler(page, templ.WithFragments("error-messages")).ServeHTTP(w, r)
+		return
+	}
+
+	user, err := m.DB.GetUserByEmail(r.Context(), email)
+	switch {
+	case errors.Is(err, repository.ErrNotFound):
+		log.Println("🔑 Password reset requested for unknown email", email)
+	case errors.Is(err, repository.ErrInactive):
+		log.Println("🔑 Password reset refused for inactive account", email)
+	case err != nil:
+		log.Println("❌ Failed to look up user for password reset:", err)
+	default:
+		// sent after the response, so its timing doesn't give away that the
+		// address is registered
+		m.sendLater(r, func(ctx context.Context) {
+			if err := m.sendPasswordReset(ctx, user); err != nil {
+				log.Println("❌ Failed to send password reset:", err)
+			}
+		})
+	}
+
+	td.Flash = resetRequested
+	templ.Handler(page, templ.WithFragments("error-messages")).ServeHTTP(w, r)
+}
+
+// sendLater runs send in the background with the request's values but not its
+// cancellation
+func (m *Repository) sendLater(r *http.Request, send func(ctx context.Context)) {
+	ctx := context.WithoutCancel(r.Context())
+	m.mailing.Add(1)
+	go func() {
+		defer m.mailing.Done()
+		send(ctx)
+	}()
+}
+
+// WaitForMail blocks until the mail sent in the background is out
+func (m *Repository) WaitForMail() {
+	m.mailing.Wait()
+}
+
+// sendPasswordReset stores a new reset token for the user and emails the link
+func (m *Repository) sendPasswordReset(ctx context.Context, user *models.User) error {
+	raw, hash := tokens.New()
+	if err := m.DB.CreatePasswordReset(ctx, user.ID, hash, time.Now().Add(passwordResetTTL)); err != nil {
+		return err
+	}
+
+	link := m.App.Server.BaseURL + "/reset-password?" + url.Values{"token": {raw}}.Encode()
+	return m.App.Mailer.Send(ctx, mail.Message{
+		To:      []string{user.Email},
+		Subject: "Reset your password",
+		Text: "Hi " + user.Name + ",\n\n" +
+			"Someone asked to reset the password for your account. Use the link below to choose a new one:\n\n" +
+			link + "\n\n" +
+			"The link works once and expires in 1 hour. If you didn't ask for this, you can ignore this email.\n",
+	})
+}
+
+// ResetPasswordPage shows the new password form for a valid reset link
+func (m *Repository) ResetPasswordPage(w http.ResponseWriter, r *http.Request) {
+	token := r.URL.Query().Get("token")
+
+	td := m.AddDefaultData(&models.TemplateData{
+		StringMap: map[string]string{"token": token},
+	}, r)
+
+	if _, err := m.DB.GetPasswordReset(r.Context(), tokens.Hash(token)); err != nil {
+		if !errors.Is(err, repository.ErrNotFound) {
+			dbError(w, err)
+			return
+		}
+		td.Errors = append(td.Errors, "This reset link is invalid or has expired. Please request a new one.")
+	}
+
+	templates.ResetPasswordPage(td).Render(r.Context(), w)
+}
+
+// ResetPassword sets the new password, spends the token and signs the user
+// out everywhere
+func (m *Repository) ResetPassword(w http.ResponseWriter, r *http.Request) {
+	token := r.FormValue("token")
+	password := r.FormValue("password")
+
+	td := m.AddDefaultData(&models.TemplateData{
+		StringMap: map[string]string{"token": token},
+	}, r)
+	page := templates.ResetPasswordPage(td)
+
+	if password == "" {
+		td.Errors = append(td.Errors, "Password is required")
+	}
+	if password != r.FormValue("confirm_password") {
+		td.Errors = append(td.Errors, "Passwords do not match")
+	}
+	if len(td.Errors) > 0 {
+		templ.Handler(page, templ.WithFragments("error-messages")).ServeHTTP(w, r)
+		return
+	}
+
+	hashed, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		td.Errors = append(td.Errors, "Failed to hash password")
+		templ.Handler(page, templ.WithFragments("error-messages")).ServeHTTP(w, r)
+		return
+	}
+
+	userID, err := m.DB.ResetPassword(r.Context(), tokens.Hash(token), string(hashed))
+	if err != nil {
+		if !errors.Is(err, repository.ErrNotFound) {
+			dbError(w, err)
+			return
+		}
+		td.Errors = append(td.Errors, "This reset link is invalid or has expired. Please request a new one.")
+		templ.Handler(page, templ.WithFragments("error-messages")).ServeHTTP(w, r)
+		return
+	}
+
+	log.Println("🔑 Password reset for user", userID)
+	if user, err := m.DB.GetUserByID(r.Context(), userID); err == nil {
+		m.audit(r, models.AuditPasswordReset, user, nil)
+
+		// the link came to this inbox, which confirms it as well as a
+		// verification link would; invited users rely on this
+		if !user.EmailVerified() {
+			if err := m.DB.VerifyEmail(r.Context(), user.ID, user.Email); err != nil {
+				log.Println("⚠️ Failed to verify email after password reset:", err)
+			}
+		}
+	}
+	m.signOutEverywhere(r.Context(), userID)
+
+	// proving control of the inbox is as good as the unlock link
+	if err := m.DB.ClearLoginFailures(r.Context(), userID); err != nil {
+		log.Println("⚠️ Failed to clear login failures for", userID+":", err)
+	}
+
+	w.Header().Set("HX-Location", "/login?reset=done")
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,76 @@
+package handlers
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/stackninja.pro/goth/internals/models"
+)
+
+// purgeActor is who the audit log names for accounts removed by the purge job
+const purgeActor = "system"
+
+// retentionDays is how many days a deleted account can still be restored
+func (m *Repository) retentionDays() int {
+	return int(m.App.Accounts.DeletedRetention / (24 * time.Hour))
+}
+
+// PurgeDeletedUsers removes for good the accounts deleted longer ago than the
+// retention period, along with their avatars, and returns how many there were
+func (m *Repository) PurgeDeletedUsers(ctx context.Context) (int, error) {
+	purged, err := m.DB.PurgeDeletedUsers(ctx, time.Now().Add(-m.App.Accounts.DeletedRetention))
+	if err != nil {
+		return 0, err
+	}
+
+	for _, user := range purged {
+		if user.AvatarKey != "" {
+			if err := m.App.Blob.Delete(ctx, user.AvatarKey); err != nil {
+				log.Println("⚠️ Failed to delete avatar of purged user:", err)
+			}
+		}
+
+		e := models.AuditEvent{
+			ActorEmail:  purgeActor,
+			Action:      models.AuditUserPurge,
+			TargetID:    user.ID,
+			TargetEmail: user.Email,
+			Changes:     models.Diff(user.AuditFields(), nil),
+		}
+		if err := m.DB.RecordAuditEvent(ctx, e); err != nil {
+			log.Println("❌ Failed to record audit event", string(e.Action)+":", err)
+		}
+	}
+	return len(purged), nil
+}
+
+// RunPurger calls PurgeDeletedUsers once at startup and then every interval
+// until ctx is done, so short-lived processes still purge
+func (m *Repository) RunPurger(ctx context.Context, interval time.Duration) {
+	m.purgeOnce(ctx)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			m.purgeOnce(ctx)
+		}
+	}
+}
+
+// purgeOnce runs PurgeDeletedUsers and logs the outcome
+func (m *Repository) purgeOnce(ctx context.Context) {
+	n, err := m.PurgeDeletedUsers(ctx)
+	if err != nil {
+		log.Println("❌ Failed to purge deleted accounts:", err)
+		return
+	}
+	if n > 0 {
+		log.Printf("🧹 Purged %d deleted accounts", n)
+	}
+}
@@ -0,0 +1,94 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stackninja.pro/goth/internals/models"
+	"github.com/stackninja.pro/goth/internals/sessionstore"
+	"github.com/stackninja.pro/goth/web/templates"
+)
+
+// sessionName is the cookie every handler reads the session from
+const sessionName = "logged-in-user"
+
+// SessionsPage lists the devices the user is signed in on
+func (m *Repository) SessionsPage(w http.ResponseWriter, r *http.Request) {
+	user := CurrentUser(r.Context())
+
+	session, err := m.App.Session.Get(r, sessionName)
+	if err != nil {
+		dbError(w, err)
+		return
+	}
+
+	list, err := m.App.Session.List(r.Context(), user.ID)
+	if err != nil {
+		dbError(w, err)
+		return
+	}
+
+	current := m.App.Session.ID(session)
+	for i := range list {
+		list[i].Current = list[i].ID == current
+	}
+
+	err = templates.SessionsPage(m.AddDefaultData(&models.TemplateData{
+		Data: map[string]interface{}{"title": "Active Sessions", "userSession": user, "sessions": list},
+	}, r)).Render(r.Context(), w)
+	if err != nil {
+		log.Println("❌ Template render error:", err)
+	}
+}
+
+// RevokeSession signs the user out on one of their other devices
+func (m *Repository) RevokeSession(w http.ResponseWriter, r *http.Request) {
+	user := CurrentUser(r.Context())
+	id := chi.URLParam(r, "id")
+
+	session, err := m.App.Session.Get(r, sessionName)
+	if err != nil {
+		dbError(w, err)
+		return
+	}
+	if id == m.App.Session.ID(session) {
+		http.Error(w, "Use Logout to end the session you are using", http.StatusBadRequest)
+		return
+	}
+
+	if err := m.App.Session.Revoke(r.Context(), user.ID, id); err != nil {
+		if errors.Is(err, sessionstore.ErrNotFound) {
+			http.Error(w, "Session not found", http.StatusNotFound)
+			return
+		}
+		dbError(w, err)
+		return
+	}
+
+	log.Printf("🔒 %s revoked a session", user.Email)
+	w.Header().Set("HX-Location", "/settings/sessions")
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// RevokeOtherSessions signs the user out everywhere except this device
+func (m *Repository) RevokeOtherSessions(w http.ResponseWriter, r *http.Request) {
+	user := CurrentUser(r.Context())
+
+	session, err := m.App.Session.Get(r, sessionName)
+	if err != nil {
+		dbError(w, err)
+		return
+	}
+
+	n, err := m.App.Session.RevokeAll(r.Context(), user.ID, m.App.Session.ID(session))
+	if err != nil {
+		dbError(w, err)
+		return
+	}
+
+	log.Printf("🔒 %s revoked %d other sessions", user.Email, n)
+	w.Header().Set("HX-Location", "/settings/sessions")
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,166 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/a-h/templ"
+	"github.com/stackninja.pro/goth/internals/models"
+	"github.com/stackninja.pro/goth/internals/repository"
+	"github.com/stackninja.pro/goth/web/templates"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// settingsData is the template data for the settings page, with an optional
+// message for the form that was submitted
+func (m *Repository) settingsData(r *http.Request, prefs models.Preferences, flash string, errs []string) *models.TemplateData {
+	return m.AddDefaultData(&models.TemplateData{
+		Data:   map[string]interface{}{"title": "Settings", "prefs": prefs},
+		IntMap: map[string]int{"retention_days": m.retentionDays()},
+		Flash:  flash,
+		Errors: errs,
+	}, r)
+}
+
+// renderSettings answers a settings form with just its message fragment
+func (m *Repository) renderSettings(w http.ResponseWriter, r *http.Request, fragment string, prefs models.Preferences, flash string, errs []string) {
+	page := templates.SettingsPage(m.settingsData(r, prefs, flash, errs))
+	templ.Handler(page, templ.WithFragments(fragment)).ServeHTTP(w, r)
+}
+
+// SettingsPage shows the password, preferences and delete account forms
+func (m *Repository) SettingsPage(w http.ResponseWriter, r *http.Request) {
+	user := CurrentUser(r.Context())
+
+	if err := templates.SettingsPage(m.settingsData(r, user.Preferences, "", nil)).Render(r.Context(), w); err != nil {
+		log.Println("❌ Template render error:", err)
+	}
+}
+
+// ChangePassword replaces the password after the current one is confirmed,
+// then signs out every other device
+func (m *Repository) ChangePassword(w http.ResponseWriter, r *http.Request) {
+	user := CurrentUser(r.Context())
+	fail := func(msgs ...string) {
+		m.renderSettings(w, r, "password-messages", user.Preferences, "", msgs)
+	}
+
+	current := r.FormValue("current_password")
+	password := r.FormValue("new_password")
+
+	var errs []string
+	if current == "" {
+		errs = append(errs, "Current password is required")
+	}
+	if password == "" {
+		errs = append(errs, "New password is required")
+	}
+	if password != r.FormValue("confirm_password") {
+		errs = append(errs, "Passwords do not match")
+	}
+	if len(errs) > 0 {
+		fail(errs...)
+		return
+	}
+
+	if _, err := m.DB.AuthenticateUser(r.Context(), user.Email, current); err != nil {
+		if !errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+			dbError(w, err)
+			return
+		}
+		fail("Current password is incorrect")
+		return
+	}
+
+	hashed, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		fail("Failed to hash password")
+		return
+	}
+	if err := m.DB.UpdatePassword(r.Context(), user.ID, string(hashed)); err != nil {
+		dbError(w, err)
+		return
+	}
+
+	session, err := m.App.Session.Get(r, sessionName)
+	if err != nil {
+		dbError(w, err)
+		return
+	}
+	if _, err := m.App.Session.RevokeAll(r.Context(), user.ID, m.App.Session.ID(session)); err != nil {
+		log.Println("⚠️ Failed to revoke sessions for", user.ID+":", err)
+	}
+
+	log.Printf("🔑 %s changed their password", user.Email)
+	m.audit(r, models.AuditPasswordChange, user, nil)
+	m.renderSettings(w, r, "password-messages", user.Preferences, "Password changed. Your other devices have been signed out.", nil)
+}
+
+// SavePreferences stores the timezone, date format and notification choices
+func (m *Repository) SavePreferences(w http.ResponseWriter, r *http.Request) {
+	user := CurrentUser(r.Context())
+
+	prefs := models.Preferences{
+		UserID:           user.ID,
+		Timezone:         r.FormValue("timezone"),
+		NotifyGrades:     r.FormValue("notify_grades") != "",
+		NotifyEnrollment: r.FormValue("notify_enrollment") != "",
+		NotifyNews:       r.FormValue("notify_news") != "",
+	}
+
+	var errs []string
+	if _, err := time.LoadLocation(prefs.Timezone); err != nil || prefs.Timezone == "" || prefs.Timezone == "Local" {
+		errs = append(errs, "Please choose a timezone such as Africa/Lagos or UTC")
+	}
+	format, err := models.ParseDateFormat(r.FormValue("date_format"))
+	if err != nil {
+		errs = append(errs, "Please choose a date format")
+	}
+	prefs.DateFormat = format
+
+	if len(errs) > 0 {
+		m.renderSettings(w, r, "preferences-messages", prefs, "", errs)
+		return
+	}
+
+	if err := m.DB.SavePreferences(r.Context(), prefs); err != nil {
+		dbError(w, err)
+		return
+	}
+
+	m.renderSettings(w, r, "preferences-messages", prefs, "Preferences saved", nil)
+}
+
+// DeleteAccount deletes the user's own account once they confirm their
+// password. An administrator can restore it until it is purged.
+func (m *Repository) DeleteAccount(w http.ResponseWriter, r *http.Request) {
+	user := CurrentUser(r.Context())
+	fail := func(msg string) {
+		m.renderSettings(w, r, "delete-messages", user.Preferences, "", []string{msg})
+	}
+
+	if _, err := m.DB.AuthenticateUser(r.Context(), user.Email, r.FormValue("password")); err != nil {
+		if !errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+			dbError(w, err)
+			return
+		}
+		fail("Password is incorrect")
+		return
+	}
+
+	m.signOutEverywhere(r.Context(), user.ID)
+	if err := m.DB.DeleteUser(r.Context(), user.ID); err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			fail("Your account no longer exists")
+			return
+		}
+		dbError(w, err)
+		return
+	}
+
+	log.Printf("🗑️ %s deleted their account", user.Email)
+	m.auditChanges(r, models.AuditUserDelete, []models.User{*user}, "deleted_at", time.Now().UTC().Format(time.RFC3339))
+	redirect(w, r, "/login?deleted=1")
+}
@@ -0,0 +1,315 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/a-h/templ"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/stackninja.pro/goth/internals/models"
+	"github.com/stackninja.pro/goth/internals/repository"
+	"github.com/stackninja.pro/goth/web/templates"
+)
+
+// TermsPage lists the academic sessions and their terms with their dates,
+// and lets an admin add them and pick the current term. The terms can be
+// narrowed to one session.
+func (m *Repository) TermsPage(w http.ResponseWriter, r *http.Request) {
+	m.renderTerms(w, r, "", nil)
+}
+
+// renderTerms shows the sessions and terms with an optional message. HTMX
+// requests only get the lists back.
+func (m *Repository) renderTerms(w http.ResponseWriter, r *http.Request, flash string, errs []string) {
+	sessions, err := m.Terms.GetSessions(r.Context())
+	if err != nil {
+		dbError(w, err)
+		return
+	}
+	terms, err := m.Terms.GetTerms(r.Context())
+	if err != nil {
+		dbError(w, err)
+		return
+	}
+
+	session := r.URL.Query().Get("session")
+	if session != "" {
+		var inSession []models.Term
+		for _, t := range terms {
+			if t.SessionID == session {
+				inSession = append(inSession, t)
+			}
+		}
+		terms = inSession
+	}
+
+	page := templates.TermsPage(m.AddDefaultData(&models.TemplateData{
+		Data:      map[string]interface{}{"title": "Terms", "sessions": sessions, "terms": terms},
+		StringMap: map[string]string{"session": session},
+		Flash:     flash,
+		Errors:    errs,
+	}, r))
+
+	if isHTMX(r) {
+		templ.Handler(page, templ.WithFragments("terms")).ServeHTTP(w, r)
+		return
+	}
+	if err := page.Render(r.Context(), w); err != nil {
+		log.Println("❌ Template render error:", err)
+	}
+}
+
+// CreateSession adds an academic session
+func (m *Repository) CreateSession(w http.ResponseWriter, r *http.Request) {
+	admin := CurrentUser(r.Context())
+
+	session, errs := parseSessionForm(r)
+	if len(errs) > 0 {
+		m.renderTerms(w, r, "", errs)
+		return
+	}
+
+	if _, err := m.Terms.CreateSession(r.Context(), session); err != nil {
+		m.sessionSaveError(w, r, session, err)
+		return
+	}
+
+	log.Printf("🗓️ %s added session %s", admin.Email, session.Name)
+	m.renderTerms(w, r, session.Name+" added", nil)
+}
+
+// UpdateSession saves an academic session's name and dates. Its terms must
+// still fit in it.
+func (m *Repository) UpdateSession(w http.ResponseWriter, r *http.Request) {
+	admin := CurrentUser(r.Context())
+
+	session, errs := parseSessionForm(r)
+	session.ID = chi.URLParam(r, "id")
+	if _, err := uuid.Parse(session.ID); err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	if len(errs) > 0 {
+		m.renderTerms(w, r, "", errs)
+		return
+	}
+
+	terms, err := m.Terms.GetTerms(r.Context())
+	if err != nil {
+		dbError(w, err)
+		return
+	}
+	for _, t := range terms {
+		if t.SessionID == session.ID && !session.Contains(t.StartDate, t.EndDate) {
+			m.renderTerms(w, r, "", []string{t.Name + " would fall outside " + session.Name})
+			return
+		}
+	}
+
+	if err := m.Terms.UpdateSession(r.Context(), session); err != nil {
+		m.sessionSaveError(w, r, session, err)
+		return
+	}
+
+	log.Printf("🗓️ %s updated session %s", admin.Email, session.Name)
+	m.renderTerms(w, r, session.Name+" saved", nil)
+}
+
+// DeleteSession removes an academic session with no terms
+func (m *Repository) DeleteSession(w http.ResponseWriter, r *http.Request) {
+	admin := CurrentUser(r.Context())
+
+	id := chi.URLParam(r, "id")
+	if _, err := uuid.Parse(id); err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	session, err := m.Terms.GetSession(r.Context(), id)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			http.NotFound(w, r)
+			return
+		}
+		dbError(w, err)
+		return
+	}
+
+	if err := m.Terms.DeleteSession(r.Context(), id); err != nil {
+		m.sessionSaveError(w, r, *session, err)
+		return
+	}
+
+	log.Printf("🗓️ %s deleted session %s", admin.Email, session.Name)
+	m.renderTerms(w, r, session.Name+" deleted", nil)
+}
+
+// sessionSaveError turns a failed save into a message above the sessions
+func (m *Repository) sessionSaveError(w http.ResponseWriter, r *http.Request, session models.AcademicSession, err error) {
+	switch {
+	case errors.Is(err, repository.ErrDuplicateSessionName):
+		m.renderTerms(w, r, "", []string{"Another session is already called " + session.Name})
+	case errors.Is(err, repository.ErrSessionInUse):
+		m.renderTerms(w, r, "", []string{session.Name + " still has terms"})
+	case errors.Is(err, repository.ErrNotFound):
+		http.NotFound(w, r)
+	default:
+		dbError(w, err)
+	}
+}
+
+// CreateTerm adds a term to a session
+func (m *Repository) CreateTerm(w http.ResponseWriter, r *http.Request) {
+	admin := CurrentUser(r.Context())
+
+	term, errs := m.parseTermForm(r)
+	if len(errs) > 0 {
+		m.renderTerms(w, r, "", errs)
+		return
+	}
+
+	if _, err := m.Terms.CreateTerm(r.Context(), term); err != nil {
+		m.termSaveError(w, r, term, err)
+		return
+	}
+
+	log.Printf("🗓️ %s added term %s", admin.Email, term.Name)
+	m.renderTerms(w, r, term.Name+" added", nil)
+}
+
+// UpdateTerm saves a term's name, session and dates
+func (m *Repository) UpdateTerm(w http.ResponseWriter, r *http.Request) {
+	admin := CurrentUser(r.Context())
+
+	term, errs := m.parseTermForm(r)
+	term.ID = chi.URLParam(r, "id")
+	if _, err := uuid.Parse(term.ID); err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	if len(errs) > 0 {
+		m.renderTerms(w, r, "", errs)
+		return
+	}
+
+	if err := m.Terms.UpdateTerm(r.Context(), term); err != nil {
+		m.termSaveError(w, r, term, err)
+		return
+	}
+
+	log.Printf("🗓️ %s updated term %s", admin.Email, term.Name)
+	m.renderTerms(w, r, term.Name+" saved", nil)
+}
+
+// SetCurrentTerm makes a term the one students enroll in
+func (m *Repository) SetCurrentTerm(w http.ResponseWriter, r *http.Request) {
+	admin := CurrentUser(r.Context())
+
+	id := chi.URLParam(r, "id")
+	if _, err := uuid.Parse(id); err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	if err := m.Terms.SetCurrentTerm(r.Context(), id); err != nil {
+		m.termSaveError(w, r, models.Term{ID: id}, err)
+		return
+	}
+
+	log.Printf("🗓️ %s made term %s current", admin.Email, id)
+	m.renderTerms(w, r, "Current term changed", nil)
+}
+
+// termSaveError turns a failed save into a message above the terms
+func (m *Repository) termSaveError(w http.ResponseWriter, r *http.Request, term models.Term, err error) {
+	switch {
+	case errors.Is(err, repository.ErrDuplicateTermName):
+		m.renderTerms(w, r, "", []string{"Another term is already called " + term.Name})
+	case errors.Is(err, repository.ErrNotFound):
+		http.NotFound(w, r)
+	default:
+		dbError(w, err)
+	}
+}
+
+// parseSessionForm reads and validates an academic session's name and dates
+func parseSessionForm(r *http.Request) (models.AcademicSession, []string) {
+	var errs []string
+	s := models.AcademicSession{Name: strings.TrimSpace(r.FormValue("name"))}
+
+	if s.Name == "" {
+		errs = append(errs, "Session name is required")
+	} else if utf8.RuneCountInString(s.Name) > models.MaxSessionName {
+		errs = append(errs, "Session name must be at most "+strconv.Itoa(models.MaxSessionName)+" characters")
+	}
+
+	s.StartDate = parseDate(r, "start_date", "Start date", &errs)
+	s.EndDate = parseDate(r, "end_date", "End date", &errs)
+	if len(errs) == 0 && s.EndDate.Before(s.StartDate) {
+		errs = append(errs, "The session can't end before it starts")
+	}
+	return s, errs
+}
+
+// parseTermForm reads and validates a term's name, session and dates. The
+// term has to fall within its session.
+func (m *Repository) parseTermForm(r *http.Request) (models.Term, []string) {
+	var errs []string
+	t := models.Term{Name: strings.TrimSpace(r.FormValue("name")), SessionID: r.FormValue("session")}
+
+	if t.Name == "" {
+		errs = append(errs, "Term name is required")
+	} else if utf8.RuneCountInString(t.Name) > models.MaxTermName {
+		errs = append(errs, "Term name must be at most "+strconv.Itoa(models.MaxTermName)+" characters")
+	}
+
+	var session *models.AcademicSession
+	if _, err := uuid.Parse(t.SessionID); err != nil {
+		errs = append(errs, "Session is required")
+	} else if session, err = m.Terms.GetSession(r.Context(), t.SessionID); err != nil {
+		errs = append(errs, "Session is required")
+	}
+
+	t.StartDate = parseDate(r, "start_date", "Start date", &errs)
+	t.EndDate = parseDate(r, "end_date", "End date", &errs)
+	t.RegistrationOpens = parseDate(r, "registration_opens", "Registration opening", &errs)
+	t.AddDeadline = parseDate(r, "add_deadline", "Add deadline", &errs)
+	t.DropDeadline = parseDate(r, "drop_deadline", "Drop deadline", &errs)
+	t.ExamStart = parseDate(r, "exam_start", "Exam start", &errs)
+	t.ExamEnd = parseDate(r, "exam_end", "Exam end", &errs)
+	if len(errs) > 0 {
+		return t, errs
+	}
+
+	if t.EndDate.Before(t.StartDate) {
+		errs = append(errs, "The term can't end before it starts")
+	}
+	if t.AddDeadline.Before(t.RegistrationOpens) {
+		errs = append(errs, "Registration can't open after the add deadline")
+	}
+	if t.DropDeadline.Before(t.AddDeadline) {
+		errs = append(errs, "The drop deadline can't be before the add deadline")
+	}
+	if t.ExamEnd.Before(t.ExamStart) {
+		errs = append(errs, "Exams can't end before they start")
+	} else if t.ExamStart.Before(t.StartDate) || t.ExamEnd.After(t.EndDate) {
+		errs = append(errs, "Exams must fall within the term")
+	}
+	if !session.Contains(t.StartDate, t.EndDate) {
+		errs = append(errs, "The term must fall within "+session.Name)
+	}
+	return t, errs
+}
+
+// parseDate reads a date field, adding an error naming it when it isn't one
+func parseDate(r *http.Request, field, label string, errs *[]string) time.Time {
+	d, err := time.Parse("2006-01-02", r.FormValue(field))
+	if err != nil {
+		*errs = append(*errs, label+" must be a date")
+	}
+	return d
+}
@@ -0,0 +1,465 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/a-h/templ"
+	"github.com/stackninja.pro/goth/internals/models"
+	"github.com/stackninja.pro/goth/internals/qr"
+	"github.com/stackninja.pro/goth/internals/repository"
+	"github.com/stackninja.pro/goth/internals/tokens"
+	"github.com/stackninja.pro/goth/internals/totp"
+	"github.com/stackninja.pro/goth/web/templates"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Session keys for a login that has passed the password check but not the
+// second step. user_id is only set once both are done.
+const (
+	twoFactorUserKey     = "2fa_user_id"
+	twoFactorStartedKey  = "2fa_started"
+	twoFactorAttemptsKey = "2fa_attempts"
+
+	// totpPendingKey holds a secret being set up until a code confirms it
+	totpPendingKey = "totp_pending"
+)
+
+const (
+	// twoFactorWindow is how long the second login step stays open
+	twoFactorWindow = 5 * time.Minute
+
+	// twoFactorMaxAttempts is how many wrong codes end the login
+	twoFactorMaxAttempts = 5
+
+	// totpIssuer names the account in authenticator apps
+	totpIssuer = "GoTH"
+)
+
+// startTwoFactor leaves the session half authenticated and sends the browser
+// to the code prompt
+func (m *Repository) startTwoFactor(w http.ResponseWriter, r *http.Request, user *models.User) error {
+	session, err := m.App.Session.Get(r, sessionName)
+	if err != nil {
+		return err
+	}
+
+	delete(session.Values, "user_id")
+	session.Values[twoFactorUserKey] = user.ID
+	session.Values[twoFactorStartedKey] = time.Now().Unix()
+	session.Values[twoFactorAttemptsKey] = 0
+	if err := m.App.Session.Rotate(r, w, session); err != nil {
+		return err
+	}
+
+	w.Header().Set("HX-Location", "/login/2fa")
+	w.WriteHeader(http.StatusNoContent)
+	return nil
+}
+
+// pendingTwoFactor returns the user waiting on the second login step, or ""
+// when there is none or it has timed out
+func pendingTwoFactor(values map[interface{}]interface{}) string {
+	userID, _ := values[twoFactorUserKey].(string)
+	started, _ := values[twoFactorStartedKey].(int64)
+	if userID == "" || time.Since(time.Unix(started, 0)) > twoFactorWindow {
+		return ""
+	}
+	return userID
+}
+
+// clearTwoFactor ends the second login step
+func clearTwoFactor(values map[interface{}]interface{}) {
+	delete(values, twoFactorUserKey)
+	delete(values, twoFactorStartedKey)
+	delete(values, twoFactorAttemptsKey)
+}
+
+// TwoFactorLoginPage asks for a code from the authenticator app
+func (m *Repository) TwoFactorLoginPage(w http.ResponseWriter, r *http.Request) {
+	session, err := m.App.Session.Get(r, sessionName)
+	if err != nil {
+		dbError(w, err)
+		return
+	}
+	if pendingTwoFactor(session.Values) == "" {
+		redirect(w, r, "/login")
+		return
+	}
+
+	templates.TwoFactorLoginPage(m.AddDefaultData(&models.TemplateData{}, r)).Render(r.Context(), w)
+}
+
+// TwoFactorLogin finishes a login with a TOTP or recovery code
+func (m *Repository) TwoFactorLogin(w http.ResponseWriter, r *http.Request) {
+	td := m.AddDefaultData(&models.TemplateData{}, r)
+	fail := func(msg string) {
+		td.Errors = append(td.Errors, msg)
+		templ.Handler(templates.TwoFactorLoginPage(td), templ.WithFragments("error-messages")).ServeHTTP(w, r)
+	}
+
+	session, err := m.App.Session.Get(r, sessionName)
+	if err != nil {
+		dbError(w, err)
+		return
+	}
+	userID := pendingTwoFactor(session.Values)
+	if userID == "" {
+		fail("Your login has timed out. Please log in again.")
+		return
+	}
+
+	user, err := m.DB.GetUserByID(r.Context(), userID)
+	if err != nil && !errors.Is(err, repository.ErrNotFound) {
+		dbError(w, err)
+		return
+	}
+	if err != nil || !user.Active() {
+		clearTwoFactor(session.Values)
+		_ = session.Save(r, w)
+		fail("This account can no longer sign in")
+		return
+	}
+
+	// codes are limited like passwords, by client and account, and count
+	// towards the same persisted lockout, so starting the login over
+	// doesn't buy more guesses
+	if msg := m.throttleLogin(w, r, user.Email); msg != "" {
+		fail(msg)
+		return
+	}
+	failures, err := m.DB.GetLoginFailures(r.Context(), user.ID)
+	if err != nil {
+		dbError(w, err)
+		return
+	}
+	if msg := m.lockedOut(failures); msg != "" {
+		fail(msg)
+		return
+	}
+
+	ok, err := m.checkSecondFactor(r, userID, r.FormValue("code"))
+	if err != nil {
+		dbError(w, err)
+		return
+	}
+	if !ok {
+		msg, locked := m.loginFailed(r, user, "That code is incorrect or has already been used", []models.AuditChange{{Field: "step", After: "two-factor"}})
+
+		attempts, _ := session.Values[twoFactorAttemptsKey].(int)
+		attempts++
+		session.Values[twoFactorAttemptsKey] = attempts
+
+		if locked || attempts >= twoFactorMaxAttempts {
+			log.Println("🔒 Too many two-factor attempts for user", userID)
+			clearTwoFactor(session.Values)
+			_ = session.Save(r, w)
+			if !locked {
+				msg = "Too many incorrect codes. Please log in again."
+			}
+			fail(msg)
+			return
+		}
+		if err := session.Save(r, w); err != nil {
+			dbError(w, err)
+			return
+		}
+		fail(msg)
+		return
+	}
+
+	clearTwoFactor(session.Values)
+	session.Values["user_id"] = user.ID
+	session.Values[csrfSessionKey] = newCSRFToken()
+	if err := m.App.Session.Rotate(r, w, session); err != nil {
+		fail("Failed to save session")
+		return
+	}
+	m.audit(r, models.AuditLogin, user, nil)
+
+	m.loginSucceeded(r, user, failures)
+
+	w.Header().Set("HX-Location", "/")
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// checkSecondFactor accepts a current TOTP code that hasn't been used yet, or
+// an unused recovery code, which is then spent
+func (m *Repository) checkSecondFactor(r *http.Request, userID, code string) (bool, error) {
+	secret, _, err := m.DB.GetTOTPSecret(r.Context(), userID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	if counter, ok := totp.Validate(secret, code, time.Now()); ok {
+		err = m.DB.UseTOTPCounter(r.Context(), userID, counter)
+	} else {
+		err = m.DB.UseRecoveryCode(r.Context(), userID, tokens.Hash(totp.NormalizeRecoveryCode(code)))
+		if err == nil {
+			log.Println("🔑 Recovery code used by user", userID)
+		}
+	}
+	if errors.Is(err, repository.ErrNotFound) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// RequireTwoFactor sends users whose role must use two-factor authentication
+// to set it up before anything else. It must run after RequireAuth.
+func (m *Repository) RequireTwoFactor(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if user := CurrentUser(r.Context()); user.TwoFactorRequired && !user.TwoFactorEnabled() {
+			redirect(w, r, "/settings/two-factor")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// twoFactorData is the template data for the two-factor settings page. A user
+// without 2FA gets the QR code for a pending secret kept in their session.
+func (m *Repository) twoFactorData(w http.ResponseWriter, r *http.Request, user *models.User) (*models.TemplateData, error) {
+	data := map[string]interface{}{"title": "Two-factor authentication"}
+
+	if user.TwoFactorEnabled() {
+		left, err := m.DB.CountRecoveryCodes(r.Context(), user.ID)
+		if err != nil {
+			return nil, err
+		}
+		data["recoveryLeft"] = left
+	} else {
+		secret, err := m.pendingSecret(w, r)
+		if err != nil {
+			return nil, err
+		}
+		code, err := qr.Encode(totp.URI(totpIssuer, user.Email, secret))
+		if err != nil {
+			return nil, err
+		}
+		data["secret"] = secret
+		data["qr"] = code.SVG(200)
+	}
+
+	return m.AddDefaultData(&models.TemplateData{Data: data}, r), nil
+}
+
+// pendingSecret returns the secret being set up, making one if needed
+func (m *Repository) pendingSecret(w http.ResponseWriter, r *http.Request) (string, error) {
+	session, err := m.App.Session.Get(r, sessionName)
+	if err != nil {
+		return "", err
+	}
+	if secret, ok := session.Values[totpPendingKey].(string); ok && secret != "" {
+		return secret, nil
+	}
+
+	secret := totp.NewSecret()
+	session.Values[totpPendingKey] = secret
+	return secret, session.Save(r, w)
+}
+
+// renderTwoFactor answers a two-factor form by redrawing the whole section
+func (m *Repository) renderTwoFactor(w http.ResponseWriter, r *http.Request, user *models.User, flash string, errs []string, codes []string) {
+	td, err := m.twoFactorData(w, r, user)
+	if err != nil {
+		dbError(w, err)
+		return
+	}
+	td.Flash = flash
+	td.Errors = errs
+	td.Data["recoveryCodes"] = codes
+
+	templ.Handler(templates.TwoFactorSettingsPage(td), templ.WithFragments("two-factor")).ServeHTTP(w, r)
+}
+
+// TwoFactorSettingsPage shows the 2FA status, or the QR code to turn it on
+func (m *Repository) TwoFactorSettingsPage(w http.ResponseWriter, r *http.Request) {
+	td, err := m.twoFactorData(w, r, CurrentUser(r.Context()))
+	if err != nil {
+		dbError(w, err)
+		return
+	}
+
+	if err := templates.TwoFactorSettingsPage(td).Render(r.Context(), w); err != nil {
+		log.Println("❌ Template render error:", err)
+	}
+}
+
+// newRecoveryCodes makes a set of recovery codes and the hashes to store
+func newRecoveryCodes() (codes, hashes []string) {
+	codes = totp.NewRecoveryCodes(totp.RecoveryCodeCount)
+	for _, c := range codes {
+		hashes = append(hashes, tokens.Hash(c))
+	}
+	return codes, hashes
+}
+
+// EnableTwoFactor turns 2FA on once a code from the pending secret checks
+// out, and shows the recovery codes this one time
+func (m *Repository) EnableTwoFactor(w http.ResponseWriter, r *http.Request) {
+	user := CurrentUser(r.Context())
+	if user.TwoFactorEnabled() {
+		m.renderTwoFactor(w, r, user, "", []string{"Two-factor authentication is already on"}, nil)
+		return
+	}
+
+	session, err := m.App.Session.Get(r, sessionName)
+	if err != nil {
+		dbError(w, err)
+		return
+	}
+	secret, _ := session.Values[totpPendingKey].(string)
+	counter, ok := totp.Validate(secret, r.FormValue("code"), time.Now())
+	if secret == "" || !ok {
+		m.renderTwoFactor(w, r, user, "", []string{"That code is incorrect. Check the time on your phone and try again."}, nil)
+		return
+	}
+
+	codes, hashes := newRecoveryCodes()
+	if err := m.DB.EnableTwoFactor(r.Context(), user.ID, secret, counter, hashes); err != nil {
+		dbError(w, err)
+		return
+	}
+
+	delete(session.Values, totpPendingKey)
+	if err := session.Save(r, w); err != nil {
+		log.Println("⚠️ Failed to clear pending TOTP secret:", err)
+	}
+
+	log.Printf("🔑 %s turned on two-factor authentication", user.Email)
+	m.audit(r, models.AuditTwoFactorOn, user, nil)
+	now := time.Now()
+	user.TOTPEnabledAt = &now
+	m.renderTwoFactor(w, r, user, "Two-factor authentication is on", nil, codes)
+}
+
+// confirmPassword checks the user's password for a sensitive 2FA change
+func (m *Repository) confirmPassword(r *http.Request, user *models.User) (bool, error) {
+	_, err := m.DB.AuthenticateUser(r.Context(), user.Email, r.FormValue("password"))
+	if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// RegenerateRecoveryCodes replaces the recovery codes after the password is
+// confirmed
+func (m *Repository) RegenerateRecoveryCodes(w http.ResponseWriter, r *http.Request) {
+	user := CurrentUser(r.Context())
+	if !user.TwoFactorEnabled() {
+		m.renderTwoFactor(w, r, user, "", []string{"Two-factor authentication is off"}, nil)
+		return
+	}
+
+	ok, err := m.confirmPassword(r, user)
+	if err != nil {
+		dbError(w, err)
+		return
+	}
+	if !ok {
+		m.renderTwoFactor(w, r, user, "", []string{"Password is incorrect"}, nil)
+		return
+	}
+
+	codes, hashes := newRecoveryCodes()
+	if err := m.DB.ReplaceRecoveryCodes(r.Context(), user.ID, hashes); err != nil {
+		dbError(w, err)
+		return
+	}
+
+	log.Printf("🔑 %s made new recovery codes", user.Email)
+	m.renderTwoFactor(w, r, user, "New recovery codes made. The old ones no longer work.", nil, codes)
+}
+
+// DisableTwoFactor turns 2FA off after the password is confirmed, unless the
+// user's role requires it
+func (m *Repository) DisableTwoFactor(w http.ResponseWriter, r *http.Request) {
+	user := CurrentUser(r.Context())
+	if user.TwoFactorRequired {
+		m.renderTwoFactor(w, r, user, "", []string{"Your role requires two-factor authentication"}, nil)
+		return
+	}
+
+	ok, err := m.confirmPassword(r, user)
+	if err != nil {
+		dbError(w, err)
+		return
+	}
+	if !ok {
+		m.renderTwoFactor(w, r, user, "", []string{"Password is incorrect"}, nil)
+		return
+	}
+
+	if err := m.DB.DisableTwoFactor(r.Context(), user.ID); err != nil {
+		dbError(w, err)
+		return
+	}
+
+	log.Printf("🔓 %s turned off two-factor authentication", user.Email)
+	m.audit(r, models.AuditTwoFactorOff, user, nil)
+	user.TOTPEnabledAt = nil
+	m.renderTwoFactor(w, r, user, "Two-factor authentication is off", nil, nil)
+}
+
+// TwoFactorPolicyPage lets admins choose which roles must use 2FA
+func (m *Repository) TwoFactorPolicyPage(w http.ResponseWriter, r *http.Request) {
+	policy, err := m.DB.GetTwoFactorPolicy(r.Context())
+	if err != nil {
+		dbError(w, err)
+		return
+	}
+
+	td := m.AddDefaultData(&models.TemplateData{Data: map[string]interface{}{"title": "Security policy", "policy": policy}}, r)
+	if err := templates.TwoFactorPolicyPage(td).Render(r.Context(), w); err != nil {
+		log.Println("❌ Template render error:", err)
+	}
+}
+
+// SaveTwoFactorPolicy stores the roles ticked as requiring 2FA
+func (m *Repository) SaveTwoFactorPolicy(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "Bad Request", http.StatusBadRequest)
+		return
+	}
+
+	before, err := m.DB.GetTwoFactorPolicy(r.Context())
+	if err != nil {
+		dbError(w, err)
+		return
+	}
+
+	policy := map[models.Role]bool{}
+	for _, role := range models.Roles {
+		policy[role] = r.Form.Has("require_" + string(role))
+		if err := m.DB.SetTwoFactorRequired(r.Context(), role, policy[role]); err != nil {
+			dbError(w, err)
+			return
+		}
+	}
+
+	log.Printf("🔒 %s updated the two-factor policy: %v", CurrentUser(r.Context()).Email, policy)
+	if changes := models.Diff(policyFields(before), policyFields(policy)); len(changes) > 0 {
+		m.audit(r, models.AuditPolicyChange, nil, changes)
+	}
+	td := m.AddDefaultData(&models.TemplateData{
+		Data:  map[string]interface{}{"title": "Security policy", "policy": policy},
+		Flash: "Policy saved",
+	}, r)
+	templ.Handler(templates.TwoFactorPolicyPage(td), templ.WithFragments("policy-messages")).ServeHTTP(w, r)
+}
+
+// policyFields describes a two-factor policy for the audit log
+func policyFields(policy map[models.Role]bool) map[string]string {
+	fields := map[string]string{}
+	for _, role := range models.Roles {
+		fields["require_2fa_"+string(role)] = strconv.FormatBool(policy[role])
+	}
+	return fields
+}
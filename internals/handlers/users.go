@@ -0,0 +1,363 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/a-h/templ"
+	"github.com/go-chi/chi/v5"
+	"github.com/stackninja.pro/goth/internals/models"
+	"github.com/stackninja.pro/goth/internals/repository"
+	"github.com/stackninja.pro/goth/web/templates"
+	"github.com/stackninja.pro/goth/web/templates/components"
+)
+
+// UsersPage is the admin user directory. HTMX requests aimed at the table
+// only get the table back.
+func (m *Repository) UsersPage(w http.ResponseWriter, r *http.Request) {
+	m.renderUsers(w, r, models.ParseUserQuery(r.URL.Query()), "", nil)
+}
+
+// renderUsers shows the page of users q selects, with an optional message
+func (m *Repository) renderUsers(w http.ResponseWriter, r *http.Request, q models.UserQuery, flash string, errs []string) {
+	q.IDs = nil
+	users, total, err := m.DB.GetAllUsers(r.Context(), q)
+	if err != nil {
+		dbError(w, err)
+		return
+	}
+
+	page := templates.UsersPage(m.AddDefaultData(&models.TemplateData{
+		Data:   map[string]interface{}{"title": "Users", "users": users, "query": q},
+		IntMap: map[string]int{"total": total, "pages": q.Pages(total)},
+		Flash:  flash,
+		Errors: errs,
+	}, r))
+
+	if isHTMX(r) && r.Header.Get("HX-Target") == "user-table" {
+		templ.Handler(page, templ.WithFragments("user-table")).ServeHTTP(w, r)
+		return
+	}
+	if err := page.Render(r.Context(), w); err != nil {
+		log.Println("❌ Template render error:", err)
+	}
+}
+
+// ChangeUserRole is the inline role editor on a directory row
+func (m *Repository) ChangeUserRole(w http.ResponseWriter, r *http.Request) {
+	admin := CurrentUser(r.Context())
+	id := chi.URLParam(r, "id")
+
+	role, err := models.ParseRole(r.FormValue("role"))
+	if err != nil {
+		m.renderUserRow(w, r, id, "Unknown role")
+		return
+	}
+	if id == admin.ID {
+		m.renderUserRow(w, r, id, "You can't change your own role")
+		return
+	}
+
+	before, err := m.DB.GetUserByID(r.Context(), id)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			http.NotFound(w, r)
+			return
+		}
+		dbError(w, err)
+		return
+	}
+
+	n, err := m.DB.UpdateUsersRole(r.Context(), []string{id}, role)
+	if err != nil {
+		dbError(w, err)
+		return
+	}
+	if n == 0 {
+		http.NotFound(w, r)
+		return
+	}
+
+	log.Printf("👤 %s made user %s %s", admin.Email, id, role.Label())
+	m.auditChanges(r, models.AuditRoleChange, []models.User{*before}, "role", string(role))
+	m.signOutEverywhere(r.Context(), id)
+	m.renderUserRow(w, r, id, "")
+}
+
+// ChangeUserStatus is the inline status editor on a directory row
+func (m *Repository) ChangeUserStatus(w http.ResponseWriter, r *http.Request) {
+	admin := CurrentUser(r.Context())
+	id := chi.URLParam(r, "id")
+
+	status, err := models.ParseStatus(r.FormValue("status"))
+	if err != nil {
+		m.renderUserRow(w, r, id, "Unknown status")
+		return
+	}
+	if id == admin.ID {
+		m.renderUserRow(w, r, id, "You can't change your own status")
+		return
+	}
+
+	before, err := m.DB.GetUserByID(r.Context(), id)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			http.NotFound(w, r)
+			return
+		}
+		dbError(w, err)
+		return
+	}
+
+	n, err := m.DB.UpdateUsersStatus(r.Context(), []string{id}, status)
+	if err != nil {
+		dbError(w, err)
+		return
+	}
+	if n == 0 {
+		http.NotFound(w, r)
+		return
+	}
+
+	log.Printf("👤 %s set user %s to %s", admin.Email, id, status.Label())
+	m.auditChanges(r, models.AuditStatusChange, []models.User{*before}, "status", string(status))
+	if !status.Active() {
+		m.signOutEverywhere(r.Context(), id)
+	}
+	m.renderUserRow(w, r, id, "")
+}
+
+// renderUserRow re-renders one directory row after an inline edit
+func (m *Repository) renderUserRow(w http.ResponseWriter, r *http.Request, id, errMsg string) {
+	user, err := m.DB.GetUserByID(r.Context(), id)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			http.NotFound(w, r)
+			return
+		}
+		dbError(w, err)
+		return
+	}
+
+	td := m.AddDefaultData(&models.TemplateData{}, r)
+	if err := components.UserRow(td, *user, errMsg).Render(r.Context(), w); err != nil {
+		log.Println("❌ Template render error:", err)
+	}
+}
+
+// BulkUpdateUsers applies one action to every selected user and re-renders
+// the table the admin was looking at
+func (m *Repository) BulkUpdateUsers(w http.ResponseWriter, r *http.Request) {
+	admin := CurrentUser(r.Context())
+
+	// htmx reports the page URL, which carries the filters in use
+	q := models.ParseUserQuery(nil)
+	if u, err := url.Parse(r.Header.Get("HX-Current-URL")); err == nil {
+		q = models.ParseUserQuery(u.Query())
+	}
+
+	if err := r.ParseForm(); err != nil {
+		m.renderUsers(w, r, q, "", []string{"Failed to parse form data"})
+		return
+	}
+
+	var errs []string
+	ids := r.PostForm["ids"]
+	if slices.Contains(ids, admin.ID) {
+		ids = slices.DeleteFunc(ids, func(id string) bool { return id == admin.ID })
+		errs = append(errs, "Your own account was skipped")
+	}
+	if len(ids) == 0 {
+		m.renderUsers(w, r, q, "", append(errs, "Select at least one other user"))
+		return
+	}
+
+	// kept for the audit log, which records each user's old value
+	action := r.PostFormValue("action")
+	before, _, err := m.DB.GetAllUsers(r.Context(), models.UserQuery{IDs: ids, Deleted: action == "restore", PerPage: models.MaxPerPage})
+	if err != nil {
+		dbError(w, err)
+		return
+	}
+
+	var (
+		n       int64
+		done    string
+		signOut bool
+
+		// what the audit log records for every user
+		audited      models.AuditAction
+		field, value string
+	)
+	switch action {
+	case "activate", "suspend", "deactivate":
+		status := map[string]models.Status{
+			"activate":   models.StatusActive,
+			"suspend":    models.StatusSuspended,
+			"deactivate": models.StatusDeactivated,
+		}[action]
+		n, err = m.DB.UpdateUsersStatus(r.Context(), ids, status)
+		done = "set to " + status.Label()
+		signOut = !status.Active()
+		audited, field, value = models.AuditStatusChange, "status", string(status)
+	case "role":
+		role, perr := models.ParseRole(r.PostFormValue("new_role"))
+		if perr != nil {
+			m.renderUsers(w, r, q, "", append(errs, "Choose a role to assign"))
+			return
+		}
+		n, err = m.DB.UpdateUsersRole(r.Context(), ids, role)
+		done = "made " + role.Label()
+		signOut = true
+		audited, field, value = models.AuditRoleChange, "role", string(role)
+	case "delete":
+		n, err = m.eachUser(r.Context(), ids, m.DB.DeleteUser)
+		done = "deleted"
+		signOut = true
+		audited, field, value = models.AuditUserDelete, "deleted_at", time.Now().UTC().Format(time.RFC3339)
+	case "restore":
+		n, err = m.eachUser(r.Context(), ids, m.DB.RestoreUser)
+		done = "restored"
+		audited, field, value = models.AuditUserRestore, "deleted_at", ""
+	default:
+		m.renderUsers(w, r, q, "", append(errs, "Unknown action"))
+		return
+	}
+	if err != nil {
+		dbError(w, err)
+		return
+	}
+	m.auditChanges(r, audited, before, field, value)
+
+	if signOut {
+		for _, id := range ids {
+			m.signOutEverywhere(r.Context(), id)
+		}
+	}
+
+	log.Printf("👥 %s bulk %s %d users", admin.Email, done, n)
+	m.renderUsers(w, r, q, fmt.Sprintf("%d users %s", n, done), errs)
+}
+
+// eachUser applies fn to every user, counting those it changed. Users fn
+// reports missing are skipped.
+func (m *Repository) eachUser(ctx context.Context, ids []string, fn func(ctx context.Context, id string) error) (int64, error) {
+	var n int64
+	for _, id := range ids {
+		switch err := fn(ctx, id); {
+		case err == nil:
+			n++
+		case !errors.Is(err, repository.ErrNotFound):
+			return n, err
+		}
+	}
+	return n, nil
+}
+
+// RestoreUser brings back a deleted account from its directory row
+func (m *Repository) RestoreUser(w http.ResponseWriter, r *http.Request) {
+	admin := CurrentUser(r.Context())
+	id := chi.URLParam(r, "id")
+
+	before, err := m.DB.GetUserByID(r.Context(), id)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			http.NotFound(w, r)
+			return
+		}
+		dbError(w, err)
+		return
+	}
+
+	if err := m.DB.RestoreUser(r.Context(), id); err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			m.renderUserRow(w, r, id, "This account isn't deleted")
+			return
+		}
+		dbError(w, err)
+		return
+	}
+
+	log.Printf("♻️ %s restored user %s", admin.Email, id)
+	m.auditChanges(r, models.AuditUserRestore, []models.User{*before}, "deleted_at", "")
+	m.renderUserRow(w, r, id, "")
+}
+
+// ExportUsers downloads the users matching the directory's filters, or only
+// the selected ones, in the chosen format and columns. Rows are written as
+// they are read rather than collected first.
+func (m *Repository) ExportUsers(w http.ResponseWriter, r *http.Request) {
+	v := r.URL.Query()
+	q := models.ParseUserQuery(v)
+
+	format := models.ExportCSV
+	if v.Has("format") {
+		f, err := models.ParseExportFormat(v.Get("format"))
+		if err != nil {
+			http.Error(w, "Unknown export format", http.StatusBadRequest)
+			return
+		}
+		format = f
+	}
+	cols := models.ParseExportColumns(v["columns"])
+
+	w.Header().Set("Content-Type", format.ContentType())
+	w.Header().Set("Content-Disposition", `attachment; filename="users-`+time.Now().Format("20060102")+"."+string(format)+`"`)
+
+	ex, err := newUserExporter(w, format, cols)
+	if err != nil {
+		log.Println("❌ Failed to start user export:", err)
+		return
+	}
+	rows := 0
+	err = m.DB.ForEachUser(r.Context(), q, func(u models.User) error {
+		rows++
+		return ex.Write(&u)
+	})
+	if cerr := ex.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		log.Println("❌ Failed to write user export:", err)
+		return
+	}
+
+	keys := make([]string, len(cols))
+	for i, c := range cols {
+		keys[i] = c.Key
+	}
+	// the export ignores paging, so it isn't part of the filter
+	filter := q.Values()
+	filter.Del("page")
+	filter.Del("per_page")
+	m.audit(r, models.AuditUserExport, nil, []models.AuditChange{
+		{Field: "format", After: string(format)},
+		{Field: "columns", After: strings.Join(keys, ",")},
+		{Field: "filter", After: filter.Encode()},
+		{Field: "rows", After: strconv.Itoa(rows)},
+	})
+}
+
+// csvCell stops user supplied text from being run as a formula when the
+// export is opened in a spreadsheet
+func csvCell(s string) string {
+	if s != "" && strings.ContainsRune("=+-@\t\r", rune(s[0])) {
+		return "'" + s
+	}
+	return s
+}
+
+// signOutEverywhere revokes every session of a user whose privileges changed
+func (m *Repository) signOutEverywhere(ctx context.Context, userID string) {
+	if _, err := m.App.Session.RevokeAll(ctx, userID, ""); err != nil {
+		log.Println("⚠️ Failed to revoke sessions for", userID+":", err)
+	}
+}
@@ -0,0 +1,145 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/a-h/templ"
+	"github.com/stackninja.pro/goth/internals/mail"
+	"github.com/stackninja.pro/goth/internals/models"
+	"github.com/stackninja.pro/goth/internals/repository"
+	"github.com/stackninja.pro/goth/internals/tokens"
+	"github.com/stackninja.pro/goth/web/templates"
+)
+
+// emailVerifyTTL is how long a verification link stays valid
+const emailVerifyTTL = 48 * time.Hour
+
+// verifyPurpose keeps verification links from being valid as any other
+// signed token
+const verifyPurpose = "verify-email"
+
+// sendVerification emails a signed link that confirms address belongs to
+// the user. address is either their current email or a pending new one.
+func (m *Repository) sendVerification(ctx context.Context, user *models.User, address string) error {
+	token := tokens.Sign([]byte(m.App.SessionConfig.Secret), verifyPurpose, user.ID+"|"+address, time.Now().Add(emailVerifyTTL))
+	link := m.App.Server.BaseURL + "/verify-email?" + url.Values{"token": {token}}.Encode()
+
+	return m.App.Mailer.Send(ctx, mail.Message{
+		To:      []string{address},
+		Subject: "Confirm your email address",
+		Text: "Hi " + user.Name + ",\n\n" +
+			"Please confirm that " + address + " is your email address by opening the link below:\n\n" +
+			link + "\n\n" +
+			"The link expires in 48 hours. If you didn't ask for this, you can ignore this email.\n",
+	})
+}
+
+// sendEmailChangeNotice tells the current address that a switch to a new
+// one has been requested, so a hijacked account doesn't change hands quietly
+func (m *Repository) sendEmailChangeNotice(ctx context.Context, user *models.User, newAddress string) error {
+	return m.App.Mailer.Send(ctx, mail.Message{
+		To:      []string{user.Email},
+		Subject: "Your email address is being changed",
+		Text: "Hi " + user.Name + ",\n\n" +
+			"Someone asked to change the email address on your account to " + newAddress + ". " +
+			"The change takes effect once the new address is confirmed.\n\n" +
+			"If this wasn't you, log in, reset your password and change the email back on your profile.\n",
+	})
+}
+
+// VerifyEmailNotice is where unverified users land, with a button to resend
+// the link
+func (m *Repository) VerifyEmailNotice(w http.ResponseWriter, r *http.Request) {
+	if user := CurrentUser(r.Context()); user.EmailVerified() && user.PendingEmail == "" {
+		redirect(w, r, "/")
+		return
+	}
+
+	templates.VerifyEmailPage(m.AddDefaultData(&models.TemplateData{}, r)).Render(r.Context(), w)
+}
+
+// ResendVerification sends a fresh link to whichever address still needs
+// confirming
+func (m *Repository) ResendVerification(w http.ResponseWriter, r *http.Request) {
+	user := CurrentUser(r.Context())
+	td := m.AddDefaultData(&models.TemplateData{}, r)
+
+	address := user.PendingEmail
+	if !user.EmailVerified() {
+		address = user.Email
+	}
+
+	if address == "" {
+		td.Errors = append(td.Errors, "Your email address is already confirmed")
+	} else if err := m.sendVerification(r.Context(), user, address); err != nil {
+		log.Println("❌ Failed to send verification email:", err)
+		td.Errors = append(td.Errors, "We couldn't send the email, please try again later")
+	} else {
+		td.Flash = "We've sent a new link to " + address
+	}
+
+	templ.Handler(templates.VerifyEmailPage(td), templ.WithFragments("error-messages")).ServeHTTP(w, r)
+}
+
+// VerifyEmail follows a link from a verification email. It works without a
+// session, as the link may be opened on another device.
+func (m *Repository) VerifyEmail(w http.ResponseWriter, r *http.Request) {
+	td := m.AddDefaultData(&models.TemplateData{}, r)
+	render := func() {
+		if err := templates.VerifyEmailResultPage(td).Render(r.Context(), w); err != nil {
+			log.Println("❌ Template render error:", err)
+		}
+	}
+	invalid := func() {
+		td.Errors = append(td.Errors, "This link is invalid or has expired. Log in to request a new one.")
+		render()
+	}
+
+	value, err := tokens.Verify([]byte(m.App.SessionConfig.Secret), verifyPurpose, r.URL.Query().Get("token"))
+	if err != nil {
+		invalid()
+		return
+	}
+	userID, address, _ := strings.Cut(value, "|")
+
+	user, err := m.DB.GetUserByID(r.Context(), userID)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			invalid()
+			return
+		}
+		dbError(w, err)
+		return
+	}
+
+	switch address {
+	case user.Email:
+		err = m.DB.VerifyEmail(r.Context(), user.ID, address)
+	case user.PendingEmail:
+		err = m.DB.ConfirmEmailChange(r.Context(), user.ID, address)
+	default:
+		err = repository.ErrNotFound
+	}
+	switch {
+	case errors.Is(err, repository.ErrNotFound):
+		invalid()
+		return
+	case errors.Is(err, repository.ErrDuplicateEmail):
+		td.Errors = append(td.Errors, "Another account has started using "+address+" since you asked for the change")
+		render()
+		return
+	case err != nil:
+		dbError(w, err)
+		return
+	}
+
+	log.Println("📧 Email address confirmed for user", user.ID)
+	td.Flash = "Thanks, " + address + " is confirmed."
+	render()
+}
@@ -0,0 +1,38 @@
+package mail
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// FileDrop writes each message to Dir as an .eml file that any mail client
+// can open, for development without a mail server
+type FileDrop struct {
+	Dir  string
+	From string
+}
+
+// NewFileDrop creates a FileDrop sender
+func NewFileDrop(dir, from string) *FileDrop {
+	return &FileDrop{Dir: dir, From: from}
+}
+
+// Send writes msg to a new file
+func (f *FileDrop) Send(ctx context.Context, msg Message) error {
+	if err := msg.validate(); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(f.Dir, 0o755); err != nil {
+		return err
+	}
+
+	suffix := make([]byte, 4)
+	rand.Read(suffix)
+	name := time.Now().Format("20060102-150405.000") + "-" + hex.EncodeToString(suffix) + ".eml"
+
+	return os.WriteFile(filepath.Join(f.Dir, name), msg.Bytes(f.From), 0o644)
+}
@@ -0,0 +1,141 @@
+package mail
+
+import (
+	"context"
+	"net"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestMessageBytes(t *testing.T) {
+	msg := Message{To: []string{"ada@example.com"}, Subject: "Héllo", Text: "line one\nline two"}
+	out := string(msg.Bytes("App <no-reply@example.com>"))
+
+	for _, want := range []string{
+		"From: App <no-reply@example.com>\r\n",
+		"To: ada@example.com\r\n",
+		"Subject: =?utf-8?q?H=C3=A9llo?=\r\n",
+		"@example.com>\r\n",
+		"\r\n\r\nline one\r\nline two",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected %q in\n%s", want, out)
+		}
+	}
+}
+
+func TestRejectsBadRecipients(t *testing.T) {
+	for _, to := range [][]string{nil, {"not an address"}, {"a@example.com\r\nBcc: b@example.com"}} {
+		if err := NewMemory().Send(context.Background(), Message{To: to}); err == nil {
+			t.Errorf("expected %q to be rejected", to)
+		}
+	}
+}
+
+func TestFileDrop(t *testing.T) {
+	dir := t.TempDir()
+	f := NewFileDrop(filepath.Join(dir, "mail"), "no-reply@example.com")
+
+	if err := f.Send(context.Background(), Message{To: []string{"ada@example.com"}, Subject: "Hi", Text: "body"}); err != nil {
+		t.Fatal(err)
+	}
+
+	files, _ := filepath.Glob(filepath.Join(dir, "mail", "*.eml"))
+	if len(files) != 1 {
+		t.Fatalf("expected one .eml file, got %v", files)
+	}
+	data, _ := os.ReadFile(files[0])
+	if !strings.Contains(string(data), "Subject: Hi") {
+		t.Errorf("unexpected file contents %q", data)
+	}
+}
+
+func TestMemoryLastTo(t *testing.T) {
+	m := NewMemory()
+	m.Send(context.Background(), Message{To: []string{"a@example.com"}, Subject: "one"})
+	m.Send(context.Background(), Message{To: []string{"b@example.com"}, Subject: "two"})
+	m.Send(context.Background(), Message{To: []string{"a@example.com"}, Subject: "three"})
+
+	if msg, ok := m.LastTo("a@example.com"); !ok || msg.Subject != "three" {
+		t.Errorf("expected the latest message to a, got %+v", msg)
+	}
+	if _, ok := m.LastTo("c@example.com"); ok {
+		t.Error("expected nothing for c")
+	}
+	if len(m.Sent()) != 3 {
+		t.Errorf("expected 3 messages, got %d", len(m.Sent()))
+	}
+}
+
+// fakeSMTP accepts one message on a local port and returns what it received
+func fakeSMTP(t *testing.T) (addr string, received <-chan string) {
+	t.Helper()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	ch := make(chan string, 1)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		tp := textproto.NewConn(conn)
+		tp.PrintfLine("220 fake ESMTP")
+
+		var log []string
+		for {
+			line, err := tp.ReadLine()
+			if err != nil {
+				return
+			}
+			log = append(log, line)
+
+			switch cmd := strings.ToUpper(strings.Fields(line + " x")[0]); cmd {
+			case "EHLO", "HELO":
+				tp.PrintfLine("250 fake")
+			case "DATA":
+				tp.PrintfLine("354 go ahead")
+				body, _ := tp.ReadDotLines()
+				log = append(log, body...)
+				tp.PrintfLine("250 queued")
+			case "QUIT":
+				tp.PrintfLine("221 bye")
+				ch <- strings.Join(log, "\n")
+				return
+			default:
+				tp.PrintfLine("250 ok")
+			}
+		}
+	}()
+
+	return ln.Addr().String(), ch
+}
+
+func TestSMTP(t *testing.T) {
+	addr, received := fakeSMTP(t)
+	host, port, _ := net.SplitHostPort(addr)
+	p, _ := strconv.Atoi(port)
+
+	s := NewSMTP(host, p, "", "", "App <no-reply@example.com>")
+	err := s.Send(context.Background(), Message{To: []string{"Ada <ada@example.com>"}, Subject: "Hi", Text: "hello there"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := <-received
+	for _, want := range []string{"MAIL FROM:<no-reply@example.com>", "RCPT TO:<ada@example.com>", "Subject: Hi", "hello there"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("expected %q in the SMTP exchange:\n%s", want, got)
+		}
+	}
+}
@@ -0,0 +1,52 @@
+package mail
+
+import (
+	"context"
+	"slices"
+	"sync"
+)
+
+// Memory keeps sent messages so tests can read them back
+type Memory struct {
+	mu   sync.Mutex
+	sent []Message
+}
+
+// NewMemory creates an empty Memory sender
+func NewMemory() *Memory {
+	return &Memory{}
+}
+
+// Send records msg
+func (m *Memory) Send(ctx context.Context, msg Message) error {
+	if err := msg.validate(); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.sent = append(m.sent, msg)
+	return nil
+}
+
+// Sent returns every message sent so far
+func (m *Memory) Sent() []Message {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return slices.Clone(m.sent)
+}
+
+// LastTo returns the most recent message to the address
+func (m *Memory) LastTo(address string) (Message, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for i := len(m.sent) - 1; i >= 0; i-- {
+		if slices.Contains(m.sent[i].To, address) {
+			return m.sent[i], true
+		}
+	}
+	return Message{}, false
+}
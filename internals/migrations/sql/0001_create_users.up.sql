@@ -0,0 +1,12 @@
+CREATE TABLE IF NOT EXISTS users (
+    id         uuid PRIMARY KEY,
+    email      text        NOT NULL UNIQUE,
+    password   text        NOT NULL,
+    name       text        NOT NULL,
+    category   integer     NOT NULL DEFAULT 0,
+    dob        date        NOT NULL,
+    bio        text        NOT NULL DEFAULT '',
+    avatar     text        NOT NULL DEFAULT '',
+    created_at timestamptz NOT NULL DEFAULT now(),
+    updated_at timestamptz NOT NULL DEFAULT now()
+);
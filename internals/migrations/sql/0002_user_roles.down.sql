@@ -0,0 +1,5 @@
+ALTER TABLE users ADD COLUMN category integer NOT NULL DEFAULT 0;
+
+UPDATE users SET category = CASE role WHEN 'student' THEN 1 WHEN 'instructor' THEN 2 ELSE 0 END;
+
+ALTER TABLE users DROP COLUMN role;
@@ -0,0 +1,10 @@
+-- category was a bare int that the registration form filled in with
+-- 1 = Student and 2 = Instructor, so that is the mapping we keep. No admin
+-- accounts could have been created through the form.
+ALTER TABLE users ADD COLUMN role text NOT NULL DEFAULT 'student';
+
+UPDATE users SET role = CASE category WHEN 2 THEN 'instructor' ELSE 'student' END;
+
+ALTER TABLE users
+    ADD CONSTRAINT users_role_check CHECK (role IN ('admin', 'instructor', 'student')),
+    DROP COLUMN category;
@@ -0,0 +1,16 @@
+-- Server-side sessions. The cookie holds a random token; id is its SHA-256
+-- so the table can't be replayed as cookies. Anonymous sessions (used for
+-- the CSRF token before login) have no user_id.
+CREATE TABLE IF NOT EXISTS sessions (
+    id           text        PRIMARY KEY,
+    user_id      uuid        REFERENCES users (id) ON DELETE CASCADE,
+    data         bytea       NOT NULL,
+    user_agent   text        NOT NULL DEFAULT '',
+    ip           text        NOT NULL DEFAULT '',
+    created_at   timestamptz NOT NULL DEFAULT now(),
+    last_seen_at timestamptz NOT NULL DEFAULT now(),
+    expires_at   timestamptz NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS sessions_user_id_idx ON sessions (user_id);
+CREATE INDEX IF NOT EXISTS sessions_expires_at_idx ON sessions (expires_at);
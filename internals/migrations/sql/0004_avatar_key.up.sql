@@ -0,0 +1,4 @@
+-- avatar keeps the URL templates show; avatar_key is the storage key the
+-- file was saved under, so the old file can be deleted on change. Avatars
+-- uploaded before this have no key and are left where they are.
+ALTER TABLE users ADD COLUMN avatar_key text NOT NULL DEFAULT '';
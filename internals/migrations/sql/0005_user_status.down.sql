@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS users_role_status_idx;
+DROP INDEX IF EXISTS users_created_at_idx;
+
+ALTER TABLE users DROP COLUMN status;
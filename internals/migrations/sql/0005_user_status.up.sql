@@ -0,0 +1,7 @@
+ALTER TABLE users
+    ADD COLUMN status text NOT NULL DEFAULT 'active',
+    ADD CONSTRAINT users_status_check CHECK (status IN ('active', 'suspended', 'deactivated'));
+
+-- the admin directory searches and sorts on these
+CREATE INDEX IF NOT EXISTS users_created_at_idx ON users (created_at);
+CREATE INDEX IF NOT EXISTS users_role_status_idx ON users (role, status);
@@ -0,0 +1,11 @@
+-- One-time password reset tokens. Only the SHA-256 of the emailed token is
+-- kept; used_at is set once it has been redeemed.
+CREATE TABLE IF NOT EXISTS password_resets (
+    token_hash text        PRIMARY KEY,
+    user_id    uuid        NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    created_at timestamptz NOT NULL DEFAULT now(),
+    expires_at timestamptz NOT NULL,
+    used_at    timestamptz
+);
+
+CREATE INDEX IF NOT EXISTS password_resets_user_id_idx ON password_resets (user_id);
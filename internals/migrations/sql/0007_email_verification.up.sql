@@ -0,0 +1,8 @@
+-- email_verified_at is set once the user follows the link sent to their
+-- address. pending_email holds a new address until it is confirmed.
+ALTER TABLE users
+    ADD COLUMN email_verified_at timestamptz,
+    ADD COLUMN pending_email text NOT NULL DEFAULT '';
+
+-- accounts created before verification existed keep their access
+UPDATE users SET email_verified_at = created_at;
@@ -0,0 +1,10 @@
+-- Per-user settings. Users without a row get models.DefaultPreferences.
+CREATE TABLE IF NOT EXISTS user_preferences (
+    user_id           uuid    PRIMARY KEY REFERENCES users (id) ON DELETE CASCADE,
+    timezone          text    NOT NULL DEFAULT 'UTC',
+    date_format       text    NOT NULL DEFAULT 'long'
+                              CHECK (date_format IN ('long', 'iso', 'dmy', 'mdy')),
+    notify_grades     boolean NOT NULL DEFAULT true,
+    notify_enrollment boolean NOT NULL DEFAULT true,
+    notify_news       boolean NOT NULL DEFAULT false
+);
@@ -0,0 +1,20 @@
+-- TOTP two-factor authentication. totp_secret is only meaningful once
+-- totp_enabled_at is set; totp_last_counter stops a code being replayed.
+ALTER TABLE users
+    ADD COLUMN totp_secret text NOT NULL DEFAULT '',
+    ADD COLUMN totp_enabled_at timestamptz,
+    ADD COLUMN totp_last_counter bigint NOT NULL DEFAULT 0;
+
+-- One-time recovery codes, kept as SHA-256 hashes
+CREATE TABLE IF NOT EXISTS recovery_codes (
+    user_id   uuid        NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    code_hash text        NOT NULL,
+    used_at   timestamptz,
+    PRIMARY KEY (user_id, code_hash)
+);
+
+-- Roles whose members must turn on two-factor authentication
+CREATE TABLE IF NOT EXISTS role_policies (
+    role               text    PRIMARY KEY,
+    require_two_factor boolean NOT NULL DEFAULT false
+);
@@ -0,0 +1,12 @@
+-- Failed logins per account. Kept in the database so a lockout survives
+-- restarts and applies on every instance. lockouts counts how many times
+-- the account has been locked since its last successful login.
+CREATE TABLE IF NOT EXISTS login_failures (
+    user_id         uuid        PRIMARY KEY REFERENCES users (id) ON DELETE CASCADE,
+    failures        integer     NOT NULL DEFAULT 0,
+    lockouts        integer     NOT NULL DEFAULT 0,
+    last_failure_at timestamptz NOT NULL DEFAULT now(),
+    locked_until    timestamptz
+);
+
+CREATE INDEX IF NOT EXISTS login_failures_locked_until_idx ON login_failures (locked_until);
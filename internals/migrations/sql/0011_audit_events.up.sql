@@ -0,0 +1,32 @@
+-- Security audit log. Rows are only ever inserted: the trigger below refuses
+-- updates, deletes and truncation. Actor and target have no foreign keys, and
+-- their emails are copied in, so events outlive the accounts they mention.
+CREATE TABLE IF NOT EXISTS audit_events (
+    id           bigserial   PRIMARY KEY,
+    actor_id     uuid,
+    actor_email  text        NOT NULL DEFAULT '',
+    action       text        NOT NULL,
+    target_id    uuid,
+    target_email text        NOT NULL DEFAULT '',
+    ip           text        NOT NULL DEFAULT '',
+    user_agent   text        NOT NULL DEFAULT '',
+    changes      jsonb       NOT NULL DEFAULT '[]',
+    created_at   timestamptz NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS audit_events_created_at_idx ON audit_events (created_at);
+CREATE INDEX IF NOT EXISTS audit_events_action_idx ON audit_events (action, created_at);
+
+CREATE OR REPLACE FUNCTION audit_events_append_only() RETURNS trigger AS $$
+BEGIN
+    RAISE EXCEPTION 'audit_events is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER audit_events_no_update
+    BEFORE UPDATE OR DELETE ON audit_events
+    FOR EACH ROW EXECUTE FUNCTION audit_events_append_only();
+
+CREATE TRIGGER audit_events_no_truncate
+    BEFORE TRUNCATE ON audit_events
+    FOR EACH STATEMENT EXECUTE FUNCTION audit_events_append_only();
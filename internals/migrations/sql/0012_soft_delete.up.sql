@@ -0,0 +1,6 @@
+-- Deleting an account only stamps deleted_at, so grades, enrollments and
+-- audit entries keep pointing at a real row. The account can be restored
+-- until the retention period is over, when the purge job removes it.
+ALTER TABLE users ADD COLUMN deleted_at timestamptz;
+
+CREATE INDEX IF NOT EXISTS users_deleted_at_idx ON users (deleted_at) WHERE deleted_at IS NOT NULL;
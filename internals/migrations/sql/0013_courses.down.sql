@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS course_instructors;
+DROP TABLE IF EXISTS courses;
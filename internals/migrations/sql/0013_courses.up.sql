@@ -0,0 +1,27 @@
+-- Courses and who teaches them. Codes are stored normalized, such as
+-- CSC 101, so a plain unique constraint keeps them distinct.
+CREATE TABLE IF NOT EXISTS courses (
+    id           uuid        PRIMARY KEY,
+    code         text        NOT NULL,
+    title        text        NOT NULL,
+    description  text        NOT NULL DEFAULT '',
+    credit_units integer     NOT NULL CHECK (credit_units BETWEEN 1 AND 10),
+    department   text        NOT NULL,
+    capacity     integer     NOT NULL CHECK (capacity BETWEEN 1 AND 1000),
+    status       text        NOT NULL DEFAULT 'draft' CHECK (status IN ('draft', 'published', 'archived')),
+    created_at   timestamptz NOT NULL DEFAULT now(),
+    updated_at   timestamptz NOT NULL DEFAULT now(),
+    CONSTRAINT courses_code_key UNIQUE (code)
+);
+
+CREATE INDEX IF NOT EXISTS courses_status_department_idx ON courses (status, department);
+
+-- position keeps the instructors in the order they were listed
+CREATE TABLE IF NOT EXISTS course_instructors (
+    course_id uuid    NOT NULL REFERENCES courses (id) ON DELETE CASCADE,
+    user_id   uuid    NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    position  integer NOT NULL DEFAULT 0,
+    PRIMARY KEY (course_id, user_id)
+);
+
+CREATE INDEX IF NOT EXISTS course_instructors_user_id_idx ON course_instructors (user_id);
@@ -0,0 +1,31 @@
+-- Terms are the periods students enroll in. The partial unique index lets at
+-- most one of them be the current term.
+CREATE TABLE IF NOT EXISTS terms (
+    id            uuid        PRIMARY KEY,
+    name          text        NOT NULL,
+    add_deadline  date        NOT NULL,
+    drop_deadline date        NOT NULL,
+    is_current    boolean     NOT NULL DEFAULT false,
+    created_at    timestamptz NOT NULL DEFAULT now(),
+    CONSTRAINT terms_name_key UNIQUE (name)
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS terms_current_idx ON terms (is_current) WHERE is_current;
+
+-- One row per student, course and term. Dropping keeps the row so the
+-- student can enroll again; position orders the waitlist and is only set
+-- while waitlisted.
+CREATE TABLE IF NOT EXISTS enrollments (
+    id         uuid        PRIMARY KEY,
+    course_id  uuid        NOT NULL REFERENCES courses (id) ON DELETE CASCADE,
+    term_id    uuid        NOT NULL REFERENCES terms (id) ON DELETE CASCADE,
+    student_id uuid        NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    status     text        NOT NULL CHECK (status IN ('enrolled', 'waitlisted', 'dropped')),
+    position   integer,
+    created_at timestamptz NOT NULL DEFAULT now(),
+    updated_at timestamptz NOT NULL DEFAULT now(),
+    CONSTRAINT enrollments_student_key UNIQUE (course_id, term_id, student_id)
+);
+
+CREATE INDEX IF NOT EXISTS enrollments_student_idx ON enrollments (student_id, term_id);
+CREATE INDEX IF NOT EXISTS enrollments_waitlist_idx ON enrollments (course_id, term_id, position) WHERE status = 'waitlisted';
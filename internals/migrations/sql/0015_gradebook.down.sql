@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS gradebooks;
+DROP TABLE IF EXISTS score_history;
+DROP TABLE IF EXISTS scores;
+DROP TABLE IF EXISTS assessments;
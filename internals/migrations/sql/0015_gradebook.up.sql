@@ -0,0 +1,46 @@
+-- Assessments belong to a course in a term; weight is the percentage of the
+-- final grade a full score is worth.
+CREATE TABLE IF NOT EXISTS assessments (
+    id         uuid         PRIMARY KEY,
+    course_id  uuid         NOT NULL REFERENCES courses (id) ON DELETE CASCADE,
+    term_id    uuid         NOT NULL REFERENCES terms (id) ON DELETE CASCADE,
+    name       text         NOT NULL,
+    category   text         NOT NULL CHECK (category IN ('test', 'assignment', 'exam')),
+    weight     numeric(5,2) NOT NULL CHECK (weight > 0 AND weight <= 100),
+    max_score  numeric(6,2) NOT NULL CHECK (max_score > 0),
+    position   integer      NOT NULL DEFAULT 0,
+    created_at timestamptz  NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS assessments_course_term_idx ON assessments (course_id, term_id, position);
+
+-- A missing row is an assessment not graded yet
+CREATE TABLE IF NOT EXISTS scores (
+    assessment_id uuid         NOT NULL REFERENCES assessments (id) ON DELETE CASCADE,
+    student_id    uuid         NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    score         numeric(6,2) NOT NULL CHECK (score >= 0),
+    updated_at    timestamptz  NOT NULL DEFAULT now(),
+    PRIMARY KEY (assessment_id, student_id)
+);
+
+-- Every change to a score, appended in the same transaction. The editor's
+-- email is copied in so the history outlives their account.
+CREATE TABLE IF NOT EXISTS score_history (
+    id            bigserial    PRIMARY KEY,
+    assessment_id uuid         NOT NULL REFERENCES assessments (id) ON DELETE CASCADE,
+    student_id    uuid         NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    before        numeric(6,2),
+    after         numeric(6,2),
+    editor_email  text         NOT NULL,
+    created_at    timestamptz  NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS score_history_student_idx ON score_history (student_id, assessment_id);
+
+-- Students only see a course's grades for a term while published_at is set
+CREATE TABLE IF NOT EXISTS gradebooks (
+    course_id    uuid        NOT NULL REFERENCES courses (id) ON DELETE CASCADE,
+    term_id      uuid        NOT NULL REFERENCES terms (id) ON DELETE CASCADE,
+    published_at timestamptz,
+    PRIMARY KEY (course_id, term_id)
+);
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS programme_students;
+DROP TABLE IF EXISTS programmes;
+DROP TABLE IF EXISTS grading_scales;
@@ -0,0 +1,47 @@
+-- Grading scales turn totals into letters and grade points, best band
+-- first, and GPAs into degree classes. The partial unique index lets at
+-- most one scale be the default for students outside any programme.
+CREATE TABLE IF NOT EXISTS grading_scales (
+    id         uuid        PRIMARY KEY,
+    name       text        NOT NULL,
+    bands      jsonb       NOT NULL,
+    classes    jsonb       NOT NULL DEFAULT '[]',
+    is_default boolean     NOT NULL DEFAULT false,
+    created_at timestamptz NOT NULL DEFAULT now(),
+    CONSTRAINT grading_scales_name_key UNIQUE (name)
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS grading_scales_default_idx ON grading_scales (is_default) WHERE is_default;
+
+-- the same scales as models.StandardScales
+INSERT INTO grading_scales (id, name, bands, classes, is_default) VALUES
+    (gen_random_uuid(), 'NUC 5-point',
+     '[{"min_score":70,"letter":"A","points":5},{"min_score":60,"letter":"B","points":4},{"min_score":50,"letter":"C","points":3},{"min_score":45,"letter":"D","points":2},{"min_score":40,"letter":"E","points":1},{"min_score":0,"letter":"F","points":0}]',
+     '[{"name":"First Class","min_gpa":4.5},{"name":"Second Class Upper","min_gpa":3.5},{"name":"Second Class Lower","min_gpa":2.4},{"name":"Third Class","min_gpa":1.5},{"name":"Pass","min_gpa":1}]',
+     true),
+    (gen_random_uuid(), 'US 4.0',
+     '[{"min_score":93,"letter":"A","points":4},{"min_score":90,"letter":"A-","points":3.7},{"min_score":87,"letter":"B+","points":3.3},{"min_score":83,"letter":"B","points":3},{"min_score":80,"letter":"B-","points":2.7},{"min_score":77,"letter":"C+","points":2.3},{"min_score":73,"letter":"C","points":2},{"min_score":70,"letter":"C-","points":1.7},{"min_score":67,"letter":"D+","points":1.3},{"min_score":60,"letter":"D","points":1},{"min_score":0,"letter":"F","points":0}]',
+     '[{"name":"Summa Cum Laude","min_gpa":3.9},{"name":"Magna Cum Laude","min_gpa":3.7},{"name":"Cum Laude","min_gpa":3.5},{"name":"Good Standing","min_gpa":2}]',
+     false),
+    (gen_random_uuid(), 'Percentage',
+     '[{"min_score":70,"letter":"A","points":4},{"min_score":60,"letter":"B","points":3},{"min_score":50,"letter":"C","points":2},{"min_score":40,"letter":"D","points":1},{"min_score":0,"letter":"F","points":0}]',
+     '[{"name":"Distinction","min_gpa":3.5},{"name":"Merit","min_gpa":2.5},{"name":"Pass","min_gpa":1}]',
+     false)
+ON CONFLICT (name) DO NOTHING;
+
+-- Programmes grade their students on one scale, which can't be deleted
+-- while a programme uses it. A student belongs to at most one programme.
+CREATE TABLE IF NOT EXISTS programmes (
+    id         uuid        PRIMARY KEY,
+    name       text        NOT NULL,
+    scale_id   uuid        NOT NULL REFERENCES grading_scales (id),
+    created_at timestamptz NOT NULL DEFAULT now(),
+    CONSTRAINT programmes_name_key UNIQUE (name)
+);
+
+CREATE TABLE IF NOT EXISTS programme_students (
+    student_id   uuid PRIMARY KEY REFERENCES users (id) ON DELETE CASCADE,
+    programme_id uuid NOT NULL REFERENCES programmes (id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS programme_students_programme_idx ON programme_students (programme_id);
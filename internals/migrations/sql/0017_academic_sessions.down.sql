@@ -0,0 +1,11 @@
+DROP INDEX IF EXISTS terms_session_idx;
+
+ALTER TABLE terms
+    DROP COLUMN exam_end,
+    DROP COLUMN exam_start,
+    DROP COLUMN registration_opens,
+    DROP COLUMN end_date,
+    DROP COLUMN start_date,
+    DROP COLUMN session_id;
+
+DROP TABLE IF EXISTS academic_sessions;
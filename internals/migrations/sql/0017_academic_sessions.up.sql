@@ -0,0 +1,38 @@
+-- Academic sessions are the years terms belong to, e.g. 2025/2026.
+CREATE TABLE IF NOT EXISTS academic_sessions (
+    id         uuid        PRIMARY KEY,
+    name       text        NOT NULL,
+    start_date date        NOT NULL,
+    end_date   date        NOT NULL,
+    created_at timestamptz NOT NULL DEFAULT now(),
+    CONSTRAINT academic_sessions_name_key UNIQUE (name),
+    CONSTRAINT academic_sessions_dates_check CHECK (start_date <= end_date)
+);
+
+-- Terms gain their teaching dates, the day registration opens (the add
+-- deadline closes it) and an exam period. Terms from before sessions have
+-- none; their dates are filled in from the deadlines until an admin edits
+-- them.
+ALTER TABLE terms
+    ADD COLUMN session_id         uuid REFERENCES academic_sessions (id),
+    ADD COLUMN start_date         date,
+    ADD COLUMN end_date           date,
+    ADD COLUMN registration_opens date,
+    ADD COLUMN exam_start         date,
+    ADD COLUMN exam_end           date;
+
+UPDATE terms SET
+    registration_opens = LEAST(created_at::date, add_deadline),
+    start_date         = LEAST(created_at::date, add_deadline),
+    end_date           = GREATEST(add_deadline, drop_deadline),
+    exam_start         = GREATEST(add_deadline, drop_deadline),
+    exam_end           = GREATEST(add_deadline, drop_deadline);
+
+ALTER TABLE terms
+    ALTER COLUMN start_date SET NOT NULL,
+    ALTER COLUMN end_date SET NOT NULL,
+    ALTER COLUMN registration_opens SET NOT NULL,
+    ALTER COLUMN exam_start SET NOT NULL,
+    ALTER COLUMN exam_end SET NOT NULL;
+
+CREATE INDEX IF NOT EXISTS terms_session_idx ON terms (session_id);
@@ -0,0 +1,9 @@
+ALTER TABLE score_history
+    DROP CONSTRAINT score_history_student_id_fkey,
+    ADD CONSTRAINT score_history_student_id_fkey FOREIGN KEY (student_id) REFERENCES users (id) ON DELETE CASCADE;
+
+ALTER TABLE scores
+    DROP CONSTRAINT scores_student_id_fkey,
+    ADD CONSTRAINT scores_student_id_fkey FOREIGN KEY (student_id) REFERENCES users (id) ON DELETE CASCADE;
+
+ALTER TABLE users DROP COLUMN anonymized_at;
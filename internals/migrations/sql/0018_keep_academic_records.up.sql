@@ -0,0 +1,13 @@
+-- Grades outlive the accounts they belong to. Scores and their history no
+-- longer cascade when a student's row is deleted; instead the purge job
+-- strips a deleted account that still has them of its personal details and
+-- stamps anonymized_at, leaving the row for the records to point at.
+ALTER TABLE users ADD COLUMN anonymized_at timestamptz;
+
+ALTER TABLE scores
+    DROP CONSTRAINT scores_student_id_fkey,
+    ADD CONSTRAINT scores_student_id_fkey FOREIGN KEY (student_id) REFERENCES users (id) ON DELETE RESTRICT;
+
+ALTER TABLE score_history
+    DROP CONSTRAINT score_history_student_id_fkey,
+    ADD CONSTRAINT score_history_student_id_fkey FOREIGN KEY (student_id) REFERENCES users (id) ON DELETE RESTRICT;
@@ -0,0 +1,3 @@
+ALTER TABLE enrollments
+    DROP CONSTRAINT enrollments_student_id_fkey,
+    ADD CONSTRAINT enrollments_student_id_fkey FOREIGN KEY (student_id) REFERENCES users (id) ON DELETE CASCADE;
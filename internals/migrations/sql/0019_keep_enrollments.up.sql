@@ -0,0 +1,6 @@
+-- Enrollments are academic records too: past rosters and published results
+-- are read through them. Like scores, they stop a student's row from being
+-- deleted, so the purge job anonymises students who have any.
+ALTER TABLE enrollments
+    DROP CONSTRAINT enrollments_student_id_fkey,
+    ADD CONSTRAINT enrollments_student_id_fkey FOREIGN KEY (student_id) REFERENCES users (id) ON DELETE RESTRICT;
@@ -0,0 +1,258 @@
+package models
+
+import (
+	"fmt"
+	"net/url"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// AuditAction names something that happened to an account
+type AuditAction string
+
+const (
+	AuditLogin          AuditAction = "login"
+	AuditLoginFailed    AuditAction = "login_failed"
+	AuditLogout         AuditAction = "logout"
+	AuditProfileUpdate  AuditAction = "profile_update"
+	AuditAvatarChange   AuditAction = "avatar_change"
+	AuditPasswordChange AuditAction = "password_change"
+	AuditPasswordReset  AuditAction = "password_reset"
+	AuditUserCreate     AuditAction = "user_create"
+	AuditRoleChange     AuditAction = "role_change"
+	AuditStatusChange   AuditAction = "status_change"
+	AuditUserDelete     AuditAction = "user_delete"
+	AuditUserRestore    AuditAction = "user_restore"
+	AuditUserPurge      AuditAction = "user_purge"
+	AuditUserExport     AuditAction = "user_export"
+	AuditTwoFactorOn    AuditAction = "two_factor_on"
+	AuditTwoFactorOff   AuditAction = "two_factor_off"
+	AuditLockout        AuditAction = "lockout"
+	AuditUnlock         AuditAction = "unlock"
+	AuditPolicyChange   AuditAction = "policy_change"
+)
+
+// AuditActions lists every action in display order
+var AuditActions = []AuditAction{
+	AuditLogin, AuditLoginFailed, AuditLogout,
+	AuditProfileUpdate, AuditAvatarChange, AuditPasswordChange, AuditPasswordReset,
+	AuditUserCreate, AuditRoleChange, AuditStatusChange,
+	AuditUserDelete, AuditUserRestore, AuditUserPurge, AuditUserExport,
+	AuditTwoFactorOn, AuditTwoFactorOff, AuditLockout, AuditUnlock, AuditPolicyChange,
+}
+
+// Label is the human readable action name
+func (a AuditAction) Label() string {
+	switch a {
+	case AuditLogin:
+		return "Logged in"
+	case AuditLoginFailed:
+		return "Failed login"
+	case AuditLogout:
+		return "Logged out"
+	case AuditProfileUpdate:
+		return "Profile updated"
+	case AuditAvatarChange:
+		return "Avatar changed"
+	case AuditPasswordChange:
+		return "Password changed"
+	case AuditPasswordReset:
+		return "Password reset"
+	case AuditUserCreate:
+		return "Account created"
+	case AuditRoleChange:
+		return "Role changed"
+	case AuditStatusChange:
+		return "Status changed"
+	case AuditUserDelete:
+		return "Account deleted"
+	case AuditUserRestore:
+		return "Account restored"
+	case AuditUserPurge:
+		return "Account purged"
+	case AuditUserExport:
+		return "Users exported"
+	case AuditTwoFactorOn:
+		return "2FA turned on"
+	case AuditTwoFactorOff:
+		return "2FA turned off"
+	case AuditLockout:
+		return "Account locked"
+	case AuditUnlock:
+		return "Account unlocked"
+	case AuditPolicyChange:
+		return "Policy changed"
+	}
+	return string(a)
+}
+
+// ParseAuditAction converts a stored or submitted value into an AuditAction
+func ParseAuditAction(s string) (AuditAction, error) {
+	if a := AuditAction(s); slices.Contains(AuditActions, a) {
+		return a, nil
+	}
+	return "", fmt.Errorf("unknown audit action %q", s)
+}
+
+// AuditChange is one field's value before and after an event
+type AuditChange struct {
+	Field  string `json:"field"`
+	Before string `json:"before"`
+	After  string `json:"after"`
+}
+
+// String shows the change as field: "before" → "after"
+func (c AuditChange) String() string {
+	return fmt.Sprintf("%s: %q → %q", c.Field, c.Before, c.After)
+}
+
+// AuditEvent is a row of the append-only audit log. The actor and target
+// emails are copied in so the trail survives the accounts being deleted.
+type AuditEvent struct {
+	ID          int64
+	ActorID     string
+	ActorEmail  string
+	Action      AuditAction
+	TargetID    string
+	TargetEmail string
+	IP          string
+	UserAgent   string
+	CreatedAt   time.Time
+	Changes     []AuditChange
+}
+
+// Diff lists the fields whose value differs between before and after,
+// sorted by name. Fields missing from one side count as empty.
+func Diff(before, after map[string]string) []AuditChange {
+	var changes []AuditChange
+	for field, b := range before {
+		if a := after[field]; a != b {
+			changes = append(changes, AuditChange{Field: field, Before: b, After: a})
+		}
+	}
+	for field, a := range after {
+		if _, ok := before[field]; !ok && a != "" {
+			changes = append(changes, AuditChange{Field: field, After: a})
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Field < changes[j].Field })
+	return changes
+}
+
+// AuditFields are the user's details worth recording in a diff. The password
+// hash is never among them.
+func (u *User) AuditFields() map[string]string {
+	dob := ""
+	if !u.DOB.IsZero() {
+		dob = u.DOB.Format("2006-01-02")
+	}
+	deleted := ""
+	if u.DeletedAt != nil {
+		deleted = u.DeletedAt.UTC().Format(time.RFC3339)
+	}
+	return map[string]string{
+		"name":          u.Name,
+		"email":         u.Email,
+		"pending_email": u.PendingEmail,
+		"role":          string(u.Role),
+		"status":        string(u.Status),
+		"dob":           dob,
+		"bio":           u.Bio,
+		"avatar":        u.Avatar,
+		"deleted_at":    deleted,
+	}
+}
+
+// AuditQuery selects one page of the audit log, newest first
+type AuditQuery struct {
+	// Search matches the actor or target email, case-insensitively
+	Search string
+	Action AuditAction
+
+	// From and To bound the event date, both days inclusive; zero leaves
+	// that end open
+	From time.Time
+	To   time.Time
+
+	Page    int
+	PerPage int
+}
+
+// Normalize fills in defaults and clamps values that came from a request
+func (q AuditQuery) Normalize() AuditQuery {
+	q.Search = strings.TrimSpace(q.Search)
+	if q.Page < 1 {
+		q.Page = 1
+	}
+	if q.PerPage < 1 {
+		q.PerPage = DefaultPerPage
+	}
+	if q.PerPage > MaxPerPage {
+		q.PerPage = MaxPerPage
+	}
+	return q
+}
+
+// Offset is the number of events before the requested page
+func (q AuditQuery) Offset() int {
+	return (q.Page - 1) * q.PerPage
+}
+
+// Pages is the number of pages total matching events fill
+func (q AuditQuery) Pages(total int) int {
+	if total == 0 {
+		return 1
+	}
+	return (total + q.PerPage - 1) / q.PerPage
+}
+
+// ParseAuditQuery reads a query from URL parameters, ignoring anything invalid
+func ParseAuditQuery(v url.Values) AuditQuery {
+	q := AuditQuery{Search: v.Get("q")}
+	if a, err := ParseAuditAction(v.Get("action")); err == nil {
+		q.Action = a
+	}
+	if t, err := time.Parse("2006-01-02", v.Get("from")); err == nil {
+		q.From = t
+	}
+	if t, err := time.Parse("2006-01-02", v.Get("to")); err == nil {
+		q.To = t
+	}
+	q.Page, _ = strconv.Atoi(v.Get("page"))
+	q.PerPage, _ = strconv.Atoi(v.Get("per_page"))
+
+	return q.Normalize()
+}
+
+// Values is the inverse of ParseAuditQuery, for building links
+func (q AuditQuery) Values() url.Values {
+	v := url.Values{}
+	if q.Search != "" {
+		v.Set("q", q.Search)
+	}
+	if q.Action != "" {
+		v.Set("action", string(q.Action))
+	}
+	if !q.From.IsZero() {
+		v.Set("from", q.From.Format("2006-01-02"))
+	}
+	if !q.To.IsZero() {
+		v.Set("to", q.To.Format("2006-01-02"))
+	}
+	if q.Page > 1 {
+		v.Set("page", strconv.Itoa(q.Page))
+	}
+	if q.PerPage != DefaultPerPage {
+		v.Set("per_page", strconv.Itoa(q.PerPage))
+	}
+	return v
+}
+
+// WithPage returns the query for another page
+func (q AuditQuery) WithPage(page int) AuditQuery {
+	q.Page = page
+	return q
+}
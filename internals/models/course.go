@@ -0,0 +1,178 @@
+package models
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CourseStatus says whether a course is being prepared, offered or retired
+type CourseStatus string
+
+const (
+	CourseDraft     CourseStatus = "draft"
+	CoursePublished CourseStatus = "published"
+	CourseArchived  CourseStatus = "archived"
+)
+
+// CourseStatuses lists every course status in display order
+var CourseStatuses = []CourseStatus{CourseDraft, CoursePublished, CourseArchived}
+
+// ParseCourseStatus converts a stored or submitted value into a CourseStatus
+func ParseCourseStatus(s string) (CourseStatus, error) {
+	if st := CourseStatus(s); slices.Contains(CourseStatuses, st) {
+		return st, nil
+	}
+	return "", fmt.Errorf("unknown course status %q", s)
+}
+
+// Label is the human readable status name
+func (s CourseStatus) Label() string {
+	switch s {
+	case CourseDraft:
+		return "Draft"
+	case CoursePublished:
+		return "Published"
+	case CourseArchived:
+		return "Archived"
+	}
+	return "Unknown"
+}
+
+// Limits on what a course may hold
+const (
+	MaxCreditUnits       = 10
+	MaxCourseCapacity    = 1000
+	MaxCourseTitle       = 200
+	MaxCourseDescription = 5000
+)
+
+// courseCode is two to four letters and three digits, such as CSC 101 or MTH 203A
+var courseCode = regexp.MustCompile(`^[A-Z]{2,4} [0-9]{3}[A-Z]?$`)
+
+// NormalizeCourseCode upper-cases a code and puts one space between the
+// letters and the number, so "csc101" and "CSC  101" are both "CSC 101"
+func NormalizeCourseCode(code string) string {
+	code = strings.ToUpper(strings.Join(strings.Fields(code), ""))
+	if i := strings.IndexFunc(code, func(r rune) bool { return r >= '0' && r <= '9' }); i > 0 {
+		code = code[:i] + " " + code[i:]
+	}
+	return code
+}
+
+// ValidCourseCode reports whether a normalized code has the expected shape
+func ValidCourseCode(code string) bool {
+	return courseCode.MatchString(code)
+}
+
+// Course is a unit of study taught by one or more instructors
+type Course struct {
+	ID          string
+	Code        string
+	Title       string
+	Description string
+	CreditUnits int
+	Department  string
+	Capacity    int
+	Status      CourseStatus
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+
+	// InstructorIDs are the users who teach the course and may edit it
+	InstructorIDs []string
+
+	// Instructors are their names, in the same order, filled in when courses are read
+	Instructors []string
+}
+
+// TaughtBy reports whether the user is one of the course's instructors
+func (c *Course) TaughtBy(userID string) bool {
+	return c != nil && slices.Contains(c.InstructorIDs, userID)
+}
+
+// CourseQuery selects one page of courses, ordered by code
+type CourseQuery struct {
+	// Search matches the code, title or description, case-insensitively
+	Search     string
+	Department string
+	Status     CourseStatus
+
+	// InstructorID limits the result to the courses that user teaches
+	InstructorID string
+
+	Page    int
+	PerPage int
+}
+
+// Normalize fills in defaults and clamps values that came from a request
+func (q CourseQuery) Normalize() CourseQuery {
+	q.Search = strings.TrimSpace(q.Search)
+	q.Department = strings.TrimSpace(q.Department)
+	if q.Page < 1 {
+		q.Page = 1
+	}
+	if q.PerPage < 1 {
+		q.PerPage = DefaultPerPage
+	}
+	if q.PerPage > MaxPerPage {
+		q.PerPage = MaxPerPage
+	}
+	return q
+}
+
+// Offset is the number of courses before the requested page
+func (q CourseQuery) Offset() int {
+	return (q.Page - 1) * q.PerPage
+}
+
+// Pages is the number of pages total matching courses fill
+func (q CourseQuery) Pages(total int) int {
+	if total == 0 {
+		return 1
+	}
+	return (total + q.PerPage - 1) / q.PerPage
+}
+
+// ParseCourseQuery reads a query from URL parameters, ignoring anything invalid
+func ParseCourseQuery(v url.Values) CourseQuery {
+	q := CourseQuery{Search: v.Get("q"), Department: v.Get("department")}
+	if s, err := ParseCourseStatus(v.Get("status")); err == nil {
+		q.Status = s
+	}
+	q.Page, _ = strconv.Atoi(v.Get("page"))
+	q.PerPage, _ = strconv.Atoi(v.Get("per_page"))
+
+	return q.Normalize()
+}
+
+// Values is the inverse of ParseCourseQuery, for building links. The
+// instructor is never part of a link; it comes from who is signed in.
+func (q CourseQuery) Values() url.Values {
+	v := url.Values{}
+	if q.Search != "" {
+		v.Set("q", q.Search)
+	}
+	if q.Department != "" {
+		v.Set("department", q.Department)
+	}
+	if q.Status != "" {
+		v.Set("status", string(q.Status))
+	}
+	if q.Page > 1 {
+		v.Set("page", strconv.Itoa(q.Page))
+	}
+	if q.PerPage != DefaultPerPage {
+		v.Set("per_page", strconv.Itoa(q.PerPage))
+	}
+	return v
+}
+
+// WithPage returns the query for another page
+func (q CourseQuery) WithPage(page int) CourseQuery {
+	q.Page = page
+	return q
+}
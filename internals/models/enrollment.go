@@ -0,0 +1,81 @@
+package models
+
+import (
+	"fmt"
+	"slices"
+	"time"
+)
+
+// EnrollmentStatus says whether a student has a seat in a course, is waiting
+// for one or has left
+type EnrollmentStatus string
+
+const (
+	EnrollmentEnrolled   EnrollmentStatus = "enrolled"
+	EnrollmentWaitlisted EnrollmentStatus = "waitlisted"
+	EnrollmentDropped    EnrollmentStatus = "dropped"
+)
+
+// EnrollmentStatuses lists every enrollment status in display order
+var EnrollmentStatuses = []EnrollmentStatus{EnrollmentEnrolled, EnrollmentWaitlisted, EnrollmentDropped}
+
+// ParseEnrollmentStatus converts a stored value into an EnrollmentStatus
+func ParseEnrollmentStatus(s string) (EnrollmentStatus, error) {
+	if st := EnrollmentStatus(s); slices.Contains(EnrollmentStatuses, st) {
+		return st, nil
+	}
+	return "", fmt.Errorf("unknown enrollment status %q", s)
+}
+
+// Label is the human readable status name
+func (s EnrollmentStatus) Label() string {
+	switch s {
+	case EnrollmentEnrolled:
+		return "Enrolled"
+	case EnrollmentWaitlisted:
+		return "Waitlisted"
+	case EnrollmentDropped:
+		return "Dropped"
+	}
+	return "Unknown"
+}
+
+// Enrollment is a student's place in a course for one term
+type Enrollment struct {
+	ID        string
+	CourseID  string
+	TermID    string
+	StudentID string
+	Status    EnrollmentStatus
+
+	// Position is the student's place on the waitlist, counting from 1, and
+	// 0 for everyone else
+	Position int
+
+	CreatedAt time.Time
+	UpdatedAt time.Time
+
+	// filled in when enrollments are read, for display
+	StudentName  string
+	StudentEmail string
+	CourseCode   string
+	CourseTitle  string
+	CreditUnits  int
+}
+
+// Active reports whether the student holds or is waiting for a seat
+func (e *Enrollment) Active() bool {
+	return e != nil && e.Status != EnrollmentDropped
+}
+
+// Seats is how full a course is in a term
+type Seats struct {
+	Capacity   int
+	Enrolled   int
+	Waitlisted int
+}
+
+// Left is the number of free seats
+func (s Seats) Left() int {
+	return max(s.Capacity-s.Enrolled, 0)
+}
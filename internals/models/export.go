@@ -0,0 +1,92 @@
+package models
+
+import (
+	"fmt"
+	"slices"
+	"time"
+)
+
+// ExportFormat is a file type the user directory can be downloaded as
+type ExportFormat string
+
+const (
+	ExportCSV    ExportFormat = "csv"
+	ExportNDJSON ExportFormat = "ndjson"
+	ExportXLSX   ExportFormat = "xlsx"
+)
+
+// ExportFormats lists every format in display order
+var ExportFormats = []ExportFormat{ExportCSV, ExportNDJSON, ExportXLSX}
+
+// ParseExportFormat converts a submitted value into an ExportFormat
+func ParseExportFormat(s string) (ExportFormat, error) {
+	if f := ExportFormat(s); slices.Contains(ExportFormats, f) {
+		return f, nil
+	}
+	return "", fmt.Errorf("unknown export format %q", s)
+}
+
+// Label is the human readable format name
+func (f ExportFormat) Label() string {
+	switch f {
+	case ExportCSV:
+		return "CSV"
+	case ExportNDJSON:
+		return "JSON (one user per line)"
+	case ExportXLSX:
+		return "Excel"
+	}
+	return string(f)
+}
+
+// ContentType is the MIME type of a file in this format
+func (f ExportFormat) ContentType() string {
+	switch f {
+	case ExportNDJSON:
+		return "application/x-ndjson"
+	case ExportXLSX:
+		return "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+	}
+	return "text/csv; charset=utf-8"
+}
+
+// ExportColumn is a user field that can be included in an export. There is
+// deliberately no column for the password hash.
+type ExportColumn struct {
+	Key   string
+	Label string
+	Value func(u *User) string
+}
+
+// ExportColumns lists every exportable column in file order
+var ExportColumns = []ExportColumn{
+	{"id", "ID", func(u *User) string { return u.ID }},
+	{"name", "Name", func(u *User) string { return u.Name }},
+	{"email", "Email", func(u *User) string { return u.Email }},
+	{"role", "Role", func(u *User) string { return string(u.Role) }},
+	{"status", "Status", func(u *User) string { return string(u.Status) }},
+	{"email_verified", "Email verified", func(u *User) string { return fmt.Sprint(u.EmailVerified()) }},
+	{"two_factor", "Two-factor", func(u *User) string { return fmt.Sprint(u.TwoFactorEnabled()) }},
+	{"dob", "Date of birth", func(u *User) string { return u.DOB.Format("2006-01-02") }},
+	{"bio", "Bio", func(u *User) string { return u.Bio }},
+	{"created_at", "Joined", func(u *User) string { return u.CreatedAt.UTC().Format(time.RFC3339) }},
+	{"updated_at", "Updated", func(u *User) string { return u.UpdatedAt.UTC().Format(time.RFC3339) }},
+}
+
+// DefaultExportColumns are exported when no columns are chosen
+var DefaultExportColumns = []string{"id", "name", "email", "role", "status", "dob", "created_at"}
+
+// ParseExportColumns returns the known columns among keys, in file order,
+// or the defaults if none are known
+func ParseExportColumns(keys []string) []ExportColumn {
+	var cols []ExportColumn
+	for _, c := range ExportColumns {
+		if slices.Contains(keys, c.Key) {
+			cols = append(cols, c)
+		}
+	}
+	if len(cols) == 0 {
+		return ParseExportColumns(DefaultExportColumns)
+	}
+	return cols
+}
@@ -0,0 +1,119 @@
+package models
+
+import (
+	"fmt"
+	"math"
+	"slices"
+	"time"
+)
+
+// AssessmentCategory groups assessments the way the school reports them
+type AssessmentCategory string
+
+const (
+	AssessmentTest       AssessmentCategory = "test"
+	AssessmentAssignment AssessmentCategory = "assignment"
+	AssessmentExam       AssessmentCategory = "exam"
+)
+
+// AssessmentCategories lists every category in display order
+var AssessmentCategories = []AssessmentCategory{AssessmentTest, AssessmentAssignment, AssessmentExam}
+
+// ParseAssessmentCategory converts a stored or submitted value into an
+// AssessmentCategory
+func ParseAssessmentCategory(s string) (AssessmentCategory, error) {
+	if c := AssessmentCategory(s); slices.Contains(AssessmentCategories, c) {
+		return c, nil
+	}
+	return "", fmt.Errorf("unknown assessment category %q", s)
+}
+
+// Label is the human readable category name
+func (c AssessmentCategory) Label() string {
+	switch c {
+	case AssessmentTest:
+		return "CA test"
+	case AssessmentAssignment:
+		return "Assignment"
+	case AssessmentExam:
+		return "Exam"
+	}
+	return "Unknown"
+}
+
+// Limits on assessments
+const (
+	MaxAssessmentName  = 100
+	MaxAssessmentScore = 1000
+)
+
+// Assessment is one graded piece of work in a course for a term. Its weight
+// is the share of the final grade, in percent, that a full score earns.
+type Assessment struct {
+	ID        string
+	CourseID  string
+	TermID    string
+	Name      string
+	Category  AssessmentCategory
+	Weight    float64
+	MaxScore  float64
+	Position  int
+	CreatedAt time.Time
+}
+
+// Scores maps assessment IDs to one student's scores. Assessments without a
+// score haven't been graded yet.
+type Scores map[string]float64
+
+// TotalWeight adds up the weights of the assessments, which should come to 100
+func TotalWeight(assessments []Assessment) float64 {
+	var total float64
+	for _, a := range assessments {
+		total += a.Weight
+	}
+	return total
+}
+
+// WeightedTotal is the student's final mark out of 100, counting missing
+// scores as zero. It is rounded to two places so that a mark landing on a
+// grade boundary, like 10/30 at 20% and 20/30 at 80%, doesn't fall just
+// short of it.
+func WeightedTotal(assessments []Assessment, scores Scores) float64 {
+	var total float64
+	for _, a := range assessments {
+		if s, ok := scores[a.ID]; ok && a.MaxScore > 0 {
+			total += s / a.MaxScore * a.Weight
+		}
+	}
+	return math.Round(total*100) / 100
+}
+
+// ScoreChange is a row of a score's edit history. A nil Before is a score
+// entered for the first time and a nil After one that was cleared.
+type ScoreChange struct {
+	ID             int64
+	AssessmentID   string
+	AssessmentName string
+	StudentID      string
+	Before         *float64
+	After          *float64
+	EditorEmail    string
+	CreatedAt      time.Time
+}
+
+// CourseGrade is a student's published result in one course for a term
+type CourseGrade struct {
+	CourseID    string
+	CourseCode  string
+	CourseTitle string
+	CreditUnits int
+	TermID      string
+	TermName    string
+	Assessments []Assessment
+	Scores      Scores
+}
+
+// Total is the student's final mark out of 100
+func (g CourseGrade) Total() float64 {
+	return WeightedTotal(g.Assessments, g.Scores)
+}
@@ -0,0 +1,37 @@
+package models
+
+// ImportColumns are the CSV headers a user import understands. password is
+// optional; the rest are required.
+var ImportColumns = []string{"name", "email", "role", "dob", "password"}
+
+// ImportRow is one line of a user import and whatever is wrong with it
+type ImportRow struct {
+	// Line is the line number in the uploaded file, counting the header
+	Line int
+	User User
+
+	// Invite is set when no password was given, so the user is emailed a
+	// link to choose one instead
+	Invite bool
+	Errors []string
+}
+
+// ImportSummary counts the rows of an import
+type ImportSummary struct {
+	Rows    int
+	Invalid int
+	Invites int
+}
+
+// SummarizeImport counts rows, rows with errors and rows that will be invited
+func SummarizeImport(rows []ImportRow) ImportSummary {
+	s := ImportSummary{Rows: len(rows)}
+	for _, row := range rows {
+		if len(row.Errors) > 0 {
+			s.Invalid++
+		} else if row.Invite {
+			s.Invites++
+		}
+	}
+	return s
+}
@@ -0,0 +1,46 @@
+package models
+
+import "time"
+
+// LoginFailures is the failed login record of one account
+type LoginFailures struct {
+	UserID        string
+	Failures      int
+	Lockouts      int
+	LastFailureAt time.Time
+	LockedUntil   *time.Time
+
+	// Email and Name are only filled in by GetLockedAccounts
+	Email string
+	Name  string
+}
+
+// Locked reports whether the account is locked at now
+func (f LoginFailures) Locked(now time.Time) bool {
+	return f.LockedUntil != nil && now.Before(*f.LockedUntil)
+}
+
+// RetryAt is when the next attempt is allowed after the first free
+// failures, doubling from one second with each further failure up to a minute
+func (f LoginFailures) RetryAt(free int) time.Time {
+	if f.Failures < free {
+		return time.Time{}
+	}
+	delay := time.Minute
+	if n := f.Failures - free; n < 6 {
+		delay = time.Second << n
+	}
+	return f.LastFailureAt.Add(delay)
+}
+
+// LockoutFor is how long the next lockout lasts: base, doubled for every
+// earlier lockout, up to a day
+func (f LoginFailures) LockoutFor(base time.Duration) time.Duration {
+	d := base
+	for range f.Lockouts {
+		if d *= 2; d >= 24*time.Hour {
+			return 24 * time.Hour
+		}
+	}
+	return d
+}
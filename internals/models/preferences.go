@@ -0,0 +1,91 @@
+package models
+
+import (
+	"fmt"
+	"time"
+)
+
+// DateFormat is how a user likes dates written
+type DateFormat string
+
+const (
+	DateLong DateFormat = "long"
+	DateISO  DateFormat = "iso"
+	DateDMY  DateFormat = "dmy"
+	DateMDY  DateFormat = "mdy"
+)
+
+// DateFormats lists every date format in display order
+var DateFormats = []DateFormat{DateLong, DateISO, DateDMY, DateMDY}
+
+// ParseDateFormat converts a stored or submitted value into a DateFormat
+func ParseDateFormat(s string) (DateFormat, error) {
+	for _, f := range DateFormats {
+		if string(f) == s {
+			return f, nil
+		}
+	}
+	return "", fmt.Errorf("unknown date format %q", s)
+}
+
+// Layout is the time.Format layout for the format
+func (f DateFormat) Layout() string {
+	switch f {
+	case DateISO:
+		return "2006-01-02"
+	case DateDMY:
+		return "02/01/2006"
+	case DateMDY:
+		return "01/02/2006"
+	}
+	return "January 2, 2006"
+}
+
+// Label shows the format by example
+func (f DateFormat) Label() string {
+	return time.Date(2006, 1, 31, 0, 0, 0, 0, time.UTC).Format(f.Layout())
+}
+
+// Preferences are the per-user display and notification settings
+type Preferences struct {
+	UserID           string
+	Timezone         string
+	DateFormat       DateFormat
+	NotifyGrades     bool
+	NotifyEnrollment bool
+	NotifyNews       bool
+}
+
+// DefaultPreferences apply to users who haven't saved any
+func DefaultPreferences() Preferences {
+	return Preferences{
+		Timezone:         "UTC",
+		DateFormat:       DateLong,
+		NotifyGrades:     true,
+		NotifyEnrollment: true,
+	}
+}
+
+// Location is the user's timezone, falling back to UTC if it is unknown
+func (p Preferences) Location() *time.Location {
+	if loc, err := time.LoadLocation(p.Timezone); err == nil {
+		return loc
+	}
+	return time.UTC
+}
+
+// FormatDate writes a calendar date, such as a date of birth, which has no
+// timezone of its own
+func (p Preferences) FormatDate(t time.Time) string {
+	return t.Format(p.DateFormat.Layout())
+}
+
+// FormatDay writes the calendar date of an instant in the user's timezone
+func (p Preferences) FormatDay(t time.Time) string {
+	return t.In(p.Location()).Format(p.DateFormat.Layout())
+}
+
+// FormatTime writes an instant in the user's timezone
+func (p Preferences) FormatTime(t time.Time) string {
+	return t.In(p.Location()).Format(p.DateFormat.Layout() + " 15:04")
+}
@@ -0,0 +1,90 @@
+package models
+
+import "fmt"
+
+// Role decides what a user may do in the system
+type Role string
+
+const (
+	RoleAdmin      Role = "admin"
+	RoleInstructor Role = "instructor"
+	RoleStudent    Role = "student"
+)
+
+// Roles lists every role in display order
+var Roles = []Role{RoleAdmin, RoleInstructor, RoleStudent}
+
+// SelfServiceRoles are the roles a visitor may pick when registering
+var SelfServiceRoles = []Role{RoleStudent, RoleInstructor}
+
+// ParseRole converts a stored or submitted value into a Role
+func ParseRole(s string) (Role, error) {
+	for _, r := range Roles {
+		if string(r) == s {
+			return r, nil
+		}
+	}
+	return "", fmt.Errorf("unknown role %q", s)
+}
+
+// Label is the human readable role name
+func (r Role) Label() string {
+	switch r {
+	case RoleAdmin:
+		return "Admin"
+	case RoleInstructor:
+		return "Instructor"
+	case RoleStudent:
+		return "Student"
+	}
+	return "Unknown"
+}
+
+// Permission names a single action that can be granted to roles
+type Permission string
+
+const (
+	PermEditOwnProfile  Permission = "profile:edit"
+	PermManageUsers     Permission = "users:manage"
+	PermViewDiagnostics Permission = "diagnostics:view"
+	PermBrowseCourses   Permission = "courses:browse"
+	PermTeachCourses    Permission = "courses:teach"
+	PermManageCourses   Permission = "courses:manage"
+	PermEnrollCourses   Permission = "courses:enroll"
+	PermManageTerms     Permission = "terms:manage"
+	PermManageGrading   Permission = "grading:manage"
+)
+
+// rolePermissions is the permission matrix. A role can do exactly what is listed here.
+var rolePermissions = map[Role][]Permission{
+	RoleAdmin: {
+		PermEditOwnProfile,
+		PermManageUsers,
+		PermViewDiagnostics,
+		PermBrowseCourses,
+		PermTeachCourses,
+		PermManageCourses,
+		PermManageTerms,
+		PermManageGrading,
+	},
+	RoleInstructor: {
+		PermEditOwnProfile,
+		PermBrowseCourses,
+		PermTeachCourses,
+	},
+	RoleStudent: {
+		PermEditOwnProfile,
+		PermBrowseCourses,
+		PermEnrollCourses,
+	},
+}
+
+// Can reports whether the role has been granted the permission
+func (r Role) Can(p Permission) bool {
+	for _, granted := range rolePermissions[r] {
+		if granted == p {
+			return true
+		}
+	}
+	return false
+}
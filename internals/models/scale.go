@@ -0,0 +1,283 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Limits on grading scales and programmes
+const (
+	MaxScaleName     = 100
+	MaxProgrammeName = 150
+	MaxGradeLetter   = 5
+	MaxDegreeClass   = 50
+)
+
+// GradeBand is the letter and grade points earned by totals from MinScore up
+// to the next band
+type GradeBand struct {
+	MinScore float64 `json:"min_score"`
+	Letter   string  `json:"letter"`
+	Points   float64 `json:"points"`
+}
+
+// DegreeClass is the class of degree awarded from MinGPA up to the next class
+type DegreeClass struct {
+	Name   string  `json:"name"`
+	MinGPA float64 `json:"min_gpa"`
+}
+
+// GradingScale turns totals out of 100 into letters and grade points, and
+// grade point averages into degree classes. Bands and classes are kept best
+// first. The default scale applies to students outside any programme.
+type GradingScale struct {
+	ID        string
+	Name      string
+	Bands     []GradeBand
+	Classes   []DegreeClass
+	Default   bool
+	CreatedAt time.Time
+}
+
+// Grade is the band a total falls in
+func (s GradingScale) Grade(total float64) GradeBand {
+	for _, b := range s.Bands {
+		if total >= b.MinScore {
+			return b
+		}
+	}
+	if len(s.Bands) > 0 {
+		return s.Bands[len(s.Bands)-1]
+	}
+	return GradeBand{Letter: "F"}
+}
+
+// MaxPoints is the grade points of the best band, the top of the GPA range
+func (s GradingScale) MaxPoints() float64 {
+	if len(s.Bands) == 0 {
+		return 0
+	}
+	return s.Bands[0].Points
+}
+
+// Class is the degree class a grade point average earns, or "" below the
+// lowest class
+func (s GradingScale) Class(gpa float64) string {
+	for _, c := range s.Classes {
+		if gpa >= c.MinGPA {
+			return c.Name
+		}
+	}
+	return ""
+}
+
+// StandardScales are the scales every installation starts with: the NUC
+// 5-point scale, which is the default, the US 4.0 scale and plain percentage
+// bands. Migration 0016 seeds the same scales.
+func StandardScales() []GradingScale {
+	return []GradingScale{
+		{
+			Name: "NUC 5-point",
+			Bands: []GradeBand{
+				{70, "A", 5}, {60, "B", 4}, {50, "C", 3}, {45, "D", 2}, {40, "E", 1}, {0, "F", 0},
+			},
+			Classes: []DegreeClass{
+				{"First Class", 4.5}, {"Second Class Upper", 3.5}, {"Second Class Lower", 2.4}, {"Third Class", 1.5}, {"Pass", 1},
+			},
+			Default: true,
+		},
+		{
+			Name: "US 4.0",
+			Bands: []GradeBand{
+				{93, "A", 4}, {90, "A-", 3.7}, {87, "B+", 3.3}, {83, "B", 3}, {80, "B-", 2.7}, {77, "C+", 2.3},
+				{73, "C", 2}, {70, "C-", 1.7}, {67, "D+", 1.3}, {60, "D", 1}, {0, "F", 0},
+			},
+			Classes: []DegreeClass{
+				{"Summa Cum Laude", 3.9}, {"Magna Cum Laude", 3.7}, {"Cum Laude", 3.5}, {"Good Standing", 2},
+			},
+		},
+		{
+			Name: "Percentage",
+			Bands: []GradeBand{
+				{70, "A", 4}, {60, "B", 3}, {50, "C", 2}, {40, "D", 1}, {0, "F", 0},
+			},
+			Classes: []DegreeClass{
+				{"Distinction", 3.5}, {"Merit", 2.5}, {"Pass", 1},
+			},
+		},
+	}
+}
+
+// ParseBands reads grade bands written one per line as the lowest total,
+// the letter and the grade points, e.g. "70 A 5". The bands are returned
+// best first; one of them must start at 0 so every total gets a grade.
+func ParseBands(text string) ([]GradeBand, error) {
+	var bands []GradeBand
+	for i, line := range nonEmptyLines(text) {
+		fields := strings.Fields(line)
+		if len(fields) != 3 {
+			return nil, fmt.Errorf("band on line %d needs a lowest score, a letter and grade points", i+1)
+		}
+		lowest, err := strconv.ParseFloat(fields[0], 64)
+		if err != nil || math.IsNaN(lowest) || lowest < 0 || lowest > 100 {
+			return nil, fmt.Errorf("lowest score on line %d must be between 0 and 100", i+1)
+		}
+		if utf8.RuneCountInString(fields[1]) > MaxGradeLetter {
+			return nil, fmt.Errorf("letter on line %d must be at most %d characters", i+1, MaxGradeLetter)
+		}
+		points, err := strconv.ParseFloat(fields[2], 64)
+		if err != nil || math.IsNaN(points) || points < 0 || points > 100 {
+			return nil, fmt.Errorf("grade points on line %d must be between 0 and 100", i+1)
+		}
+		bands = append(bands, GradeBand{MinScore: lowest, Letter: fields[1], Points: points})
+	}
+
+	if len(bands) == 0 {
+		return nil, errors.New("add at least one band")
+	}
+	sort.SliceStable(bands, func(i, j int) bool { return bands[i].MinScore > bands[j].MinScore })
+	for i := 1; i < len(bands); i++ {
+		if bands[i].MinScore == bands[i-1].MinScore {
+			return nil, fmt.Errorf("two bands start at %s", strconv.FormatFloat(bands[i].MinScore, 'f', -1, 64))
+		}
+		if bands[i].Points > bands[i-1].Points {
+			return nil, fmt.Errorf("band %s is worth more points than the band above it", bands[i].Letter)
+		}
+	}
+	if bands[len(bands)-1].MinScore != 0 {
+		return nil, errors.New("the lowest band must start at 0")
+	}
+	return bands, nil
+}
+
+// FormatBands writes bands the way ParseBands reads them
+func FormatBands(bands []GradeBand) string {
+	var b strings.Builder
+	for _, band := range bands {
+		fmt.Fprintf(&b, "%s %s %s\n", strconv.FormatFloat(band.MinScore, 'f', -1, 64), band.Letter, strconv.FormatFloat(band.Points, 'f', -1, 64))
+	}
+	return b.String()
+}
+
+// ParseClasses reads degree classes written one per line as the lowest
+// grade point average and the name, e.g. "4.5 First Class". No classes at
+// all is fine. The classes are returned best first.
+func ParseClasses(text string, maxPoints float64) ([]DegreeClass, error) {
+	var classes []DegreeClass
+	for i, line := range nonEmptyLines(text) {
+		gpa, name, ok := strings.Cut(line, " ")
+		name = strings.TrimSpace(name)
+		if !ok || name == "" {
+			return nil, fmt.Errorf("class on line %d needs a lowest GPA and a name", i+1)
+		}
+		lowest, err := strconv.ParseFloat(gpa, 64)
+		if err != nil || math.IsNaN(lowest) || lowest < 0 || lowest > maxPoints {
+			return nil, fmt.Errorf("lowest GPA on line %d must be between 0 and %s", i+1, strconv.FormatFloat(maxPoints, 'f', -1, 64))
+		}
+		if utf8.RuneCountInString(name) > MaxDegreeClass {
+			return nil, fmt.Errorf("class name on line %d must be at most %d characters", i+1, MaxDegreeClass)
+		}
+		classes = append(classes, DegreeClass{Name: name, MinGPA: lowest})
+	}
+
+	sort.SliceStable(classes, func(i, j int) bool { return classes[i].MinGPA > classes[j].MinGPA })
+	for i := 1; i < len(classes); i++ {
+		if classes[i].MinGPA == classes[i-1].MinGPA {
+			return nil, fmt.Errorf("two classes start at %s", strconv.FormatFloat(classes[i].MinGPA, 'f', -1, 64))
+		}
+	}
+	return classes, nil
+}
+
+// FormatClasses writes classes the way ParseClasses reads them
+func FormatClasses(classes []DegreeClass) string {
+	var b strings.Builder
+	for _, c := range classes {
+		fmt.Fprintf(&b, "%s %s\n", strconv.FormatFloat(c.MinGPA, 'f', -1, 64), c.Name)
+	}
+	return b.String()
+}
+
+// nonEmptyLines splits text into trimmed lines, skipping blank ones
+func nonEmptyLines(text string) []string {
+	var lines []string
+	for _, line := range strings.Split(text, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
+
+// Programme is a course of study. Its students are graded on its scale.
+type Programme struct {
+	ID        string
+	Name      string
+	ScaleID   string
+	ScaleName string
+	Students  int
+	CreatedAt time.Time
+}
+
+// TermResult is a student's published grades in one term with the GPA they
+// make
+type TermResult struct {
+	TermID   string
+	TermName string
+	Grades   []CourseGrade
+	Units    int
+	GPA      float64
+}
+
+// Transcript is a student's published results term by term, newest first,
+// graded on their programme's scale
+type Transcript struct {
+	Scale GradingScale
+	Terms []TermResult
+	Units int
+	CGPA  float64
+	Class string
+}
+
+// NewTranscript groups published grades, newest term first, into terms and
+// works out each term's GPA and the cumulative CGPA. Both are averages of
+// the courses' grade points weighted by credit units, rounded to two places.
+func NewTranscript(scale GradingScale, grades []CourseGrade) Transcript {
+	t := Transcript{Scale: scale}
+	var points float64
+	for _, g := range grades {
+		if len(t.Terms) == 0 || t.Terms[len(t.Terms)-1].TermID != g.TermID {
+			t.Terms = append(t.Terms, TermResult{TermID: g.TermID, TermName: g.TermName})
+		}
+		term := &t.Terms[len(t.Terms)-1]
+		term.Grades = append(term.Grades, g)
+		term.Units += g.CreditUnits
+		term.GPA += scale.Grade(g.Total()).Points * float64(g.CreditUnits)
+
+		t.Units += g.CreditUnits
+		points += scale.Grade(g.Total()).Points * float64(g.CreditUnits)
+	}
+
+	for i := range t.Terms {
+		t.Terms[i].GPA = gradePointAverage(t.Terms[i].GPA, t.Terms[i].Units)
+	}
+	t.CGPA = gradePointAverage(points, t.Units)
+	if t.Units > 0 {
+		t.Class = scale.Class(t.CGPA)
+	}
+	return t
+}
+
+// gradePointAverage divides the weighted points by the units, to two places
+func gradePointAverage(points float64, units int) float64 {
+	if units == 0 {
+		return 0
+	}
+	return math.Round(points/float64(units)*100) / 100
+}
@@ -0,0 +1,18 @@
+package models
+
+import "time"
+
+// UserSession is one signed-in device as listed on the sessions settings page
+type UserSession struct {
+	// ID is a hash of the cookie token, safe to show and to revoke by
+	ID         string
+	UserID     string
+	UserAgent  string
+	IP         string
+	CreatedAt  time.Time
+	LastSeenAt time.Time
+	ExpiresAt  time.Time
+
+	// Current marks the session the request was made with
+	Current bool
+}
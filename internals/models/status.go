@@ -0,0 +1,43 @@
+package models
+
+import "fmt"
+
+// Status says whether an account may be used
+type Status string
+
+const (
+	StatusActive      Status = "active"
+	StatusSuspended   Status = "suspended"
+	StatusDeactivated Status = "deactivated"
+)
+
+// Statuses lists every status in display order
+var Statuses = []Status{StatusActive, StatusSuspended, StatusDeactivated}
+
+// ParseStatus converts a stored or submitted value into a Status
+func ParseStatus(s string) (Status, error) {
+	for _, st := range Statuses {
+		if string(st) == s {
+			return st, nil
+		}
+	}
+	return "", fmt.Errorf("unknown status %q", s)
+}
+
+// Label is the human readable status name
+func (s Status) Label() string {
+	switch s {
+	case StatusActive:
+		return "Active"
+	case StatusSuspended:
+		return "Suspended"
+	case StatusDeactivated:
+		return "Deactivated"
+	}
+	return "Unknown"
+}
+
+// Active reports whether the account may sign in
+func (s Status) Active() bool {
+	return s == StatusActive
+}
@@ -0,0 +1,67 @@
+package models
+
+import "time"
+
+// AcademicSession is an academic year, such as 2025/2026, that terms belong
+// to
+type AcademicSession struct {
+	ID        string
+	Name      string
+	StartDate time.Time
+	EndDate   time.Time
+	Terms     int
+	CreatedAt time.Time
+}
+
+// Contains reports whether the whole of start to end falls in the session
+func (s *AcademicSession) Contains(start, end time.Time) bool {
+	return !start.Before(s.StartDate) && !end.After(s.EndDate)
+}
+
+// Term is a teaching period students enroll in, such as a semester. One term
+// at a time is the current one, which enrollment happens in.
+type Term struct {
+	ID          string
+	Name        string
+	SessionID   string
+	SessionName string
+
+	// StartDate and EndDate are the first and last days of teaching
+	StartDate time.Time
+	EndDate   time.Time
+
+	// RegistrationOpens is the first day courses may be added. AddDeadline
+	// and DropDeadline are the last days, inclusive, on which courses may be
+	// added or dropped.
+	RegistrationOpens time.Time
+	AddDeadline       time.Time
+	DropDeadline      time.Time
+
+	// ExamStart and ExamEnd are the first and last days of exams
+	ExamStart time.Time
+	ExamEnd   time.Time
+
+	Current   bool
+	CreatedAt time.Time
+}
+
+// RegistrationStarted reports whether registration has opened at now
+func (t *Term) RegistrationStarted(now time.Time) bool {
+	return t != nil && !now.Before(t.RegistrationOpens)
+}
+
+// AddOpen reports whether courses may still be added at now
+func (t *Term) AddOpen(now time.Time) bool {
+	return t != nil && now.Before(t.AddDeadline.AddDate(0, 0, 1))
+}
+
+// DropOpen reports whether courses may still be dropped at now
+func (t *Term) DropOpen(now time.Time) bool {
+	return t != nil && now.Before(t.DropDeadline.AddDate(0, 0, 1))
+}
+
+// Limits on term and session names
+const (
+	MaxTermName    = 100
+	MaxSessionName = 50
+)
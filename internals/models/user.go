@@ -2,16 +2,74 @@ package models
 
 import "time"
 
+// User is an account holder of any role
 type User struct {
-	ID           string
-	Email        string
-	Password     string
-	Name         string
-	Category     int
-	DOB          time.Time
-	DOBFormatted string
-	Bio          string
-	Avatar       string
-	CreatedAt    time.Time
-	UpdatedAt    time.Time
+	ID              string
+	Email           string
+	EmailVerifiedAt *time.Time
+	PendingEmail    string
+	Password        string
+	Name            string
+	Role            Role
+	Status          Status
+	DOB             time.Time
+	DOBFormatted    string
+	Bio             string
+	Avatar          string
+	AvatarKey       string
+	TOTPEnabledAt   *time.Time
+	CreatedAt       time.Time
+	UpdatedAt       time.Time
+
+	// DeletedAt is set once the account is deleted; it is purged for good
+	// after the retention period
+	DeletedAt *time.Time
+
+	// AnonymizedAt is set when the purge kept the account for its academic
+	// records and stripped it of personal details instead
+	AnonymizedAt *time.Time
+
+	// Preferences and TwoFactorRequired are only filled in by GetUserByID
+	Preferences       Preferences
+	TwoFactorRequired bool
+}
+
+// EmailVerified reports whether the user has confirmed their email address
+func (u *User) EmailVerified() bool {
+	return u != nil && u.EmailVerifiedAt != nil
+}
+
+// TwoFactorEnabled reports whether the user signs in with a TOTP code as well
+// as their password
+func (u *User) TwoFactorEnabled() bool {
+	return u != nil && u.TOTPEnabledAt != nil
+}
+
+// Deleted reports whether the account has been deleted and is waiting to be purged
+func (u *User) Deleted() bool {
+	return u != nil && u.DeletedAt != nil
+}
+
+// An account the purge anonymises is renamed AnonymizedName, and its email
+// becomes its ID at AnonymizedDomain, which keeps it unique
+const (
+	AnonymizedName   = "Deleted user"
+	AnonymizedDomain = "deleted.invalid"
+)
+
+// Anonymized reports whether the purge has stripped the account of personal
+// details; it can't be restored
+func (u *User) Anonymized() bool {
+	return u != nil && u.AnonymizedAt != nil
+}
+
+// Active reports whether the account may sign in: its status is active and
+// it hasn't been deleted
+func (u *User) Active() bool {
+	return u != nil && u.Status.Active() && u.DeletedAt == nil
+}
+
+// Can reports whether the user's role has been granted the permission
+func (u *User) Can(p Permission) bool {
+	return u != nil && u.Role.Can(p)
 }
@@ -0,0 +1,158 @@
+package models
+
+import (
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// DefaultPerPage is the page size when none is asked for
+	DefaultPerPage = 25
+
+	// MaxPerPage caps the page size so a request can't load the whole table
+	MaxPerPage = 100
+)
+
+// UserSortColumns are the columns the user directory can be sorted by
+var UserSortColumns = []string{"name", "email", "role", "status", "created_at"}
+
+// UserQuery selects one page of users for the admin directory
+type UserQuery struct {
+	// Search matches name or email, case-insensitively
+	Search string
+	Role   Role
+	Status Status
+
+	// CreatedFrom and CreatedTo bound the creation date, both days
+	// inclusive; zero leaves that end open
+	CreatedFrom time.Time
+	CreatedTo   time.Time
+
+	// IDs limits the result to these users, for acting on a selection
+	IDs []string
+
+	// Deleted lists only deleted accounts; otherwise they are left out
+	Deleted bool
+
+	Sort    string
+	Desc    bool
+	Page    int
+	PerPage int
+}
+
+// Normalize fills in defaults and clamps values that came from a request
+func (q UserQuery) Normalize() UserQuery {
+	q.Search = strings.TrimSpace(q.Search)
+	if !slices.Contains(UserSortColumns, q.Sort) {
+		q.Sort = "created_at"
+		q.Desc = true
+	}
+	if q.Page < 1 {
+		q.Page = 1
+	}
+	if q.PerPage < 1 {
+		q.PerPage = DefaultPerPage
+	}
+	if q.PerPage > MaxPerPage {
+		q.PerPage = MaxPerPage
+	}
+	return q
+}
+
+// Offset is the number of users before the requested page
+func (q UserQuery) Offset() int {
+	return (q.Page - 1) * q.PerPage
+}
+
+// Pages is the number of pages total matching users fill
+func (q UserQuery) Pages(total int) int {
+	if total == 0 {
+		return 1
+	}
+	return (total + q.PerPage - 1) / q.PerPage
+}
+
+// ParseUserQuery reads a query from URL parameters, ignoring anything invalid
+func ParseUserQuery(v url.Values) UserQuery {
+	q := UserQuery{
+		Search:  v.Get("q"),
+		Sort:    v.Get("sort"),
+		Desc:    v.Get("dir") == "desc",
+		IDs:     v["ids"],
+		Deleted: v.Get("deleted") == "1",
+	}
+	if r, err := ParseRole(v.Get("role")); err == nil {
+		q.Role = r
+	}
+	if s, err := ParseStatus(v.Get("status")); err == nil {
+		q.Status = s
+	}
+	if t, err := time.Parse("2006-01-02", v.Get("from")); err == nil {
+		q.CreatedFrom = t
+	}
+	if t, err := time.Parse("2006-01-02", v.Get("to")); err == nil {
+		q.CreatedTo = t
+	}
+	q.Page, _ = strconv.Atoi(v.Get("page"))
+	q.PerPage, _ = strconv.Atoi(v.Get("per_page"))
+
+	return q.Normalize()
+}
+
+// Values is the inverse of ParseUserQuery, for building links
+func (q UserQuery) Values() url.Values {
+	v := url.Values{}
+	if q.Search != "" {
+		v.Set("q", q.Search)
+	}
+	if q.Role != "" {
+		v.Set("role", string(q.Role))
+	}
+	if q.Status != "" {
+		v.Set("status", string(q.Status))
+	}
+	if q.Deleted {
+		v.Set("deleted", "1")
+	}
+	if !q.CreatedFrom.IsZero() {
+		v.Set("from", q.CreatedFrom.Format("2006-01-02"))
+	}
+	if !q.CreatedTo.IsZero() {
+		v.Set("to", q.CreatedTo.Format("2006-01-02"))
+	}
+	v.Set("sort", q.Sort)
+	if q.Desc {
+		v.Set("dir", "desc")
+	} else {
+		v.Set("dir", "asc")
+	}
+	if q.Page > 1 {
+		v.Set("page", strconv.Itoa(q.Page))
+	}
+	if q.PerPage != DefaultPerPage {
+		v.Set("per_page", strconv.Itoa(q.PerPage))
+	}
+	return v
+}
+
+// WithPage returns the query for another page
+func (q UserQuery) WithPage(page int) UserQuery {
+	q.Page = page
+	return q
+}
+
+// WithSort returns the query sorted by column, flipping the direction when
+// it is already sorted by it, and back on the first page
+func (q UserQuery) WithSort(column string) UserQuery {
+	if q.Sort == column {
+		q.Desc = !q.Desc
+	} else {
+		q.Sort = column
+		q.Desc = false
+	}
+	q.Page = 1
+	return q
+}
@@ -0,0 +1,461 @@
+// Package qr encodes text as a QR code (ISO/IEC 18004) and draws it as SVG.
+// It covers what the app needs for authenticator enrollment: byte mode,
+// error correction level M and versions 1 to 20, which hold up to 666 bytes.
+package qr
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrTooLong is returned when the text doesn't fit in the largest version
+var ErrTooLong = errors.New("qr: text too long")
+
+// Code is an encoded QR symbol. Modules[y][x] is true for a dark module.
+type Code struct {
+	Version int
+	Size    int
+	Modules [][]bool
+
+	// function marks finder, timing, alignment, format and version modules,
+	// which masking leaves alone
+	function [][]bool
+}
+
+// blockSpec is the level M block structure of one version
+type blockSpec struct {
+	ecPerBlock int
+	groups     [][2]int // {blocks, data codewords per block}
+}
+
+// levelM is table 9 of the standard for error correction level M
+var levelM = [...]blockSpec{
+	1:  {10, [][2]int{{1, 16}}},
+	2:  {16, [][2]int{{1, 28}}},
+	3:  {26, [][2]int{{1, 44}}},
+	4:  {18, [][2]int{{2, 32}}},
+	5:  {24, [][2]int{{2, 43}}},
+	6:  {16, [][2]int{{4, 27}}},
+	7:  {18, [][2]int{{4, 31}}},
+	8:  {22, [][2]int{{2, 38}, {2, 39}}},
+	9:  {22, [][2]int{{3, 36}, {2, 37}}},
+	10: {26, [][2]int{{4, 43}, {1, 44}}},
+	11: {30, [][2]int{{1, 50}, {4, 51}}},
+	12: {22, [][2]int{{6, 36}, {2, 37}}},
+	13: {22, [][2]int{{8, 37}, {1, 38}}},
+	14: {24, [][2]int{{4, 40}, {5, 41}}},
+	15: {24, [][2]int{{5, 41}, {5, 42}}},
+	16: {28, [][2]int{{7, 45}, {3, 46}}},
+	17: {28, [][2]int{{10, 46}, {1, 47}}},
+	18: {26, [][2]int{{9, 43}, {4, 44}}},
+	19: {26, [][2]int{{3, 44}, {11, 45}}},
+	20: {26, [][2]int{{3, 41}, {13, 42}}},
+}
+
+// alignment lists the alignment pattern centres of each version
+var alignment = [...][]int{
+	2: {6, 18}, 3: {6, 22}, 4: {6, 26}, 5: {6, 30}, 6: {6, 34},
+	7: {6, 22, 38}, 8: {6, 24, 42}, 9: {6, 26, 46}, 10: {6, 28, 50},
+	11: {6, 30, 54}, 12: {6, 32, 58}, 13: {6, 34, 62},
+	14: {6, 26, 46, 66}, 15: {6, 26, 48, 70}, 16: {6, 26, 50, 74},
+	17: {6, 30, 54, 78}, 18: {6, 30, 56, 82}, 19: {6, 30, 58, 86}, 20: {6, 34, 62, 90},
+}
+
+// formatBitsM is the two bit indicator of level M in the format information
+const formatBitsM = 0
+
+// dataCapacity is how many data codewords a version holds
+func (b blockSpec) dataCapacity() int {
+	n := 0
+	for _, g := range b.groups {
+		n += g[0] * g[1]
+	}
+	return n
+}
+
+// Encode picks the smallest version that holds text and builds the symbol
+func Encode(text string) (*Code, error) {
+	data := []byte(text)
+
+	version := 0
+	for v := 1; v < len(levelM); v++ {
+		countBits := 8
+		if v >= 10 {
+			countBits = 16
+		}
+		if 4+countBits+8*len(data) <= 8*levelM[v].dataCapacity() {
+			version = v
+			break
+		}
+	}
+	if version == 0 {
+		return nil, ErrTooLong
+	}
+
+	c := newCode(version)
+	c.drawFunctionPatterns()
+	c.drawCodewords(addErrorCorrection(version, dataCodewords(version, data)))
+	c.applyBestMask()
+	return c, nil
+}
+
+func newCode(version int) *Code {
+	size := 17 + 4*version
+	c := &Code{Version: version, Size: size}
+	c.Modules = make([][]bool, size)
+	c.function = make([][]bool, size)
+	for i := range size {
+		c.Modules[i] = make([]bool, size)
+		c.function[i] = make([]bool, size)
+	}
+	return c
+}
+
+// dataCodewords packs text in byte mode and pads it to the version's capacity
+func dataCodewords(version int, data []byte) []byte {
+	var bits bitBuffer
+	bits.append(0b0100, 4)
+	if version >= 10 {
+		bits.append(len(data), 16)
+	} else {
+		bits.append(len(data), 8)
+	}
+	for _, b := range data {
+		bits.append(int(b), 8)
+	}
+
+	capacity := 8 * levelM[version].dataCapacity()
+	bits.append(0, min(4, capacity-len(bits)))
+	bits.append(0, (8-len(bits)%8)%8)
+	for pad := 0xEC; len(bits) < capacity; pad ^= 0xEC ^ 0x11 {
+		bits.append(pad, 8)
+	}
+
+	out := make([]byte, len(bits)/8)
+	for i, bit := range bits {
+		if bit {
+			out[i/8] |= 0x80 >> (i % 8)
+		}
+	}
+	return out
+}
+
+// addErrorCorrection splits data into blocks, adds Reed-Solomon codewords to
+// each and interleaves the result
+func addErrorCorrection(version int, data []byte) []byte {
+	spec := levelM[version]
+	divisor := rsDivisor(spec.ecPerBlock)
+
+	var blocks, ecs [][]byte
+	for _, g := range spec.groups {
+		for range g[0] {
+			block := data[:g[1]]
+			data = data[g[1]:]
+			blocks = append(blocks, block)
+			ecs = append(ecs, rsRemainder(block, divisor))
+		}
+	}
+
+	var out []byte
+	longest := spec.groups[len(spec.groups)-1][1]
+	for i := range longest {
+		for _, b := range blocks {
+			if i < len(b) {
+				out = append(out, b[i])
+			}
+		}
+	}
+	for i := range spec.ecPerBlock {
+		for _, e := range ecs {
+			out = append(out, e[i])
+		}
+	}
+	return out
+}
+
+// setFunction places a function module
+func (c *Code) setFunction(x, y int, dark bool) {
+	c.Modules[y][x] = dark
+	c.function[y][x] = true
+}
+
+func (c *Code) drawFunctionPatterns() {
+	for i := range c.Size {
+		c.setFunction(6, i, i%2 == 0)
+		c.setFunction(i, 6, i%2 == 0)
+	}
+
+	c.drawFinder(3, 3)
+	c.drawFinder(c.Size-4, 3)
+	c.drawFinder(3, c.Size-4)
+
+	if c.Version >= 2 {
+		pos := alignment[c.Version]
+		last := len(pos) - 1
+		for i := range pos {
+			for j := range pos {
+				// these corners are taken by the finder patterns
+				if (i == 0 && j == 0) || (i == 0 && j == last) || (i == last && j == 0) {
+					continue
+				}
+				c.drawAlignment(pos[i], pos[j])
+			}
+		}
+	}
+
+	// reserve the format area; the real bits go in once the mask is chosen
+	c.drawFormat(0)
+	c.drawVersion()
+}
+
+// drawFinder draws a finder pattern and its light separator around (cx, cy)
+func (c *Code) drawFinder(cx, cy int) {
+	for dy := -4; dy <= 4; dy++ {
+		for dx := -4; dx <= 4; dx++ {
+			x, y := cx+dx, cy+dy
+			if x < 0 || x >= c.Size || y < 0 || y >= c.Size {
+				continue
+			}
+			d := max(abs(dx), abs(dy))
+			c.setFunction(x, y, d != 2 && d != 4)
+		}
+	}
+}
+
+func (c *Code) drawAlignment(cx, cy int) {
+	for dy := -2; dy <= 2; dy++ {
+		for dx := -2; dx <= 2; dx++ {
+			c.setFunction(cx+dx, cy+dy, max(abs(dx), abs(dy)) != 1)
+		}
+	}
+}
+
+// formatBits is the 15 bit BCH protected format information for level M
+func formatBits(mask int) int {
+	data := formatBitsM<<3 | mask
+	rem := data
+	for range 10 {
+		rem = rem<<1 ^ (rem>>9)*0x537
+	}
+	return (data<<10 | rem) ^ 0x5412
+}
+
+// drawFormat writes both copies of the format information
+func (c *Code) drawFormat(mask int) {
+	bits := formatBits(mask)
+	bit := func(i int) bool { return bits>>i&1 != 0 }
+
+	for i := range 6 {
+		c.setFunction(8, i, bit(i))
+	}
+	c.setFunction(8, 7, bit(6))
+	c.setFunction(8, 8, bit(7))
+	c.setFunction(7, 8, bit(8))
+	for i := 9; i < 15; i++ {
+		c.setFunction(14-i, 8, bit(i))
+	}
+
+	for i := range 8 {
+		c.setFunction(c.Size-1-i, 8, bit(i))
+	}
+	for i := 8; i < 15; i++ {
+		c.setFunction(8, c.Size-15+i, bit(i))
+	}
+	c.setFunction(8, c.Size-8, true) // always dark
+}
+
+// versionBits is the 18 bit BCH protected version information
+func versionBits(version int) int {
+	rem := version
+	for range 12 {
+		rem = rem<<1 ^ (rem>>11)*0x1F25
+	}
+	return version<<12 | rem
+}
+
+// drawVersion writes both copies of the version information, from version 7
+func (c *Code) drawVersion() {
+	if c.Version < 7 {
+		return
+	}
+	bits := versionBits(c.Version)
+	for i := range 18 {
+		dark := bits>>i&1 != 0
+		a, b := c.Size-11+i%3, i/3
+		c.setFunction(a, b, dark)
+		c.setFunction(b, a, dark)
+	}
+}
+
+// drawCodewords fills the data area in the standard two column zigzag
+func (c *Code) drawCodewords(data []byte) {
+	i := 0
+	for right := c.Size - 1; right >= 1; right -= 2 {
+		if right == 6 {
+			right = 5 // skip the vertical timing pattern
+		}
+		for vert := range c.Size {
+			for j := range 2 {
+				x := right - j
+				y := vert
+				if (right+1)&2 == 0 {
+					y = c.Size - 1 - vert
+				}
+				if c.function[y][x] || i >= len(data)*8 {
+					continue
+				}
+				c.Modules[y][x] = data[i/8]>>(7-i%8)&1 != 0
+				i++
+			}
+		}
+	}
+}
+
+// masks are the eight data mask conditions, true where a module is flipped
+var masks = [8]func(x, y int) bool{
+	func(x, y int) bool { return (x+y)%2 == 0 },
+	func(x, y int) bool { return y%2 == 0 },
+	func(x, y int) bool { return x%3 == 0 },
+	func(x, y int) bool { return (x+y)%3 == 0 },
+	func(x, y int) bool { return (x/3+y/2)%2 == 0 },
+	func(x, y int) bool { return x*y%2+x*y%3 == 0 },
+	func(x, y int) bool { return (x*y%2+x*y%3)%2 == 0 },
+	func(x, y int) bool { return ((x+y)%2+x*y%3)%2 == 0 },
+}
+
+// applyMask flips the data modules selected by mask; applying it twice undoes it
+func (c *Code) applyMask(mask int) {
+	for y := range c.Size {
+		for x := range c.Size {
+			if !c.function[y][x] && masks[mask](x, y) {
+				c.Modules[y][x] = !c.Modules[y][x]
+			}
+		}
+	}
+}
+
+// applyBestMask tries every mask and keeps the one with the lowest penalty
+func (c *Code) applyBestMask() {
+	best, bestScore := 0, -1
+	for mask := range masks {
+		c.applyMask(mask)
+		c.drawFormat(mask)
+		if score := c.penalty(); bestScore < 0 || score < bestScore {
+			best, bestScore = mask, score
+		}
+		c.applyMask(mask)
+	}
+	c.applyMask(best)
+	c.drawFormat(best)
+}
+
+// penalty scores the symbol with the four rules of the standard
+func (c *Code) penalty() int {
+	n := c.Size
+	score := 0
+
+	at := func(x, y int, vertical bool) bool {
+		if vertical {
+			return c.Modules[x][y]
+		}
+		return c.Modules[y][x]
+	}
+
+	finderA := []bool{true, false, true, true, true, false, true, false, false, false, false}
+	finderB := []bool{false, false, false, false, true, false, true, true, true, false, true}
+
+	for _, vertical := range []bool{false, true} {
+		for y := range n {
+			// rule 1: runs of five or more modules of one colour
+			run := 1
+			for x := 1; x < n; x++ {
+				if at(x, y, vertical) == at(x-1, y, vertical) {
+					run++
+					continue
+				}
+				if run >= 5 {
+					score += run - 2
+				}
+				run = 1
+			}
+			if run >= 5 {
+				score += run - 2
+			}
+
+			// rule 3: patterns that look like a finder
+			for x := 0; x+len(finderA) <= n; x++ {
+				matchA, matchB := true, true
+				for k := range finderA {
+					m := at(x+k, y, vertical)
+					matchA = matchA && m == finderA[k]
+					matchB = matchB && m == finderB[k]
+				}
+				if matchA {
+					score += 40
+				}
+				if matchB {
+					score += 40
+				}
+			}
+		}
+	}
+
+	// rule 2: 2x2 blocks of one colour
+	dark := 0
+	for y := range n {
+		for x := range n {
+			if c.Modules[y][x] {
+				dark++
+			}
+			if x+1 < n && y+1 < n {
+				m := c.Modules[y][x]
+				if m == c.Modules[y][x+1] && m == c.Modules[y+1][x] && m == c.Modules[y+1][x+1] {
+					score += 3
+				}
+			}
+		}
+	}
+
+	// rule 4: balance of dark and light
+	percent := dark * 100 / (n * n)
+	score += abs(percent-50) / 5 * 10
+
+	return score
+}
+
+// SVG draws the symbol with a four module quiet zone. size is the rendered
+// width and height in pixels.
+func (c *Code) SVG(size int) string {
+	const quiet = 4
+	dim := c.Size + 2*quiet
+
+	var path strings.Builder
+	for y := range c.Size {
+		for x := range c.Size {
+			if c.Modules[y][x] {
+				fmt.Fprintf(&path, "M%d %dh1v1h-1z", x+quiet, y+quiet)
+			}
+		}
+	}
+
+	return fmt.Sprintf(`<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d" shape-rendering="crispEdges" role="img" aria-label="QR code">`+
+		`<rect width="100%%" height="100%%" fill="#fff"/><path d="%s" fill="#000"/></svg>`,
+		size, size, dim, dim, path.String())
+}
+
+// bitBuffer collects bits most significant first
+type bitBuffer []bool
+
+func (b *bitBuffer) append(value, n int) {
+	for i := n - 1; i >= 0; i-- {
+		*b = append(*b, value>>i&1 != 0)
+	}
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
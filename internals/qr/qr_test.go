@@ -0,0 +1,165 @@
+package qr
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestReedSolomon(t *testing.T) {
+	// the 1-M "HELLO WORLD" worked example
+	data := []byte{32, 91, 11, 120, 209, 114, 220, 77, 67, 64, 236, 17, 236, 17, 236, 17}
+	want := []byte{196, 35, 39, 119, 235, 215, 231, 226, 93, 23}
+
+	if got := rsRemainder(data, rsDivisor(10)); !bytes.Equal(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+}
+
+func TestFormatAndVersionBits(t *testing.T) {
+	// level M with masks 0 and 5, from the format information table
+	if got := formatBits(0); got != 0b101010000010010 {
+		t.Errorf("mask 0: got %015b", got)
+	}
+	if got := formatBits(5); got != 0b100000011001110 {
+		t.Errorf("mask 5: got %015b", got)
+	}
+	if got := versionBits(7); got != 0x07C94 {
+		t.Errorf("version 7: got %#x", got)
+	}
+}
+
+func TestBlockTablesMatchSymbolSize(t *testing.T) {
+	for v := 1; v < len(levelM); v++ {
+		c := newCode(v)
+		c.drawFunctionPatterns()
+
+		free := 0
+		for y := range c.Size {
+			for x := range c.Size {
+				if !c.function[y][x] {
+					free++
+				}
+			}
+		}
+
+		spec := levelM[v]
+		blocks := 0
+		for _, g := range spec.groups {
+			blocks += g[0]
+		}
+		if total := spec.dataCapacity() + blocks*spec.ecPerBlock; total != free/8 {
+			t.Errorf("version %d: table has %d codewords, symbol has room for %d", v, total, free/8)
+		}
+	}
+}
+
+// readCodewords undoes the mask and reads the data area back in placement order
+func readCodewords(c *Code) (mask int, out []byte) {
+	var bits int
+	for i := 0; i < 15; i++ {
+		// the second copy, along the bottom and right edges
+		var dark bool
+		if i < 8 {
+			dark = c.Modules[8][c.Size-1-i]
+		} else {
+			dark = c.Modules[c.Size-15+i][8]
+		}
+		if dark {
+			bits |= 1 << i
+		}
+	}
+	for m := range masks {
+		if formatBits(m) == bits {
+			mask = m
+		}
+	}
+
+	c.applyMask(mask)
+	defer c.applyMask(mask)
+
+	var cur byte
+	n := 0
+	for right := c.Size - 1; right >= 1; right -= 2 {
+		if right == 6 {
+			right = 5
+		}
+		for vert := range c.Size {
+			for j := range 2 {
+				x, y := right-j, vert
+				if (right+1)&2 == 0 {
+					y = c.Size - 1 - vert
+				}
+				if c.function[y][x] {
+					continue
+				}
+				cur = cur<<1 | boolByte(c.Modules[y][x])
+				if n++; n%8 == 0 {
+					out = append(out, cur)
+					cur = 0
+				}
+			}
+		}
+	}
+	return mask, out
+}
+
+func boolByte(b bool) byte {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+func TestEncodeRoundTrip(t *testing.T) {
+	for _, text := range []string{
+		"hi",
+		"otpauth://totp/GoTH:ada@example.com?algorithm=SHA1&digits=6&issuer=GoTH&period=30&secret=JBSWY3DPEHPK3PXPJBSWY3DPEHPK3PXP",
+		strings.Repeat("x", 300),
+	} {
+		c, err := Encode(text)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		want := addErrorCorrection(c.Version, dataCodewords(c.Version, []byte(text)))
+		_, got := readCodewords(c)
+		if !bytes.Equal(got[:len(want)], want) {
+			t.Errorf("%d bytes: codewords read back differ from those encoded", len(text))
+		}
+
+		// the payload starts with the byte mode indicator and the length
+		if got[0]>>4 != 0b0100 {
+			t.Errorf("%d bytes: expected byte mode, got %04b", len(text), got[0]>>4)
+		}
+	}
+}
+
+func TestEncodePicksSmallestVersion(t *testing.T) {
+	tests := []struct {
+		n, version int
+	}{
+		{14, 1}, {15, 2}, {106, 6}, {107, 7}, {666, 20},
+	}
+	for _, tt := range tests {
+		c, err := Encode(strings.Repeat("a", tt.n))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if c.Version != tt.version {
+			t.Errorf("%d bytes: expected version %d, got %d", tt.n, tt.version, c.Version)
+		}
+	}
+
+	if _, err := Encode(strings.Repeat("a", 667)); err != ErrTooLong {
+		t.Errorf("expected ErrTooLong, got %v", err)
+	}
+}
+
+func TestSVG(t *testing.T) {
+	c, _ := Encode("hello")
+	svg := c.SVG(200)
+	if !strings.HasPrefix(svg, "<svg ") || !strings.Contains(svg, `viewBox="0 0 29 29"`) {
+		t.Errorf("unexpected SVG %q", svg[:80])
+	}
+}
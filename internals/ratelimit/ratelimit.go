@@ -0,0 +1,93 @@
+// Package ratelimit keeps a token bucket per key, such as a client IP or an
+// email address. Buckets live in memory, so each instance limits separately.
+package ratelimit
+
+import (
+	"sync"
+	"time"
+)
+
+// Limiter allows bursts of up to Burst requests per key, refilling one token
+// every Refill
+type Limiter struct {
+	burst  float64
+	refill time.Duration
+
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	lastSweep time.Time
+
+	// now is replaced in tests
+	now func() time.Time
+}
+
+// bucket is the state for one key
+type bucket struct {
+	tokens float64
+	at     time.Time
+}
+
+// New creates a Limiter with full buckets
+func New(burst int, refill time.Duration) *Limiter {
+	return &Limiter{
+		burst:   float64(burst),
+		refill:  refill,
+		buckets: map[string]*bucket{},
+		now:     time.Now,
+	}
+}
+
+// Allow takes a token for key. When the bucket is empty it returns false and
+// how long until the next token arrives.
+func (l *Limiter) Allow(key string) (bool, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	l.sweep(now)
+
+	b := l.fill(key, now)
+	if b.tokens >= 1 {
+		b.tokens--
+		return true, 0
+	}
+	return false, time.Duration((1 - b.tokens) * float64(l.refill))
+}
+
+// Reset refills the bucket for key
+func (l *Limiter) Reset(key string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	delete(l.buckets, key)
+}
+
+// fill tops up the bucket for key to now; callers must hold the lock
+func (l *Limiter) fill(key string, now time.Time) *bucket {
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{tokens: l.burst, at: now}
+		l.buckets[key] = b
+		return b
+	}
+
+	b.tokens = min(l.burst, b.tokens+float64(now.Sub(b.at))/float64(l.refill))
+	b.at = now
+	return b
+}
+
+// sweep drops buckets that have refilled, as they are the same as no bucket.
+// It runs at most once per full refill period; callers must hold the lock.
+func (l *Limiter) sweep(now time.Time) {
+	period := time.Duration(l.burst) * l.refill
+	if now.Sub(l.lastSweep) < period {
+		return
+	}
+	l.lastSweep = now
+
+	for key, b := range l.buckets {
+		if now.Sub(b.at) >= period {
+			delete(l.buckets, key)
+		}
+	}
+}
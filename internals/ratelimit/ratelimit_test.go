@@ -0,0 +1,56 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLimiter(t *testing.T) {
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	l := New(3, 10*time.Second)
+	l.now = func() time.Time { return now }
+
+	for i := range 3 {
+		if ok, _ := l.Allow("a"); !ok {
+			t.Fatalf("request %d should be allowed", i+1)
+		}
+	}
+	ok, wait := l.Allow("a")
+	if ok || wait != 10*time.Second {
+		t.Fatalf("expected to wait 10s, got %v %s", ok, wait)
+	}
+
+	// other keys have their own bucket
+	if ok, _ := l.Allow("b"); !ok {
+		t.Error("expected a separate bucket for b")
+	}
+
+	now = now.Add(4 * time.Second)
+	if _, wait := l.Allow("a"); wait != 6*time.Second {
+		t.Errorf("expected 6s left, got %s", wait)
+	}
+	now = now.Add(6 * time.Second)
+	if ok, _ := l.Allow("a"); !ok {
+		t.Error("expected a token after the refill")
+	}
+
+	l.Reset("a")
+	if ok, _ := l.Allow("a"); !ok {
+		t.Error("expected a full bucket after Reset")
+	}
+}
+
+func TestLimiterSweep(t *testing.T) {
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	l := New(2, time.Second)
+	l.now = func() time.Time { return now }
+
+	l.Allow("a")
+	l.Allow("b")
+
+	now = now.Add(time.Minute)
+	l.Allow("c")
+	if len(l.buckets) != 1 {
+		t.Errorf("expected refilled buckets to be dropped, got %d", len(l.buckets))
+	}
+}
@@ -0,0 +1,134 @@
+package dbrepo
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/stackninja.pro/goth/internals/models"
+)
+
+// auditColumns is what every audit_events query selects
+const auditColumns = "id, COALESCE(actor_id::text, ''), actor_email, action, COALESCE(target_id::text, ''), target_email, ip, user_agent, changes, created_at"
+
+// auditDest returns the scan destinations for auditColumns
+func auditDest(e *models.AuditEvent) []any {
+	return []any{&e.ID, &e.ActorID, &e.ActorEmail, &e.Action, &e.TargetID, &e.TargetEmail, &e.IP, &e.UserAgent, &e.Changes, &e.CreatedAt}
+}
+
+// RecordAuditEvent appends an event to the audit log
+func (m *neonDBRepo) RecordAuditEvent(ctx context.Context, e models.AuditEvent) error {
+	ctx, cancel := m.withTimeout(ctx)
+	defer cancel()
+
+	changes, err := json.Marshal(nonNil(e.Changes))
+	if err != nil {
+		return err
+	}
+
+	_, err = m.DB.Exec(ctx, `
+		INSERT INTO audit_events (actor_id, actor_email, action, target_id, target_email, ip, user_agent, changes)
+		VALUES (NULLIF($1, '')::uuid, $2, $3, NULLIF($4, '')::uuid, $5, $6, $7, $8)
+	`, e.ActorID, e.ActorEmail, e.Action, e.TargetID, e.TargetEmail, e.IP, e.UserAgent, changes)
+	return translateErr(ctx, err)
+}
+
+// GetAuditEvents returns one page of the events matching q, newest first,
+// and how many match in total
+func (m *neonDBRepo) GetAuditEvents(ctx context.Context, q models.AuditQuery) ([]models.AuditEvent, int, error) {
+	ctx, cancel := m.withTimeout(ctx)
+	defer cancel()
+
+	q = q.Normalize()
+	where, args := auditFilter(q)
+
+	var total int
+	if err := m.DB.QueryRow(ctx, "SELECT count(*) FROM audit_events"+where, args...).Scan(&total); err != nil {
+		return nil, 0, translateErr(ctx, err)
+	}
+
+	query := fmt.Sprintf("SELECT %s FROM audit_events%s ORDER BY id DESC LIMIT %d OFFSET %d", auditColumns, where, q.PerPage, q.Offset())
+	rows, err := m.DB.Query(ctx, query, args...)
+	if err != nil {
+		return nil, 0, translateErr(ctx, err)
+	}
+
+	events, err := collectAuditEvents(rows)
+	return events, total, translateErr(ctx, err)
+}
+
+// ForEachAuditEvent calls fn with every event matching q, newest first,
+// without holding them all in memory. Paging in q is ignored.
+func (m *neonDBRepo) ForEachAuditEvent(ctx context.Context, q models.AuditQuery, fn func(models.AuditEvent) error) error {
+	// no query timeout: an export runs as long as the client keeps reading
+	where, args := auditFilter(q.Normalize())
+	rows, err := m.DB.Query(ctx, "SELECT "+auditColumns+" FROM audit_events"+where+" ORDER BY id DESC", args...)
+	if err != nil {
+		return translateErr(ctx, err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var e models.AuditEvent
+		if err := rows.Scan(auditDest(&e)...); err != nil {
+			return translateErr(ctx, err)
+		}
+		if err := fn(e); err != nil {
+			return err
+		}
+	}
+	return translateErr(ctx, rows.Err())
+}
+
+// collectAuditEvents scans every row and closes rows
+func collectAuditEvents(rows pgx.Rows) ([]models.AuditEvent, error) {
+	defer rows.Close()
+
+	var events []models.AuditEvent
+	for rows.Next() {
+		var e models.AuditEvent
+		if err := rows.Scan(auditDest(&e)...); err != nil {
+			return nil, err
+		}
+		events = append(events, e)
+	}
+	return events, rows.Err()
+}
+
+// auditFilter turns the filters in q into a WHERE clause and its arguments
+func auditFilter(q models.AuditQuery) (string, []any) {
+	var conds []string
+	var args []any
+	add := func(cond string, arg any) {
+		args = append(args, arg)
+		conds = append(conds, fmt.Sprintf(cond, len(args)))
+	}
+
+	if q.Search != "" {
+		add("(actor_email ILIKE $%[1]d OR target_email ILIKE $%[1]d)", "%"+escapeLike(q.Search)+"%")
+	}
+	if q.Action != "" {
+		add("action = $%d", q.Action)
+	}
+	if !q.From.IsZero() {
+		add("created_at >= $%d", q.From)
+	}
+	if !q.To.IsZero() {
+		add("created_at < $%d", q.To.AddDate(0, 0, 1))
+	}
+
+	if len(conds) == 0 {
+		return "", nil
+	}
+	return " WHERE " + strings.Join(conds, " AND "), args
+}
+
+// nonNil turns a nil slice into an empty one, so it is stored as [] rather than null
+func nonNil[T any](s []T) []T {
+	if s == nil {
+		return []T{}
+	}
+	return s
+}
@@ -0,0 +1,221 @@
+package dbrepo
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/stackninja.pro/goth/internals/models"
+	"github.com/stackninja.pro/goth/internals/repository"
+)
+
+// courseColumns is what every course query selects, the instructors included
+const courseColumns = `c.id, c.code, c.title, c.description, c.credit_units, c.department, c.capacity, c.status, c.created_at, c.updated_at,
+	COALESCE((SELECT array_agg(ci.user_id::text ORDER BY ci.position) FROM course_instructors ci WHERE ci.course_id = c.id), '{}'),
+	COALESCE((SELECT array_agg(u.name ORDER BY ci.position) FROM course_instructors ci JOIN users u ON u.id = ci.user_id WHERE ci.course_id = c.id), '{}')`
+
+// courseDest returns the scan destinations for courseColumns
+func courseDest(c *models.Course) []any {
+	return []any{&c.ID, &c.Code, &c.Title, &c.Description, &c.CreditUnits, &c.Department, &c.Capacity, &c.Status, &c.CreatedAt, &c.UpdatedAt, &c.InstructorIDs, &c.Instructors}
+}
+
+// GetCourses returns one page of the courses matching q, ordered by code,
+// and how many match in total
+func (m *neonDBRepo) GetCourses(ctx context.Context, q models.CourseQuery) ([]models.Course, int, error) {
+	ctx, cancel := m.withTimeout(ctx)
+	defer cancel()
+
+	q = q.Normalize()
+	where, args := courseFilter(q)
+
+	var total int
+	if err := m.DB.QueryRow(ctx, "SELECT count(*) FROM courses c"+where, args...).Scan(&total); err != nil {
+		return nil, 0, translateErr(ctx, err)
+	}
+
+	query := fmt.Sprintf("SELECT %s FROM courses c%s ORDER BY c.code LIMIT %d OFFSET %d", courseColumns, where, q.PerPage, q.Offset())
+	rows, err := m.DB.Query(ctx, query, args...)
+	if err != nil {
+		return nil, 0, translateErr(ctx, err)
+	}
+	defer rows.Close()
+
+	var courses []models.Course
+	for rows.Next() {
+		var c models.Course
+		if err := rows.Scan(courseDest(&c)...); err != nil {
+			return nil, 0, translateErr(ctx, err)
+		}
+		courses = append(courses, c)
+	}
+	return courses, total, translateErr(ctx, rows.Err())
+}
+
+// courseFilter turns q into a WHERE clause and its arguments
+func courseFilter(q models.CourseQuery) (string, []any) {
+	var conds []string
+	var args []any
+	add := func(cond string, arg any) {
+		args = append(args, arg)
+		conds = append(conds, fmt.Sprintf(cond, len(args)))
+	}
+
+	if q.Search != "" {
+		add("(c.code ILIKE $%[1]d OR c.title ILIKE $%[1]d OR c.description ILIKE $%[1]d)", "%"+escapeLike(q.Search)+"%")
+	}
+	if q.Department != "" {
+		add("c.department = $%d", q.Department)
+	}
+	if q.Status != "" {
+		add("c.status = $%d", q.Status)
+	}
+	if q.InstructorID != "" {
+		add("EXISTS (SELECT 1 FROM course_instructors ci WHERE ci.course_id = c.id AND ci.user_id::text = $%d)", q.InstructorID)
+	}
+
+	if len(conds) == 0 {
+		return "", nil
+	}
+	return " WHERE " + strings.Join(conds, " AND "), args
+}
+
+// GetCourse retrieves a course by its ID
+func (m *neonDBRepo) GetCourse(ctx context.Context, id string) (*models.Course, error) {
+	ctx, cancel := m.withTimeout(ctx)
+	defer cancel()
+
+	var c models.Course
+	if err := m.DB.QueryRow(ctx, "SELECT "+courseColumns+" FROM courses c WHERE c.id = $1", id).Scan(courseDest(&c)...); err != nil {
+		return nil, translateErr(ctx, err)
+	}
+	return &c, nil
+}
+
+// GetDepartments lists the departments with a published course, for the
+// catalog's filter
+func (m *neonDBRepo) GetDepartments(ctx context.Context) ([]string, error) {
+	ctx, cancel := m.withTimeout(ctx)
+	defer cancel()
+
+	rows, err := m.DB.Query(ctx, "SELECT DISTINCT department FROM courses WHERE status = $1 ORDER BY department", models.CoursePublished)
+	if err != nil {
+		return nil, translateErr(ctx, err)
+	}
+	departments, err := pgx.CollectRows(rows, pgx.RowTo[string])
+	return departments, translateErr(ctx, err)
+}
+
+// CreateCourse stores a new course and its instructors, returning its ID
+func (m *neonDBRepo) CreateCourse(ctx context.Context, c models.Course) (string, error) {
+	ctx, cancel := m.withTimeout(ctx)
+	defer cancel()
+
+	id, err := uuid.NewUUID()
+	if err != nil {
+		return "", err
+	}
+	c.ID = id.String()
+	if c.Status == "" {
+		c.Status = models.CourseDraft
+	}
+
+	err = pgx.BeginFunc(ctx, m.DB, func(tx pgx.Tx) error {
+		_, err := tx.Exec(ctx, `
+			INSERT INTO courses (id, code, title, description, credit_units, department, capacity, status)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`,
+			c.ID, c.Code, c.Title, c.Description, c.CreditUnits, c.Department, c.Capacity, c.Status)
+		if err != nil {
+			return err
+		}
+		return setInstructors(ctx, tx, c.ID, c.InstructorIDs)
+	})
+	if isForeignKeyViolation(err) {
+		return "", repository.ErrNotFound
+	}
+	if err != nil {
+		return "", translateErr(ctx, err)
+	}
+	return c.ID, nil
+}
+
+// UpdateCourse saves a course's details and replaces its instructors, then
+// fills any seats a raised capacity frees from the waitlists and returns
+// who was promoted. The status is left alone; SetCourseStatus changes it.
+func (m *neonDBRepo) UpdateCourse(ctx context.Context, c models.Course) ([]models.Enrollment, error) {
+	ctx, cancel := m.withTimeout(ctx)
+	defer cancel()
+
+	var promoted []models.Enrollment
+	err := pgx.BeginFunc(ctx, m.DB, func(tx pgx.Tx) error {
+		tag, err := tx.Exec(ctx, `
+			UPDATE courses SET code = $2, title = $3, description = $4, credit_units = $5, department = $6, capacity = $7, updated_at = now()
+			WHERE id = $1`,
+			c.ID, c.Code, c.Title, c.Description, c.CreditUnits, c.Department, c.Capacity)
+		if err != nil {
+			return err
+		}
+		if tag.RowsAffected() == 0 {
+			return repository.ErrNotFound
+		}
+
+		if _, err := tx.Exec(ctx, "DELETE FROM course_instructors WHERE course_id = $1", c.ID); err != nil {
+			return err
+		}
+		if err := setInstructors(ctx, tx, c.ID, c.InstructorIDs); err != nil {
+			return err
+		}
+
+		// a raised capacity frees seats for the waitlists; the UPDATE above
+		// holds the course row lock promoteWaitlist needs
+		rows, err := tx.Query(ctx, "SELECT DISTINCT term_id FROM enrollments WHERE course_id = $1 AND status = 'waitlisted'", c.ID)
+		if err != nil {
+			return err
+		}
+		terms, err := pgx.CollectRows(rows, pgx.RowTo[string])
+		if err != nil {
+			return err
+		}
+		for _, termID := range terms {
+			p, err := promoteWaitlist(ctx, tx, c.ID, termID, c.Capacity)
+			if err != nil {
+				return err
+			}
+			promoted = append(promoted, p...)
+		}
+		return nil
+	})
+	if isForeignKeyViolation(err) {
+		return nil, repository.ErrNotFound
+	}
+	if err != nil {
+		return nil, translateErr(ctx, err)
+	}
+	return promoted, nil
+}
+
+// setInstructors inserts the course's instructors in the order given
+func setInstructors(ctx context.Context, tx pgx.Tx, courseID string, userIDs []string) error {
+	for i, userID := range userIDs {
+		if _, err := tx.Exec(ctx, "INSERT INTO course_instructors (course_id, user_id, position) VALUES ($1, $2, $3)", courseID, userID, i); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SetCourseStatus drafts, publishes or archives a course
+func (m *neonDBRepo) SetCourseStatus(ctx context.Context, id string, status models.CourseStatus) error {
+	ctx, cancel := m.withTimeout(ctx)
+	defer cancel()
+
+	tag, err := m.DB.Exec(ctx, "UPDATE courses SET status = $2, updated_at = now() WHERE id = $1", id, status)
+	if err != nil {
+		return translateErr(ctx, err)
+	}
+	if tag.RowsAffected() == 0 {
+		return repository.ErrNotFound
+	}
+	return nil
+}
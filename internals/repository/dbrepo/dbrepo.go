@@ -1,21 +1,95 @@
 package dbrepo
 
 import (
+	"context"
+	"errors"
+	"fmt"
+
 	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/stackninja.pro/goth/internals/repository"
 	"github.com/stackninja.pro/goth/src/config"
 )
 
 type neonDBRepo struct {
 	App *config.AppConfig
-	DB  *pgx.Conn
+	DB  *pgxpool.Pool
 }
 
-
-
-func NewPostgresRepo(a *config.AppConfig, conn *pgx.Conn) repository.DatabaseRepo {
+// NewPostgresRepo creates a repository backed by the shared connection pool.
+// It is a repository.CourseRepo, repository.TermRepo, repository.GradeRepo
+// and repository.ScaleRepo as well.
+func NewPostgresRepo(a *config.AppConfig, pool *pgxpool.Pool) repository.DatabaseRepo {
 	return &neonDBRepo{
 		App: a,
-		DB:  conn,
+		DB:  pool,
+	}
+}
+
+// withTimeout bounds a query by the configured per-query timeout. The parent
+// is normally the request context, so a disconnecting client aborts the query too.
+func (m *neonDBRepo) withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if m.App == nil || m.App.Database.QueryTimeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, m.App.Database.QueryTimeout)
+}
+
+// Postgres error codes we react to
+const (
+	uniqueViolation     = "23505"
+	foreignKeyViolation = "23503"
+)
+
+// isForeignKeyViolation reports whether err is a foreign key failure, such as
+// a row pointing at a user that doesn't exist
+func isForeignKeyViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == foreignKeyViolation
+}
+
+// translateErr maps driver errors onto the repository sentinel errors. ctx is
+// the query context, used to tell a timeout apart from a cancelled request.
+func translateErr(ctx context.Context, err error) error {
+	if err == nil {
+		return nil
+	}
+
+	if errors.Is(err, pgx.ErrNoRows) {
+		return repository.ErrNotFound
+	}
+
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == uniqueViolation {
+		switch pgErr.ConstraintName {
+		case "users_email_key":
+			return repository.ErrDuplicateEmail
+		case "courses_code_key":
+			return repository.ErrDuplicateCourseCode
+		case "terms_name_key":
+			return repository.ErrDuplicateTermName
+		case "academic_sessions_name_key":
+			return repository.ErrDuplicateSessionName
+		case "enrollments_student_key":
+			return repository.ErrAlreadyEnrolled
+		case "grading_scales_name_key":
+			return repository.ErrDuplicateScaleName
+		case "programmes_name_key":
+			return repository.ErrDuplicateProgrammeName
+		}
+	}
+
+	return contextErr(ctx, err)
+}
+
+// contextErr tags err with ErrTimeout or ErrCanceled when ctx is the reason it failed
+func contextErr(ctx context.Context, err error) error {
+	switch {
+	case errors.Is(ctx.Err(), context.DeadlineExceeded):
+		return fmt.Errorf("%w: %w", repository.ErrTimeout, err)
+	case errors.Is(ctx.Err(), context.Canceled):
+		return fmt.Errorf("%w: %w", repository.ErrCanceled, err)
 	}
+	return err
 }
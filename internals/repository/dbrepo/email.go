@@ -0,0 +1,73 @@
+package dbrepo
+
+import (
+	"context"
+
+	"github.com/stackninja.pro/goth/internals/repository"
+)
+
+// VerifyEmail marks the user's address as confirmed. It returns ErrNotFound
+// when the user's address is no longer email.
+func (m *neonDBRepo) VerifyEmail(ctx context.Context, id, email string) error {
+	ctx, cancel := m.withTimeout(ctx)
+	defer cancel()
+
+	tag, err := m.DB.Exec(ctx, "UPDATE users SET email_verified_at = COALESCE(email_verified_at, NOW()), updated_at = NOW() WHERE id = $1 AND email = $2", id, email)
+	if err != nil {
+		return translateErr(ctx, err)
+	}
+	if tag.RowsAffected() == 0 {
+		return repository.ErrNotFound
+	}
+
+	return nil
+}
+
+// SetPendingEmail records an address the user wants to switch to, or
+// clears it when email is empty. The current address stays in use until
+// ConfirmEmailChange.
+func (m *neonDBRepo) SetPendingEmail(ctx context.Context, id, email string) error {
+	ctx, cancel := m.withTimeout(ctx)
+	defer cancel()
+
+	if email != "" {
+		var taken bool
+		if err := m.DB.QueryRow(ctx, "SELECT EXISTS (SELECT 1 FROM users WHERE email = $1 AND id <> $2)", email, id).Scan(&taken); err != nil {
+			return translateErr(ctx, err)
+		}
+		if taken {
+			return repository.ErrDuplicateEmail
+		}
+	}
+
+	tag, err := m.DB.Exec(ctx, "UPDATE users SET pending_email = $2, updated_at = NOW() WHERE id = $1", id, email)
+	if err != nil {
+		return translateErr(ctx, err)
+	}
+	if tag.RowsAffected() == 0 {
+		return repository.ErrNotFound
+	}
+
+	return nil
+}
+
+// ConfirmEmailChange swaps in the pending address, which counts as verified.
+// It returns ErrNotFound when email is no longer the pending address.
+func (m *neonDBRepo) ConfirmEmailChange(ctx context.Context, id, email string) error {
+	ctx, cancel := m.withTimeout(ctx)
+	defer cancel()
+
+	tag, err := m.DB.Exec(ctx, `
+		UPDATE users
+		SET email = pending_email, pending_email = '', email_verified_at = NOW(), updated_at = NOW()
+		WHERE id = $1 AND pending_email = $2 AND pending_email <> ''
+	`, id, email)
+	if err != nil {
+		return translateErr(ctx, err)
+	}
+	if tag.RowsAffected() == 0 {
+		return repository.ErrNotFound
+	}
+
+	return nil
+}
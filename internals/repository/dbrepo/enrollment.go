@@ -0,0 +1,238 @@
+package dbrepo
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/stackninja.pro/goth/internals/models"
+	"github.com/stackninja.pro/goth/internals/repository"
+)
+
+// enrollmentColumns is what every enrollment query selects, from enrollments
+// e joined to the student u and the course c. The waitlist position is the
+// student's rank among those still waiting.
+const enrollmentColumns = `e.id, e.course_id, e.term_id, e.student_id, e.status,
+	CASE WHEN e.status = 'waitlisted' THEN (
+		SELECT count(*) FROM enrollments w
+		WHERE w.course_id = e.course_id AND w.term_id = e.term_id AND w.status = 'waitlisted' AND w.position <= e.position
+	) ELSE 0 END,
+	e.created_at, e.updated_at, u.name, u.email, c.code, c.title, c.credit_units`
+
+// enrollmentFrom joins the tables enrollmentColumns reads
+const enrollmentFrom = " FROM enrollments e JOIN users u ON u.id = e.student_id JOIN courses c ON c.id = e.course_id"
+
+// enrollmentDest returns the scan destinations for enrollmentColumns
+func enrollmentDest(e *models.Enrollment) []any {
+	return []any{&e.ID, &e.CourseID, &e.TermID, &e.StudentID, &e.Status, &e.Position,
+		&e.CreatedAt, &e.UpdatedAt, &e.StudentName, &e.StudentEmail, &e.CourseCode, &e.CourseTitle, &e.CreditUnits}
+}
+
+// Enroll gives the student a seat in the course for the term, or a place at
+// the end of its waitlist when the course is full, and returns the waitlisted
+// students promoted ahead of them. The course row stays locked until the
+// transaction ends, so concurrent enrollments in the same course take turns
+// and can't oversubscribe it.
+func (m *neonDBRepo) Enroll(ctx context.Context, courseID, termID, studentID string) (*models.Enrollment, []models.Enrollment, error) {
+	ctx, cancel := m.withTimeout(ctx)
+	defer cancel()
+
+	id, err := uuid.NewUUID()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var promoted []models.Enrollment
+	err = pgx.BeginFunc(ctx, m.DB, func(tx pgx.Tx) error {
+		capacity, err := lockCourse(ctx, tx, courseID)
+		if err != nil {
+			return err
+		}
+
+		// students already waiting get any free seat before a newcomer does
+		if promoted, err = promoteWaitlist(ctx, tx, courseID, termID, capacity); err != nil {
+			return err
+		}
+
+		var enrolled, next int
+		err = tx.QueryRow(ctx, `
+			SELECT count(*) FILTER (WHERE status = 'enrolled'), COALESCE(max(position), 0) + 1
+			FROM enrollments WHERE course_id = $1 AND term_id = $2`,
+			courseID, termID).Scan(&enrolled, &next)
+		if err != nil {
+			return err
+		}
+
+		status, position := models.EnrollmentEnrolled, (*int)(nil)
+		if enrolled >= capacity {
+			status, position = models.EnrollmentWaitlisted, &next
+		}
+
+		// a student who dropped earlier gets their old row back
+		tag, err := tx.Exec(ctx, `
+			INSERT INTO enrollments (id, course_id, term_id, student_id, status, position)
+			VALUES ($1, $2, $3, $4, $5, $6)
+			ON CONFLICT (course_id, term_id, student_id) DO UPDATE
+				SET status = EXCLUDED.status, position = EXCLUDED.position, updated_at = now()
+				WHERE enrollments.status = 'dropped'`,
+			id.String(), courseID, termID, studentID, status, position)
+		if err != nil {
+			return err
+		}
+		if tag.RowsAffected() == 0 {
+			return repository.ErrAlreadyEnrolled
+		}
+		return nil
+	})
+	if isForeignKeyViolation(err) {
+		return nil, nil, repository.ErrNotFound
+	}
+	if err != nil {
+		return nil, nil, translateErr(ctx, err)
+	}
+	e, err := m.GetEnrollment(ctx, courseID, termID, studentID)
+	if err != nil {
+		return nil, nil, err
+	}
+	return e, promoted, nil
+}
+
+// DropEnrollment gives up the student's seat or waitlist place and promotes
+// whoever is next on the waitlist into a freed seat
+func (m *neonDBRepo) DropEnrollment(ctx context.Context, courseID, termID, studentID string) ([]models.Enrollment, error) {
+	ctx, cancel := m.withTimeout(ctx)
+	defer cancel()
+
+	var promoted []models.Enrollment
+	err := pgx.BeginFunc(ctx, m.DB, func(tx pgx.Tx) error {
+		capacity, err := lockCourse(ctx, tx, courseID)
+		if err != nil {
+			return err
+		}
+
+		tag, err := tx.Exec(ctx, `
+			UPDATE enrollments SET status = 'dropped', position = NULL, updated_at = now()
+			WHERE course_id = $1 AND term_id = $2 AND student_id = $3 AND status <> 'dropped'`,
+			courseID, termID, studentID)
+		if err != nil {
+			return err
+		}
+		if tag.RowsAffected() == 0 {
+			return repository.ErrNotFound
+		}
+
+		promoted, err = promoteWaitlist(ctx, tx, courseID, termID, capacity)
+		return err
+	})
+	if err != nil {
+		return nil, translateErr(ctx, err)
+	}
+	return promoted, nil
+}
+
+// lockCourse locks the course row for the rest of the transaction and
+// returns its capacity
+func lockCourse(ctx context.Context, tx pgx.Tx, courseID string) (int, error) {
+	var capacity int
+	err := tx.QueryRow(ctx, "SELECT capacity FROM courses WHERE id = $1 FOR UPDATE", courseID).Scan(&capacity)
+	return capacity, err
+}
+
+// promoteWaitlist fills the course's free seats in the term from the front
+// of the waitlist. The caller must hold the course row lock.
+func promoteWaitlist(ctx context.Context, tx pgx.Tx, courseID, termID string, capacity int) ([]models.Enrollment, error) {
+	rows, err := tx.Query(ctx, `
+		UPDATE enrollments SET status = 'enrolled', position = NULL, updated_at = now()
+		WHERE id IN (
+			SELECT id FROM enrollments
+			WHERE course_id = $1 AND term_id = $2 AND status = 'waitlisted'
+			ORDER BY position
+			LIMIT GREATEST($3 - (SELECT count(*) FROM enrollments WHERE course_id = $1 AND term_id = $2 AND status = 'enrolled'), 0)
+		)
+		RETURNING id, student_id`,
+		courseID, termID, capacity)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var promoted []models.Enrollment
+	for rows.Next() {
+		e := models.Enrollment{CourseID: courseID, TermID: termID, Status: models.EnrollmentEnrolled}
+		if err := rows.Scan(&e.ID, &e.StudentID); err != nil {
+			return nil, err
+		}
+		promoted = append(promoted, e)
+	}
+	return promoted, rows.Err()
+}
+
+// GetEnrollment retrieves the student's enrollment in the course for the
+// term, dropped or not
+func (m *neonDBRepo) GetEnrollment(ctx context.Context, courseID, termID, studentID string) (*models.Enrollment, error) {
+	ctx, cancel := m.withTimeout(ctx)
+	defer cancel()
+
+	var e models.Enrollment
+	err := m.DB.QueryRow(ctx, "SELECT "+enrollmentColumns+enrollmentFrom+" WHERE e.course_id = $1 AND e.term_id = $2 AND e.student_id = $3",
+		courseID, termID, studentID).Scan(enrollmentDest(&e)...)
+	if err != nil {
+		return nil, translateErr(ctx, err)
+	}
+	return &e, nil
+}
+
+// GetRoster lists the course's students in the term: those enrolled by
+// name, then the waitlist in order
+func (m *neonDBRepo) GetRoster(ctx context.Context, courseID, termID string) ([]models.Enrollment, error) {
+	return m.queryEnrollments(ctx, `
+		WHERE e.course_id = $1 AND e.term_id = $2 AND e.status <> 'dropped'
+		ORDER BY e.status = 'waitlisted', e.position, u.name`, courseID, termID)
+}
+
+// GetStudentEnrollments lists the courses the student holds or waits for a
+// seat in during the term, by course code
+func (m *neonDBRepo) GetStudentEnrollments(ctx context.Context, studentID, termID string) ([]models.Enrollment, error) {
+	return m.queryEnrollments(ctx, `
+		WHERE e.student_id = $1 AND e.term_id = $2 AND e.status <> 'dropped'
+		ORDER BY c.code`, studentID, termID)
+}
+
+// queryEnrollments runs an enrollment query with the given WHERE and ORDER BY
+func (m *neonDBRepo) queryEnrollments(ctx context.Context, where string, args ...any) ([]models.Enrollment, error) {
+	ctx, cancel := m.withTimeout(ctx)
+	defer cancel()
+
+	rows, err := m.DB.Query(ctx, "SELECT "+enrollmentColumns+enrollmentFrom+where, args...)
+	if err != nil {
+		return nil, translateErr(ctx, err)
+	}
+	defer rows.Close()
+
+	var enrollments []models.Enrollment
+	for rows.Next() {
+		var e models.Enrollment
+		if err := rows.Scan(enrollmentDest(&e)...); err != nil {
+			return nil, translateErr(ctx, err)
+		}
+		enrollments = append(enrollments, e)
+	}
+	return enrollments, translateErr(ctx, rows.Err())
+}
+
+// GetSeats counts the course's seats taken and waitlist places in the term
+func (m *neonDBRepo) GetSeats(ctx context.Context, courseID, termID string) (models.Seats, error) {
+	ctx, cancel := m.withTimeout(ctx)
+	defer cancel()
+
+	var s models.Seats
+	err := m.DB.QueryRow(ctx, `
+		SELECT c.capacity,
+			count(e.id) FILTER (WHERE e.status = 'enrolled'),
+			count(e.id) FILTER (WHERE e.status = 'waitlisted')
+		FROM courses c LEFT JOIN enrollments e ON e.course_id = c.id AND e.term_id = $2
+		WHERE c.id = $1
+		GROUP BY c.capacity`,
+		courseID, termID).Scan(&s.Capacity, &s.Enrolled, &s.Waitlisted)
+	return s, translateErr(ctx, err)
+}
@@ -0,0 +1,315 @@
+package dbrepo
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/stackninja.pro/goth/internals/models"
+	"github.com/stackninja.pro/goth/internals/repository"
+)
+
+// assessmentColumns is what every assessment query selects
+const assessmentColumns = "id, course_id, term_id, name, category, weight, max_score, position, created_at"
+
+// assessmentDest returns the scan destinations for assessmentColumns
+func assessmentDest(a *models.Assessment) []any {
+	return []any{&a.ID, &a.CourseID, &a.TermID, &a.Name, &a.Category, &a.Weight, &a.MaxScore, &a.Position, &a.CreatedAt}
+}
+
+// GetAssessments lists the course's assessments for the term in the order
+// they were added
+func (m *neonDBRepo) GetAssessments(ctx context.Context, courseID, termID string) ([]models.Assessment, error) {
+	ctx, cancel := m.withTimeout(ctx)
+	defer cancel()
+
+	rows, err := m.DB.Query(ctx, "SELECT "+assessmentColumns+" FROM assessments WHERE course_id = $1 AND term_id = $2 ORDER BY position, created_at", courseID, termID)
+	if err != nil {
+		return nil, translateErr(ctx, err)
+	}
+	defer rows.Close()
+
+	var assessments []models.Assessment
+	for rows.Next() {
+		var a models.Assessment
+		if err := rows.Scan(assessmentDest(&a)...); err != nil {
+			return nil, translateErr(ctx, err)
+		}
+		assessments = append(assessments, a)
+	}
+	return assessments, translateErr(ctx, rows.Err())
+}
+
+// GetAssessment retrieves an assessment by its ID
+func (m *neonDBRepo) GetAssessment(ctx context.Context, id string) (*models.Assessment, error) {
+	ctx, cancel := m.withTimeout(ctx)
+	defer cancel()
+
+	var a models.Assessment
+	if err := m.DB.QueryRow(ctx, "SELECT "+assessmentColumns+" FROM assessments WHERE id = $1", id).Scan(assessmentDest(&a)...); err != nil {
+		return nil, translateErr(ctx, err)
+	}
+	return &a, nil
+}
+
+// CreateAssessment adds an assessment after the course's others and returns
+// its ID
+func (m *neonDBRepo) CreateAssessment(ctx context.Context, a models.Assessment) (string, error) {
+	ctx, cancel := m.withTimeout(ctx)
+	defer cancel()
+
+	id, err := uuid.NewUUID()
+	if err != nil {
+		return "", err
+	}
+
+	_, err = m.DB.Exec(ctx, `
+		INSERT INTO assessments (id, course_id, term_id, name, category, weight, max_score, position)
+		SELECT $1, $2, $3, $4, $5, $6, $7, COALESCE(max(position), 0) + 1
+		FROM assessments WHERE course_id = $2 AND term_id = $3`,
+		id.String(), a.CourseID, a.TermID, a.Name, a.Category, a.Weight, a.MaxScore)
+	if isForeignKeyViolation(err) {
+		return "", repository.ErrNotFound
+	}
+	if err != nil {
+		return "", translateErr(ctx, err)
+	}
+	return id.String(), nil
+}
+
+// UpdateAssessment saves an assessment's name, category, weight and maximum
+func (m *neonDBRepo) UpdateAssessment(ctx context.Context, a models.Assessment) error {
+	ctx, cancel := m.withTimeout(ctx)
+	defer cancel()
+
+	tag, err := m.DB.Exec(ctx, "UPDATE assessments SET name = $2, category = $3, weight = $4, max_score = $5 WHERE id = $1",
+		a.ID, a.Name, a.Category, a.Weight, a.MaxScore)
+	if err != nil {
+		return translateErr(ctx, err)
+	}
+	if tag.RowsAffected() == 0 {
+		return repository.ErrNotFound
+	}
+	return nil
+}
+
+// DeleteAssessment removes an assessment with its scores and their history
+func (m *neonDBRepo) DeleteAssessment(ctx context.Context, id string) error {
+	ctx, cancel := m.withTimeout(ctx)
+	defer cancel()
+
+	tag, err := m.DB.Exec(ctx, "DELETE FROM assessments WHERE id = $1", id)
+	if err != nil {
+		return translateErr(ctx, err)
+	}
+	if tag.RowsAffected() == 0 {
+		return repository.ErrNotFound
+	}
+	return nil
+}
+
+// GetScores returns the course's scores for the term by student ID
+func (m *neonDBRepo) GetScores(ctx context.Context, courseID, termID string) (map[string]models.Scores, error) {
+	ctx, cancel := m.withTimeout(ctx)
+	defer cancel()
+
+	rows, err := m.DB.Query(ctx, `
+		SELECT s.student_id, s.assessment_id, s.score
+		FROM scores s JOIN assessments a ON a.id = s.assessment_id
+		WHERE a.course_id = $1 AND a.term_id = $2`, courseID, termID)
+	if err != nil {
+		return nil, translateErr(ctx, err)
+	}
+	defer rows.Close()
+
+	scores := map[string]models.Scores{}
+	for rows.Next() {
+		var studentID, assessmentID string
+		var score float64
+		if err := rows.Scan(&studentID, &assessmentID, &score); err != nil {
+			return nil, translateErr(ctx, err)
+		}
+		if scores[studentID] == nil {
+			scores[studentID] = models.Scores{}
+		}
+		scores[studentID][assessmentID] = score
+	}
+	return scores, translateErr(ctx, rows.Err())
+}
+
+// SetScore records or clears a score and appends the change to its history
+// in the same transaction
+func (m *neonDBRepo) SetScore(ctx context.Context, assessmentID, studentID string, score *float64, editorEmail string) error {
+	ctx, cancel := m.withTimeout(ctx)
+	defer cancel()
+
+	err := pgx.BeginFunc(ctx, m.DB, func(tx pgx.Tx) error {
+		var before *float64
+		err := tx.QueryRow(ctx, "SELECT score FROM scores WHERE assessment_id = $1 AND student_id = $2 FOR UPDATE", assessmentID, studentID).Scan(&before)
+		if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+			return err
+		}
+		if sameScore(before, score) {
+			return nil
+		}
+
+		if score == nil {
+			_, err = tx.Exec(ctx, "DELETE FROM scores WHERE assessment_id = $1 AND student_id = $2", assessmentID, studentID)
+		} else {
+			_, err = tx.Exec(ctx, `
+				INSERT INTO scores (assessment_id, student_id, score) VALUES ($1, $2, $3)
+				ON CONFLICT (assessment_id, student_id) DO UPDATE SET score = EXCLUDED.score, updated_at = now()`,
+				assessmentID, studentID, *score)
+		}
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.Exec(ctx, "INSERT INTO score_history (assessment_id, student_id, before, after, editor_email) VALUES ($1, $2, $3, $4, $5)",
+			assessmentID, studentID, before, score, editorEmail)
+		return err
+	})
+	if isForeignKeyViolation(err) {
+		return repository.ErrNotFound
+	}
+	return translateErr(ctx, err)
+}
+
+// sameScore reports whether two optional scores are equal
+func sameScore(a, b *float64) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+// GetScoreHistory lists the changes to one student's scores in the course
+// for the term, newest first
+func (m *neonDBRepo) GetScoreHistory(ctx context.Context, courseID, termID, studentID string) ([]models.ScoreChange, error) {
+	ctx, cancel := m.withTimeout(ctx)
+	defer cancel()
+
+	rows, err := m.DB.Query(ctx, `
+		SELECT h.id, h.assessment_id, a.name, h.student_id, h.before, h.after, h.editor_email, h.created_at
+		FROM score_history h JOIN assessments a ON a.id = h.assessment_id
+		WHERE a.course_id = $1 AND a.term_id = $2 AND h.student_id = $3
+		ORDER BY h.id DESC`, courseID, termID, studentID)
+	if err != nil {
+		return nil, translateErr(ctx, err)
+	}
+	defer rows.Close()
+
+	var changes []models.ScoreChange
+	for rows.Next() {
+		var c models.ScoreChange
+		if err := rows.Scan(&c.ID, &c.AssessmentID, &c.AssessmentName, &c.StudentID, &c.Before, &c.After, &c.EditorEmail, &c.CreatedAt); err != nil {
+			return nil, translateErr(ctx, err)
+		}
+		changes = append(changes, c)
+	}
+	return changes, translateErr(ctx, rows.Err())
+}
+
+// GetGradesPublished returns when the course's grades for the term were
+// published, or nil while they aren't
+func (m *neonDBRepo) GetGradesPublished(ctx context.Context, courseID, termID string) (*time.Time, error) {
+	ctx, cancel := m.withTimeout(ctx)
+	defer cancel()
+
+	var published *time.Time
+	err := m.DB.QueryRow(ctx, "SELECT published_at FROM gradebooks WHERE course_id = $1 AND term_id = $2", courseID, termID).Scan(&published)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	return published, translateErr(ctx, err)
+}
+
+// SetGradesPublished shows the course's grades for the term to its students,
+// or hides them again
+func (m *neonDBRepo) SetGradesPublished(ctx context.Context, courseID, termID string, published bool) error {
+	ctx, cancel := m.withTimeout(ctx)
+	defer cancel()
+
+	_, err := m.DB.Exec(ctx, `
+		INSERT INTO gradebooks (course_id, term_id, published_at) VALUES ($1, $2, CASE WHEN $3::boolean THEN now() END)
+		ON CONFLICT (course_id, term_id) DO UPDATE SET published_at = EXCLUDED.published_at`,
+		courseID, termID, published)
+	if isForeignKeyViolation(err) {
+		return repository.ErrNotFound
+	}
+	return translateErr(ctx, err)
+}
+
+// publishedGradebooks is the (course, term) pairs the student $1 is
+// enrolled in whose grades are published
+const publishedGradebooks = `
+	SELECT e.course_id, e.term_id
+	FROM enrollments e
+	JOIN gradebooks g ON g.course_id = e.course_id AND g.term_id = e.term_id AND g.published_at IS NOT NULL
+	WHERE e.student_id = $1 AND e.status = 'enrolled'`
+
+// GetPublishedGrades returns the student's published results in the courses
+// they are enrolled in, newest term first
+func (m *neonDBRepo) GetPublishedGrades(ctx context.Context, studentID string) ([]models.CourseGrade, error) {
+	ctx, cancel := m.withTimeout(ctx)
+	defer cancel()
+
+	rows, err := m.DB.Query(ctx, `
+		WITH p AS (`+publishedGradebooks+`)
+		SELECT c.id, c.code, c.title, c.credit_units, t.id, t.name
+		FROM p
+		JOIN courses c ON c.id = p.course_id
+		JOIN terms t ON t.id = p.term_id
+		ORDER BY t.start_date DESC, c.code`, studentID)
+	if err != nil {
+		return nil, translateErr(ctx, err)
+	}
+	grades, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (models.CourseGrade, error) {
+		var g models.CourseGrade
+		err := row.Scan(&g.CourseID, &g.CourseCode, &g.CourseTitle, &g.CreditUnits, &g.TermID, &g.TermName)
+		return g, err
+	})
+	if err != nil || len(grades) == 0 {
+		return grades, translateErr(ctx, err)
+	}
+
+	type key struct{ courseID, termID string }
+	byKey := make(map[key]*models.CourseGrade, len(grades))
+	for i := range grades {
+		grades[i].Scores = models.Scores{}
+		byKey[key{grades[i].CourseID, grades[i].TermID}] = &grades[i]
+	}
+
+	// The assessments of every published course come back with this
+	// student's scores in one query, rather than one per course
+	rows, err = m.DB.Query(ctx, `
+		WITH p AS (`+publishedGradebooks+`)
+		SELECT a.id, a.course_id, a.term_id, a.name, a.category, a.weight, a.max_score, a.position, a.created_at, s.score
+		FROM p
+		JOIN assessments a ON a.course_id = p.course_id AND a.term_id = p.term_id
+		LEFT JOIN scores s ON s.assessment_id = a.id AND s.student_id = $1
+		ORDER BY a.position, a.created_at`, studentID)
+	if err != nil {
+		return nil, translateErr(ctx, err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var a models.Assessment
+		var score *float64
+		if err := rows.Scan(append(assessmentDest(&a), &score)...); err != nil {
+			return nil, translateErr(ctx, err)
+		}
+		g, ok := byKey[key{a.CourseID, a.TermID}]
+		if !ok {
+			continue
+		}
+		g.Assessments = append(g.Assessments, a)
+		if score != nil {
+			g.Scores[a.ID] = *score
+		}
+	}
+	return grades, translateErr(ctx, rows.Err())
+}
@@ -0,0 +1,101 @@
+package dbrepo
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/stackninja.pro/goth/internals/models"
+	"github.com/stackninja.pro/goth/internals/repository"
+)
+
+// failureColumns is what every login_failures query selects
+const failureColumns = "f.user_id, f.failures, f.lockouts, f.last_failure_at, f.locked_until"
+
+// failureDest returns the scan destinations for failureColumns
+func failureDest(f *models.LoginFailures) []any {
+	return []any{&f.UserID, &f.Failures, &f.Lockouts, &f.LastFailureAt, &f.LockedUntil}
+}
+
+// GetLoginFailures returns the account's failed login record, which is empty
+// when the last login succeeded
+func (m *neonDBRepo) GetLoginFailures(ctx context.Context, userID string) (models.LoginFailures, error) {
+	ctx, cancel := m.withTimeout(ctx)
+	defer cancel()
+
+	f := models.LoginFailures{UserID: userID}
+	err := m.DB.QueryRow(ctx, "SELECT "+failureColumns+" FROM login_failures f WHERE f.user_id = $1", userID).Scan(failureDest(&f)...)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		return f, translateErr(ctx, err)
+	}
+	return f, nil
+}
+
+// RecordLoginFailure counts a wrong password and returns the updated record
+func (m *neonDBRepo) RecordLoginFailure(ctx context.Context, userID string) (models.LoginFailures, error) {
+	ctx, cancel := m.withTimeout(ctx)
+	defer cancel()
+
+	var f models.LoginFailures
+	err := m.DB.QueryRow(ctx, `
+		INSERT INTO login_failures AS f (user_id, failures, last_failure_at) VALUES ($1, 1, NOW())
+		ON CONFLICT (user_id) DO UPDATE SET failures = f.failures + 1, last_failure_at = NOW()
+		RETURNING `+failureColumns, userID).Scan(failureDest(&f)...)
+	if isForeignKeyViolation(err) {
+		return f, repository.ErrNotFound
+	}
+	return f, translateErr(ctx, err)
+}
+
+// LockAccount refuses logins to the account until the given time. The failure
+// count starts again so the delays restart once the lockout ends.
+func (m *neonDBRepo) LockAccount(ctx context.Context, userID string, until time.Time) error {
+	ctx, cancel := m.withTimeout(ctx)
+	defer cancel()
+
+	tag, err := m.DB.Exec(ctx, "UPDATE login_failures SET failures = 0, lockouts = lockouts + 1, locked_until = $2 WHERE user_id = $1", userID, until)
+	if err != nil {
+		return translateErr(ctx, err)
+	}
+	if tag.RowsAffected() == 0 {
+		return repository.ErrNotFound
+	}
+	return nil
+}
+
+// ClearLoginFailures forgets the account's failures and lifts any lockout
+func (m *neonDBRepo) ClearLoginFailures(ctx context.Context, userID string) error {
+	ctx, cancel := m.withTimeout(ctx)
+	defer cancel()
+
+	_, err := m.DB.Exec(ctx, "DELETE FROM login_failures WHERE user_id = $1", userID)
+	return translateErr(ctx, err)
+}
+
+// GetLockedAccounts lists the accounts that are locked now, soonest to unlock first
+func (m *neonDBRepo) GetLockedAccounts(ctx context.Context) ([]models.LoginFailures, error) {
+	ctx, cancel := m.withTimeout(ctx)
+	defer cancel()
+
+	rows, err := m.DB.Query(ctx, `
+		SELECT `+failureColumns+`, u.email, u.name
+		FROM login_failures f JOIN users u ON u.id = f.user_id
+		WHERE f.locked_until > NOW()
+		ORDER BY f.locked_until, u.email
+	`)
+	if err != nil {
+		return nil, translateErr(ctx, err)
+	}
+	defer rows.Close()
+
+	var locked []models.LoginFailures
+	for rows.Next() {
+		var f models.LoginFailures
+		if err := rows.Scan(append(failureDest(&f), &f.Email, &f.Name)...); err != nil {
+			return nil, translateErr(ctx, err)
+		}
+		locked = append(locked, f)
+	}
+	return locked, translateErr(ctx, rows.Err())
+}
@@ -0,0 +1,2398 @@
+package dbrepo
+
+import (
+	"context"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stackninja.pro/goth/internals/models"
+	"github.com/stackninja.pro/goth/internals/repository"
+	"github.com/stackninja.pro/goth/src/config"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// memoryDBRepo keeps everything in maps. It mirrors neonDBRepo closely enough
+// to stand in for it in tests and offline development.
+type memoryDBRepo struct {
+	App *config.AppConfig
+
+	mu     sync.RWMutex
+	users  map[string]models.User
+	resets map[string]passwordReset
+	prefs  map[string]models.Preferences
+	totp   map[string]totpState
+	policy map[models.Role]bool
+	fails  map[string]models.LoginFailures
+	audit  []models.AuditEvent // oldest first; only ever appended to
+
+	courses     map[string]models.Course
+	sessions    map[string]models.AcademicSession
+	terms       map[string]models.Term
+	enrollments map[string]models.Enrollment // position is the raw waitlist order, not the rank
+
+	assessments  map[string]models.Assessment
+	scores       map[scoreKey]float64
+	scoreHistory []models.ScoreChange       // oldest first; only ever appended to
+	gradebooks   map[gradebookKey]time.Time // published gradebooks only
+
+	scales            map[string]models.GradingScale
+	programmes        map[string]models.Programme
+	programmeStudents map[string]string // student ID → programme ID
+}
+
+// scoreKey is the primary key of the scores table
+type scoreKey struct{ assessmentID, studentID string }
+
+// gradebookKey is the primary key of the gradebooks table
+type gradebookKey struct{ courseID, termID string }
+
+// totpState is a user's TOTP columns and recovery_codes rows
+type totpState struct {
+	secret      string
+	lastCounter int64
+	recovery    map[string]bool // code hash → used
+}
+
+// passwordReset is a row of the password_resets table
+type passwordReset struct {
+	userID    string
+	expiresAt time.Time
+	used      bool
+}
+
+// NewMemoryRepo creates an in-memory repository holding only the standard
+// grading scales. It is a repository.CourseRepo, repository.TermRepo,
+// repository.GradeRepo and repository.ScaleRepo as well.
+func NewMemoryRepo(a *config.AppConfig) repository.DatabaseRepo {
+	m := &memoryDBRepo{
+		App:    a,
+		users:  map[string]models.User{},
+		resets: map[string]passwordReset{},
+		prefs:  map[string]models.Preferences{},
+		totp:   map[string]totpState{},
+		policy: map[models.Role]bool{},
+		fails:  map[string]models.LoginFailures{},
+
+		courses:     map[string]models.Course{},
+		sessions:    map[string]models.AcademicSession{},
+		terms:       map[string]models.Term{},
+		enrollments: map[string]models.Enrollment{},
+
+		assessments: map[string]models.Assessment{},
+		scores:      map[scoreKey]float64{},
+		gradebooks:  map[gradebookKey]time.Time{},
+
+		scales:            map[string]models.GradingScale{},
+		programmes:        map[string]models.Programme{},
+		programmeStudents: map[string]string{},
+	}
+	// the standard scales migration 0016 seeds
+	for _, scale := range models.StandardScales() {
+		scale.ID = uuid.NewString()
+		scale.CreatedAt = time.Now()
+		m.scales[scale.ID] = scale
+	}
+	return m
+}
+
+// GetAllUsers returns one page of the users matching q, without password
+// hashes, and how many match in total
+func (m *memoryDBRepo) GetAllUsers(ctx context.Context, q models.UserQuery) ([]models.User, int, error) {
+	if err := checkCtx(ctx); err != nil {
+		return nil, 0, err
+	}
+
+	q = q.Normalize()
+	users := m.matchingUsers(q)
+
+	total := len(users)
+	start := min(q.Offset(), total)
+	end := min(start+q.PerPage, total)
+	return users[start:end], total, nil
+}
+
+// ForEachUser calls fn with every user matching q, in q's order and without
+// password hashes. Paging in q is ignored.
+func (m *memoryDBRepo) ForEachUser(ctx context.Context, q models.UserQuery, fn func(models.User) error) error {
+	for _, u := range m.matchingUsers(q.Normalize()) {
+		if err := checkCtx(ctx); err != nil {
+			return err
+		}
+		if err := fn(u); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// matchingUsers copies out the users matching q, sorted, without password hashes
+func (m *memoryDBRepo) matchingUsers(q models.UserQuery) []models.User {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var users []models.User
+	for _, u := range m.users {
+		if matchesUserQuery(u, q) {
+			u.Password = ""
+			users = append(users, u)
+		}
+	}
+
+	sort.Slice(users, func(i, j int) bool {
+		a, b := users[i], users[j]
+		if q.Desc {
+			a, b = b, a
+		}
+		if c := compareUsers(a, b, q.Sort); c != 0 {
+			return c < 0
+		}
+		return users[i].ID < users[j].ID
+	})
+	return users
+}
+
+// matchesUserQuery applies the same filters as the Postgres userFilter
+func matchesUserQuery(u models.User, q models.UserQuery) bool {
+	if q.Search != "" {
+		s := strings.ToLower(q.Search)
+		if !strings.Contains(strings.ToLower(u.Name), s) && !strings.Contains(strings.ToLower(u.Email), s) {
+			return false
+		}
+	}
+	if q.Role != "" && u.Role != q.Role {
+		return false
+	}
+	if q.Status != "" && u.Status != q.Status {
+		return false
+	}
+	if !q.CreatedFrom.IsZero() && u.CreatedAt.Before(q.CreatedFrom) {
+		return false
+	}
+	if !q.CreatedTo.IsZero() && !u.CreatedAt.Before(q.CreatedTo.AddDate(0, 0, 1)) {
+		return false
+	}
+	if len(q.IDs) > 0 && !slices.Contains(q.IDs, u.ID) {
+		return false
+	}
+	return u.Deleted() == q.Deleted
+}
+
+// compareUsers orders two users by one of models.UserSortColumns
+func compareUsers(a, b models.User, column string) int {
+	switch column {
+	case "name":
+		return strings.Compare(a.Name, b.Name)
+	case "email":
+		return strings.Compare(a.Email, b.Email)
+	case "role":
+		return strings.Compare(string(a.Role), string(b.Role))
+	case "status":
+		return strings.Compare(string(a.Status), string(b.Status))
+	default:
+		return a.CreatedAt.Compare(b.CreatedAt)
+	}
+}
+
+// GetUserByID retrieves a user by their ID, with their preferences
+func (m *memoryDBRepo) GetUserByID(ctx context.Context, id string) (*models.User, error) {
+	if err := checkCtx(ctx); err != nil {
+		return nil, err
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	user, ok := m.users[id]
+	if !ok {
+		return nil, repository.ErrNotFound
+	}
+
+	user.Password = ""
+	user.Preferences = m.preferences(id)
+	user.TwoFactorRequired = m.policy[user.Role]
+	user.DOBFormatted = user.Preferences.FormatDate(user.DOB)
+	return &user, nil
+}
+
+// GetUserByEmail retrieves a user by their email, including the password
+// hash. Suspended, deactivated and deleted accounts are refused with ErrInactive.
+func (m *memoryDBRepo) GetUserByEmail(ctx context.Context, email string) (*models.User, error) {
+	user, err := m.userByEmail(ctx, email)
+	if err != nil {
+		return nil, err
+	}
+	if !user.Active() {
+		return nil, repository.ErrInactive
+	}
+	return user, nil
+}
+
+// userByEmail finds a user with their password hash, whatever their status
+func (m *memoryDBRepo) userByEmail(ctx context.Context, email string) (*models.User, error) {
+	if err := checkCtx(ctx); err != nil {
+		return nil, err
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	user, ok := m.findByEmail(email)
+	if !ok {
+		return nil, repository.ErrNotFound
+	}
+	return &user, nil
+}
+
+// CreateUser stores a new user with a fresh ID
+func (m *memoryDBRepo) CreateUser(ctx context.Context, user models.User) error {
+	if err := checkCtx(ctx); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, taken := m.findByEmail(user.Email); taken {
+		return repository.ErrDuplicateEmail
+	}
+	if err := newUser(&user); err != nil {
+		return err
+	}
+
+	m.users[user.ID] = user
+	return nil
+}
+
+// CreateUsers inserts every user or, if any address is taken, none of them
+func (m *memoryDBRepo) CreateUsers(ctx context.Context, users []models.User) ([]models.User, error) {
+	if err := checkCtx(ctx); err != nil {
+		return nil, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	seen := map[string]bool{}
+	for _, user := range users {
+		if _, taken := m.findByEmail(user.Email); taken || seen[user.Email] {
+			return nil, repository.ErrDuplicateEmail
+		}
+		seen[user.Email] = true
+	}
+
+	created := make([]models.User, len(users))
+	for i, user := range users {
+		if err := newUser(&user); err != nil {
+			return nil, err
+		}
+		created[i] = user
+	}
+	for _, user := range created {
+		m.users[user.ID] = user
+	}
+	return created, nil
+}
+
+// UpdateUser replaces the editable profile fields
+func (m *memoryDBRepo) UpdateUser(ctx context.Context, id string, user models.User) error {
+	if err := checkCtx(ctx); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	existing, ok := m.users[id]
+	if !ok {
+		return repository.ErrNotFound
+	}
+	if other, taken := m.findByEmail(user.Email); taken && other.ID != id {
+		return repository.ErrDuplicateEmail
+	}
+
+	existing.Name = user.Name
+	existing.Email = user.Email
+	existing.DOB = user.DOB
+	existing.Bio = user.Bio
+	existing.UpdatedAt = time.Now()
+
+	m.users[id] = existing
+	return nil
+}
+
+// UpdatePassword replaces the user's password hash
+func (m *memoryDBRepo) UpdatePassword(ctx context.Context, id, passwordHash string) error {
+	if err := checkCtx(ctx); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	user, ok := m.users[id]
+	if !ok {
+		return repository.ErrNotFound
+	}
+
+	user.Password = passwordHash
+	user.UpdatedAt = time.Now()
+	m.users[id] = user
+	return nil
+}
+
+// UpdateUserRole changes the user's role
+func (m *memoryDBRepo) UpdateUserRole(ctx context.Context, id string, role models.Role) error {
+	if err := checkCtx(ctx); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	user, ok := m.users[id]
+	if !ok {
+		return repository.ErrNotFound
+	}
+
+	user.Role = role
+	user.UpdatedAt = time.Now()
+	m.users[id] = user
+	return nil
+}
+
+// UpdateUsersRole changes the role of every listed user and reports how many changed
+func (m *memoryDBRepo) UpdateUsersRole(ctx context.Context, ids []string, role models.Role) (int64, error) {
+	return m.updateUsers(ctx, ids, func(u *models.User) { u.Role = role })
+}
+
+// UpdateUsersStatus changes the status of every listed user and reports how many changed
+func (m *memoryDBRepo) UpdateUsersStatus(ctx context.Context, ids []string, status models.Status) (int64, error) {
+	return m.updateUsers(ctx, ids, func(u *models.User) { u.Status = status })
+}
+
+// updateUsers applies change to every listed user that exists
+func (m *memoryDBRepo) updateUsers(ctx context.Context, ids []string, change func(*models.User)) (int64, error) {
+	if err := checkCtx(ctx); err != nil {
+		return 0, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var n int64
+	for _, id := range slices.Compact(slices.Sorted(slices.Values(ids))) {
+		user, ok := m.users[id]
+		if !ok {
+			continue
+		}
+		change(&user)
+		user.UpdatedAt = time.Now()
+		m.users[id] = user
+		n++
+	}
+	return n, nil
+}
+
+// UpdateUserAvatar stores the avatar URL and the storage key it was saved under
+func (m *memoryDBRepo) UpdateUserAvatar(ctx context.Context, userID, url, key string) error {
+	if err := checkCtx(ctx); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	user, ok := m.users[userID]
+	if !ok {
+		return repository.ErrNotFound
+	}
+
+	user.Avatar = url
+	user.AvatarKey = key
+	user.UpdatedAt = time.Now()
+	m.users[userID] = user
+	return nil
+}
+
+// DeleteUser marks the user deleted until PurgeDeletedUsers removes them
+func (m *memoryDBRepo) DeleteUser(ctx context.Context, id string) error {
+	return m.setDeletedAt(ctx, id, true)
+}
+
+// RestoreUser undoes DeleteUser
+func (m *memoryDBRepo) RestoreUser(ctx context.Context, id string) error {
+	return m.setDeletedAt(ctx, id, false)
+}
+
+// setDeletedAt stamps or clears deleted_at, failing with ErrNotFound when the
+// user doesn't exist or is already in that state
+func (m *memoryDBRepo) setDeletedAt(ctx context.Context, id string, deleted bool) error {
+	if err := checkCtx(ctx); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	user, ok := m.users[id]
+	if !ok || user.Deleted() == deleted || user.Anonymized() {
+		return repository.ErrNotFound
+	}
+
+	now := time.Now()
+	user.DeletedAt = nil
+	if deleted {
+		user.DeletedAt = &now
+	}
+	user.UpdatedAt = now
+	m.users[id] = user
+	return nil
+}
+
+// PurgeDeletedUsers removes the users deleted before the cutoff and
+// everything kept for them. Users with academic records keep their row,
+// anonymised, for the records to point at.
+func (m *memoryDBRepo) PurgeDeletedUsers(ctx context.Context, before time.Time) ([]models.User, error) {
+	if err := checkCtx(ctx); err != nil {
+		return nil, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var purged []models.User
+	for id, user := range m.users {
+		if !user.Deleted() || !user.DeletedAt.Before(before) || user.Anonymized() {
+			continue
+		}
+
+		delete(m.prefs, id)
+		delete(m.totp, id)
+		delete(m.fails, id)
+		for hash, r := range m.resets {
+			if r.userID == id {
+				delete(m.resets, hash)
+			}
+		}
+
+		if m.hasAcademicRecords(id) {
+			now := time.Now()
+			kept := user
+			kept.Email = id + "@" + models.AnonymizedDomain
+			kept.PendingEmail, kept.Password, kept.Name, kept.Bio = "", "", models.AnonymizedName, ""
+			kept.Avatar, kept.AvatarKey, kept.TOTPEnabledAt = "", "", nil
+			kept.AnonymizedAt, kept.UpdatedAt = &now, now
+			m.users[id] = kept
+		} else {
+			delete(m.users, id)
+		}
+		user.Password = ""
+		purged = append(purged, user)
+	}
+	return purged, nil
+}
+
+// hasAcademicRecords reports whether the user's enrollments and grades must
+// outlive their account; callers must hold the lock
+func (m *memoryDBRepo) hasAcademicRecords(userID string) bool {
+	for _, e := range m.enrollments {
+		if e.StudentID == userID {
+			return true
+		}
+	}
+	for k := range m.scores {
+		if k.studentID == userID {
+			return true
+		}
+	}
+	return slices.ContainsFunc(m.scoreHistory, func(c models.ScoreChange) bool { return c.StudentID == userID })
+}
+
+// AuthenticateUser checks the password against the stored bcrypt hash, for
+// any account whatever its status
+func (m *memoryDBRepo) AuthenticateUser(ctx context.Context, email, password string) (*models.User, error) {
+	user, err := m.userByEmail(ctx, email)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(password)); err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
+
+// GetPreferences returns the user's preferences, or the defaults if they
+// haven't saved any
+func (m *memoryDBRepo) GetPreferences(ctx context.Context, userID string) (models.Preferences, error) {
+	if err := checkCtx(ctx); err != nil {
+		return models.Preferences{}, err
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if _, ok := m.users[userID]; !ok {
+		return models.Preferences{}, repository.ErrNotFound
+	}
+	return m.preferences(userID), nil
+}
+
+// SavePreferences stores the user's preferences, replacing any saved before
+func (m *memoryDBRepo) SavePreferences(ctx context.Context, p models.Preferences) error {
+	if err := checkCtx(ctx); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.users[p.UserID]; !ok {
+		return repository.ErrNotFound
+	}
+	m.prefs[p.UserID] = p
+	return nil
+}
+
+// preferences returns the saved or default preferences; callers must hold the lock
+func (m *memoryDBRepo) preferences(userID string) models.Preferences {
+	if p, ok := m.prefs[userID]; ok {
+		return p
+	}
+	p := models.DefaultPreferences()
+	p.UserID = userID
+	return p
+}
+
+// VerifyEmail marks the user's address as confirmed. It returns ErrNotFound
+// when the user's address is no longer email.
+func (m *memoryDBRepo) VerifyEmail(ctx context.Context, id, email string) error {
+	if err := checkCtx(ctx); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	user, ok := m.users[id]
+	if !ok || user.Email != email {
+		return repository.ErrNotFound
+	}
+
+	now := time.Now()
+	if user.EmailVerifiedAt == nil {
+		user.EmailVerifiedAt = &now
+	}
+	user.UpdatedAt = now
+	m.users[id] = user
+	return nil
+}
+
+// SetPendingEmail records an address the user wants to switch to, or
+// clears it when email is empty
+func (m *memoryDBRepo) SetPendingEmail(ctx context.Context, id, email string) error {
+	if err := checkCtx(ctx); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	user, ok := m.users[id]
+	if !ok {
+		return repository.ErrNotFound
+	}
+	if other, taken := m.findByEmail(email); email != "" && taken && other.ID != id {
+		return repository.ErrDuplicateEmail
+	}
+
+	user.PendingEmail = email
+	user.UpdatedAt = time.Now()
+	m.users[id] = user
+	return nil
+}
+
+// ConfirmEmailChange swaps in the pending address, which counts as verified
+func (m *memoryDBRepo) ConfirmEmailChange(ctx context.Context, id, email string) error {
+	if err := checkCtx(ctx); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	user, ok := m.users[id]
+	if !ok || user.PendingEmail == "" || user.PendingEmail != email {
+		return repository.ErrNotFound
+	}
+	if other, taken := m.findByEmail(email); taken && other.ID != id {
+		return repository.ErrDuplicateEmail
+	}
+
+	now := time.Now()
+	user.Email = email
+	user.PendingEmail = ""
+	user.EmailVerifiedAt = &now
+	user.UpdatedAt = now
+	m.users[id] = user
+	return nil
+}
+
+// EnableTwoFactor turns on TOTP with secret and replaces the user's recovery
+// codes. counter is the step of the code that proved the secret works.
+func (m *memoryDBRepo) EnableTwoFactor(ctx context.Context, userID, secret string, counter int64, codeHashes []string) error {
+	if err := checkCtx(ctx); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	user, ok := m.users[userID]
+	if !ok {
+		return repository.ErrNotFound
+	}
+
+	now := time.Now()
+	user.TOTPEnabledAt = &now
+	user.UpdatedAt = now
+	m.users[userID] = user
+	m.totp[userID] = totpState{secret: secret, lastCounter: counter, recovery: recoveryCodes(codeHashes)}
+	return nil
+}
+
+// DisableTwoFactor forgets the user's TOTP secret and recovery codes
+func (m *memoryDBRepo) DisableTwoFactor(ctx context.Context, userID string) error {
+	if err := checkCtx(ctx); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	user, ok := m.users[userID]
+	if !ok {
+		return repository.ErrNotFound
+	}
+
+	user.TOTPEnabledAt = nil
+	user.UpdatedAt = time.Now()
+	m.users[userID] = user
+	delete(m.totp, userID)
+	return nil
+}
+
+// GetTOTPSecret returns the user's secret and the last time step used to sign
+// in, or ErrNotFound when two-factor authentication is off
+func (m *memoryDBRepo) GetTOTPSecret(ctx context.Context, userID string) (string, int64, error) {
+	if err := checkCtx(ctx); err != nil {
+		return "", 0, err
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	state, ok := m.totp[userID]
+	if !ok || m.users[userID].TOTPEnabledAt == nil {
+		return "", 0, repository.ErrNotFound
+	}
+	return state.secret, state.lastCounter, nil
+}
+
+// UseTOTPCounter records that the code for counter has been used. It returns
+// ErrNotFound when that step or a later one was used already.
+func (m *memoryDBRepo) UseTOTPCounter(ctx context.Context, userID string, counter int64) error {
+	if err := checkCtx(ctx); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	state, ok := m.totp[userID]
+	if !ok || state.lastCounter >= counter {
+		return repository.ErrNotFound
+	}
+	state.lastCounter = counter
+	m.totp[userID] = state
+	return nil
+}
+
+// UseRecoveryCode spends one of the user's recovery codes, or returns
+// ErrNotFound when it is unknown or used
+func (m *memoryDBRepo) UseRecoveryCode(ctx context.Context, userID, codeHash string) error {
+	if err := checkCtx(ctx); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	state, ok := m.totp[userID]
+	if used, known := state.recovery[codeHash]; !ok || !known || used {
+		return repository.ErrNotFound
+	}
+	state.recovery[codeHash] = true
+	return nil
+}
+
+// ReplaceRecoveryCodes swaps the user's recovery codes for a new set
+func (m *memoryDBRepo) ReplaceRecoveryCodes(ctx context.Context, userID string, codeHashes []string) error {
+	if err := checkCtx(ctx); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.users[userID]; !ok {
+		return repository.ErrNotFound
+	}
+	state := m.totp[userID]
+	state.recovery = recoveryCodes(codeHashes)
+	m.totp[userID] = state
+	return nil
+}
+
+// CountRecoveryCodes returns how many of the user's recovery codes are unused
+func (m *memoryDBRepo) CountRecoveryCodes(ctx context.Context, userID string) (int, error) {
+	if err := checkCtx(ctx); err != nil {
+		return 0, err
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	n := 0
+	for _, used := range m.totp[userID].recovery {
+		if !used {
+			n++
+		}
+	}
+	return n, nil
+}
+
+// recoveryCodes makes an unused set of recovery codes
+func recoveryCodes(hashes []string) map[string]bool {
+	codes := make(map[string]bool, len(hashes))
+	for _, h := range hashes {
+		codes[h] = false
+	}
+	return codes
+}
+
+// GetTwoFactorPolicy returns which roles must use two-factor authentication
+func (m *memoryDBRepo) GetTwoFactorPolicy(ctx context.Context) (map[models.Role]bool, error) {
+	if err := checkCtx(ctx); err != nil {
+		return nil, err
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	policy := make(map[models.Role]bool, len(m.policy))
+	for role, required := range m.policy {
+		policy[role] = required
+	}
+	return policy, nil
+}
+
+// SetTwoFactorRequired sets whether members of role must use two-factor
+// authentication
+func (m *memoryDBRepo) SetTwoFactorRequired(ctx context.Context, role models.Role, required bool) error {
+	if err := checkCtx(ctx); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.policy[role] = required
+	return nil
+}
+
+// GetLoginFailures returns the account's failed login record, which is empty
+// when the last login succeeded
+func (m *memoryDBRepo) GetLoginFailures(ctx context.Context, userID string) (models.LoginFailures, error) {
+	if err := checkCtx(ctx); err != nil {
+		return models.LoginFailures{}, err
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if f, ok := m.fails[userID]; ok {
+		return f, nil
+	}
+	return models.LoginFailures{UserID: userID}, nil
+}
+
+// RecordLoginFailure counts a wrong password and returns the updated record
+func (m *memoryDBRepo) RecordLoginFailure(ctx context.Context, userID string) (models.LoginFailures, error) {
+	if err := checkCtx(ctx); err != nil {
+		return models.LoginFailures{}, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.users[userID]; !ok {
+		return models.LoginFailures{}, repository.ErrNotFound
+	}
+
+	f, ok := m.fails[userID]
+	if !ok {
+		f.UserID = userID
+	}
+	f.Failures++
+	f.LastFailureAt = time.Now()
+	m.fails[userID] = f
+	return f, nil
+}
+
+// LockAccount refuses logins to the account until the given time. The failure
+// count starts again so the delays restart once the lockout ends.
+func (m *memoryDBRepo) LockAccount(ctx context.Context, userID string, until time.Time) error {
+	if err := checkCtx(ctx); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	f, ok := m.fails[userID]
+	if !ok {
+		return repository.ErrNotFound
+	}
+	f.Failures = 0
+	f.Lockouts++
+	f.LockedUntil = &until
+	m.fails[userID] = f
+	return nil
+}
+
+// ClearLoginFailures forgets the account's failures and lifts any lockout
+func (m *memoryDBRepo) ClearLoginFailures(ctx context.Context, userID string) error {
+	if err := checkCtx(ctx); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.fails, userID)
+	return nil
+}
+
+// GetLockedAccounts lists the accounts that are locked now, soonest to unlock first
+func (m *memoryDBRepo) GetLockedAccounts(ctx context.Context) ([]models.LoginFailures, error) {
+	if err := checkCtx(ctx); err != nil {
+		return nil, err
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	now := time.Now()
+	var locked []models.LoginFailures
+	for id, f := range m.fails {
+		if f.Locked(now) {
+			f.Email = m.users[id].Email
+			f.Name = m.users[id].Name
+			locked = append(locked, f)
+		}
+	}
+	sort.Slice(locked, func(i, j int) bool {
+		if c := locked[i].LockedUntil.Compare(*locked[j].LockedUntil); c != 0 {
+			return c < 0
+		}
+		return locked[i].Email < locked[j].Email
+	})
+	return locked, nil
+}
+
+// RecordAuditEvent appends an event to the audit log
+func (m *memoryDBRepo) RecordAuditEvent(ctx context.Context, e models.AuditEvent) error {
+	if err := checkCtx(ctx); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	e.ID = int64(len(m.audit) + 1)
+	e.CreatedAt = time.Now()
+	e.Changes = slices.Clone(e.Changes)
+	m.audit = append(m.audit, e)
+	return nil
+}
+
+// GetAuditEvents returns one page of the events matching q, newest first,
+// and how many match in total
+func (m *memoryDBRepo) GetAuditEvents(ctx context.Context, q models.AuditQuery) ([]models.AuditEvent, int, error) {
+	if err := checkCtx(ctx); err != nil {
+		return nil, 0, err
+	}
+
+	q = q.Normalize()
+	events := m.matchingAuditEvents(q)
+
+	total := len(events)
+	start := min(q.Offset(), total)
+	end := min(start+q.PerPage, total)
+	return events[start:end], total, nil
+}
+
+// ForEachAuditEvent calls fn with every event matching q, newest first.
+// Paging in q is ignored.
+func (m *memoryDBRepo) ForEachAuditEvent(ctx context.Context, q models.AuditQuery, fn func(models.AuditEvent) error) error {
+	for _, e := range m.matchingAuditEvents(q.Normalize()) {
+		if err := checkCtx(ctx); err != nil {
+			return err
+		}
+		if err := fn(e); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// matchingAuditEvents copies out the events matching q, newest first
+func (m *memoryDBRepo) matchingAuditEvents(q models.AuditQuery) []models.AuditEvent {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	s := strings.ToLower(q.Search)
+	var events []models.AuditEvent
+	for i := len(m.audit) - 1; i >= 0; i-- {
+		e := m.audit[i]
+		if s != "" && !strings.Contains(strings.ToLower(e.ActorEmail), s) && !strings.Contains(strings.ToLower(e.TargetEmail), s) {
+			continue
+		}
+		if q.Action != "" && e.Action != q.Action {
+			continue
+		}
+		if !q.From.IsZero() && e.CreatedAt.Before(q.From) {
+			continue
+		}
+		if !q.To.IsZero() && !e.CreatedAt.Before(q.To.AddDate(0, 0, 1)) {
+			continue
+		}
+		events = append(events, e)
+	}
+	return events
+}
+
+// CreatePasswordReset stores the hash of a reset token issued to the user
+func (m *memoryDBRepo) CreatePasswordReset(ctx context.Context, userID, tokenHash string, expiresAt time.Time) error {
+	if err := checkCtx(ctx); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.users[userID]; !ok {
+		return repository.ErrNotFound
+	}
+
+	m.resets[tokenHash] = passwordReset{userID: userID, expiresAt: expiresAt}
+	return nil
+}
+
+// GetPasswordReset returns the user a reset token belongs to, or
+// ErrNotFound when it is unknown, used or expired
+func (m *memoryDBRepo) GetPasswordReset(ctx context.Context, tokenHash string) (string, error) {
+	if err := checkCtx(ctx); err != nil {
+		return "", err
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	reset, ok := m.resets[tokenHash]
+	if !ok || reset.used || !time.Now().Before(reset.expiresAt) {
+		return "", repository.ErrNotFound
+	}
+	return reset.userID, nil
+}
+
+// ResetPassword redeems a reset token and sets the new password hash. The
+// user's other outstanding tokens are spent too.
+func (m *memoryDBRepo) ResetPassword(ctx context.Context, tokenHash, passwordHash string) (string, error) {
+	if err := checkCtx(ctx); err != nil {
+		return "", err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	reset, ok := m.resets[tokenHash]
+	if !ok || reset.used || !time.Now().Before(reset.expiresAt) {
+		return "", repository.ErrNotFound
+	}
+	user, ok := m.users[reset.userID]
+	if !ok {
+		return "", repository.ErrNotFound
+	}
+
+	user.Password = passwordHash
+	user.UpdatedAt = time.Now()
+	m.users[user.ID] = user
+
+	for hash, r := range m.resets {
+		if r.userID == user.ID {
+			r.used = true
+			m.resets[hash] = r
+		}
+	}
+	return user.ID, nil
+}
+
+// GetCourses returns one page of the courses matching q, ordered by code,
+// and how many match in total
+func (m *memoryDBRepo) GetCourses(ctx context.Context, q models.CourseQuery) ([]models.Course, int, error) {
+	if err := checkCtx(ctx); err != nil {
+		return nil, 0, err
+	}
+
+	q = q.Normalize()
+	m.mu.RLock()
+	var courses []models.Course
+	for _, c := range m.courses {
+		if matchesCourseQuery(c, q) {
+			courses = append(courses, m.withInstructors(c))
+		}
+	}
+	m.mu.RUnlock()
+	sort.Slice(courses, func(i, j int) bool { return courses[i].Code < courses[j].Code })
+
+	total := len(courses)
+	start := min(q.Offset(), total)
+	end := min(start+q.PerPage, total)
+	return courses[start:end], total, nil
+}
+
+// matchesCourseQuery applies the filters of q to one course
+func matchesCourseQuery(c models.Course, q models.CourseQuery) bool {
+	if q.Search != "" {
+		s := strings.ToLower(q.Search)
+		if !strings.Contains(strings.ToLower(c.Code), s) && !strings.Contains(strings.ToLower(c.Title), s) && !strings.Contains(strings.ToLower(c.Description), s) {
+			return false
+		}
+	}
+	if q.Department != "" && c.Department != q.Department {
+		return false
+	}
+	if q.Status != "" && c.Status != q.Status {
+		return false
+	}
+	if q.InstructorID != "" && !c.TaughtBy(q.InstructorID) {
+		return false
+	}
+	return true
+}
+
+// withInstructors copies a course and fills in its instructors' names;
+// callers must hold the lock
+func (m *memoryDBRepo) withInstructors(c models.Course) models.Course {
+	c.InstructorIDs = slices.Clone(c.InstructorIDs)
+	c.Instructors = nil
+	for _, id := range c.InstructorIDs {
+		c.Instructors = append(c.Instructors, m.users[id].Name)
+	}
+	return c
+}
+
+// GetCourse retrieves a course by its ID
+func (m *memoryDBRepo) GetCourse(ctx context.Context, id string) (*models.Course, error) {
+	if err := checkCtx(ctx); err != nil {
+		return nil, err
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	c, ok := m.courses[id]
+	if !ok {
+		return nil, repository.ErrNotFound
+	}
+	c = m.withInstructors(c)
+	return &c, nil
+}
+
+// GetDepartments lists the departments with a published course
+func (m *memoryDBRepo) GetDepartments(ctx context.Context) ([]string, error) {
+	if err := checkCtx(ctx); err != nil {
+		return nil, err
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var departments []string
+	for _, c := range m.courses {
+		if c.Status == models.CoursePublished && !slices.Contains(departments, c.Department) {
+			departments = append(departments, c.Department)
+		}
+	}
+	slices.Sort(departments)
+	return departments, nil
+}
+
+// CreateCourse stores a new course with a fresh ID and returns the ID
+func (m *memoryDBRepo) CreateCourse(ctx context.Context, c models.Course) (string, error) {
+	if err := checkCtx(ctx); err != nil {
+		return "", err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if err := m.checkCourse(c); err != nil {
+		return "", err
+	}
+
+	id, err := uuid.NewUUID()
+	if err != nil {
+		return "", err
+	}
+	c.ID = id.String()
+	if c.Status == "" {
+		c.Status = models.CourseDraft
+	}
+	c.CreatedAt = time.Now()
+	c.UpdatedAt = c.CreatedAt
+	c.InstructorIDs = slices.Clone(c.InstructorIDs)
+	c.Instructors = nil
+	m.courses[c.ID] = c
+	return c.ID, nil
+}
+
+// UpdateCourse saves a course's details and replaces its instructors, then
+// fills any seats a raised capacity frees from the waitlists and returns who
+// was promoted. The status is left alone.
+func (m *memoryDBRepo) UpdateCourse(ctx context.Context, c models.Course) ([]models.Enrollment, error) {
+	if err := checkCtx(ctx); err != nil {
+		return nil, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	old, ok := m.courses[c.ID]
+	if !ok {
+		return nil, repository.ErrNotFound
+	}
+	if err := m.checkCourse(c); err != nil {
+		return nil, err
+	}
+
+	c.Status = old.Status
+	c.CreatedAt = old.CreatedAt
+	c.UpdatedAt = time.Now()
+	c.InstructorIDs = slices.Clone(c.InstructorIDs)
+	c.Instructors = nil
+	m.courses[c.ID] = c
+
+	// a raised capacity frees seats for the waitlists
+	var promoted []models.Enrollment
+	for termID := range m.terms {
+		promoted = append(promoted, m.promoteWaitlist(c.ID, termID, c.Capacity)...)
+	}
+	return promoted, nil
+}
+
+// checkCourse enforces the unique code and the instructor foreign keys;
+// callers must hold the lock
+func (m *memoryDBRepo) checkCourse(c models.Course) error {
+	for _, other := range m.courses {
+		if other.Code == c.Code && other.ID != c.ID {
+			return repository.ErrDuplicateCourseCode
+		}
+	}
+	for _, id := range c.InstructorIDs {
+		if _, ok := m.users[id]; !ok {
+			return repository.ErrNotFound
+		}
+	}
+	return nil
+}
+
+// SetCourseStatus drafts, publishes or archives a course
+func (m *memoryDBRepo) SetCourseStatus(ctx context.Context, id string, status models.CourseStatus) error {
+	if err := checkCtx(ctx); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	c, ok := m.courses[id]
+	if !ok {
+		return repository.ErrNotFound
+	}
+	c.Status = status
+	c.UpdatedAt = time.Now()
+	m.courses[id] = c
+	return nil
+}
+
+// GetSessions lists every academic session, newest first
+func (m *memoryDBRepo) GetSessions(ctx context.Context) ([]models.AcademicSession, error) {
+	if err := checkCtx(ctx); err != nil {
+		return nil, err
+	}
+
+	m.mu.RLock()
+	sessions := make([]models.AcademicSession, 0, len(m.sessions))
+	for _, s := range m.sessions {
+		sessions = append(sessions, m.withTermCount(s))
+	}
+	m.mu.RUnlock()
+	sort.Slice(sessions, func(i, j int) bool {
+		if !sessions[i].StartDate.Equal(sessions[j].StartDate) {
+			return sessions[i].StartDate.After(sessions[j].StartDate)
+		}
+		return sessions[i].Name < sessions[j].Name
+	})
+	return sessions, nil
+}
+
+// GetSession retrieves an academic session by its ID
+func (m *memoryDBRepo) GetSession(ctx context.Context, id string) (*models.AcademicSession, error) {
+	if err := checkCtx(ctx); err != nil {
+		return nil, err
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	s, ok := m.sessions[id]
+	if !ok {
+		return nil, repository.ErrNotFound
+	}
+	s = m.withTermCount(s)
+	return &s, nil
+}
+
+// CreateSession stores a new academic session and returns its ID
+func (m *memoryDBRepo) CreateSession(ctx context.Context, s models.AcademicSession) (string, error) {
+	if err := checkCtx(ctx); err != nil {
+		return "", err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.sessionNameTaken(s) {
+		return "", repository.ErrDuplicateSessionName
+	}
+	id, err := uuid.NewUUID()
+	if err != nil {
+		return "", err
+	}
+	s.ID = id.String()
+	s.Terms = 0
+	s.CreatedAt = time.Now()
+	m.sessions[s.ID] = s
+	return s.ID, nil
+}
+
+// UpdateSession saves an academic session's name and dates
+func (m *memoryDBRepo) UpdateSession(ctx context.Context, s models.AcademicSession) error {
+	if err := checkCtx(ctx); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	old, ok := m.sessions[s.ID]
+	if !ok {
+		return repository.ErrNotFound
+	}
+	if m.sessionNameTaken(s) {
+		return repository.ErrDuplicateSessionName
+	}
+	old.Name, old.StartDate, old.EndDate = s.Name, s.StartDate, s.EndDate
+	m.sessions[s.ID] = old
+	return nil
+}
+
+// DeleteSession removes an academic session that has no terms
+func (m *memoryDBRepo) DeleteSession(ctx context.Context, id string) error {
+	if err := checkCtx(ctx); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	s, ok := m.sessions[id]
+	if !ok {
+		return repository.ErrNotFound
+	}
+	if m.withTermCount(s).Terms > 0 {
+		return repository.ErrSessionInUse
+	}
+	delete(m.sessions, id)
+	return nil
+}
+
+// sessionNameTaken reports whether another session has s's name; callers
+// must hold the lock
+func (m *memoryDBRepo) sessionNameTaken(s models.AcademicSession) bool {
+	for _, other := range m.sessions {
+		if other.Name == s.Name && other.ID != s.ID {
+			return true
+		}
+	}
+	return false
+}
+
+// withTermCount fills in how many terms the session has; callers must hold
+// the lock
+func (m *memoryDBRepo) withTermCount(s models.AcademicSession) models.AcademicSession {
+	s.Terms = 0
+	for _, t := range m.terms {
+		if t.SessionID == s.ID {
+			s.Terms++
+		}
+	}
+	return s
+}
+
+// withSessionName fills in the name of the term's session; callers must
+// hold the lock
+func (m *memoryDBRepo) withSessionName(t models.Term) models.Term {
+	t.SessionName = m.sessions[t.SessionID].Name
+	return t
+}
+
+// GetTerms lists every term, latest start first
+func (m *memoryDBRepo) GetTerms(ctx context.Context) ([]models.Term, error) {
+	if err := checkCtx(ctx); err != nil {
+		return nil, err
+	}
+
+	m.mu.RLock()
+	terms := make([]models.Term, 0, len(m.terms))
+	for _, t := range m.terms {
+		terms = append(terms, m.withSessionName(t))
+	}
+	m.mu.RUnlock()
+	sort.Slice(terms, func(i, j int) bool {
+		if !terms[i].StartDate.Equal(terms[j].StartDate) {
+			return terms[i].StartDate.After(terms[j].StartDate)
+		}
+		return terms[i].CreatedAt.After(terms[j].CreatedAt)
+	})
+	return terms, nil
+}
+
+// GetTerm retrieves a term by its ID
+func (m *memoryDBRepo) GetTerm(ctx context.Context, id string) (*models.Term, error) {
+	if err := checkCtx(ctx); err != nil {
+		return nil, err
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	t, ok := m.terms[id]
+	if !ok {
+		return nil, repository.ErrNotFound
+	}
+	t = m.withSessionName(t)
+	return &t, nil
+}
+
+// GetCurrentTerm retrieves the term enrollment happens in, or ErrNotFound
+// when none has been chosen
+func (m *memoryDBRepo) GetCurrentTerm(ctx context.Context) (*models.Term, error) {
+	if err := checkCtx(ctx); err != nil {
+		return nil, err
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	for _, t := range m.terms {
+		if t.Current {
+			t = m.withSessionName(t)
+			return &t, nil
+		}
+	}
+	return nil, repository.ErrNotFound
+}
+
+// CreateTerm stores a new term, which is not current, and returns its ID
+func (m *memoryDBRepo) CreateTerm(ctx context.Context, t models.Term) (string, error) {
+	if err := checkCtx(ctx); err != nil {
+		return "", err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if !m.sessionExists(t.SessionID) {
+		return "", repository.ErrNotFound
+	}
+	if m.termNameTaken(t) {
+		return "", repository.ErrDuplicateTermName
+	}
+	id, err := uuid.NewUUID()
+	if err != nil {
+		return "", err
+	}
+	t.ID = id.String()
+	t.SessionName = ""
+	t.Current = false
+	t.CreatedAt = time.Now()
+	m.terms[t.ID] = t
+	return t.ID, nil
+}
+
+// UpdateTerm saves a term's name, session and dates
+func (m *memoryDBRepo) UpdateTerm(ctx context.Context, t models.Term) error {
+	if err := checkCtx(ctx); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	old, ok := m.terms[t.ID]
+	if !ok || !m.sessionExists(t.SessionID) {
+		return repository.ErrNotFound
+	}
+	if m.termNameTaken(t) {
+		return repository.ErrDuplicateTermName
+	}
+	old.Name, old.SessionID = t.Name, t.SessionID
+	old.StartDate, old.EndDate = t.StartDate, t.EndDate
+	old.RegistrationOpens, old.AddDeadline, old.DropDeadline = t.RegistrationOpens, t.AddDeadline, t.DropDeadline
+	old.ExamStart, old.ExamEnd = t.ExamStart, t.ExamEnd
+	m.terms[t.ID] = old
+	return nil
+}
+
+// sessionExists reports whether a term may belong to the session: an empty
+// ID, like the terms from before sessions, or a stored one. Callers must
+// hold the lock.
+func (m *memoryDBRepo) sessionExists(id string) bool {
+	if id == "" {
+		return true
+	}
+	_, ok := m.sessions[id]
+	return ok
+}
+
+// termNameTaken reports whether another term has t's name; callers must
+// hold the lock
+func (m *memoryDBRepo) termNameTaken(t models.Term) bool {
+	for _, other := range m.terms {
+		if other.Name == t.Name && other.ID != t.ID {
+			return true
+		}
+	}
+	return false
+}
+
+// SetCurrentTerm makes the term the current one in place of any other
+func (m *memoryDBRepo) SetCurrentTerm(ctx context.Context, id string) error {
+	if err := checkCtx(ctx); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.terms[id]; !ok {
+		return repository.ErrNotFound
+	}
+	for tid, t := range m.terms {
+		t.Current = tid == id
+		m.terms[tid] = t
+	}
+	return nil
+}
+
+// Enroll gives the student a seat in the course for the term, or a place at
+// the end of its waitlist when the course is full, and returns the waitlisted
+// students promoted ahead of them. The write lock plays the part of the
+// course row lock.
+func (m *memoryDBRepo) Enroll(ctx context.Context, courseID, termID, studentID string) (*models.Enrollment, []models.Enrollment, error) {
+	if err := checkCtx(ctx); err != nil {
+		return nil, nil, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	course, ok := m.courses[courseID]
+	if !ok {
+		return nil, nil, repository.ErrNotFound
+	}
+	if _, ok := m.terms[termID]; !ok {
+		return nil, nil, repository.ErrNotFound
+	}
+	if _, ok := m.users[studentID]; !ok {
+		return nil, nil, repository.ErrNotFound
+	}
+
+	e, exists := m.findEnrollment(courseID, termID, studentID)
+	if exists && e.Active() {
+		return nil, nil, repository.ErrAlreadyEnrolled
+	}
+	if !exists {
+		id, err := uuid.NewUUID()
+		if err != nil {
+			return nil, nil, err
+		}
+		e = models.Enrollment{ID: id.String(), CourseID: courseID, TermID: termID, StudentID: studentID, CreatedAt: time.Now()}
+	}
+
+	// students already waiting get any free seat before a newcomer does
+	promoted := m.promoteWaitlist(courseID, termID, course.Capacity)
+
+	enrolled, next := 0, 1
+	for _, other := range m.enrollments {
+		if other.CourseID == courseID && other.TermID == termID {
+			if other.Status == models.EnrollmentEnrolled {
+				enrolled++
+			}
+			next = max(next, other.Position+1)
+		}
+	}
+
+	e.Status, e.Position = models.EnrollmentEnrolled, 0
+	if enrolled >= course.Capacity {
+		e.Status, e.Position = models.EnrollmentWaitlisted, next
+	}
+	e.UpdatedAt = time.Now()
+	m.enrollments[e.ID] = e
+
+	e = m.withDetails(e)
+	return &e, promoted, nil
+}
+
+// DropEnrollment gives up the student's seat or waitlist place and promotes
+// whoever is next on the waitlist into a freed seat
+func (m *memoryDBRepo) DropEnrollment(ctx context.Context, courseID, termID, studentID string) ([]models.Enrollment, error) {
+	if err := checkCtx(ctx); err != nil {
+		return nil, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	course, ok := m.courses[courseID]
+	if !ok {
+		return nil, repository.ErrNotFound
+	}
+	e, ok := m.findEnrollment(courseID, termID, studentID)
+	if !ok || !e.Active() {
+		return nil, repository.ErrNotFound
+	}
+
+	e.Status, e.Position, e.UpdatedAt = models.EnrollmentDropped, 0, time.Now()
+	m.enrollments[e.ID] = e
+	return m.promoteWaitlist(courseID, termID, course.Capacity), nil
+}
+
+// findEnrollment finds the student's row for the course and term; callers
+// must hold the lock
+func (m *memoryDBRepo) findEnrollment(courseID, termID, studentID string) (models.Enrollment, bool) {
+	for _, e := range m.enrollments {
+		if e.CourseID == courseID && e.TermID == termID && e.StudentID == studentID {
+			return e, true
+		}
+	}
+	return models.Enrollment{}, false
+}
+
+// promoteWaitlist fills the course's free seats in the term from the front
+// of the waitlist; callers must hold the write lock
+func (m *memoryDBRepo) promoteWaitlist(courseID, termID string, capacity int) []models.Enrollment {
+	var enrolled int
+	var waiting []models.Enrollment
+	for _, e := range m.enrollments {
+		if e.CourseID != courseID || e.TermID != termID {
+			continue
+		}
+		switch e.Status {
+		case models.EnrollmentEnrolled:
+			enrolled++
+		case models.EnrollmentWaitlisted:
+			waiting = append(waiting, e)
+		}
+	}
+	sort.Slice(waiting, func(i, j int) bool { return waiting[i].Position < waiting[j].Position })
+
+	var promoted []models.Enrollment
+	for _, e := range waiting[:min(max(capacity-enrolled, 0), len(waiting))] {
+		e.Status, e.Position, e.UpdatedAt = models.EnrollmentEnrolled, 0, time.Now()
+		m.enrollments[e.ID] = e
+		promoted = append(promoted, e)
+	}
+	return promoted
+}
+
+// withDetails fills in the names shown with an enrollment and turns the
+// stored waitlist position into the student's rank; callers must hold the lock
+func (m *memoryDBRepo) withDetails(e models.Enrollment) models.Enrollment {
+	if e.Status == models.EnrollmentWaitlisted {
+		rank := 0
+		for _, other := range m.enrollments {
+			if other.CourseID == e.CourseID && other.TermID == e.TermID && other.Status == models.EnrollmentWaitlisted && other.Position <= e.Position {
+				rank++
+			}
+		}
+		e.Position = rank
+	}
+	student, course := m.users[e.StudentID], m.courses[e.CourseID]
+	e.StudentName, e.StudentEmail = student.Name, student.Email
+	e.CourseCode, e.CourseTitle, e.CreditUnits = course.Code, course.Title, course.CreditUnits
+	return e
+}
+
+// GetEnrollment retrieves the student's enrollment in the course for the
+// term, dropped or not
+func (m *memoryDBRepo) GetEnrollment(ctx context.Context, courseID, termID, studentID string) (*models.Enrollment, error) {
+	if err := checkCtx(ctx); err != nil {
+		return nil, err
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	e, ok := m.findEnrollment(courseID, termID, studentID)
+	if !ok {
+		return nil, repository.ErrNotFound
+	}
+	e = m.withDetails(e)
+	return &e, nil
+}
+
+// GetRoster lists the course's students in the term: those enrolled by
+// name, then the waitlist in order
+func (m *memoryDBRepo) GetRoster(ctx context.Context, courseID, termID string) ([]models.Enrollment, error) {
+	if err := checkCtx(ctx); err != nil {
+		return nil, err
+	}
+
+	roster := m.activeEnrollments(func(e models.Enrollment) bool { return e.CourseID == courseID && e.TermID == termID })
+	sort.Slice(roster, func(i, j int) bool {
+		a, b := roster[i], roster[j]
+		if a.Status != b.Status {
+			return a.Status == models.EnrollmentEnrolled
+		}
+		if a.Position != b.Position {
+			return a.Position < b.Position
+		}
+		return a.StudentName < b.StudentName
+	})
+	return roster, nil
+}
+
+// GetStudentEnrollments lists the courses the student holds or waits for a
+// seat in during the term, by course code
+func (m *memoryDBRepo) GetStudentEnrollments(ctx context.Context, studentID, termID string) ([]models.Enrollment, error) {
+	if err := checkCtx(ctx); err != nil {
+		return nil, err
+	}
+
+	enrollments := m.activeEnrollments(func(e models.Enrollment) bool { return e.StudentID == studentID && e.TermID == termID })
+	sort.Slice(enrollments, func(i, j int) bool { return enrollments[i].CourseCode < enrollments[j].CourseCode })
+	return enrollments, nil
+}
+
+// activeEnrollments returns the enrollments keep accepts that haven't been
+// dropped, with their details
+func (m *memoryDBRepo) activeEnrollments(keep func(models.Enrollment) bool) []models.Enrollment {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var enrollments []models.Enrollment
+	for _, e := range m.enrollments {
+		if e.Active() && keep(e) {
+			enrollments = append(enrollments, m.withDetails(e))
+		}
+	}
+	return enrollments
+}
+
+// GetSeats counts the course's seats taken and waitlist places in the term
+func (m *memoryDBRepo) GetSeats(ctx context.Context, courseID, termID string) (models.Seats, error) {
+	if err := checkCtx(ctx); err != nil {
+		return models.Seats{}, err
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	course, ok := m.courses[courseID]
+	if !ok {
+		return models.Seats{}, repository.ErrNotFound
+	}
+	s := models.Seats{Capacity: course.Capacity}
+	for _, e := range m.enrollments {
+		if e.CourseID != courseID || e.TermID != termID {
+			continue
+		}
+		switch e.Status {
+		case models.EnrollmentEnrolled:
+			s.Enrolled++
+		case models.EnrollmentWaitlisted:
+			s.Waitlisted++
+		}
+	}
+	return s, nil
+}
+
+// GetAssessments lists the course's assessments for the term in the order
+// they were added
+func (m *memoryDBRepo) GetAssessments(ctx context.Context, courseID, termID string) ([]models.Assessment, error) {
+	if err := checkCtx(ctx); err != nil {
+		return nil, err
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.courseAssessments(courseID, termID), nil
+}
+
+// courseAssessments is GetAssessments for callers holding the lock
+func (m *memoryDBRepo) courseAssessments(courseID, termID string) []models.Assessment {
+	var assessments []models.Assessment
+	for _, a := range m.assessments {
+		if a.CourseID == courseID && a.TermID == termID {
+			assessments = append(assessments, a)
+		}
+	}
+	sort.Slice(assessments, func(i, j int) bool { return assessments[i].Position < assessments[j].Position })
+	return assessments
+}
+
+// GetAssessment retrieves an assessment by its ID
+func (m *memoryDBRepo) GetAssessment(ctx context.Context, id string) (*models.Assessment, error) {
+	if err := checkCtx(ctx); err != nil {
+		return nil, err
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	a, ok := m.assessments[id]
+	if !ok {
+		return nil, repository.ErrNotFound
+	}
+	return &a, nil
+}
+
+// CreateAssessment adds an assessment after the course's others and returns
+// its ID
+func (m *memoryDBRepo) CreateAssessment(ctx context.Context, a models.Assessment) (string, error) {
+	if err := checkCtx(ctx); err != nil {
+		return "", err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.courses[a.CourseID]; !ok {
+		return "", repository.ErrNotFound
+	}
+	if _, ok := m.terms[a.TermID]; !ok {
+		return "", repository.ErrNotFound
+	}
+	id, err := uuid.NewUUID()
+	if err != nil {
+		return "", err
+	}
+	a.ID = id.String()
+	a.Position = 1
+	for _, other := range m.courseAssessments(a.CourseID, a.TermID) {
+		a.Position = max(a.Position, other.Position+1)
+	}
+	a.CreatedAt = time.Now()
+	m.assessments[a.ID] = a
+	return a.ID, nil
+}
+
+// UpdateAssessment saves an assessment's name, category, weight and maximum
+func (m *memoryDBRepo) UpdateAssessment(ctx context.Context, a models.Assessment) error {
+	if err := checkCtx(ctx); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	old, ok := m.assessments[a.ID]
+	if !ok {
+		return repository.ErrNotFound
+	}
+	old.Name, old.Category, old.Weight, old.MaxScore = a.Name, a.Category, a.Weight, a.MaxScore
+	m.assessments[a.ID] = old
+	return nil
+}
+
+// DeleteAssessment removes an assessment with its scores and their history
+func (m *memoryDBRepo) DeleteAssessment(ctx context.Context, id string) error {
+	if err := checkCtx(ctx); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.assessments[id]; !ok {
+		return repository.ErrNotFound
+	}
+	delete(m.assessments, id)
+	for k := range m.scores {
+		if k.assessmentID == id {
+			delete(m.scores, k)
+		}
+	}
+	m.scoreHistory = slices.DeleteFunc(m.scoreHistory, func(c models.ScoreChange) bool { return c.AssessmentID == id })
+	return nil
+}
+
+// GetScores returns the course's scores for the term by student ID
+func (m *memoryDBRepo) GetScores(ctx context.Context, courseID, termID string) (map[string]models.Scores, error) {
+	if err := checkCtx(ctx); err != nil {
+		return nil, err
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.courseScores(courseID, termID), nil
+}
+
+// courseScores is GetScores for callers holding the lock
+func (m *memoryDBRepo) courseScores(courseID, termID string) map[string]models.Scores {
+	scores := map[string]models.Scores{}
+	for k, score := range m.scores {
+		a := m.assessments[k.assessmentID]
+		if a.CourseID != courseID || a.TermID != termID {
+			continue
+		}
+		if scores[k.studentID] == nil {
+			scores[k.studentID] = models.Scores{}
+		}
+		scores[k.studentID][k.assessmentID] = score
+	}
+	return scores
+}
+
+// SetScore records or clears a score and appends the change to its history
+func (m *memoryDBRepo) SetScore(ctx context.Context, assessmentID, studentID string, score *float64, editorEmail string) error {
+	if err := checkCtx(ctx); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.assessments[assessmentID]; !ok {
+		return repository.ErrNotFound
+	}
+	if _, ok := m.users[studentID]; !ok {
+		return repository.ErrNotFound
+	}
+
+	k := scoreKey{assessmentID, studentID}
+	var before *float64
+	if old, ok := m.scores[k]; ok {
+		before = &old
+	}
+	if sameScore(before, score) {
+		return nil
+	}
+
+	var after *float64
+	if score == nil {
+		delete(m.scores, k)
+	} else {
+		v := *score
+		m.scores[k], after = v, &v
+	}
+	m.scoreHistory = append(m.scoreHistory, models.ScoreChange{
+		ID:           int64(len(m.scoreHistory) + 1),
+		AssessmentID: assessmentID,
+		StudentID:    studentID,
+		Before:       before,
+		After:        after,
+		EditorEmail:  editorEmail,
+		CreatedAt:    time.Now(),
+	})
+	return nil
+}
+
+// GetScoreHistory lists the changes to one student's scores in the course
+// for the term, newest first
+func (m *memoryDBRepo) GetScoreHistory(ctx context.Context, courseID, termID, studentID string) ([]models.ScoreChange, error) {
+	if err := checkCtx(ctx); err != nil {
+		return nil, err
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var changes []models.ScoreChange
+	for i := len(m.scoreHistory) - 1; i >= 0; i-- {
+		c := m.scoreHistory[i]
+		a := m.assessments[c.AssessmentID]
+		if c.StudentID == studentID && a.CourseID == courseID && a.TermID == termID {
+			c.AssessmentName = a.Name
+			changes = append(changes, c)
+		}
+	}
+	return changes, nil
+}
+
+// GetGradesPublished returns when the course's grades for the term were
+// published, or nil while they aren't
+func (m *memoryDBRepo) GetGradesPublished(ctx context.Context, courseID, termID string) (*time.Time, error) {
+	if err := checkCtx(ctx); err != nil {
+		return nil, err
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	published, ok := m.gradebooks[gradebookKey{courseID, termID}]
+	if !ok {
+		return nil, nil
+	}
+	return &published, nil
+}
+
+// SetGradesPublished shows the course's grades for the term to its students,
+// or hides them again
+func (m *memoryDBRepo) SetGradesPublished(ctx context.Context, courseID, termID string, published bool) error {
+	if err := checkCtx(ctx); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.courses[courseID]; !ok {
+		return repository.ErrNotFound
+	}
+	if _, ok := m.terms[termID]; !ok {
+		return repository.ErrNotFound
+	}
+	if published {
+		m.gradebooks[gradebookKey{courseID, termID}] = time.Now()
+	} else {
+		delete(m.gradebooks, gradebookKey{courseID, termID})
+	}
+	return nil
+}
+
+// GetPublishedGrades returns the student's published results in the courses
+// they are enrolled in, newest term first
+func (m *memoryDBRepo) GetPublishedGrades(ctx context.Context, studentID string) ([]models.CourseGrade, error) {
+	if err := checkCtx(ctx); err != nil {
+		return nil, err
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var grades []models.CourseGrade
+	for _, e := range m.enrollments {
+		if e.StudentID != studentID || e.Status != models.EnrollmentEnrolled {
+			continue
+		}
+		if _, ok := m.gradebooks[gradebookKey{e.CourseID, e.TermID}]; !ok {
+			continue
+		}
+		course := m.courses[e.CourseID]
+		grades = append(grades, models.CourseGrade{
+			CourseID:    course.ID,
+			CourseCode:  course.Code,
+			CourseTitle: course.Title,
+			CreditUnits: course.CreditUnits,
+			TermID:      e.TermID,
+			TermName:    m.terms[e.TermID].Name,
+			Assessments: m.courseAssessments(e.CourseID, e.TermID),
+			Scores:      m.courseScores(e.CourseID, e.TermID)[studentID],
+		})
+	}
+	sort.Slice(grades, func(i, j int) bool {
+		a, b := grades[i], grades[j]
+		if a.TermID != b.TermID {
+			return m.terms[a.TermID].StartDate.After(m.terms[b.TermID].StartDate)
+		}
+		return a.CourseCode < b.CourseCode
+	})
+	return grades, nil
+}
+
+// GetScales lists every grading scale, the default first and then by name
+func (m *memoryDBRepo) GetScales(ctx context.Context) ([]models.GradingScale, error) {
+	if err := checkCtx(ctx); err != nil {
+		return nil, err
+	}
+
+	m.mu.RLock()
+	scales := make([]models.GradingScale, 0, len(m.scales))
+	for _, s := range m.scales {
+		scales = append(scales, s)
+	}
+	m.mu.RUnlock()
+	sort.Slice(scales, func(i, j int) bool {
+		if scales[i].Default != scales[j].Default {
+			return scales[i].Default
+		}
+		return scales[i].Name < scales[j].Name
+	})
+	return scales, nil
+}
+
+// GetScale retrieves a grading scale by its ID
+func (m *memoryDBRepo) GetScale(ctx context.Context, id string) (*models.GradingScale, error) {
+	if err := checkCtx(ctx); err != nil {
+		return nil, err
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	s, ok := m.scales[id]
+	if !ok {
+		return nil, repository.ErrNotFound
+	}
+	return &s, nil
+}
+
+// CreateScale stores a new grading scale, never as the default, and returns
+// its ID
+func (m *memoryDBRepo) CreateScale(ctx context.Context, s models.GradingScale) (string, error) {
+	if err := checkCtx(ctx); err != nil {
+		return "", err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.scaleNameTaken(s) {
+		return "", repository.ErrDuplicateScaleName
+	}
+	id, err := uuid.NewUUID()
+	if err != nil {
+		return "", err
+	}
+	s.ID = id.String()
+	s.Bands, s.Classes = slices.Clone(s.Bands), slices.Clone(s.Classes)
+	s.Default = false
+	s.CreatedAt = time.Now()
+	m.scales[s.ID] = s
+	return s.ID, nil
+}
+
+// UpdateScale saves a grading scale's name, bands and classes
+func (m *memoryDBRepo) UpdateScale(ctx context.Context, s models.GradingScale) error {
+	if err := checkCtx(ctx); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	old, ok := m.scales[s.ID]
+	if !ok {
+		return repository.ErrNotFound
+	}
+	if m.scaleNameTaken(s) {
+		return repository.ErrDuplicateScaleName
+	}
+	old.Name, old.Bands, old.Classes = s.Name, slices.Clone(s.Bands), slices.Clone(s.Classes)
+	m.scales[s.ID] = old
+	return nil
+}
+
+// scaleNameTaken reports whether another scale already has s's name;
+// callers must hold the lock
+func (m *memoryDBRepo) scaleNameTaken(s models.GradingScale) bool {
+	for _, other := range m.scales {
+		if other.ID != s.ID && other.Name == s.Name {
+			return true
+		}
+	}
+	return false
+}
+
+// DeleteScale removes a grading scale no programme uses. The default scale
+// can't be removed.
+func (m *memoryDBRepo) DeleteScale(ctx context.Context, id string) error {
+	if err := checkCtx(ctx); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	s, ok := m.scales[id]
+	if !ok {
+		return repository.ErrNotFound
+	}
+	if s.Default {
+		return repository.ErrScaleInUse
+	}
+	for _, p := range m.programmes {
+		if p.ScaleID == id {
+			return repository.ErrScaleInUse
+		}
+	}
+	delete(m.scales, id)
+	return nil
+}
+
+// SetDefaultScale makes a grading scale the one students outside any
+// programme are graded on
+func (m *memoryDBRepo) SetDefaultScale(ctx context.Context, id string) error {
+	if err := checkCtx(ctx); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.scales[id]; !ok {
+		return repository.ErrNotFound
+	}
+	for sid, s := range m.scales {
+		s.Default = sid == id
+		m.scales[sid] = s
+	}
+	return nil
+}
+
+// GetProgrammes lists every programme by name
+func (m *memoryDBRepo) GetProgrammes(ctx context.Context) ([]models.Programme, error) {
+	if err := checkCtx(ctx); err != nil {
+		return nil, err
+	}
+
+	m.mu.RLock()
+	programmes := make([]models.Programme, 0, len(m.programmes))
+	for _, p := range m.programmes {
+		programmes = append(programmes, m.withScale(p))
+	}
+	m.mu.RUnlock()
+	sort.Slice(programmes, func(i, j int) bool { return programmes[i].Name < programmes[j].Name })
+	return programmes, nil
+}
+
+// withScale fills in the programme's scale name and student count; callers
+// must hold the lock
+func (m *memoryDBRepo) withScale(p models.Programme) models.Programme {
+	p.ScaleName = m.scales[p.ScaleID].Name
+	p.Students = 0
+	for _, pid := range m.programmeStudents {
+		if pid == p.ID {
+			p.Students++
+		}
+	}
+	return p
+}
+
+// GetProgramme retrieves a programme by its ID
+func (m *memoryDBRepo) GetProgramme(ctx context.Context, id string) (*models.Programme, error) {
+	if err := checkCtx(ctx); err != nil {
+		return nil, err
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	p, ok := m.programmes[id]
+	if !ok {
+		return nil, repository.ErrNotFound
+	}
+	p = m.withScale(p)
+	return &p, nil
+}
+
+// CreateProgramme stores a new programme and returns its ID
+func (m *memoryDBRepo) CreateProgramme(ctx context.Context, p models.Programme) (string, error) {
+	if err := checkCtx(ctx); err != nil {
+		return "", err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if err := m.checkProgramme(p); err != nil {
+		return "", err
+	}
+	id, err := uuid.NewUUID()
+	if err != nil {
+		return "", err
+	}
+	p.ID = id.String()
+	p.CreatedAt = time.Now()
+	m.programmes[p.ID] = p
+	return p.ID, nil
+}
+
+// UpdateProgramme saves a programme's name and scale
+func (m *memoryDBRepo) UpdateProgramme(ctx context.Context, p models.Programme) error {
+	if err := checkCtx(ctx); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	old, ok := m.programmes[p.ID]
+	if !ok {
+		return repository.ErrNotFound
+	}
+	if err := m.checkProgramme(p); err != nil {
+		return err
+	}
+	old.Name, old.ScaleID = p.Name, p.ScaleID
+	m.programmes[p.ID] = old
+	return nil
+}
+
+// checkProgramme enforces the unique name and the scale's foreign key;
+// callers must hold the lock
+func (m *memoryDBRepo) checkProgramme(p models.Programme) error {
+	if _, ok := m.scales[p.ScaleID]; !ok {
+		return repository.ErrNotFound
+	}
+	for _, other := range m.programmes {
+		if other.ID != p.ID && other.Name == p.Name {
+			return repository.ErrDuplicateProgrammeName
+		}
+	}
+	return nil
+}
+
+// DeleteProgramme removes a programme. Its students fall back to the
+// default scale.
+func (m *memoryDBRepo) DeleteProgramme(ctx context.Context, id string) error {
+	if err := checkCtx(ctx); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.programmes[id]; !ok {
+		return repository.ErrNotFound
+	}
+	delete(m.programmes, id)
+	for sid, pid := range m.programmeStudents {
+		if pid == id {
+			delete(m.programmeStudents, sid)
+		}
+	}
+	return nil
+}
+
+// GetProgrammeStudents lists the programme's students by name
+func (m *memoryDBRepo) GetProgrammeStudents(ctx context.Context, programmeID string) ([]models.User, error) {
+	if err := checkCtx(ctx); err != nil {
+		return nil, err
+	}
+
+	m.mu.RLock()
+	var users []models.User
+	for sid, pid := range m.programmeStudents {
+		if u, ok := m.users[sid]; ok && pid == programmeID && u.DeletedAt == nil {
+			u.Password = ""
+			users = append(users, u)
+		}
+	}
+	m.mu.RUnlock()
+	sort.Slice(users, func(i, j int) bool { return users[i].Name < users[j].Name })
+	return users, nil
+}
+
+// SetStudentProgramme moves a student into a programme, or out of theirs
+// when programmeID is empty
+func (m *memoryDBRepo) SetStudentProgramme(ctx context.Context, studentID, programmeID string) error {
+	if err := checkCtx(ctx); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if programmeID == "" {
+		delete(m.programmeStudents, studentID)
+		return nil
+	}
+	if _, ok := m.users[studentID]; !ok {
+		return repository.ErrNotFound
+	}
+	if _, ok := m.programmes[programmeID]; !ok {
+		return repository.ErrNotFound
+	}
+	m.programmeStudents[studentID] = programmeID
+	return nil
+}
+
+// GetStudentScales returns the scale each student is graded on: their
+// programme's, or the default scale for students outside any programme
+func (m *memoryDBRepo) GetStudentScales(ctx context.Context, studentIDs []string) (map[string]models.GradingScale, error) {
+	if err := checkCtx(ctx); err != nil {
+		return nil, err
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var fallback models.GradingScale
+	hasDefault := false
+	for _, s := range m.scales {
+		if s.Default {
+			fallback, hasDefault = s, true
+		}
+	}
+
+	scales := map[string]models.GradingScale{}
+	for _, id := range studentIDs {
+		if pid, ok := m.programmeStudents[id]; ok {
+			scales[id] = m.scales[m.programmes[pid].ScaleID]
+		} else if hasDefault {
+			scales[id] = fallback
+		}
+	}
+	return scales, nil
+}
+
+// findByEmail looks a user up by exact email; callers must hold the lock
+func (m *memoryDBRepo) findByEmail(email string) (models.User, bool) {
+	for _, u := range m.users {
+		if u.Email == email {
+			return u, true
+		}
+	}
+	return models.User{}, false
+}
+
+// checkCtx fails fast on a cancelled or expired context, tagged the same way
+// as the Postgres repository's errors
+func checkCtx(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return contextErr(ctx, err)
+	}
+	return nil
+}
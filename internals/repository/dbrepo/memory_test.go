@@ -0,0 +1,748 @@
+package dbrepo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stackninja.pro/goth/internals/models"
+	"github.com/stackninja.pro/goth/internals/repository"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestMemoryRepoUserLifecycle(t *testing.T) {
+	ctx := context.Background()
+	repo := NewMemoryRepo(nil)
+
+	hash, _ := bcrypt.GenerateFromPassword([]byte("secret"), bcrypt.MinCost)
+	user := models.User{Name: "Ada", Email: "ada@example.com", Password: string(hash)}
+
+	if err := repo.CreateUser(ctx, user); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.CreateUser(ctx, user); !errors.Is(err, repository.ErrDuplicateEmail) {
+		t.Errorf("expected ErrDuplicateEmail, got %v", err)
+	}
+
+	got, err := repo.AuthenticateUser(ctx, "ada@example.com", "secret")
+	if err != nil {
+		t.Fatalf("authenticate: %v", err)
+	}
+	if _, err := repo.AuthenticateUser(ctx, "ada@example.com", "wrong"); !errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+		t.Errorf("expected password mismatch, got %v", err)
+	}
+
+	byID, err := repo.GetUserByID(ctx, got.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if byID.Password != "" {
+		t.Error("GetUserByID must not return the password hash")
+	}
+
+	if err := repo.DeleteUser(ctx, got.ID); err != nil {
+		t.Fatal(err)
+	}
+	if deleted, err := repo.GetUserByID(ctx, got.ID); err != nil || !deleted.Deleted() {
+		t.Errorf("expected the user to be kept and marked deleted, got %+v, %v", deleted, err)
+	}
+	if _, err := repo.GetUserByEmail(ctx, "ada@example.com"); !errors.Is(err, repository.ErrInactive) {
+		t.Errorf("expected ErrInactive for a deleted user, got %v", err)
+	}
+	if err := repo.DeleteUser(ctx, got.ID); !errors.Is(err, repository.ErrNotFound) {
+		t.Errorf("expected ErrNotFound deleting twice, got %v", err)
+	}
+
+	if err := repo.RestoreUser(ctx, got.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := repo.GetUserByEmail(ctx, "ada@example.com"); err != nil {
+		t.Errorf("expected a restored user to be found, got %v", err)
+	}
+	if err := repo.RestoreUser(ctx, got.ID); !errors.Is(err, repository.ErrNotFound) {
+		t.Errorf("expected ErrNotFound restoring an active user, got %v", err)
+	}
+
+	if err := repo.DeleteUser(ctx, got.ID); err != nil {
+		t.Fatal(err)
+	}
+	if purged, err := repo.PurgeDeletedUsers(ctx, time.Now().Add(-time.Hour)); err != nil || len(purged) != 0 {
+		t.Errorf("expected nothing deleted before the cutoff, got %d, %v", len(purged), err)
+	}
+	purged, err := repo.PurgeDeletedUsers(ctx, time.Now().Add(time.Second))
+	if err != nil || len(purged) != 1 || purged[0].ID != got.ID {
+		t.Fatalf("expected the user to be purged, got %+v, %v", purged, err)
+	}
+	if _, err := repo.GetUserByID(ctx, got.ID); !errors.Is(err, repository.ErrNotFound) {
+		t.Errorf("expected ErrNotFound after purge, got %v", err)
+	}
+	if err := repo.UpdateUserAvatar(ctx, got.ID, "/uploads/avatars/x.png", "avatars/x.png"); !errors.Is(err, repository.ErrNotFound) {
+		t.Errorf("expected ErrNotFound for missing user, got %v", err)
+	}
+}
+
+func TestMemoryRepoContextErrors(t *testing.T) {
+	repo := NewMemoryRepo(nil)
+
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, _, err := repo.GetAllUsers(cancelled, models.UserQuery{}); !errors.Is(err, repository.ErrCanceled) {
+		t.Errorf("expected ErrCanceled, got %v", err)
+	}
+
+	expired, cancel := context.WithDeadline(context.Background(), time.Now().Add(-time.Second))
+	defer cancel()
+	if _, _, err := repo.GetAllUsers(expired, models.UserQuery{}); !errors.Is(err, repository.ErrTimeout) {
+		t.Errorf("expected ErrTimeout, got %v", err)
+	}
+}
+
+func TestMemoryRepoUserQuery(t *testing.T) {
+	ctx := context.Background()
+	repo := NewMemoryRepo(nil)
+
+	base := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	for i, u := range []models.User{
+		{Name: "Ada Obi", Email: "ada@example.com", Role: models.RoleStudent},
+		{Name: "Bola Ade", Email: "bola@example.com", Role: models.RoleInstructor},
+		{Name: "Chidi Eze", Email: "chidi@school.ng", Role: models.RoleStudent, Status: models.StatusSuspended},
+		{Name: "Dayo 100%", Email: "dayo@example.com", Role: models.RoleAdmin},
+		{Name: "Efe Gone", Email: "efe@example.com", Role: models.RoleStudent},
+	} {
+		u.CreatedAt = base.AddDate(0, 0, i)
+		if err := repo.CreateUser(ctx, u); err != nil {
+			t.Fatal(err)
+		}
+	}
+	efe, _ := repo.GetUserByEmail(ctx, "efe@example.com")
+	if err := repo.DeleteUser(ctx, efe.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	names := func(users []models.User) []string {
+		var out []string
+		for _, u := range users {
+			out = append(out, u.Name)
+		}
+		return out
+	}
+
+	tests := []struct {
+		name  string
+		q     models.UserQuery
+		want  []string
+		total int
+	}{
+		{"default is newest first", models.UserQuery{}, []string{"Dayo 100%", "Chidi Eze", "Bola Ade", "Ada Obi"}, 4},
+		{"search name or email", models.UserQuery{Search: "SCHOOL"}, []string{"Chidi Eze"}, 1},
+		{"search is literal", models.UserQuery{Search: "100%"}, []string{"Dayo 100%"}, 1},
+		{"role", models.UserQuery{Role: models.RoleStudent, Sort: "name"}, []string{"Ada Obi", "Chidi Eze"}, 2},
+		{"status", models.UserQuery{Status: models.StatusActive, Sort: "name", Desc: true}, []string{"Dayo 100%", "Bola Ade", "Ada Obi"}, 3},
+		{"created range is inclusive", models.UserQuery{CreatedFrom: base.AddDate(0, 0, 1), CreatedTo: base.AddDate(0, 0, 2), Sort: "created_at"}, []string{"Bola Ade", "Chidi Eze"}, 2},
+		{"second page", models.UserQuery{Sort: "email", Page: 2, PerPage: 3}, []string{"dayo@example.com"}, 4},
+		{"deleted only", models.UserQuery{Deleted: true}, []string{"Efe Gone"}, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			users, total, err := repo.GetAllUsers(ctx, tt.q)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := names(users)
+			if tt.q.Sort == "email" {
+				got = nil
+				for _, u := range users {
+					got = append(got, u.Email)
+				}
+			}
+			if total != tt.total || strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("got %v (total %d), want %v (total %d)", got, total, tt.want, tt.total)
+			}
+			for _, u := range users {
+				if u.Password != "" {
+					t.Error("listing must not return password hashes")
+				}
+			}
+		})
+	}
+}
+
+func TestMemoryRepoEmailChange(t *testing.T) {
+	ctx := context.Background()
+	repo := NewMemoryRepo(nil)
+
+	repo.CreateUser(ctx, models.User{Name: "Ada", Email: "ada@example.com"})
+	repo.CreateUser(ctx, models.User{Name: "Bola", Email: "bola@example.com"})
+	ada, _ := repo.GetUserByEmail(ctx, "ada@example.com")
+
+	if err := repo.VerifyEmail(ctx, ada.ID, "other@example.com"); !errors.Is(err, repository.ErrNotFound) {
+		t.Errorf("expected ErrNotFound for a stale address, got %v", err)
+	}
+	if err := repo.VerifyEmail(ctx, ada.ID, "ada@example.com"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := repo.SetPendingEmail(ctx, ada.ID, "bola@example.com"); !errors.Is(err, repository.ErrDuplicateEmail) {
+		t.Errorf("expected ErrDuplicateEmail, got %v", err)
+	}
+	if err := repo.SetPendingEmail(ctx, ada.ID, "ada@new.example.com"); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.ConfirmEmailChange(ctx, ada.ID, "ada@old.example.com"); !errors.Is(err, repository.ErrNotFound) {
+		t.Errorf("expected ErrNotFound for an address that isn't pending, got %v", err)
+	}
+	if err := repo.ConfirmEmailChange(ctx, ada.ID, "ada@new.example.com"); err != nil {
+		t.Fatal(err)
+	}
+
+	got, _ := repo.GetUserByID(ctx, ada.ID)
+	if got.Email != "ada@new.example.com" || got.PendingEmail != "" || !got.EmailVerified() {
+		t.Errorf("email change not applied: %+v", got)
+	}
+}
+
+func TestMemoryRepoTwoFactor(t *testing.T) {
+	ctx := context.Background()
+	repo := NewMemoryRepo(nil)
+
+	repo.CreateUser(ctx, models.User{Name: "Ada", Email: "ada@example.com", Role: models.RoleAdmin})
+	ada, _ := repo.GetUserByEmail(ctx, "ada@example.com")
+
+	if _, _, err := repo.GetTOTPSecret(ctx, ada.ID); !errors.Is(err, repository.ErrNotFound) {
+		t.Errorf("expected ErrNotFound before enabling, got %v", err)
+	}
+	if err := repo.EnableTwoFactor(ctx, ada.ID, "SECRET", 10, []string{"a", "b"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := repo.UseTOTPCounter(ctx, ada.ID, 10); !errors.Is(err, repository.ErrNotFound) {
+		t.Errorf("expected the enrolment code to be spent, got %v", err)
+	}
+	if err := repo.UseTOTPCounter(ctx, ada.ID, 11); err != nil {
+		t.Fatal(err)
+	}
+	if secret, last, _ := repo.GetTOTPSecret(ctx, ada.ID); secret != "SECRET" || last != 11 {
+		t.Errorf("got secret %q, last counter %d", secret, last)
+	}
+
+	if err := repo.UseRecoveryCode(ctx, ada.ID, "a"); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.UseRecoveryCode(ctx, ada.ID, "a"); !errors.Is(err, repository.ErrNotFound) {
+		t.Errorf("expected a used code to be refused, got %v", err)
+	}
+	if n, _ := repo.CountRecoveryCodes(ctx, ada.ID); n != 1 {
+		t.Errorf("expected 1 unused code, got %d", n)
+	}
+
+	repo.SetTwoFactorRequired(ctx, models.RoleAdmin, true)
+	got, _ := repo.GetUserByID(ctx, ada.ID)
+	if !got.TwoFactorEnabled() || !got.TwoFactorRequired {
+		t.Errorf("expected 2FA enabled and required: %+v", got)
+	}
+
+	if err := repo.DisableTwoFactor(ctx, ada.ID); err != nil {
+		t.Fatal(err)
+	}
+	if n, _ := repo.CountRecoveryCodes(ctx, ada.ID); n != 0 {
+		t.Errorf("expected recovery codes to be removed, got %d", n)
+	}
+}
+
+func TestMemoryRepoLoginFailures(t *testing.T) {
+	ctx := context.Background()
+	repo := NewMemoryRepo(nil)
+
+	repo.CreateUser(ctx, models.User{Name: "Ada", Email: "ada@example.com"})
+	ada, _ := repo.GetUserByEmail(ctx, "ada@example.com")
+
+	if _, err := repo.RecordLoginFailure(ctx, "missing"); !errors.Is(err, repository.ErrNotFound) {
+		t.Errorf("expected ErrNotFound for an unknown user, got %v", err)
+	}
+
+	repo.RecordLoginFailure(ctx, ada.ID)
+	f, _ := repo.RecordLoginFailure(ctx, ada.ID)
+	if f.Failures != 2 {
+		t.Fatalf("expected 2 failures, got %d", f.Failures)
+	}
+
+	if err := repo.LockAccount(ctx, ada.ID, time.Now().Add(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	locked, _ := repo.GetLockedAccounts(ctx)
+	if len(locked) != 1 || locked[0].Email != "ada@example.com" || locked[0].Failures != 0 || locked[0].Lockouts != 1 {
+		t.Fatalf("unexpected lockouts %+v", locked)
+	}
+
+	repo.ClearLoginFailures(ctx, ada.ID)
+	if f, _ := repo.GetLoginFailures(ctx, ada.ID); f.Locked(time.Now()) || f.Failures != 0 {
+		t.Errorf("expected the record to be cleared, got %+v", f)
+	}
+}
+
+func TestMemoryRepoAuditEvents(t *testing.T) {
+	ctx := context.Background()
+	repo := NewMemoryRepo(nil)
+
+	for _, e := range []models.AuditEvent{
+		{Action: models.AuditLogin, ActorEmail: "ada@example.com", TargetEmail: "ada@example.com"},
+		{Action: models.AuditRoleChange, ActorEmail: "root@example.com", TargetEmail: "bob@example.com",
+			Changes: []models.AuditChange{{Field: "role", Before: "student", After: "admin"}}},
+		{Action: models.AuditLogout, ActorEmail: "ada@example.com", TargetEmail: "ada@example.com"},
+	} {
+		if err := repo.RecordAuditEvent(ctx, e); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	events, total, _ := repo.GetAuditEvents(ctx, models.AuditQuery{Search: "ADA@"})
+	if total != 2 || events[0].Action != models.AuditLogout || events[1].Action != models.AuditLogin {
+		t.Fatalf("expected Ada's events newest first, got %d %+v", total, events)
+	}
+
+	events, _, _ = repo.GetAuditEvents(ctx, models.AuditQuery{Action: models.AuditRoleChange})
+	if len(events) != 1 || events[0].Changes[0].After != "admin" {
+		t.Errorf("expected the role change, got %+v", events)
+	}
+
+	tomorrow := time.Now().AddDate(0, 0, 1)
+	if _, total, _ := repo.GetAuditEvents(ctx, models.AuditQuery{From: tomorrow}); total != 0 {
+		t.Errorf("expected no events from tomorrow, got %d", total)
+	}
+
+	var n int
+	repo.ForEachAuditEvent(ctx, models.AuditQuery{PerPage: 1}, func(models.AuditEvent) error { n++; return nil })
+	if n != 3 {
+		t.Errorf("expected paging to be ignored when streaming, got %d events", n)
+	}
+}
+
+func TestMemoryRepoCreateUsers(t *testing.T) {
+	ctx := context.Background()
+	repo := NewMemoryRepo(nil)
+	repo.CreateUser(ctx, models.User{Name: "Ada", Email: "ada@example.com"})
+
+	// one taken address stops the whole batch
+	_, err := repo.CreateUsers(ctx, []models.User{{Name: "Bob", Email: "bob@example.com"}, {Name: "Ada", Email: "ada@example.com"}})
+	if !errors.Is(err, repository.ErrDuplicateEmail) {
+		t.Fatalf("expected ErrDuplicateEmail, got %v", err)
+	}
+	if _, err := repo.GetUserByEmail(ctx, "bob@example.com"); !errors.Is(err, repository.ErrNotFound) {
+		t.Errorf("expected nothing to be created, got %v", err)
+	}
+
+	created, err := repo.CreateUsers(ctx, []models.User{{Name: "Bob", Email: "bob@example.com"}, {Name: "Cy", Email: "cy@example.com"}})
+	if err != nil || len(created) != 2 || created[0].ID == "" || created[0].Status != models.StatusActive {
+		t.Fatalf("unexpected result %+v %v", created, err)
+	}
+	if got, _ := repo.GetUserByEmail(ctx, "cy@example.com"); got == nil || got.ID != created[1].ID {
+		t.Errorf("expected the returned IDs to be stored, got %+v", got)
+	}
+}
+
+func TestMemoryRepoForEachUser(t *testing.T) {
+	ctx := context.Background()
+	repo := NewMemoryRepo(nil)
+	for _, name := range []string{"Cy", "Ada", "Bob"} {
+		repo.CreateUser(ctx, models.User{Name: name, Email: strings.ToLower(name) + "@example.com", Password: "hash", Role: models.RoleStudent})
+	}
+
+	var names []string
+	err := repo.ForEachUser(ctx, models.UserQuery{Sort: "name", PerPage: 1, Page: 2}, func(u models.User) error {
+		if u.Password != "" {
+			t.Errorf("expected no password hash for %s", u.Name)
+		}
+		names = append(names, u.Name)
+		return nil
+	})
+	if err != nil || strings.Join(names, ",") != "Ada,Bob,Cy" {
+		t.Errorf("expected every user in name order, got %v %v", names, err)
+	}
+
+	stop := errors.New("stop")
+	if err := repo.ForEachUser(ctx, models.UserQuery{}, func(models.User) error { return stop }); err != stop {
+		t.Errorf("expected the callback's error back, got %v", err)
+	}
+}
+
+func TestMemoryRepoCourses(t *testing.T) {
+	ctx := context.Background()
+	repo := NewMemoryRepo(nil)
+	courses := repo.(repository.CourseRepo)
+
+	if err := repo.CreateUser(ctx, models.User{Name: "Ife Teacher", Email: "ife@example.com", Role: models.RoleInstructor}); err != nil {
+		t.Fatal(err)
+	}
+	ife, _ := repo.GetUserByEmail(ctx, "ife@example.com")
+
+	course := models.Course{Code: "CSC 101", Title: "Intro to Computing", Department: "Computer Science", CreditUnits: 3, Capacity: 40, InstructorIDs: []string{ife.ID}}
+	id, err := courses.CreateCourse(ctx, course)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := courses.CreateCourse(ctx, course); !errors.Is(err, repository.ErrDuplicateCourseCode) {
+		t.Errorf("expected ErrDuplicateCourseCode, got %v", err)
+	}
+	course.Code, course.InstructorIDs = "CSC 102", []string{"missing"}
+	if _, err := courses.CreateCourse(ctx, course); !errors.Is(err, repository.ErrNotFound) {
+		t.Errorf("expected ErrNotFound for an unknown instructor, got %v", err)
+	}
+
+	got, err := courses.GetCourse(ctx, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Status != models.CourseDraft || len(got.Instructors) != 1 || got.Instructors[0] != "Ife Teacher" {
+		t.Errorf("expected a draft taught by Ife Teacher, got %+v", got)
+	}
+
+	got.Title = "Introduction to Computing"
+	if _, err := courses.UpdateCourse(ctx, *got); err != nil {
+		t.Fatal(err)
+	}
+	if err := courses.SetCourseStatus(ctx, id, models.CoursePublished); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := courses.CreateCourse(ctx, models.Course{Code: "MTH 201", Title: "Linear Algebra", Department: "Mathematics", CreditUnits: 2, Capacity: 30, InstructorIDs: []string{ife.ID}}); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		q    models.CourseQuery
+		want []string
+	}{
+		{"everything", models.CourseQuery{}, []string{"CSC 101", "MTH 201"}},
+		{"published", models.CourseQuery{Status: models.CoursePublished}, []string{"CSC 101"}},
+		{"search title", models.CourseQuery{Search: "introduction"}, []string{"CSC 101"}},
+		{"department", models.CourseQuery{Department: "Mathematics"}, []string{"MTH 201"}},
+		{"instructor", models.CourseQuery{InstructorID: ife.ID}, []string{"CSC 101", "MTH 201"}},
+		{"other instructor", models.CourseQuery{InstructorID: "someone"}, nil},
+	}
+	for _, tt := range tests {
+		list, total, err := courses.GetCourses(ctx, tt.q)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var codes []string
+		for _, c := range list {
+			codes = append(codes, c.Code)
+		}
+		if total != len(tt.want) || strings.Join(codes, ",") != strings.Join(tt.want, ",") {
+			t.Errorf("%s: got %v (%d), want %v", tt.name, codes, total, tt.want)
+		}
+	}
+
+	if departments, _ := courses.GetDepartments(ctx); len(departments) != 1 || departments[0] != "Computer Science" {
+		t.Errorf("expected only the published course's department, got %v", departments)
+	}
+	if err := courses.SetCourseStatus(ctx, "missing", models.CourseArchived); !errors.Is(err, repository.ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+}
+
+func TestMemoryRepoEnrollment(t *testing.T) {
+	ctx := context.Background()
+	repo := NewMemoryRepo(nil)
+	courses, terms := repo.(repository.CourseRepo), repo.(repository.TermRepo)
+
+	var students []string
+	for i := range 4 {
+		email := fmt.Sprintf("student%d@example.com", i)
+		if err := repo.CreateUser(ctx, models.User{Name: fmt.Sprintf("Student %d", i), Email: email}); err != nil {
+			t.Fatal(err)
+		}
+		u, _ := repo.GetUserByEmail(ctx, email)
+		students = append(students, u.ID)
+	}
+
+	termID, err := terms.CreateTerm(ctx, models.Term{Name: "2025/2026 First", AddDeadline: time.Now(), DropDeadline: time.Now()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := terms.CreateTerm(ctx, models.Term{Name: "2025/2026 First"}); !errors.Is(err, repository.ErrDuplicateTermName) {
+		t.Errorf("expected ErrDuplicateTermName, got %v", err)
+	}
+	if _, err := terms.GetCurrentTerm(ctx); !errors.Is(err, repository.ErrNotFound) {
+		t.Errorf("expected no current term yet, got %v", err)
+	}
+	if err := terms.SetCurrentTerm(ctx, termID); err != nil {
+		t.Fatal(err)
+	}
+	if current, err := terms.GetCurrentTerm(ctx); err != nil || current.ID != termID {
+		t.Errorf("expected the term to be current, got %v, %v", current, err)
+	}
+
+	courseID, err := courses.CreateCourse(ctx, models.Course{Code: "CSC 101", Title: "Computing", Department: "CS", CreditUnits: 3, Capacity: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	e, _, err := courses.Enroll(ctx, courseID, termID, students[0])
+	if err != nil || e.Status != models.EnrollmentEnrolled {
+		t.Fatalf("expected a seat, got %+v, %v", e, err)
+	}
+	if _, _, err := courses.Enroll(ctx, courseID, termID, students[0]); !errors.Is(err, repository.ErrAlreadyEnrolled) {
+		t.Errorf("expected ErrAlreadyEnrolled, got %v", err)
+	}
+	for i, student := range students[1:3] {
+		e, _, err := courses.Enroll(ctx, courseID, termID, student)
+		if err != nil || e.Status != models.EnrollmentWaitlisted || e.Position != i+1 {
+			t.Fatalf("expected waitlist place %d, got %+v, %v", i+1, e, err)
+		}
+	}
+
+	promoted, err := courses.DropEnrollment(ctx, courseID, termID, students[0])
+	if err != nil || len(promoted) != 1 || promoted[0].StudentID != students[1] {
+		t.Fatalf("expected the first on the waitlist to be promoted, got %+v, %v", promoted, err)
+	}
+	if e, _ := courses.GetEnrollment(ctx, courseID, termID, students[2]); e.Position != 1 {
+		t.Errorf("expected the remaining student to move up the waitlist, got %d", e.Position)
+	}
+	if _, err := courses.DropEnrollment(ctx, courseID, termID, students[0]); !errors.Is(err, repository.ErrNotFound) {
+		t.Errorf("expected dropping twice to fail, got %v", err)
+	}
+
+	// dropping keeps the row, so enrolling again joins the back of the queue
+	if e, _, err := courses.Enroll(ctx, courseID, termID, students[0]); err != nil || e.Status != models.EnrollmentWaitlisted || e.Position != 2 {
+		t.Errorf("expected to rejoin at the end of the waitlist, got %+v, %v", e, err)
+	}
+
+	course, _ := courses.GetCourse(ctx, courseID)
+	course.Capacity = 2
+	promoted, err = courses.UpdateCourse(ctx, *course)
+	if err != nil || len(promoted) != 1 || promoted[0].StudentID != students[2] {
+		t.Fatalf("expected raising the capacity to promote the next student, got %+v, %v", promoted, err)
+	}
+	roster, err := courses.GetRoster(ctx, courseID, termID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, e := range roster {
+		got = append(got, fmt.Sprintf("%s:%s:%d", e.StudentName, e.Status, e.Position))
+	}
+	want := "Student 1:enrolled:0,Student 2:enrolled:0,Student 0:waitlisted:1"
+	if strings.Join(got, ",") != want {
+		t.Errorf("roster after raising capacity = %v, want %s", got, want)
+	}
+
+	if seats, _ := courses.GetSeats(ctx, courseID, termID); seats != (models.Seats{Capacity: 2, Enrolled: 2, Waitlisted: 1}) {
+		t.Errorf("unexpected seats %+v", seats)
+	}
+	if mine, _ := courses.GetStudentEnrollments(ctx, students[2], termID); len(mine) != 1 || mine[0].CourseCode != "CSC 101" {
+		t.Errorf("expected the student's enrollment with its course, got %+v", mine)
+	}
+}
+
+func TestMemoryRepoSessions(t *testing.T) {
+	ctx := context.Background()
+	terms := NewMemoryRepo(nil).(repository.TermRepo)
+
+	start := time.Date(2025, time.September, 1, 0, 0, 0, 0, time.UTC)
+	sessionID, err := terms.CreateSession(ctx, models.AcademicSession{Name: "2025/2026", StartDate: start, EndDate: start.AddDate(1, 0, -1)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := terms.CreateSession(ctx, models.AcademicSession{Name: "2025/2026"}); !errors.Is(err, repository.ErrDuplicateSessionName) {
+		t.Errorf("expected ErrDuplicateSessionName, got %v", err)
+	}
+	if _, err := terms.CreateTerm(ctx, models.Term{Name: "Orphan", SessionID: "missing"}); !errors.Is(err, repository.ErrNotFound) {
+		t.Errorf("expected a term in a missing session to be ErrNotFound, got %v", err)
+	}
+
+	first, _ := terms.CreateTerm(ctx, models.Term{Name: "First", SessionID: sessionID, StartDate: start})
+	second, _ := terms.CreateTerm(ctx, models.Term{Name: "Second", SessionID: sessionID, StartDate: start.AddDate(0, 5, 0)})
+	list, err := terms.GetTerms(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != 2 || list[0].ID != second || list[1].ID != first || list[0].SessionName != "2025/2026" {
+		t.Errorf("expected the terms latest start first with their session, got %+v", list)
+	}
+
+	session, err := terms.GetSession(ctx, sessionID)
+	if err != nil || session.Terms != 2 {
+		t.Errorf("expected the session to count 2 terms, got %+v, %v", session, err)
+	}
+	if err := terms.DeleteSession(ctx, sessionID); !errors.Is(err, repository.ErrSessionInUse) {
+		t.Errorf("expected ErrSessionInUse, got %v", err)
+	}
+}
+
+func TestMemoryRepoConcurrentEnrollment(t *testing.T) {
+	ctx := context.Background()
+	repo := NewMemoryRepo(nil)
+	courses, terms := repo.(repository.CourseRepo), repo.(repository.TermRepo)
+
+	termID, _ := terms.CreateTerm(ctx, models.Term{Name: "Rush"})
+	courseID, _ := courses.CreateCourse(ctx, models.Course{Code: "POP 101", Title: "Popular", Department: "X", CreditUnits: 1, Capacity: 5})
+
+	var wg sync.WaitGroup
+	for i := range 50 {
+		email := fmt.Sprintf("rush%d@example.com", i)
+		if err := repo.CreateUser(ctx, models.User{Name: email, Email: email}); err != nil {
+			t.Fatal(err)
+		}
+		u, _ := repo.GetUserByEmail(ctx, email)
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, _, err := courses.Enroll(ctx, courseID, termID, u.ID); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if seats, _ := courses.GetSeats(ctx, courseID, termID); seats.Enrolled != 5 || seats.Waitlisted != 45 {
+		t.Errorf("expected 5 seats taken and 45 waiting, got %+v", seats)
+	}
+}
+
+func TestMemoryRepoGrades(t *testing.T) {
+	ctx := context.Background()
+	repo := NewMemoryRepo(nil)
+	courses, terms, grades := repo.(repository.CourseRepo), repo.(repository.TermRepo), repo.(repository.GradeRepo)
+
+	if err := repo.CreateUser(ctx, models.User{Name: "Student", Email: "student@example.com"}); err != nil {
+		t.Fatal(err)
+	}
+	student, _ := repo.GetUserByEmail(ctx, "student@example.com")
+	courseID, err := courses.CreateCourse(ctx, models.Course{Code: "MTH 101", Title: "Algebra", CreditUnits: 3, Capacity: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	termID, _ := terms.CreateTerm(ctx, models.Term{Name: "First", StartDate: time.Date(2025, 9, 1, 0, 0, 0, 0, time.UTC)})
+	if _, _, err := courses.Enroll(ctx, courseID, termID, student.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	testID, _ := grades.CreateAssessment(ctx, models.Assessment{CourseID: courseID, TermID: termID, Name: "Test", Category: models.AssessmentTest, Weight: 40, MaxScore: 20})
+	examID, _ := grades.CreateAssessment(ctx, models.Assessment{CourseID: courseID, TermID: termID, Name: "Exam", Category: models.AssessmentExam, Weight: 60, MaxScore: 100})
+	if _, err := grades.CreateAssessment(ctx, models.Assessment{CourseID: "missing", TermID: termID}); !errors.Is(err, repository.ErrNotFound) {
+		t.Errorf("expected ErrNotFound for a missing course, got %v", err)
+	}
+	if assessments, _ := grades.GetAssessments(ctx, courseID, termID); len(assessments) != 2 || assessments[0].ID != testID || assessments[1].Position != 2 {
+		t.Errorf("expected the assessments in the order added, got %+v", assessments)
+	}
+
+	score := func(v float64) *float64 { return &v }
+	for _, s := range []*float64{score(10), score(10), score(15), nil, score(18)} {
+		if err := grades.SetScore(ctx, testID, student.ID, s, "teacher@example.com"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := grades.SetScore(ctx, examID, student.ID, score(50), "teacher@example.com"); err != nil {
+		t.Fatal(err)
+	}
+	history, _ := grades.GetScoreHistory(ctx, courseID, termID, student.ID)
+	if len(history) != 5 {
+		t.Fatalf("expected 5 changes as repeating a score isn't one, got %d", len(history))
+	}
+	if h := history[1]; h.AssessmentName != "Test" || h.Before != nil || *h.After != 18 {
+		t.Errorf("expected the test to be regraded after being cleared, got %+v", h)
+	}
+
+	if published, _ := grades.GetGradesPublished(ctx, courseID, termID); published != nil {
+		t.Error("expected a new gradebook to be unpublished")
+	}
+	if got, _ := grades.GetPublishedGrades(ctx, student.ID); len(got) != 0 {
+		t.Errorf("expected no published grades, got %+v", got)
+	}
+	if err := grades.SetGradesPublished(ctx, courseID, termID, true); err != nil {
+		t.Fatal(err)
+	}
+	got, _ := grades.GetPublishedGrades(ctx, student.ID)
+	// 18/20 of 40 plus 50/100 of 60 is 66
+	if len(got) != 1 || got[0].Total() != 66 {
+		t.Errorf("expected a published total of 66, got %+v", got)
+	}
+
+	// A term added later but starting earlier is listed after this one
+	summerID, _ := terms.CreateTerm(ctx, models.Term{Name: "Summer", StartDate: time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)})
+	if _, _, err := courses.Enroll(ctx, courseID, summerID, student.ID); err != nil {
+		t.Fatal(err)
+	}
+	if err := grades.SetGradesPublished(ctx, courseID, summerID, true); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := grades.GetPublishedGrades(ctx, student.ID); len(got) != 2 || got[0].TermID != termID || got[1].TermID != summerID {
+		t.Errorf("expected the newest term by start date first, got %+v", got)
+	}
+
+	if err := grades.DeleteAssessment(ctx, testID); err != nil {
+		t.Fatal(err)
+	}
+	if history, _ := grades.GetScoreHistory(ctx, courseID, termID, student.ID); len(history) != 1 {
+		t.Errorf("expected the test's history to go with it, got %d changes", len(history))
+	}
+	if scores, _ := grades.GetScores(ctx, courseID, termID); len(scores[student.ID]) != 1 {
+		t.Errorf("expected only the exam score to remain, got %v", scores)
+	}
+}
+
+func TestMemoryRepoScales(t *testing.T) {
+	ctx := context.Background()
+	repo := NewMemoryRepo(nil)
+	scales := repo.(repository.ScaleRepo)
+
+	standard, _ := scales.GetScales(ctx)
+	if len(standard) != 3 || standard[0].Name != "NUC 5-point" || !standard[0].Default {
+		t.Fatalf("expected the standard scales with NUC as the default, got %+v", standard)
+	}
+	nuc, us := standard[0], standard[2]
+	if err := scales.DeleteScale(ctx, nuc.ID); !errors.Is(err, repository.ErrScaleInUse) {
+		t.Errorf("expected the default scale to be kept, got %v", err)
+	}
+	if _, err := scales.CreateScale(ctx, models.GradingScale{Name: "US 4.0"}); !errors.Is(err, repository.ErrDuplicateScaleName) {
+		t.Errorf("expected ErrDuplicateScaleName, got %v", err)
+	}
+
+	if err := repo.CreateUser(ctx, models.User{Name: "Student", Email: "student@example.com"}); err != nil {
+		t.Fatal(err)
+	}
+	student, _ := repo.GetUserByEmail(ctx, "student@example.com")
+	programmeID, err := scales.CreateProgramme(ctx, models.Programme{Name: "Exchange", ScaleID: us.ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := scales.CreateProgramme(ctx, models.Programme{Name: "Other", ScaleID: "missing"}); !errors.Is(err, repository.ErrNotFound) {
+		t.Errorf("expected ErrNotFound for a missing scale, got %v", err)
+	}
+
+	if got, _ := scales.GetStudentScales(ctx, []string{student.ID}); got[student.ID].ID != nuc.ID {
+		t.Errorf("expected the default scale outside any programme, got %+v", got)
+	}
+	if err := scales.SetStudentProgramme(ctx, student.ID, programmeID); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := scales.GetStudentScales(ctx, []string{student.ID}); got[student.ID].ID != us.ID {
+		t.Errorf("expected the programme's scale, got %+v", got)
+	}
+	if p, _ := scales.GetProgramme(ctx, programmeID); p.Students != 1 || p.ScaleName != "US 4.0" {
+		t.Errorf("expected one student on US 4.0, got %+v", p)
+	}
+	if err := scales.DeleteScale(ctx, us.ID); !errors.Is(err, repository.ErrScaleInUse) {
+		t.Errorf("expected a scale in use to be kept, got %v", err)
+	}
+
+	if err := scales.SetDefaultScale(ctx, us.ID); err != nil {
+		t.Fatal(err)
+	}
+	if err := scales.DeleteProgramme(ctx, programmeID); err != nil {
+		t.Fatal(err)
+	}
+	if err := scales.DeleteScale(ctx, nuc.ID); err != nil {
+		t.Errorf("expected a former default scale to be deletable, got %v", err)
+	}
+	if got, _ := scales.GetStudentScales(ctx, []string{student.ID}); got[student.ID].ID != us.ID {
+		t.Errorf("expected the new default scale once the programme is gone, got %+v", got)
+	}
+}
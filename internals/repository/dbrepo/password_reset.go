@@ -0,0 +1,70 @@
+package dbrepo
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/stackninja.pro/goth/internals/repository"
+)
+
+// CreatePasswordReset stores the hash of a reset token issued to the user
+func (m *neonDBRepo) CreatePasswordReset(ctx context.Context, userID, tokenHash string, expiresAt time.Time) error {
+	ctx, cancel := m.withTimeout(ctx)
+	defer cancel()
+
+	_, err := m.DB.Exec(ctx, "INSERT INTO password_resets (token_hash, user_id, expires_at) VALUES ($1, $2, $3)", tokenHash, userID, expiresAt)
+	return translateErr(ctx, err)
+}
+
+// GetPasswordReset returns the user a reset token belongs to, or
+// ErrNotFound when it is unknown, used or expired
+func (m *neonDBRepo) GetPasswordReset(ctx context.Context, tokenHash string) (string, error) {
+	ctx, cancel := m.withTimeout(ctx)
+	defer cancel()
+
+	var userID string
+	err := m.DB.QueryRow(ctx, `
+		SELECT user_id FROM password_resets
+		WHERE token_hash = $1 AND used_at IS NULL AND expires_at > NOW()
+	`, tokenHash).Scan(&userID)
+	if err != nil {
+		return "", translateErr(ctx, err)
+	}
+	return userID, nil
+}
+
+// ResetPassword redeems a reset token and sets the new password hash in one
+// transaction. The user's other outstanding tokens are spent too.
+func (m *neonDBRepo) ResetPassword(ctx context.Context, tokenHash, passwordHash string) (string, error) {
+	ctx, cancel := m.withTimeout(ctx)
+	defer cancel()
+
+	var userID string
+	err := pgx.BeginFunc(ctx, m.DB, func(tx pgx.Tx) error {
+		// the row lock stops two requests redeeming the same token
+		err := tx.QueryRow(ctx, `
+			SELECT user_id FROM password_resets
+			WHERE token_hash = $1 AND used_at IS NULL AND expires_at > NOW()
+			FOR UPDATE
+		`, tokenHash).Scan(&userID)
+		if err != nil {
+			return err
+		}
+
+		tag, err := tx.Exec(ctx, "UPDATE users SET password = $2, updated_at = NOW() WHERE id = $1", userID, passwordHash)
+		if err != nil {
+			return err
+		}
+		if tag.RowsAffected() == 0 {
+			return repository.ErrNotFound
+		}
+
+		_, err = tx.Exec(ctx, "UPDATE password_resets SET used_at = NOW() WHERE user_id = $1 AND used_at IS NULL", userID)
+		return err
+	})
+	if err != nil {
+		return "", translateErr(ctx, err)
+	}
+	return userID, nil
+}
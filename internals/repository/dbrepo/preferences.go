@@ -0,0 +1,75 @@
+package dbrepo
+
+import (
+	"context"
+
+	"github.com/stackninja.pro/goth/internals/models"
+	"github.com/stackninja.pro/goth/internals/repository"
+)
+
+// prefColumns are the user_preferences columns, read through a LEFT JOIN
+// aliased p so a missing row scans as NULLs
+const prefColumns = "p.timezone, p.date_format, p.notify_grades, p.notify_enrollment, p.notify_news"
+
+// nullPrefs receives prefColumns
+type nullPrefs struct {
+	timezone, dateFormat     *string
+	grades, enrollment, news *bool
+}
+
+func (n *nullPrefs) dest() []any {
+	return []any{&n.timezone, &n.dateFormat, &n.grades, &n.enrollment, &n.news}
+}
+
+// value returns the stored preferences, or the defaults if there was no row
+func (n *nullPrefs) value(userID string) models.Preferences {
+	p := models.DefaultPreferences()
+	p.UserID = userID
+	if n.timezone == nil {
+		return p
+	}
+
+	p.Timezone = *n.timezone
+	p.DateFormat = models.DateFormat(*n.dateFormat)
+	p.NotifyGrades = *n.grades
+	p.NotifyEnrollment = *n.enrollment
+	p.NotifyNews = *n.news
+	return p
+}
+
+// GetPreferences returns the user's preferences, or the defaults if they
+// haven't saved any
+func (m *neonDBRepo) GetPreferences(ctx context.Context, userID string) (models.Preferences, error) {
+	ctx, cancel := m.withTimeout(ctx)
+	defer cancel()
+
+	var n nullPrefs
+	err := m.DB.QueryRow(ctx, "SELECT "+prefColumns+" FROM users LEFT JOIN user_preferences p ON p.user_id = users.id WHERE users.id = $1", userID).Scan(n.dest()...)
+	if err != nil {
+		return models.Preferences{}, translateErr(ctx, err)
+	}
+	return n.value(userID), nil
+}
+
+// SavePreferences stores the user's preferences, replacing any saved before
+func (m *neonDBRepo) SavePreferences(ctx context.Context, p models.Preferences) error {
+	ctx, cancel := m.withTimeout(ctx)
+	defer cancel()
+
+	_, err := m.DB.Exec(ctx, `
+		INSERT INTO user_preferences (user_id, timezone, date_format, notify_grades, notify_enrollment, notify_news)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (user_id) DO UPDATE SET
+			timezone = EXCLUDED.timezone,
+			date_format = EXCLUDED.date_format,
+			notify_grades = EXCLUDED.notify_grades,
+			notify_enrollment = EXCLUDED.notify_enrollment,
+			notify_news = EXCLUDED.notify_news
+	`, p.UserID, p.Timezone, p.DateFormat, p.NotifyGrades, p.NotifyEnrollment, p.NotifyNews)
+
+	// the foreign key fails when the user doesn't exist
+	if isForeignKeyViolation(err) {
+		return repository.ErrNotFound
+	}
+	return translateErr(ctx, err)
+}
@@ -0,0 +1,332 @@
+package dbrepo
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/stackninja.pro/goth/internals/models"
+	"github.com/stackninja.pro/goth/internals/repository"
+)
+
+// scaleColumns is what every grading scale query selects
+const scaleColumns = "s.id, s.name, s.bands, s.classes, s.is_default, s.created_at"
+
+// scaleDest returns the scan destinations for scaleColumns
+func scaleDest(s *models.GradingScale) []any {
+	return []any{&s.ID, &s.Name, &s.Bands, &s.Classes, &s.Default, &s.CreatedAt}
+}
+
+// GetScales lists every grading scale, the default first and then by name
+func (m *neonDBRepo) GetScales(ctx context.Context) ([]models.GradingScale, error) {
+	ctx, cancel := m.withTimeout(ctx)
+	defer cancel()
+
+	rows, err := m.DB.Query(ctx, "SELECT "+scaleColumns+" FROM grading_scales s ORDER BY s.is_default DESC, s.name")
+	if err != nil {
+		return nil, translateErr(ctx, err)
+	}
+	defer rows.Close()
+
+	var scales []models.GradingScale
+	for rows.Next() {
+		var s models.GradingScale
+		if err := rows.Scan(scaleDest(&s)...); err != nil {
+			return nil, translateErr(ctx, err)
+		}
+		scales = append(scales, s)
+	}
+	return scales, translateErr(ctx, rows.Err())
+}
+
+// GetScale retrieves a grading scale by its ID
+func (m *neonDBRepo) GetScale(ctx context.Context, id string) (*models.GradingScale, error) {
+	ctx, cancel := m.withTimeout(ctx)
+	defer cancel()
+
+	var s models.GradingScale
+	if err := m.DB.QueryRow(ctx, "SELECT "+scaleColumns+" FROM grading_scales s WHERE s.id = $1", id).Scan(scaleDest(&s)...); err != nil {
+		return nil, translateErr(ctx, err)
+	}
+	return &s, nil
+}
+
+// CreateScale stores a new grading scale, never as the default, and returns
+// its ID
+func (m *neonDBRepo) CreateScale(ctx context.Context, s models.GradingScale) (string, error) {
+	ctx, cancel := m.withTimeout(ctx)
+	defer cancel()
+
+	id, err := uuid.NewUUID()
+	if err != nil {
+		return "", err
+	}
+	bands, classes, err := scaleJSON(s)
+	if err != nil {
+		return "", err
+	}
+
+	_, err = m.DB.Exec(ctx, "INSERT INTO grading_scales (id, name, bands, classes) VALUES ($1, $2, $3, $4)", id.String(), s.Name, bands, classes)
+	if err != nil {
+		return "", translateErr(ctx, err)
+	}
+	return id.String(), nil
+}
+
+// UpdateScale saves a grading scale's name, bands and classes
+func (m *neonDBRepo) UpdateScale(ctx context.Context, s models.GradingScale) error {
+	ctx, cancel := m.withTimeout(ctx)
+	defer cancel()
+
+	bands, classes, err := scaleJSON(s)
+	if err != nil {
+		return err
+	}
+
+	tag, err := m.DB.Exec(ctx, "UPDATE grading_scales SET name = $2, bands = $3, classes = $4 WHERE id = $1", s.ID, s.Name, bands, classes)
+	if err != nil {
+		return translateErr(ctx, err)
+	}
+	if tag.RowsAffected() == 0 {
+		return repository.ErrNotFound
+	}
+	return nil
+}
+
+// scaleJSON encodes a scale's bands and classes for their jsonb columns
+func scaleJSON(s models.GradingScale) ([]byte, []byte, error) {
+	bands, err := json.Marshal(nonNil(s.Bands))
+	if err != nil {
+		return nil, nil, err
+	}
+	classes, err := json.Marshal(nonNil(s.Classes))
+	if err != nil {
+		return nil, nil, err
+	}
+	return bands, classes, nil
+}
+
+// DeleteScale removes a grading scale no programme uses. The default scale
+// can't be removed.
+func (m *neonDBRepo) DeleteScale(ctx context.Context, id string) error {
+	ctx, cancel := m.withTimeout(ctx)
+	defer cancel()
+
+	var isDefault bool
+	if err := m.DB.QueryRow(ctx, "SELECT is_default FROM grading_scales WHERE id = $1", id).Scan(&isDefault); err != nil {
+		return translateErr(ctx, err)
+	}
+	if isDefault {
+		return repository.ErrScaleInUse
+	}
+
+	tag, err := m.DB.Exec(ctx, "DELETE FROM grading_scales WHERE id = $1 AND NOT is_default", id)
+	if isForeignKeyViolation(err) {
+		return repository.ErrScaleInUse
+	}
+	if err != nil {
+		return translateErr(ctx, err)
+	}
+	if tag.RowsAffected() == 0 {
+		return repository.ErrScaleInUse
+	}
+	return nil
+}
+
+// SetDefaultScale makes a grading scale the one students outside any
+// programme are graded on
+func (m *neonDBRepo) SetDefaultScale(ctx context.Context, id string) error {
+	ctx, cancel := m.withTimeout(ctx)
+	defer cancel()
+
+	err := pgx.BeginFunc(ctx, m.DB, func(tx pgx.Tx) error {
+		// cleared first, as the unique index is checked row by row
+		if _, err := tx.Exec(ctx, "UPDATE grading_scales SET is_default = false WHERE is_default AND id <> $1", id); err != nil {
+			return err
+		}
+		tag, err := tx.Exec(ctx, "UPDATE grading_scales SET is_default = true WHERE id = $1", id)
+		if err != nil {
+			return err
+		}
+		if tag.RowsAffected() == 0 {
+			return repository.ErrNotFound
+		}
+		return nil
+	})
+	return translateErr(ctx, err)
+}
+
+// programmeColumns is what every programme query selects, with the scale's
+// name and the number of students
+const programmeColumns = `p.id, p.name, p.scale_id, s.name, p.created_at,
+	(SELECT count(*) FROM programme_students ps WHERE ps.programme_id = p.id)`
+
+// programmeDest returns the scan destinations for programmeColumns
+func programmeDest(p *models.Programme) []any {
+	return []any{&p.ID, &p.Name, &p.ScaleID, &p.ScaleName, &p.CreatedAt, &p.Students}
+}
+
+// GetProgrammes lists every programme by name
+func (m *neonDBRepo) GetProgrammes(ctx context.Context) ([]models.Programme, error) {
+	ctx, cancel := m.withTimeout(ctx)
+	defer cancel()
+
+	rows, err := m.DB.Query(ctx, "SELECT "+programmeColumns+" FROM programmes p JOIN grading_scales s ON s.id = p.scale_id ORDER BY p.name")
+	if err != nil {
+		return nil, translateErr(ctx, err)
+	}
+	defer rows.Close()
+
+	var programmes []models.Programme
+	for rows.Next() {
+		var p models.Programme
+		if err := rows.Scan(programmeDest(&p)...); err != nil {
+			return nil, translateErr(ctx, err)
+		}
+		programmes = append(programmes, p)
+	}
+	return programmes, translateErr(ctx, rows.Err())
+}
+
+// GetProgramme retrieves a programme by its ID
+func (m *neonDBRepo) GetProgramme(ctx context.Context, id string) (*models.Programme, error) {
+	ctx, cancel := m.withTimeout(ctx)
+	defer cancel()
+
+	var p models.Programme
+	err := m.DB.QueryRow(ctx, "SELECT "+programmeColumns+" FROM programmes p JOIN grading_scales s ON s.id = p.scale_id WHERE p.id = $1", id).Scan(programmeDest(&p)...)
+	if err != nil {
+		return nil, translateErr(ctx, err)
+	}
+	return &p, nil
+}
+
+// CreateProgramme stores a new programme and returns its ID
+func (m *neonDBRepo) CreateProgramme(ctx context.Context, p models.Programme) (string, error) {
+	ctx, cancel := m.withTimeout(ctx)
+	defer cancel()
+
+	id, err := uuid.NewUUID()
+	if err != nil {
+		return "", err
+	}
+
+	_, err = m.DB.Exec(ctx, "INSERT INTO programmes (id, name, scale_id) VALUES ($1, $2, $3)", id.String(), p.Name, p.ScaleID)
+	if isForeignKeyViolation(err) {
+		return "", repository.ErrNotFound
+	}
+	if err != nil {
+		return "", translateErr(ctx, err)
+	}
+	return id.String(), nil
+}
+
+// UpdateProgramme saves a programme's name and scale
+func (m *neonDBRepo) UpdateProgramme(ctx context.Context, p models.Programme) error {
+	ctx, cancel := m.withTimeout(ctx)
+	defer cancel()
+
+	tag, err := m.DB.Exec(ctx, "UPDATE programmes SET name = $2, scale_id = $3 WHERE id = $1", p.ID, p.Name, p.ScaleID)
+	if isForeignKeyViolation(err) {
+		return repository.ErrNotFound
+	}
+	if err != nil {
+		return translateErr(ctx, err)
+	}
+	if tag.RowsAffected() == 0 {
+		return repository.ErrNotFound
+	}
+	return nil
+}
+
+// DeleteProgramme removes a programme. Its students fall back to the
+// default scale.
+func (m *neonDBRepo) DeleteProgramme(ctx context.Context, id string) error {
+	ctx, cancel := m.withTimeout(ctx)
+	defer cancel()
+
+	tag, err := m.DB.Exec(ctx, "DELETE FROM programmes WHERE id = $1", id)
+	if err != nil {
+		return translateErr(ctx, err)
+	}
+	if tag.RowsAffected() == 0 {
+		return repository.ErrNotFound
+	}
+	return nil
+}
+
+// GetProgrammeStudents lists the programme's students by name
+func (m *neonDBRepo) GetProgrammeStudents(ctx context.Context, programmeID string) ([]models.User, error) {
+	ctx, cancel := m.withTimeout(ctx)
+	defer cancel()
+
+	rows, err := m.DB.Query(ctx, `
+		SELECT `+userColumns+` FROM users
+		WHERE id IN (SELECT student_id FROM programme_students WHERE programme_id = $1) AND deleted_at IS NULL
+		ORDER BY name`, programmeID)
+	if err != nil {
+		return nil, translateErr(ctx, err)
+	}
+	defer rows.Close()
+
+	var users []models.User
+	for rows.Next() {
+		var u models.User
+		if err := rows.Scan(userDest(&u)...); err != nil {
+			return nil, translateErr(ctx, err)
+		}
+		users = append(users, u)
+	}
+	return users, translateErr(ctx, rows.Err())
+}
+
+// SetStudentProgramme moves a student into a programme, or out of theirs
+// when programmeID is empty
+func (m *neonDBRepo) SetStudentProgramme(ctx context.Context, studentID, programmeID string) error {
+	ctx, cancel := m.withTimeout(ctx)
+	defer cancel()
+
+	var err error
+	if programmeID == "" {
+		_, err = m.DB.Exec(ctx, "DELETE FROM programme_students WHERE student_id = $1", studentID)
+	} else {
+		_, err = m.DB.Exec(ctx, `
+			INSERT INTO programme_students (student_id, programme_id) VALUES ($1, $2)
+			ON CONFLICT (student_id) DO UPDATE SET programme_id = EXCLUDED.programme_id`,
+			studentID, programmeID)
+	}
+	if isForeignKeyViolation(err) {
+		return repository.ErrNotFound
+	}
+	return translateErr(ctx, err)
+}
+
+// GetStudentScales returns the scale each student is graded on: their
+// programme's, or the default scale for students outside any programme
+func (m *neonDBRepo) GetStudentScales(ctx context.Context, studentIDs []string) (map[string]models.GradingScale, error) {
+	ctx, cancel := m.withTimeout(ctx)
+	defer cancel()
+
+	rows, err := m.DB.Query(ctx, `
+		SELECT ids.id::text, `+scaleColumns+`
+		FROM unnest($1::uuid[]) AS ids (id)
+		LEFT JOIN programme_students ps ON ps.student_id = ids.id
+		LEFT JOIN programmes p ON p.id = ps.programme_id
+		JOIN grading_scales s ON s.id = p.scale_id OR (p.id IS NULL AND s.is_default)`, studentIDs)
+	if err != nil {
+		return nil, translateErr(ctx, err)
+	}
+	defer rows.Close()
+
+	scales := map[string]models.GradingScale{}
+	for rows.Next() {
+		var studentID string
+		var s models.GradingScale
+		if err := rows.Scan(append([]any{&studentID}, scaleDest(&s)...)...); err != nil {
+			return nil, translateErr(ctx, err)
+		}
+		scales[studentID] = s
+	}
+	return scales, translateErr(ctx, rows.Err())
+}
@@ -0,0 +1,236 @@
+package dbrepo
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/stackninja.pro/goth/internals/models"
+	"github.com/stackninja.pro/goth/internals/repository"
+)
+
+// sessionColumns is what every academic session query selects, with the
+// number of terms in the session
+const sessionColumns = `s.id, s.name, s.start_date, s.end_date, s.created_at,
+	(SELECT count(*) FROM terms t WHERE t.session_id = s.id)`
+
+// sessionDest returns the scan destinations for sessionColumns
+func sessionDest(s *models.AcademicSession) []any {
+	return []any{&s.ID, &s.Name, &s.StartDate, &s.EndDate, &s.CreatedAt, &s.Terms}
+}
+
+// GetSessions lists every academic session, newest first
+func (m *neonDBRepo) GetSessions(ctx context.Context) ([]models.AcademicSession, error) {
+	ctx, cancel := m.withTimeout(ctx)
+	defer cancel()
+
+	rows, err := m.DB.Query(ctx, "SELECT "+sessionColumns+" FROM academic_sessions s ORDER BY s.start_date DESC, s.name")
+	if err != nil {
+		return nil, translateErr(ctx, err)
+	}
+	defer rows.Close()
+
+	var sessions []models.AcademicSession
+	for rows.Next() {
+		var s models.AcademicSession
+		if err := rows.Scan(sessionDest(&s)...); err != nil {
+			return nil, translateErr(ctx, err)
+		}
+		sessions = append(sessions, s)
+	}
+	return sessions, translateErr(ctx, rows.Err())
+}
+
+// GetSession retrieves an academic session by its ID
+func (m *neonDBRepo) GetSession(ctx context.Context, id string) (*models.AcademicSession, error) {
+	ctx, cancel := m.withTimeout(ctx)
+	defer cancel()
+
+	var s models.AcademicSession
+	if err := m.DB.QueryRow(ctx, "SELECT "+sessionColumns+" FROM academic_sessions s WHERE s.id = $1", id).Scan(sessionDest(&s)...); err != nil {
+		return nil, translateErr(ctx, err)
+	}
+	return &s, nil
+}
+
+// CreateSession stores a new academic session and returns its ID
+func (m *neonDBRepo) CreateSession(ctx context.Context, s models.AcademicSession) (string, error) {
+	ctx, cancel := m.withTimeout(ctx)
+	defer cancel()
+
+	id, err := uuid.NewUUID()
+	if err != nil {
+		return "", err
+	}
+
+	_, err = m.DB.Exec(ctx, "INSERT INTO academic_sessions (id, name, start_date, end_date) VALUES ($1, $2, $3, $4)",
+		id.String(), s.Name, s.StartDate, s.EndDate)
+	if err != nil {
+		return "", translateErr(ctx, err)
+	}
+	return id.String(), nil
+}
+
+// UpdateSession saves an academic session's name and dates
+func (m *neonDBRepo) UpdateSession(ctx context.Context, s models.AcademicSession) error {
+	ctx, cancel := m.withTimeout(ctx)
+	defer cancel()
+
+	tag, err := m.DB.Exec(ctx, "UPDATE academic_sessions SET name = $2, start_date = $3, end_date = $4 WHERE id = $1",
+		s.ID, s.Name, s.StartDate, s.EndDate)
+	if err != nil {
+		return translateErr(ctx, err)
+	}
+	if tag.RowsAffected() == 0 {
+		return repository.ErrNotFound
+	}
+	return nil
+}
+
+// DeleteSession removes an academic session that has no terms
+func (m *neonDBRepo) DeleteSession(ctx context.Context, id string) error {
+	ctx, cancel := m.withTimeout(ctx)
+	defer cancel()
+
+	tag, err := m.DB.Exec(ctx, "DELETE FROM academic_sessions WHERE id = $1", id)
+	if isForeignKeyViolation(err) {
+		return repository.ErrSessionInUse
+	}
+	if err != nil {
+		return translateErr(ctx, err)
+	}
+	if tag.RowsAffected() == 0 {
+		return repository.ErrNotFound
+	}
+	return nil
+}
+
+// termColumns is what every term query selects, with the session's name.
+// Terms from before sessions have none.
+const termColumns = `t.id, t.name, coalesce(t.session_id::text, ''), coalesce(s.name, ''),
+	t.start_date, t.end_date, t.registration_opens, t.add_deadline, t.drop_deadline,
+	t.exam_start, t.exam_end, t.is_current, t.created_at`
+
+// termTables joins each term to its session for termColumns
+const termTables = "terms t LEFT JOIN academic_sessions s ON s.id = t.session_id"
+
+// termDest returns the scan destinations for termColumns
+func termDest(t *models.Term) []any {
+	return []any{&t.ID, &t.Name, &t.SessionID, &t.SessionName,
+		&t.StartDate, &t.EndDate, &t.RegistrationOpens, &t.AddDeadline, &t.DropDeadline,
+		&t.ExamStart, &t.ExamEnd, &t.Current, &t.CreatedAt}
+}
+
+// GetTerms lists every term, latest start first
+func (m *neonDBRepo) GetTerms(ctx context.Context) ([]models.Term, error) {
+	ctx, cancel := m.withTimeout(ctx)
+	defer cancel()
+
+	rows, err := m.DB.Query(ctx, "SELECT "+termColumns+" FROM "+termTables+" ORDER BY t.start_date DESC, t.created_at DESC")
+	if err != nil {
+		return nil, translateErr(ctx, err)
+	}
+	defer rows.Close()
+
+	var terms []models.Term
+	for rows.Next() {
+		var t models.Term
+		if err := rows.Scan(termDest(&t)...); err != nil {
+			return nil, translateErr(ctx, err)
+		}
+		terms = append(terms, t)
+	}
+	return terms, translateErr(ctx, rows.Err())
+}
+
+// GetTerm retrieves a term by its ID
+func (m *neonDBRepo) GetTerm(ctx context.Context, id string) (*models.Term, error) {
+	ctx, cancel := m.withTimeout(ctx)
+	defer cancel()
+
+	var t models.Term
+	if err := m.DB.QueryRow(ctx, "SELECT "+termColumns+" FROM "+termTables+" WHERE t.id = $1", id).Scan(termDest(&t)...); err != nil {
+		return nil, translateErr(ctx, err)
+	}
+	return &t, nil
+}
+
+// GetCurrentTerm retrieves the term enrollment happens in, or ErrNotFound
+// when none has been chosen
+func (m *neonDBRepo) GetCurrentTerm(ctx context.Context) (*models.Term, error) {
+	ctx, cancel := m.withTimeout(ctx)
+	defer cancel()
+
+	var t models.Term
+	if err := m.DB.QueryRow(ctx, "SELECT "+termColumns+" FROM "+termTables+" WHERE t.is_current").Scan(termDest(&t)...); err != nil {
+		return nil, translateErr(ctx, err)
+	}
+	return &t, nil
+}
+
+// CreateTerm stores a new term, which is not current, and returns its ID
+func (m *neonDBRepo) CreateTerm(ctx context.Context, t models.Term) (string, error) {
+	ctx, cancel := m.withTimeout(ctx)
+	defer cancel()
+
+	id, err := uuid.NewUUID()
+	if err != nil {
+		return "", err
+	}
+
+	_, err = m.DB.Exec(ctx, `
+		INSERT INTO terms (id, name, session_id, start_date, end_date, registration_opens, add_deadline, drop_deadline, exam_start, exam_end)
+		VALUES ($1, $2, NULLIF($3, '')::uuid, $4, $5, $6, $7, $8, $9, $10)`,
+		id.String(), t.Name, t.SessionID, t.StartDate, t.EndDate, t.RegistrationOpens, t.AddDeadline, t.DropDeadline, t.ExamStart, t.ExamEnd)
+	if isForeignKeyViolation(err) {
+		return "", repository.ErrNotFound
+	}
+	if err != nil {
+		return "", translateErr(ctx, err)
+	}
+	return id.String(), nil
+}
+
+// UpdateTerm saves a term's name, session and dates
+func (m *neonDBRepo) UpdateTerm(ctx context.Context, t models.Term) error {
+	ctx, cancel := m.withTimeout(ctx)
+	defer cancel()
+
+	tag, err := m.DB.Exec(ctx, `
+		UPDATE terms SET name = $2, session_id = NULLIF($3, '')::uuid, start_date = $4, end_date = $5, registration_opens = $6,
+			add_deadline = $7, drop_deadline = $8, exam_start = $9, exam_end = $10
+		WHERE id = $1`,
+		t.ID, t.Name, t.SessionID, t.StartDate, t.EndDate, t.RegistrationOpens, t.AddDeadline, t.DropDeadline, t.ExamStart, t.ExamEnd)
+	if isForeignKeyViolation(err) {
+		return repository.ErrNotFound
+	}
+	if err != nil {
+		return translateErr(ctx, err)
+	}
+	if tag.RowsAffected() == 0 {
+		return repository.ErrNotFound
+	}
+	return nil
+}
+
+// SetCurrentTerm makes the term the current one in place of any other
+func (m *neonDBRepo) SetCurrentTerm(ctx context.Context, id string) error {
+	ctx, cancel := m.withTimeout(ctx)
+	defer cancel()
+
+	err := pgx.BeginFunc(ctx, m.DB, func(tx pgx.Tx) error {
+		// cleared first, as the unique index is checked row by row
+		if _, err := tx.Exec(ctx, "UPDATE terms SET is_current = false WHERE is_current AND id <> $1", id); err != nil {
+			return err
+		}
+		tag, err := tx.Exec(ctx, "UPDATE terms SET is_current = true WHERE id = $1", id)
+		if err != nil {
+			return err
+		}
+		if tag.RowsAffected() == 0 {
+			return repository.ErrNotFound
+		}
+		return nil
+	})
+	return translateErr(ctx, err)
+}
@@ -0,0 +1,177 @@
+package dbrepo
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/stackninja.pro/goth/internals/models"
+	"github.com/stackninja.pro/goth/internals/repository"
+)
+
+// twoFactorRequired selects whether the user's role must use two-factor
+// authentication
+const twoFactorRequired = "COALESCE((SELECT require_two_factor FROM role_policies WHERE role = users.role), false)"
+
+// EnableTwoFactor turns on TOTP with secret and replaces the user's recovery
+// codes. counter is the step of the code that proved the secret works, so it
+// can't be used again to sign in.
+func (m *neonDBRepo) EnableTwoFactor(ctx context.Context, userID, secret string, counter int64, codeHashes []string) error {
+	ctx, cancel := m.withTimeout(ctx)
+	defer cancel()
+
+	err := pgx.BeginFunc(ctx, m.DB, func(tx pgx.Tx) error {
+		tag, err := tx.Exec(ctx, `
+			UPDATE users SET totp_secret = $2, totp_enabled_at = NOW(), totp_last_counter = $3, updated_at = NOW()
+			WHERE id = $1
+		`, userID, secret, counter)
+		if err != nil {
+			return err
+		}
+		if tag.RowsAffected() == 0 {
+			return repository.ErrNotFound
+		}
+		return replaceRecoveryCodes(ctx, tx, userID, codeHashes)
+	})
+	return translateErr(ctx, err)
+}
+
+// DisableTwoFactor forgets the user's TOTP secret and recovery codes
+func (m *neonDBRepo) DisableTwoFactor(ctx context.Context, userID string) error {
+	ctx, cancel := m.withTimeout(ctx)
+	defer cancel()
+
+	err := pgx.BeginFunc(ctx, m.DB, func(tx pgx.Tx) error {
+		tag, err := tx.Exec(ctx, `
+			UPDATE users SET totp_secret = '', totp_enabled_at = NULL, totp_last_counter = 0, updated_at = NOW()
+			WHERE id = $1
+		`, userID)
+		if err != nil {
+			return err
+		}
+		if tag.RowsAffected() == 0 {
+			return repository.ErrNotFound
+		}
+		_, err = tx.Exec(ctx, "DELETE FROM recovery_codes WHERE user_id = $1", userID)
+		return err
+	})
+	return translateErr(ctx, err)
+}
+
+// GetTOTPSecret returns the user's secret and the last time step used to sign
+// in, or ErrNotFound when two-factor authentication is off
+func (m *neonDBRepo) GetTOTPSecret(ctx context.Context, userID string) (string, int64, error) {
+	ctx, cancel := m.withTimeout(ctx)
+	defer cancel()
+
+	var secret string
+	var counter int64
+	err := m.DB.QueryRow(ctx, "SELECT totp_secret, totp_last_counter FROM users WHERE id = $1 AND totp_enabled_at IS NOT NULL", userID).Scan(&secret, &counter)
+	if err != nil {
+		return "", 0, translateErr(ctx, err)
+	}
+	return secret, counter, nil
+}
+
+// UseTOTPCounter records that the code for counter has been used. It returns
+// ErrNotFound when that step or a later one was used already.
+func (m *neonDBRepo) UseTOTPCounter(ctx context.Context, userID string, counter int64) error {
+	ctx, cancel := m.withTimeout(ctx)
+	defer cancel()
+
+	tag, err := m.DB.Exec(ctx, "UPDATE users SET totp_last_counter = $2 WHERE id = $1 AND totp_last_counter < $2", userID, counter)
+	if err != nil {
+		return translateErr(ctx, err)
+	}
+	if tag.RowsAffected() == 0 {
+		return repository.ErrNotFound
+	}
+	return nil
+}
+
+// UseRecoveryCode spends one of the user's recovery codes, or returns
+// ErrNotFound when it is unknown or used
+func (m *neonDBRepo) UseRecoveryCode(ctx context.Context, userID, codeHash string) error {
+	ctx, cancel := m.withTimeout(ctx)
+	defer cancel()
+
+	tag, err := m.DB.Exec(ctx, "UPDATE recovery_codes SET used_at = NOW() WHERE user_id = $1 AND code_hash = $2 AND used_at IS NULL", userID, codeHash)
+	if err != nil {
+		return translateErr(ctx, err)
+	}
+	if tag.RowsAffected() == 0 {
+		return repository.ErrNotFound
+	}
+	return nil
+}
+
+// ReplaceRecoveryCodes swaps the user's recovery codes for a new set
+func (m *neonDBRepo) ReplaceRecoveryCodes(ctx context.Context, userID string, codeHashes []string) error {
+	ctx, cancel := m.withTimeout(ctx)
+	defer cancel()
+
+	err := pgx.BeginFunc(ctx, m.DB, func(tx pgx.Tx) error {
+		return replaceRecoveryCodes(ctx, tx, userID, codeHashes)
+	})
+	if isForeignKeyViolation(err) {
+		return repository.ErrNotFound
+	}
+	return translateErr(ctx, err)
+}
+
+// replaceRecoveryCodes deletes the old codes and inserts the new ones in tx
+func replaceRecoveryCodes(ctx context.Context, tx pgx.Tx, userID string, codeHashes []string) error {
+	if _, err := tx.Exec(ctx, "DELETE FROM recovery_codes WHERE user_id = $1", userID); err != nil {
+		return err
+	}
+	_, err := tx.Exec(ctx, "INSERT INTO recovery_codes (user_id, code_hash) SELECT $1, unnest($2::text[])", userID, codeHashes)
+	return err
+}
+
+// CountRecoveryCodes returns how many of the user's recovery codes are unused
+func (m *neonDBRepo) CountRecoveryCodes(ctx context.Context, userID string) (int, error) {
+	ctx, cancel := m.withTimeout(ctx)
+	defer cancel()
+
+	var n int
+	err := m.DB.QueryRow(ctx, "SELECT count(*) FROM recovery_codes WHERE user_id = $1 AND used_at IS NULL", userID).Scan(&n)
+	if err != nil {
+		return 0, translateErr(ctx, err)
+	}
+	return n, nil
+}
+
+// GetTwoFactorPolicy returns which roles must use two-factor authentication
+func (m *neonDBRepo) GetTwoFactorPolicy(ctx context.Context) (map[models.Role]bool, error) {
+	ctx, cancel := m.withTimeout(ctx)
+	defer cancel()
+
+	rows, err := m.DB.Query(ctx, "SELECT role, require_two_factor FROM role_policies")
+	if err != nil {
+		return nil, translateErr(ctx, err)
+	}
+	defer rows.Close()
+
+	policy := map[models.Role]bool{}
+	for rows.Next() {
+		var role models.Role
+		var required bool
+		if err := rows.Scan(&role, &required); err != nil {
+			return nil, translateErr(ctx, err)
+		}
+		policy[role] = required
+	}
+	return policy, translateErr(ctx, rows.Err())
+}
+
+// SetTwoFactorRequired sets whether members of role must use two-factor
+// authentication
+func (m *neonDBRepo) SetTwoFactorRequired(ctx context.Context, role models.Role, required bool) error {
+	ctx, cancel := m.withTimeout(ctx)
+	defer cancel()
+
+	_, err := m.DB.Exec(ctx, `
+		INSERT INTO role_policies (role, require_two_factor) VALUES ($1, $2)
+		ON CONFLICT (role) DO UPDATE SET require_two_factor = EXCLUDED.require_two_factor
+	`, role, required)
+	return translateErr(ctx, err)
+}
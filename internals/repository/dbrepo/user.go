@@ -2,138 +2,452 @@ package dbrepo
 
 import (
 	"context"
+	"fmt"
+	"strings"
+	"time"
 
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
 	"github.com/stackninja.pro/goth/internals/models"
+	"github.com/stackninja.pro/goth/internals/repository"
 	"golang.org/x/crypto/bcrypt"
 )
 
-// GetAllUsers retrieves all the user from the database
-func (m *neonDBRepo) GetAllUsers() ([]models.User, error) {
+// userColumns is what every listing selects; password is never among them
+const userColumns = "id, email, email_verified_at, pending_email, name, role, status, dob, bio, avatar, avatar_key, totp_enabled_at, created_at, updated_at, deleted_at, anonymized_at"
+
+// userDest returns the scan destinations for userColumns
+func userDest(u *models.User) []any {
+	return []any{&u.ID, &u.Email, &u.EmailVerifiedAt, &u.PendingEmail, &u.Name, &u.Role, &u.Status, &u.DOB, &u.Bio, &u.Avatar, &u.AvatarKey, &u.TOTPEnabledAt, &u.CreatedAt, &u.UpdatedAt, &u.DeletedAt, &u.AnonymizedAt}
+}
+
+// GetAllUsers returns one page of the users matching q, and how many match in total
+func (m *neonDBRepo) GetAllUsers(ctx context.Context, q models.UserQuery) ([]models.User, int, error) {
+	ctx, cancel := m.withTimeout(ctx)
+	defer cancel()
+
+	q = q.Normalize()
+	where, args := userFilter(q)
+
+	var total int
+	if err := m.DB.QueryRow(ctx, "SELECT count(*) FROM users"+where, args...).Scan(&total); err != nil {
+		return nil, 0, translateErr(ctx, err)
+	}
+
+	query := fmt.Sprintf("SELECT %s FROM users%s%s LIMIT %d OFFSET %d",
+		userColumns, where, userOrder(q), q.PerPage, q.Offset())
+
 	var users []models.User
-	rows, err := m.DB.Query(context.Background(), "SELECT id, email, name, category, dob, bio, avatar, created_at, updated_at FROM users")
+	rows, err := m.DB.Query(ctx, query, args...)
 	if err != nil {
-		return nil, err
+		return nil, 0, translateErr(ctx, err)
 	}
 	defer rows.Close()
 
 	for rows.Next() {
 		var user models.User
-		if err := rows.Scan(&user.ID, &user.Email, &user.Name, &user.Category, &user.DOB, &user.Bio, &user.Avatar, &user.CreatedAt, &user.UpdatedAt); err != nil {
-			return nil, err
+		if err := rows.Scan(userDest(&user)...); err != nil {
+			return nil, 0, translateErr(ctx, err)
 		}
 		users = append(users, user)
 	}
-	return users, nil
+	return users, total, translateErr(ctx, rows.Err())
 }
 
-// GetUserByID retrieves a user by their ID
-func (m *neonDBRepo) GetUserByID(id string) (*models.User, error) {
+// ForEachUser calls fn with every user matching q, in q's order and without
+// password hashes, one row at a time. Paging in q is ignored.
+func (m *neonDBRepo) ForEachUser(ctx context.Context, q models.UserQuery, fn func(models.User) error) error {
+	// no query timeout: an export runs as long as the client keeps reading
+	q = q.Normalize()
+	where, args := userFilter(q)
+	rows, err := m.DB.Query(ctx, "SELECT "+userColumns+" FROM users"+where+userOrder(q), args...)
+	if err != nil {
+		return translateErr(ctx, err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var user models.User
+		if err := rows.Scan(userDest(&user)...); err != nil {
+			return translateErr(ctx, err)
+		}
+		if err := fn(user); err != nil {
+			return err
+		}
+	}
+	return translateErr(ctx, rows.Err())
+}
+
+// userOrder is the ORDER BY clause for q. q.Sort is one of
+// models.UserSortColumns once normalized, so it is safe to splice in.
+func userOrder(q models.UserQuery) string {
+	dir := "ASC"
+	if q.Desc {
+		dir = "DESC"
+	}
+	return " ORDER BY " + q.Sort + " " + dir + ", id"
+}
+
+// userFilter turns the filters in q into a WHERE clause and its arguments
+func userFilter(q models.UserQuery) (string, []any) {
+	var conds []string
+	var args []any
+	add := func(cond string, arg any) {
+		args = append(args, arg)
+		conds = append(conds, fmt.Sprintf(cond, len(args)))
+	}
+
+	if q.Search != "" {
+		add("(name ILIKE $%[1]d OR email ILIKE $%[1]d)", "%"+escapeLike(q.Search)+"%")
+	}
+	if q.Role != "" {
+		add("role = $%d", q.Role)
+	}
+	if q.Status != "" {
+		add("status = $%d", q.Status)
+	}
+	if !q.CreatedFrom.IsZero() {
+		add("created_at >= $%d", q.CreatedFrom)
+	}
+	if !q.CreatedTo.IsZero() {
+		add("created_at < $%d", q.CreatedTo.AddDate(0, 0, 1))
+	}
+	if len(q.IDs) > 0 {
+		add("id::text = ANY($%d)", q.IDs)
+	}
+
+	if q.Deleted {
+		conds = append(conds, "deleted_at IS NOT NULL")
+	} else {
+		conds = append(conds, "deleted_at IS NULL")
+	}
+	return " WHERE " + strings.Join(conds, " AND "), args
+}
+
+// escapeLike stops % and _ in a search from acting as wildcards
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(s)
+}
+
+// GetUserByID retrieves a user by their ID, with their preferences
+func (m *neonDBRepo) GetUserByID(ctx context.Context, id string) (*models.User, error) {
+	ctx, cancel := m.withTimeout(ctx)
+	defer cancel()
+
 	var user models.User
-	row := m.DB.QueryRow(context.Background(), "SELECT id, email, name, category, dob, bio, avatar, created_at, updated_at FROM users WHERE id = $1", id)
-	if err := row.Scan(&user.ID, &user.Email, &user.Name, &user.Category, &user.DOB, &user.Bio, &user.Avatar, &user.CreatedAt, &user.UpdatedAt); err != nil {
-		return nil, err
+	var prefs nullPrefs
+	row := m.DB.QueryRow(ctx, "SELECT "+userColumns+", "+prefColumns+", "+twoFactorRequired+" FROM users LEFT JOIN user_preferences p ON p.user_id = users.id WHERE users.id = $1", id)
+	if err := row.Scan(append(append(userDest(&user), prefs.dest()...), &user.TwoFactorRequired)...); err != nil {
+		return nil, translateErr(ctx, err)
 	}
 
-	//Format the date using a friendly format
-	user.DOBFormatted = user.DOB.Format("January 2, 2006")
+	// Format the date the way the user asked for
+	user.Preferences = prefs.value(user.ID)
+	user.DOBFormatted = user.Preferences.FormatDate(user.DOB)
 
 	return &user, nil
 }
 
-// GetUserByEmail retrieves a user by their email
-func (m *neonDBRepo) GetUserByEmail(email string) (*models.User, error) {
-    user := &models.User{}
-    query := `
-        SELECT id, email, password, name, category, dob, bio, avatar, created_at, updated_at
-        FROM users
-        WHERE email = $1
-    `
-    row := m.DB.QueryRow(context.Background(), query, email)
-
-    err := row.Scan(
-        &user.ID,
-        &user.Email,
-        &user.Password, // ✅ must scan this
-        &user.Name,
-        &user.Category,
-        &user.DOB,
-        &user.Bio,
-        &user.Avatar,
-        &user.CreatedAt,
-        &user.UpdatedAt,
-    )
-    if err != nil {
-        return nil, err
-    }
-    return user, nil
+// GetUserByEmail retrieves a user by their email. Suspended, deactivated
+// and deleted accounts are refused with ErrInactive.
+func (m *neonDBRepo) GetUserByEmail(ctx context.Context, email string) (*models.User, error) {
+	user, err := m.userByEmail(ctx, email)
+	if err != nil {
+		return nil, err
+	}
+	if !user.Active() {
+		return nil, repository.ErrInactive
+	}
+	return user, nil
 }
 
+// userByEmail loads a user with their password hash, whatever their status
+func (m *neonDBRepo) userByEmail(ctx context.Context, email string) (*models.User, error) {
+	ctx, cancel := m.withTimeout(ctx)
+	defer cancel()
 
-// CreateUser creates a new user in the database
-func (m *neonDBRepo) CreateUser(user models.User) error {
-	id, err := uuid.NewUUID()
+	user := &models.User{}
+	row := m.DB.QueryRow(ctx, "SELECT password, "+userColumns+" FROM users WHERE email = $1", email)
+
+	err := row.Scan(append([]any{&user.Password}, userDest(user)...)...)
 	if err != nil {
+		return nil, translateErr(ctx, err)
+	}
+	return user, nil
+}
+
+// CreateUser creates a new user in the database
+func (m *neonDBRepo) CreateUser(ctx context.Context, user models.User) error {
+	ctx, cancel := m.withTimeout(ctx)
+	defer cancel()
+
+	if err := newUser(&user); err != nil {
 		return err
 	}
+	_, err := m.DB.Exec(ctx, insertUser, insertUserArgs(user)...)
 
-	// convert id to string and set it on the user
-	user.ID = id.String()
+	return translateErr(ctx, err)
+}
+
+// CreateUsers inserts every user in one transaction, so either all of them
+// are created or none are. The users are returned with their new IDs.
+func (m *neonDBRepo) CreateUsers(ctx context.Context, users []models.User) ([]models.User, error) {
+	ctx, cancel := m.withTimeout(ctx)
+	defer cancel()
+
+	created := make([]models.User, len(users))
+	batch := &pgx.Batch{}
+	for i, user := range users {
+		if err := newUser(&user); err != nil {
+			return nil, err
+		}
+		created[i] = user
+		batch.Queue(insertUser, insertUserArgs(user)...)
+	}
 
-	stm, err := m.DB.Prepare(context.Background(), "insert_user", "INSERT INTO users (id, email, password, name, category, dob, bio, avatar, created_at, updated_at) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)")
+	err := pgx.BeginFunc(ctx, m.DB, func(tx pgx.Tx) error {
+		return tx.SendBatch(ctx, batch).Close()
+	})
+	if err != nil {
+		return nil, translateErr(ctx, err)
+	}
+	return created, nil
+}
+
+// insertUser adds one row to users; insertUserArgs supplies its arguments
+const insertUser = "INSERT INTO users (id, email, email_verified_at, password, name, role, status, dob, bio, avatar, avatar_key, totp_enabled_at, created_at, updated_at) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)"
+
+func insertUserArgs(user models.User) []any {
+	return []any{user.ID, user.Email, user.EmailVerifiedAt, user.Password, user.Name, user.Role, user.Status, user.DOB, user.Bio, user.Avatar, user.AvatarKey, user.TOTPEnabledAt, user.CreatedAt, user.UpdatedAt}
+}
+
+// newUser gives a user about to be inserted an ID and fills in the status
+// and timestamps it was created without
+func newUser(user *models.User) error {
+	id, err := uuid.NewUUID()
 	if err != nil {
 		return err
 	}
+	user.ID = id.String()
 
-	_, err = m.DB.Exec(context.Background(), stm.Name, user.ID, user.Email, user.Password, user.Name, user.Category, user.DOB, user.Bio, user.Avatar, user.CreatedAt, user.UpdatedAt)
+	if user.Status == "" {
+		user.Status = models.StatusActive
+	}
 
-	return err
+	now := time.Now()
+	if user.CreatedAt.IsZero() {
+		user.CreatedAt = now
+	}
+	if user.UpdatedAt.IsZero() {
+		user.UpdatedAt = now
+	}
+	return nil
 }
 
-func (m *neonDBRepo) UpdateUser(id string, user models.User) error {
+func (m *neonDBRepo) UpdateUser(ctx context.Context, id string, user models.User) error {
+	ctx, cancel := m.withTimeout(ctx)
+	defer cancel()
+
 	query := `
 		UPDATE users
-		SET name = $1, email = $2, dob = $3, bio = $4, category = $5, updated_at = NOW()
-		WHERE id = $6
+		SET name = $1, email = $2, dob = $3, bio = $4, updated_at = NOW()
+		WHERE id = $5
 	`
-	stm, err := m.DB.Prepare(context.Background(), "update_user", query)
+	tag, err := m.DB.Exec(ctx, query, user.Name, user.Email, user.DOB, user.Bio, id)
 	if err != nil {
-		return err
+		return translateErr(ctx, err)
+	}
+	if tag.RowsAffected() == 0 {
+		return repository.ErrNotFound
 	}
 
-	_, err = m.DB.Exec(context.Background(), stm.Name, user.Name, user.Email, user.DOB, user.Bio, user.Category, id)
+	return nil
+}
+
+// UpdatePassword replaces the user's password hash
+func (m *neonDBRepo) UpdatePassword(ctx context.Context, id, passwordHash string) error {
+	ctx, cancel := m.withTimeout(ctx)
+	defer cancel()
+
+	tag, err := m.DB.Exec(ctx, "UPDATE users SET password = $2, updated_at = NOW() WHERE id = $1", id, passwordHash)
+	if err != nil {
+		return translateErr(ctx, err)
+	}
+	if tag.RowsAffected() == 0 {
+		return repository.ErrNotFound
+	}
 
-	return err
+	return nil
 }
 
-func (m *neonDBRepo) UpdateUserAvatar(userID, filePath string) error {
-	stm, err := m.DB.Prepare(context.Background(), "update_user_avatar", 
-		"UPDATE users SET avatar = $2 WHERE id = $1")
+// UpdateUserRole changes the user's role
+func (m *neonDBRepo) UpdateUserRole(ctx context.Context, id string, role models.Role) error {
+	ctx, cancel := m.withTimeout(ctx)
+	defer cancel()
+
+	tag, err := m.DB.Exec(ctx, "UPDATE users SET role = $2, updated_at = NOW() WHERE id = $1", id, role)
 	if err != nil {
-		return err
+		return translateErr(ctx, err)
+	}
+	if tag.RowsAffected() == 0 {
+		return repository.ErrNotFound
 	}
 
-	// ✅ Correct order: userID first, filePath second
-	_, err = m.DB.Exec(context.Background(), stm.Name, userID, filePath)
-	return err
+	return nil
 }
 
+// UpdateUsersRole changes the role of every listed user and reports how many changed
+func (m *neonDBRepo) UpdateUsersRole(ctx context.Context, ids []string, role models.Role) (int64, error) {
+	ctx, cancel := m.withTimeout(ctx)
+	defer cancel()
 
-func (m *neonDBRepo) DeleteUser(id string) error {
-	stm, err := m.DB.Prepare(context.Background(), "delete_user", "DELETE FROM users WHERE id = $1")
+	tag, err := m.DB.Exec(ctx, "UPDATE users SET role = $2, updated_at = NOW() WHERE id::text = ANY($1)", ids, role)
 	if err != nil {
-		return err
+		return 0, translateErr(ctx, err)
 	}
+	return tag.RowsAffected(), nil
+}
 
-	_, err = m.DB.Exec(context.Background(), stm.Name, id)
+// UpdateUsersStatus changes the status of every listed user and reports how many changed
+func (m *neonDBRepo) UpdateUsersStatus(ctx context.Context, ids []string, status models.Status) (int64, error) {
+	ctx, cancel := m.withTimeout(ctx)
+	defer cancel()
 
-	return err
+	tag, err := m.DB.Exec(ctx, "UPDATE users SET status = $2, updated_at = NOW() WHERE id::text = ANY($1)", ids, status)
+	if err != nil {
+		return 0, translateErr(ctx, err)
+	}
+	return tag.RowsAffected(), nil
 }
 
-func (m *neonDBRepo) AuthenticateUser(email, password string) (*models.User, error) {
-	var user models.User
-	row := m.DB.QueryRow(context.Background(), "SELECT id, email, password, name, category, dob, bio, avatar, created_at, updated_at FROM users WHERE email = $1", email)
-	if err := row.Scan(&user.ID, &user.Email, &user.Password, &user.Name, &user.Category, &user.DOB, &user.Bio, &user.Avatar, &user.CreatedAt, &user.UpdatedAt); err != nil {
+// UpdateUserAvatar stores the avatar URL and the storage key it was saved under
+func (m *neonDBRepo) UpdateUserAvatar(ctx context.Context, userID, url, key string) error {
+	ctx, cancel := m.withTimeout(ctx)
+	defer cancel()
+
+	tag, err := m.DB.Exec(ctx, "UPDATE users SET avatar = $2, avatar_key = $3, updated_at = NOW() WHERE id = $1", userID, url, key)
+	if err != nil {
+		return translateErr(ctx, err)
+	}
+	if tag.RowsAffected() == 0 {
+		return repository.ErrNotFound
+	}
+
+	return nil
+}
+
+// DeleteUser marks the user deleted. The row stays until PurgeDeletedUsers
+// removes it, so the account can be restored in the meantime.
+func (m *neonDBRepo) DeleteUser(ctx context.Context, id string) error {
+	ctx, cancel := m.withTimeout(ctx)
+	defer cancel()
+
+	tag, err := m.DB.Exec(ctx, "UPDATE users SET deleted_at = now(), updated_at = now() WHERE id = $1 AND deleted_at IS NULL", id)
+	if err != nil {
+		return translateErr(ctx, err)
+	}
+	if tag.RowsAffected() == 0 {
+		return repository.ErrNotFound
+	}
+
+	return nil
+}
+
+// RestoreUser undoes DeleteUser
+func (m *neonDBRepo) RestoreUser(ctx context.Context, id string) error {
+	ctx, cancel := m.withTimeout(ctx)
+	defer cancel()
+
+	tag, err := m.DB.Exec(ctx, "UPDATE users SET deleted_at = NULL, updated_at = now() WHERE id = $1 AND deleted_at IS NOT NULL AND anonymized_at IS NULL", id)
+	if err != nil {
+		return translateErr(ctx, err)
+	}
+	if tag.RowsAffected() == 0 {
+		return repository.ErrNotFound
+	}
+
+	return nil
+}
+
+// academicRecords matches the users whose enrollments and grades must
+// outlive their account
+const academicRecords = `(
+	EXISTS (SELECT 1 FROM enrollments WHERE enrollments.student_id = users.id) OR
+	EXISTS (SELECT 1 FROM scores WHERE scores.student_id = users.id) OR
+	EXISTS (SELECT 1 FROM score_history WHERE score_history.student_id = users.id))`
+
+// PurgeDeletedUsers removes for good the users deleted before the cutoff,
+// along with everything that cascades from them, and returns who they were.
+// Users with academic records are anonymised instead, so their grades keep
+// pointing at a row.
+func (m *neonDBRepo) PurgeDeletedUsers(ctx context.Context, before time.Time) ([]models.User, error) {
+	ctx, cancel := m.withTimeout(ctx)
+	defer cancel()
+
+	var users []models.User
+	err := pgx.BeginFunc(ctx, m.DB, func(tx pgx.Tx) error {
+		rows, err := tx.Query(ctx, `
+			WITH old AS (
+				SELECT `+userColumns+` FROM users
+				WHERE deleted_at < $1 AND anonymized_at IS NULL AND `+academicRecords+`
+				FOR UPDATE
+			)
+			UPDATE users SET
+				email = users.id || '@' || $3, pending_email = '', password = '', name = $2, bio = '',
+				avatar = '', avatar_key = '', totp_secret = '', totp_enabled_at = NULL, totp_last_counter = 0,
+				anonymized_at = now(), updated_at = now()
+			FROM old WHERE users.id = old.id
+			RETURNING old.*`,
+			before, models.AnonymizedName, models.AnonymizedDomain)
+		if err != nil {
+			return err
+		}
+		anonymized, err := collectUsers(rows)
+		if err != nil {
+			return err
+		}
+
+		// what would have cascaded from the row goes with the details
+		if len(anonymized) > 0 {
+			ids := make([]string, len(anonymized))
+			for i, u := range anonymized {
+				ids[i] = u.ID
+			}
+			for _, table := range []string{"sessions", "password_resets", "user_preferences", "recovery_codes", "login_failures"} {
+				if _, err := tx.Exec(ctx, "DELETE FROM "+table+" WHERE user_id::text = ANY($1)", ids); err != nil {
+					return err
+				}
+			}
+		}
+
+		rows, err = tx.Query(ctx, "DELETE FROM users WHERE deleted_at < $1 AND anonymized_at IS NULL RETURNING "+userColumns, before)
+		if err != nil {
+			return err
+		}
+		deleted, err := collectUsers(rows)
+		users = append(anonymized, deleted...)
+		return err
+	})
+	if err != nil {
+		return nil, translateErr(ctx, err)
+	}
+	return users, nil
+}
+
+// collectUsers scans rows of userColumns
+func collectUsers(rows pgx.Rows) ([]models.User, error) {
+	return pgx.CollectRows(rows, func(row pgx.CollectableRow) (models.User, error) {
+		var u models.User
+		err := row.Scan(userDest(&u)...)
+		return u, err
+	})
+}
+
+// AuthenticateUser checks the password of any account, active or not, so the
+// caller can decide what to tell a user who isn't allowed in
+func (m *neonDBRepo) AuthenticateUser(ctx context.Context, email, password string) (*models.User, error) {
+	user, err := m.userByEmail(ctx, email)
+	if err != nil {
 		return nil, err
 	}
 
@@ -142,5 +456,5 @@ func (m *neonDBRepo) AuthenticateUser(email, password string) (*models.User, err
 		return nil, err
 	}
 
-	return &user, nil
+	return user, nil
 }
@@ -0,0 +1,51 @@
+package repository
+
+import "errors"
+
+var (
+	// ErrNotFound is returned when the requested record does not exist
+	ErrNotFound = errors.New("record not found")
+
+	// ErrInactive is returned when a lookup for signing in finds an account
+	// that is suspended, deactivated or deleted
+	ErrInactive = errors.New("account is not active")
+
+	// ErrDuplicateEmail is returned when another user already has the email address
+	ErrDuplicateEmail = errors.New("email address already in use")
+
+	// ErrDuplicateCourseCode is returned when another course already has the code
+	ErrDuplicateCourseCode = errors.New("course code already in use")
+
+	// ErrDuplicateTermName is returned when another term already has the name
+	ErrDuplicateTermName = errors.New("term name already in use")
+
+	// ErrDuplicateSessionName is returned when another academic session
+	// already has the name
+	ErrDuplicateSessionName = errors.New("session name already in use")
+
+	// ErrSessionInUse is returned when deleting an academic session that
+	// still has terms
+	ErrSessionInUse = errors.New("session has terms")
+
+	// ErrDuplicateScaleName is returned when another grading scale already
+	// has the name
+	ErrDuplicateScaleName = errors.New("grading scale name already in use")
+
+	// ErrDuplicateProgrammeName is returned when another programme already
+	// has the name
+	ErrDuplicateProgrammeName = errors.New("programme name already in use")
+
+	// ErrScaleInUse is returned when deleting the default grading scale or
+	// one a programme grades on
+	ErrScaleInUse = errors.New("grading scale in use")
+
+	// ErrAlreadyEnrolled is returned when the student already holds a seat
+	// or waitlist place in the course for the term
+	ErrAlreadyEnrolled = errors.New("already enrolled")
+
+	// ErrTimeout is returned when a query ran past its deadline
+	ErrTimeout = errors.New("query timed out")
+
+	// ErrCanceled is returned when the caller gave up first, e.g. the client disconnected
+	ErrCanceled = errors.New("request cancelled")
+)
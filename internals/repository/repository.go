@@ -1,16 +1,165 @@
 package repository
 
 import (
+	"context"
+	"time"
+
 	"github.com/stackninja.pro/goth/internals/models"
 )
 
 type DatabaseRepo interface {
-	GetAllUsers() ([]models.User, error)
-	GetUserByID(id string) (*models.User, error)
-	GetUserByEmail(email string) (*models.User, error)
-	CreateUser(user models.User) error
-	UpdateUser(id string, user models.User) error
-	UpdateUserAvatar(userID, filePath string) error
-	DeleteUser(id string) error
-	AuthenticateUser(email, password string) (*models.User, error)
+	GetAllUsers(ctx context.Context, q models.UserQuery) ([]models.User, int, error)
+	ForEachUser(ctx context.Context, q models.UserQuery, fn func(models.User) error) error
+	GetUserByID(ctx context.Context, id string) (*models.User, error)
+	GetUserByEmail(ctx context.Context, email string) (*models.User, error)
+	CreateUser(ctx context.Context, user models.User) error
+	CreateUsers(ctx context.Context, users []models.User) ([]models.User, error)
+	UpdateUser(ctx context.Context, id string, user models.User) error
+	UpdatePassword(ctx context.Context, id, passwordHash string) error
+	UpdateUserRole(ctx context.Context, id string, role models.Role) error
+	UpdateUsersRole(ctx context.Context, ids []string, role models.Role) (int64, error)
+	UpdateUsersStatus(ctx context.Context, ids []string, status models.Status) (int64, error)
+	UpdateUserAvatar(ctx context.Context, userID, url, key string) error
+	DeleteUser(ctx context.Context, id string) error
+	RestoreUser(ctx context.Context, id string) error
+	PurgeDeletedUsers(ctx context.Context, before time.Time) ([]models.User, error)
+	AuthenticateUser(ctx context.Context, email, password string) (*models.User, error)
+
+	GetPreferences(ctx context.Context, userID string) (models.Preferences, error)
+	SavePreferences(ctx context.Context, p models.Preferences) error
+
+	VerifyEmail(ctx context.Context, id, email string) error
+	SetPendingEmail(ctx context.Context, id, email string) error
+	ConfirmEmailChange(ctx context.Context, id, email string) error
+
+	EnableTwoFactor(ctx context.Context, userID, secret string, counter int64, codeHashes []string) error
+	DisableTwoFactor(ctx context.Context, userID string) error
+	GetTOTPSecret(ctx context.Context, userID string) (string, int64, error)
+	UseTOTPCounter(ctx context.Context, userID string, counter int64) error
+	UseRecoveryCode(ctx context.Context, userID, codeHash string) error
+	ReplaceRecoveryCodes(ctx context.Context, userID string, codeHashes []string) error
+	CountRecoveryCodes(ctx context.Context, userID string) (int, error)
+	GetTwoFactorPolicy(ctx context.Context) (map[models.Role]bool, error)
+	SetTwoFactorRequired(ctx context.Context, role models.Role, required bool) error
+
+	GetLoginFailures(ctx context.Context, userID string) (models.LoginFailures, error)
+	RecordLoginFailure(ctx context.Context, userID string) (models.LoginFailures, error)
+	LockAccount(ctx context.Context, userID string, until time.Time) error
+	ClearLoginFailures(ctx context.Context, userID string) error
+	GetLockedAccounts(ctx context.Context) ([]models.LoginFailures, error)
+
+	RecordAuditEvent(ctx context.Context, e models.AuditEvent) error
+	GetAuditEvents(ctx context.Context, q models.AuditQuery) ([]models.AuditEvent, int, error)
+	ForEachAuditEvent(ctx context.Context, q models.AuditQuery, fn func(models.AuditEvent) error) error
+
+	CreatePasswordReset(ctx context.Context, userID, tokenHash string, expiresAt time.Time) error
+	GetPasswordReset(ctx context.Context, tokenHash string) (string, error)
+	ResetPassword(ctx context.Context, tokenHash, passwordHash string) (string, error)
+}
+
+// CourseRepo stores courses, who teaches them and who is enrolled in them
+type CourseRepo interface {
+	GetCourses(ctx context.Context, q models.CourseQuery) ([]models.Course, int, error)
+	GetCourse(ctx context.Context, id string) (*models.Course, error)
+	GetDepartments(ctx context.Context) ([]string, error)
+	CreateCourse(ctx context.Context, c models.Course) (string, error)
+
+	// UpdateCourse saves the course and returns the waitlisted students
+	// promoted into seats a raised capacity frees
+	UpdateCourse(ctx context.Context, c models.Course) ([]models.Enrollment, error)
+
+	SetCourseStatus(ctx context.Context, id string, status models.CourseStatus) error
+
+	// Enroll gives the student a seat in the course for the term, or a place
+	// at the end of its waitlist when the course is full. It also returns
+	// the waitlisted students promoted into free seats ahead of them.
+	Enroll(ctx context.Context, courseID, termID, studentID string) (*models.Enrollment, []models.Enrollment, error)
+
+	// DropEnrollment gives up the student's seat or waitlist place and
+	// returns the waitlisted students promoted into the freed seat
+	DropEnrollment(ctx context.Context, courseID, termID, studentID string) ([]models.Enrollment, error)
+
+	GetEnrollment(ctx context.Context, courseID, termID, studentID string) (*models.Enrollment, error)
+	GetRoster(ctx context.Context, courseID, termID string) ([]models.Enrollment, error)
+	GetStudentEnrollments(ctx context.Context, studentID, termID string) ([]models.Enrollment, error)
+	GetSeats(ctx context.Context, courseID, termID string) (models.Seats, error)
+}
+
+// TermRepo stores the terms courses are taken in and the academic sessions
+// they belong to
+type TermRepo interface {
+	GetSessions(ctx context.Context) ([]models.AcademicSession, error)
+	GetSession(ctx context.Context, id string) (*models.AcademicSession, error)
+	CreateSession(ctx context.Context, s models.AcademicSession) (string, error)
+	UpdateSession(ctx context.Context, s models.AcademicSession) error
+	DeleteSession(ctx context.Context, id string) error
+
+	GetTerms(ctx context.Context) ([]models.Term, error)
+	GetTerm(ctx context.Context, id string) (*models.Term, error)
+	GetCurrentTerm(ctx context.Context) (*models.Term, error)
+	CreateTerm(ctx context.Context, t models.Term) (string, error)
+	UpdateTerm(ctx context.Context, t models.Term) error
+	SetCurrentTerm(ctx context.Context, id string) error
+}
+
+// GradeRepo stores each course's assessments, the scores students earn in
+// them with their edit history, and whether the grades are published
+type GradeRepo interface {
+	GetAssessments(ctx context.Context, courseID, termID string) ([]models.Assessment, error)
+	GetAssessment(ctx context.Context, id string) (*models.Assessment, error)
+	CreateAssessment(ctx context.Context, a models.Assessment) (string, error)
+	UpdateAssessment(ctx context.Context, a models.Assessment) error
+	DeleteAssessment(ctx context.Context, id string) error
+
+	// GetScores returns the course's scores for the term by student ID
+	GetScores(ctx context.Context, courseID, termID string) (map[string]models.Scores, error)
+
+	// SetScore records a score, or clears it when score is nil, along with
+	// a history entry naming the editor. Setting the same score again
+	// changes nothing.
+	SetScore(ctx context.Context, assessmentID, studentID string, score *float64, editorEmail string) error
+
+	// GetScoreHistory lists the changes to one student's scores in the
+	// course for the term, newest first
+	GetScoreHistory(ctx context.Context, courseID, termID, studentID string) ([]models.ScoreChange, error)
+
+	// GetGradesPublished returns when the course's grades for the term were
+	// published, or nil while they aren't
+	GetGradesPublished(ctx context.Context, courseID, termID string) (*time.Time, error)
+	SetGradesPublished(ctx context.Context, courseID, termID string, published bool) error
+
+	// GetPublishedGrades returns the student's published results in the
+	// courses they are enrolled in, newest term first
+	GetPublishedGrades(ctx context.Context, studentID string) ([]models.CourseGrade, error)
+}
+
+// ScaleRepo stores the grading scales and the programmes that grade their
+// students on them
+type ScaleRepo interface {
+	GetScales(ctx context.Context) ([]models.GradingScale, error)
+	GetScale(ctx context.Context, id string) (*models.GradingScale, error)
+	CreateScale(ctx context.Context, s models.GradingScale) (string, error)
+	UpdateScale(ctx context.Context, s models.GradingScale) error
+
+	// DeleteScale returns ErrScaleInUse for the default scale and for scales
+	// a programme uses
+	DeleteScale(ctx context.Context, id string) error
+	SetDefaultScale(ctx context.Context, id string) error
+
+	GetProgrammes(ctx context.Context) ([]models.Programme, error)
+	GetProgramme(ctx context.Context, id string) (*models.Programme, error)
+	CreateProgramme(ctx context.Context, p models.Programme) (string, error)
+	UpdateProgramme(ctx context.Context, p models.Programme) error
+	DeleteProgramme(ctx context.Context, id string) error
+
+	// GetProgrammeStudents lists the programme's students by name
+	GetProgrammeStudents(ctx context.Context, programmeID string) ([]models.User, error)
+
+	// SetStudentProgramme moves a student into a programme, or out of
+	// theirs when programmeID is empty
+	SetStudentProgramme(ctx context.Context, studentID, programmeID string) error
+
+	// GetStudentScales returns the scale each student is graded on: their
+	// programme's, or the default scale for students outside any programme
+	GetStudentScales(ctx context.Context, studentIDs []string) (map[string]models.GradingScale, error)
 }
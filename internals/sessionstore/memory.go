@@ -0,0 +1,117 @@
+package sessionstore
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/stackninja.pro/goth/internals/models"
+)
+
+// memoryBackend keeps sessions in a map, for tests and offline development
+type memoryBackend struct {
+	mu       sync.Mutex
+	sessions map[string]record
+}
+
+// NewMemoryStore creates a Store that keeps sessions in memory
+func NewMemoryStore(keyPairs ...[]byte) *Store {
+	return newStore(&memoryBackend{sessions: map[string]record{}}, keyPairs...)
+}
+
+func (b *memoryBackend) load(ctx context.Context, id string) (*record, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	rec, ok := b.sessions[id]
+	if !ok || !rec.ExpiresAt.After(time.Now()) {
+		return nil, ErrNotFound
+	}
+	return &rec, nil
+}
+
+func (b *memoryBackend) save(ctx context.Context, rec record) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if existing, ok := b.sessions[rec.ID]; ok {
+		rec.CreatedAt = existing.CreatedAt
+	}
+	b.sessions[rec.ID] = rec
+	return nil
+}
+
+func (b *memoryBackend) touch(ctx context.Context, id string, at time.Time) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if rec, ok := b.sessions[id]; ok {
+		rec.LastSeenAt = at
+		b.sessions[id] = rec
+	}
+	return nil
+}
+
+func (b *memoryBackend) delete(ctx context.Context, id string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	delete(b.sessions, id)
+	return nil
+}
+
+func (b *memoryBackend) list(ctx context.Context, userID string) ([]models.UserSession, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := time.Now()
+	var list []models.UserSession
+	for _, rec := range b.sessions {
+		if rec.UserID == userID && rec.ExpiresAt.After(now) {
+			list = append(list, rec.UserSession)
+		}
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].LastSeenAt.After(list[j].LastSeenAt) })
+	return list, nil
+}
+
+func (b *memoryBackend) deleteForUser(ctx context.Context, userID, id string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	rec, ok := b.sessions[id]
+	if !ok || rec.UserID != userID {
+		return ErrNotFound
+	}
+	delete(b.sessions, id)
+	return nil
+}
+
+func (b *memoryBackend) deleteAllForUser(ctx context.Context, userID, exceptID string) (int64, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	var n int64
+	for id, rec := range b.sessions {
+		if rec.UserID == userID && id != exceptID {
+			delete(b.sessions, id)
+			n++
+		}
+	}
+	return n, nil
+}
+
+func (b *memoryBackend) deleteExpired(ctx context.Context, now time.Time) (int64, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	var n int64
+	for id, rec := range b.sessions {
+		if !rec.ExpiresAt.After(now) {
+			delete(b.sessions, id)
+			n++
+		}
+	}
+	return n, nil
+}
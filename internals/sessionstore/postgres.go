@@ -0,0 +1,112 @@
+package sessionstore
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/stackninja.pro/goth/internals/models"
+)
+
+// pgBackend stores sessions in the sessions table
+type pgBackend struct {
+	DB *pgxpool.Pool
+}
+
+// NewPGStore creates a Store backed by the sessions table. keyPairs sign the
+// cookie as in sessions.NewCookieStore.
+func NewPGStore(pool *pgxpool.Pool, keyPairs ...[]byte) *Store {
+	return newStore(&pgBackend{DB: pool}, keyPairs...)
+}
+
+func (b *pgBackend) load(ctx context.Context, id string) (*record, error) {
+	var rec record
+	row := b.DB.QueryRow(ctx, `
+		SELECT id, COALESCE(user_id::text, ''), data, user_agent, ip, created_at, last_seen_at, expires_at
+		FROM sessions
+		WHERE id = $1 AND expires_at > NOW()`, id)
+	err := row.Scan(&rec.ID, &rec.UserID, &rec.Data, &rec.UserAgent, &rec.IP, &rec.CreatedAt, &rec.LastSeenAt, &rec.ExpiresAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &rec, nil
+}
+
+func (b *pgBackend) save(ctx context.Context, rec record) error {
+	_, err := b.DB.Exec(ctx, `
+		INSERT INTO sessions (id, user_id, data, user_agent, ip, created_at, last_seen_at, expires_at)
+		VALUES ($1, NULLIF($2, '')::uuid, $3, $4, $5, $6, $7, $8)
+		ON CONFLICT (id) DO UPDATE
+		SET user_id = EXCLUDED.user_id,
+		    data = EXCLUDED.data,
+		    user_agent = EXCLUDED.user_agent,
+		    ip = EXCLUDED.ip,
+		    last_seen_at = EXCLUDED.last_seen_at,
+		    expires_at = EXCLUDED.expires_at`,
+		rec.ID, rec.UserID, rec.Data, rec.UserAgent, rec.IP, rec.CreatedAt, rec.LastSeenAt, rec.ExpiresAt)
+	return err
+}
+
+func (b *pgBackend) touch(ctx context.Context, id string, at time.Time) error {
+	_, err := b.DB.Exec(ctx, "UPDATE sessions SET last_seen_at = $2 WHERE id = $1", id, at)
+	return err
+}
+
+func (b *pgBackend) delete(ctx context.Context, id string) error {
+	_, err := b.DB.Exec(ctx, "DELETE FROM sessions WHERE id = $1", id)
+	return err
+}
+
+func (b *pgBackend) list(ctx context.Context, userID string) ([]models.UserSession, error) {
+	rows, err := b.DB.Query(ctx, `
+		SELECT id, user_id::text, user_agent, ip, created_at, last_seen_at, expires_at
+		FROM sessions
+		WHERE user_id = $1 AND expires_at > NOW()
+		ORDER BY last_seen_at DESC`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var list []models.UserSession
+	for rows.Next() {
+		var s models.UserSession
+		if err := rows.Scan(&s.ID, &s.UserID, &s.UserAgent, &s.IP, &s.CreatedAt, &s.LastSeenAt, &s.ExpiresAt); err != nil {
+			return nil, err
+		}
+		list = append(list, s)
+	}
+	return list, rows.Err()
+}
+
+func (b *pgBackend) deleteForUser(ctx context.Context, userID, id string) error {
+	tag, err := b.DB.Exec(ctx, "DELETE FROM sessions WHERE id = $1 AND user_id = $2", id, userID)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (b *pgBackend) deleteAllForUser(ctx context.Context, userID, exceptID string) (int64, error) {
+	tag, err := b.DB.Exec(ctx, "DELETE FROM sessions WHERE user_id = $1 AND id <> $2", userID, exceptID)
+	if err != nil {
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
+}
+
+func (b *pgBackend) deleteExpired(ctx context.Context, now time.Time) (int64, error) {
+	tag, err := b.DB.Exec(ctx, "DELETE FROM sessions WHERE expires_at <= $1", now)
+	if err != nil {
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
+}
@@ -0,0 +1,267 @@
+// Package sessionstore keeps sessions on the server so they can be listed and
+// revoked. The cookie only carries a random token; the values live in Postgres
+// (or memory in tests) under a hash of that token.
+package sessionstore
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/securecookie"
+	"github.com/gorilla/sessions"
+	"github.com/stackninja.pro/goth/internals/clientip"
+	"github.com/stackninja.pro/goth/internals/models"
+)
+
+// ErrNotFound is returned when a session doesn't exist, has expired or
+// belongs to someone else
+var ErrNotFound = errors.New("sessionstore: session not found")
+
+const (
+	// userIDKey is the session value handlers store the logged in user under
+	userIDKey = "user_id"
+
+	// touchInterval limits how often reading a session updates last_seen_at
+	touchInterval = time.Minute
+
+	tokenLength = 32
+)
+
+// record is a stored session
+type record struct {
+	models.UserSession
+	Data []byte
+}
+
+// backend persists records keyed by the hashed token
+type backend interface {
+	load(ctx context.Context, id string) (*record, error)
+	save(ctx context.Context, rec record) error
+	touch(ctx context.Context, id string, at time.Time) error
+	delete(ctx context.Context, id string) error
+	list(ctx context.Context, userID string) ([]models.UserSession, error)
+	deleteForUser(ctx context.Context, userID, id string) error
+	deleteAllForUser(ctx context.Context, userID, exceptID string) (int64, error)
+	deleteExpired(ctx context.Context, now time.Time) (int64, error)
+}
+
+// Store is a gorilla sessions.Store that keeps session values server side
+type Store struct {
+	Codecs  []securecookie.Codec
+	Options *sessions.Options
+
+	// ClientIP is the address recorded for a new session. It defaults to the
+	// peer address, ignoring X-Forwarded-For.
+	ClientIP func(r *http.Request) string
+
+	backend    backend
+	serializer securecookie.GobEncoder
+}
+
+func newStore(b backend, keyPairs ...[]byte) *Store {
+	return &Store{
+		Codecs: securecookie.CodecsFromPairs(keyPairs...),
+		Options: &sessions.Options{
+			Path:     "/",
+			MaxAge:   86400 * 30,
+			HttpOnly: true,
+		},
+		backend: b,
+	}
+}
+
+// Get returns the named session, cached for the rest of the request
+func (s *Store) Get(r *http.Request, name string) (*sessions.Session, error) {
+	return sessions.GetRegistry(r).Get(s, name)
+}
+
+// New loads the session named by the request's cookie. A missing, tampered,
+// expired or revoked cookie gives a fresh session rather than an error, so
+// only storage failures are reported.
+func (s *Store) New(r *http.Request, name string) (*sessions.Session, error) {
+	session := sessions.NewSession(s, name)
+	opts := *s.Options
+	session.Options = &opts
+	session.IsNew = true
+
+	c, err := r.Cookie(name)
+	if err != nil {
+		return session, nil
+	}
+
+	var token string
+	if err := securecookie.DecodeMulti(name, c.Value, &token, s.Codecs...); err != nil {
+		return session, nil
+	}
+
+	id := hashToken(token)
+	rec, err := s.backend.load(r.Context(), id)
+	if errors.Is(err, ErrNotFound) {
+		return session, nil
+	}
+	if err != nil {
+		return session, err
+	}
+
+	if err := s.serializer.Deserialize(rec.Data, &session.Values); err != nil {
+		return session, err
+	}
+	session.ID = token
+	session.IsNew = false
+
+	if now := time.Now(); now.Sub(rec.LastSeenAt) > touchInterval {
+		if err := s.backend.touch(r.Context(), id, now); err != nil {
+			log.Println("⚠️ Failed to update session last seen:", err)
+		}
+	}
+
+	return session, nil
+}
+
+// Save writes the session and its cookie. A negative MaxAge deletes the
+// stored session, which is what makes logging out revoke it.
+func (s *Store) Save(r *http.Request, w http.ResponseWriter, session *sessions.Session) error {
+	if session.Options.MaxAge < 0 {
+		if session.ID != "" {
+			if err := s.backend.delete(r.Context(), hashToken(session.ID)); err != nil && !errors.Is(err, ErrNotFound) {
+				return err
+			}
+		}
+		http.SetCookie(w, sessions.NewCookie(session.Name(), "", session.Options))
+		return nil
+	}
+
+	if session.ID == "" {
+		session.ID = newToken()
+	}
+
+	data, err := s.serializer.Serialize(session.Values)
+	if err != nil {
+		return err
+	}
+
+	maxAge := session.Options.MaxAge
+	if maxAge == 0 {
+		// a browser-session cookie still needs an end on the server
+		maxAge = s.Options.MaxAge
+	}
+
+	now := time.Now()
+	userID, _ := session.Values[userIDKey].(string)
+	rec := record{
+		UserSession: models.UserSession{
+			ID:         hashToken(session.ID),
+			UserID:     userID,
+			UserAgent:  r.UserAgent(),
+			IP:         s.clientIP(r),
+			CreatedAt:  now,
+			LastSeenAt: now,
+			ExpiresAt:  now.Add(time.Duration(maxAge) * time.Second),
+		},
+		Data: data,
+	}
+	if err := s.backend.save(r.Context(), rec); err != nil {
+		return err
+	}
+
+	encoded, err := securecookie.EncodeMulti(session.Name(), session.ID, s.Codecs...)
+	if err != nil {
+		return err
+	}
+	http.SetCookie(w, sessions.NewCookie(session.Name(), encoded, session.Options))
+	return nil
+}
+
+// Rotate moves the session's values to a new token and drops the old one, so
+// a token seen before a login or privilege change stops working
+func (s *Store) Rotate(r *http.Request, w http.ResponseWriter, session *sessions.Session) error {
+	if session.ID != "" {
+		if err := s.backend.delete(r.Context(), hashToken(session.ID)); err != nil && !errors.Is(err, ErrNotFound) {
+			return err
+		}
+	}
+	session.ID = ""
+	return s.Save(r, w, session)
+}
+
+// ID returns the public ID of a session, as used by List and Revoke
+func (s *Store) ID(session *sessions.Session) string {
+	if session == nil || session.ID == "" {
+		return ""
+	}
+	return hashToken(session.ID)
+}
+
+// List returns the user's live sessions, most recently used first
+func (s *Store) List(ctx context.Context, userID string) ([]models.UserSession, error) {
+	return s.backend.list(ctx, userID)
+}
+
+// Revoke ends one of the user's sessions
+func (s *Store) Revoke(ctx context.Context, userID, id string) error {
+	return s.backend.deleteForUser(ctx, userID, id)
+}
+
+// RevokeAll ends every session of the user except exceptID, which may be
+// empty, and reports how many were ended
+func (s *Store) RevokeAll(ctx context.Context, userID, exceptID string) (int64, error) {
+	return s.backend.deleteAllForUser(ctx, userID, exceptID)
+}
+
+// Sweep deletes expired sessions
+func (s *Store) Sweep(ctx context.Context) (int64, error) {
+	return s.backend.deleteExpired(ctx, time.Now())
+}
+
+// RunSweeper calls Sweep every interval until ctx is done
+func (s *Store) RunSweeper(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			n, err := s.Sweep(ctx)
+			if err != nil {
+				log.Println("❌ Failed to sweep expired sessions:", err)
+				continue
+			}
+			if n > 0 {
+				log.Printf("🧹 Swept %d expired sessions", n)
+			}
+		}
+	}
+}
+
+// hashToken is the ID a token is stored under, so a leaked table can't be
+// replayed as cookies
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// newToken returns a random URL-safe session token
+func newToken() string {
+	b := make([]byte, tokenLength)
+	if _, err := rand.Read(b); err != nil {
+		panic("sessionstore: crypto/rand failed: " + err.Error())
+	}
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// clientIP is the address shown on the sessions page
+func (s *Store) clientIP(r *http.Request) string {
+	if s.ClientIP != nil {
+		return s.ClientIP(r)
+	}
+	return clientip.FromRequest(r, false)
+}
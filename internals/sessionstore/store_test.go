@@ -0,0 +1,163 @@
+package sessionstore
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+const testName = "test-session"
+
+func newTestStore() *Store {
+	return NewMemoryStore([]byte("test-session-secret-0123456789"))
+}
+
+// saveSession stores values in a new session and returns the cookie it set
+func saveSession(t *testing.T, s *Store, values map[interface{}]interface{}) (*http.Cookie, string) {
+	t.Helper()
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("User-Agent", "Mozilla/5.0 (X11; Linux x86_64) Firefox/128.0")
+	session, err := s.New(req, testName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for k, v := range values {
+		session.Values[k] = v
+	}
+
+	rr := httptest.NewRecorder()
+	if err := s.Save(req, rr, session); err != nil {
+		t.Fatal(err)
+	}
+	return rr.Result().Cookies()[0], s.ID(session)
+}
+
+// load reads the session named by cookie the way a new request would
+func load(t *testing.T, s *Store, cookie *http.Cookie) (map[interface{}]interface{}, bool) {
+	t.Helper()
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.AddCookie(cookie)
+	session, err := s.New(req, testName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return session.Values, !session.IsNew
+}
+
+func TestStoreRoundTrip(t *testing.T) {
+	s := newTestStore()
+	cookie, id := saveSession(t, s, map[interface{}]interface{}{userIDKey: "u1", "n": 3})
+
+	values, ok := load(t, s, cookie)
+	if !ok || values[userIDKey] != "u1" || values["n"] != 3 {
+		t.Fatalf("expected stored values back, got %v (found=%t)", values, ok)
+	}
+
+	list, err := s.List(context.Background(), "u1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != 1 || list[0].ID != id || list[0].UserAgent == "" || list[0].IP == "" {
+		t.Errorf("unexpected session list: %+v", list)
+	}
+}
+
+func TestStoreIgnoresBadCookies(t *testing.T) {
+	s := newTestStore()
+	cookie, _ := saveSession(t, s, map[interface{}]interface{}{userIDKey: "u1"})
+
+	tampered := *cookie
+	tampered.Value = cookie.Value[:len(cookie.Value)-4] + "AAAA"
+	if _, ok := load(t, s, &tampered); ok {
+		t.Error("tampered cookie loaded a session")
+	}
+
+	other := NewMemoryStore([]byte("a-different-secret-0123456789"))
+	if _, ok := load(t, other, cookie); ok {
+		t.Error("cookie signed with another key loaded a session")
+	}
+}
+
+func TestStoreRotate(t *testing.T) {
+	s := newTestStore()
+	cookie, _ := saveSession(t, s, map[interface{}]interface{}{userIDKey: "u1"})
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.AddCookie(cookie)
+	session, err := s.New(req, testName)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rr := httptest.NewRecorder()
+	if err := s.Rotate(req, rr, session); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, ok := load(t, s, cookie); ok {
+		t.Error("old cookie still valid after rotation")
+	}
+	if values, ok := load(t, s, rr.Result().Cookies()[0]); !ok || values[userIDKey] != "u1" {
+		t.Errorf("rotated cookie lost the session values: %v", values)
+	}
+}
+
+func TestStoreRevoke(t *testing.T) {
+	s := newTestStore()
+	ctx := context.Background()
+
+	keep, keepID := saveSession(t, s, map[interface{}]interface{}{userIDKey: "u1"})
+	gone, goneID := saveSession(t, s, map[interface{}]interface{}{userIDKey: "u1"})
+	also, _ := saveSession(t, s, map[interface{}]interface{}{userIDKey: "u1"})
+	theirs, theirsID := saveSession(t, s, map[interface{}]interface{}{userIDKey: "u2"})
+
+	if err := s.Revoke(ctx, "u1", theirsID); err != ErrNotFound {
+		t.Errorf("expected ErrNotFound revoking another user's session, got %v", err)
+	}
+	if err := s.Revoke(ctx, "u1", goneID); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := load(t, s, gone); ok {
+		t.Error("revoked session still loads")
+	}
+
+	n, err := s.RevokeAll(ctx, "u1", keepID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 1 {
+		t.Errorf("expected 1 other session revoked, got %d", n)
+	}
+	if _, ok := load(t, s, also); ok {
+		t.Error("other session still loads after RevokeAll")
+	}
+	if _, ok := load(t, s, keep); !ok {
+		t.Error("RevokeAll ended the session it was told to keep")
+	}
+	if _, ok := load(t, s, theirs); !ok {
+		t.Error("RevokeAll ended another user's session")
+	}
+}
+
+func TestStoreSweep(t *testing.T) {
+	s := newTestStore()
+	s.Options.MaxAge = 1
+
+	cookie, _ := saveSession(t, s, nil)
+
+	b := s.backend.(*memoryBackend)
+	n, err := b.deleteExpired(context.Background(), time.Now().Add(2*time.Second))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 1 {
+		t.Errorf("expected 1 expired session swept, got %d", n)
+	}
+	if _, ok := load(t, s, cookie); ok {
+		t.Error("swept session still loads")
+	}
+}
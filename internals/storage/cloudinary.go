@@ -0,0 +1,116 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"path"
+	"strings"
+
+	"github.com/cloudinary/cloudinary-go/v2"
+	"github.com/cloudinary/cloudinary-go/v2/api/uploader"
+)
+
+// Cloudinary keeps images in a Cloudinary account. Cloudinary public IDs
+// have no extension, so "avatars/x.png" is stored as "avatars/x" and
+// delivered as PNG through its URL.
+type Cloudinary struct {
+	cld       *cloudinary.Cloudinary
+	cloudName string
+	client    *http.Client
+}
+
+// NewCloudinary creates a Cloudinary store from the account credentials
+func NewCloudinary(cloudName, apiKey, apiSecret string) (*Cloudinary, error) {
+	cld, err := cloudinary.NewFromParams(cloudName, apiKey, apiSecret)
+	if err != nil {
+		return nil, err
+	}
+	return &Cloudinary{cld: cld, cloudName: cloudName, client: http.DefaultClient}, nil
+}
+
+// Put uploads the image under the key's public ID
+func (c *Cloudinary) Put(ctx context.Context, key string, r io.Reader, contentType string) error {
+	publicID, err := c.publicID(key)
+	if err != nil {
+		return err
+	}
+
+	overwrite := true
+	res, err := c.cld.Upload.Upload(ctx, r, uploader.UploadParams{
+		PublicID:  publicID,
+		Overwrite: &overwrite,
+	})
+	if err != nil {
+		return err
+	}
+	if res.Error.Message != "" {
+		return fmt.Errorf("storage: cloudinary upload: %s", res.Error.Message)
+	}
+	return nil
+}
+
+// Get downloads the image from its delivery URL
+func (c *Cloudinary) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	if _, err := c.publicID(key); err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.URL(key), nil)
+	if err != nil {
+		return nil, err
+	}
+	res, err := c.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if res.StatusCode == http.StatusNotFound {
+		res.Body.Close()
+		return nil, ErrNotFound
+	}
+	if res.StatusCode != http.StatusOK {
+		res.Body.Close()
+		return nil, fmt.Errorf("storage: cloudinary get %s: %s", key, res.Status)
+	}
+	return res.Body, nil
+}
+
+// Delete destroys the image and invalidates cached copies
+func (c *Cloudinary) Delete(ctx context.Context, key string) error {
+	publicID, err := c.publicID(key)
+	if err != nil {
+		return err
+	}
+
+	invalidate := true
+	res, err := c.cld.Upload.Destroy(ctx, uploader.DestroyParams{
+		PublicID:   publicID,
+		Invalidate: &invalidate,
+	})
+	if err != nil {
+		return err
+	}
+	if res.Error.Message != "" {
+		return fmt.Errorf("storage: cloudinary destroy: %s", res.Error.Message)
+	}
+	// "not found" means there was nothing to delete
+	if res.Result != "ok" && res.Result != "not found" {
+		return errors.New("storage: cloudinary destroy: " + res.Result)
+	}
+	return nil
+}
+
+// URL returns the HTTPS delivery URL; the extension picks the format
+func (c *Cloudinary) URL(key string) string {
+	return "https://res.cloudinary.com/" + c.cloudName + "/image/upload/" + key
+}
+
+func (c *Cloudinary) publicID(key string) (string, error) {
+	key, err := cleanKey(key)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSuffix(key, path.Ext(key)), nil
+}
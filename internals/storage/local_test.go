@@ -0,0 +1,53 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestLocalPutGetDelete(t *testing.T) {
+	l := NewLocal(t.TempDir(), "/uploads/")
+	ctx := context.Background()
+
+	if err := l.Put(ctx, "avatars/a.png", strings.NewReader("png bytes"), "image/png"); err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := l.Get(ctx, "avatars/a.png")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, _ := io.ReadAll(f)
+	f.Close()
+	if string(got) != "png bytes" {
+		t.Errorf("expected stored bytes back, got %q", got)
+	}
+
+	if url := l.URL("avatars/a.png"); url != "/uploads/avatars/a.png" {
+		t.Errorf("unexpected URL %q", url)
+	}
+
+	if err := l.Delete(ctx, "avatars/a.png"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := l.Get(ctx, "avatars/a.png"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound after delete, got %v", err)
+	}
+	if err := l.Delete(ctx, "avatars/a.png"); err != nil {
+		t.Errorf("deleting a missing object should succeed, got %v", err)
+	}
+}
+
+func TestCleanKey(t *testing.T) {
+	for _, key := range []string{"", "/etc/passwd", "../secret", "avatars/../../secret", "a//b", "a\\b", "."} {
+		if _, err := cleanKey(key); !errors.Is(err, ErrInvalidKey) {
+			t.Errorf("cleanKey(%q): expected ErrInvalidKey, got %v", key, err)
+		}
+	}
+	if _, err := cleanKey("avatars/x.png"); err != nil {
+		t.Errorf("cleanKey rejected a valid key: %v", err)
+	}
+}
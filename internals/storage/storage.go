@@ -0,0 +1,46 @@
+// Package storage hides where uploaded files such as avatars are kept.
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"path"
+	"strings"
+)
+
+var (
+	// ErrNotFound is returned when no object is stored under the key
+	ErrNotFound = errors.New("storage: object not found")
+
+	// ErrInvalidKey is returned for keys that are empty or try to leave the
+	// store, such as "../etc/passwd"
+	ErrInvalidKey = errors.New("storage: invalid key")
+)
+
+// Blob stores objects under slash separated keys such as "avatars/<id>.png"
+type Blob interface {
+	// Put stores the contents of r under key, replacing any existing object
+	Put(ctx context.Context, key string, r io.Reader, contentType string) error
+
+	// Get opens the object stored under key
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+
+	// Delete removes the object; deleting a missing object is not an error
+	Delete(ctx context.Context, key string) error
+
+	// URL is the address browsers load the object from
+	URL(key string) string
+}
+
+// cleanKey rejects keys that are empty, absolute or climb out with ".."
+func cleanKey(key string) (string, error) {
+	if key == "" || strings.HasPrefix(key, "/") || strings.Contains(key, "\\") {
+		return "", ErrInvalidKey
+	}
+	clean := path.Clean(key)
+	if clean != key || clean == "." || clean == ".." || strings.HasPrefix(clean, "../") {
+		return "", ErrInvalidKey
+	}
+	return clean, nil
+}
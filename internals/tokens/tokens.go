@@ -0,0 +1,27 @@
+// Package tokens makes the random one-time tokens sent to users by email.
+// Only the hash is stored, so a leaked table can't be used to take over accounts.
+package tokens
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+)
+
+// length is the number of random bytes in a token
+const length = 32
+
+// New returns a fresh URL-safe token and the hash to store for it
+func New() (raw, hash string) {
+	b := make([]byte, length)
+	rand.Read(b)
+	raw = base64.RawURLEncoding.EncodeToString(b)
+	return raw, Hash(raw)
+}
+
+// Hash returns the stored form of a token
+func Hash(raw string) string {
+	sum := sha256.Sum256([]byte(raw))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,48 @@
+package tokens
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestNew(t *testing.T) {
+	raw, hash := New()
+	if raw == "" || hash != Hash(raw) {
+		t.Fatalf("hash %q does not match token %q", hash, raw)
+	}
+	if other, _ := New(); other == raw {
+		t.Error("expected fresh tokens to differ")
+	}
+}
+
+func TestSignVerify(t *testing.T) {
+	key := []byte("test-key")
+	token := Sign(key, "verify", "user-1|ada@example.com", time.Now().Add(time.Hour))
+
+	got, err := Verify(key, "verify", token)
+	if err != nil || got != "user-1|ada@example.com" {
+		t.Fatalf("expected the value back, got %q, %v", got, err)
+	}
+
+	tests := []struct {
+		name    string
+		key     []byte
+		purpose string
+		token   string
+		want    error
+	}{
+		{"wrong key", []byte("other-key"), "verify", token, ErrInvalid},
+		{"wrong purpose", key, "reset", token, ErrInvalid},
+		{"tampered", key, "verify", "x" + token, ErrInvalid},
+		{"garbage", key, "verify", "not-a-token", ErrInvalid},
+		{"expired", key, "verify", Sign(key, "verify", "v", time.Now().Add(-time.Second)), ErrExpired},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Verify(tt.key, tt.purpose, tt.token); !errors.Is(err, tt.want) {
+				t.Errorf("expected %v, got %v", tt.want, err)
+			}
+		})
+	}
+}
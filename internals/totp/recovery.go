@@ -0,0 +1,32 @@
+package totp
+
+import (
+	"crypto/rand"
+	"strings"
+)
+
+// RecoveryCodeCount is how many recovery codes a user is given at a time
+const RecoveryCodeCount = 10
+
+// NewRecoveryCodes returns n random codes of the form xxxxx-xxxxx, for
+// signing in when the authenticator is lost. Each is good for one use.
+func NewRecoveryCodes(n int) []string {
+	codes := make([]string, n)
+	for i := range codes {
+		b := make([]byte, 7)
+		rand.Read(b)
+		s := strings.ToLower(encoding.EncodeToString(b))[:10]
+		codes[i] = s[:5] + "-" + s[5:]
+	}
+	return codes
+}
+
+// NormalizeRecoveryCode puts a typed recovery code into the form it was
+// issued in, forgiving case, spaces and a missing dash
+func NormalizeRecoveryCode(s string) string {
+	s = strings.ToLower(strings.NewReplacer(" ", "", "-", "").Replace(s))
+	if len(s) != 10 {
+		return s
+	}
+	return s[:5] + "-" + s[5:]
+}
@@ -0,0 +1,108 @@
+// Package totp implements RFC 6238 time-based one-time passwords, as used by
+// authenticator apps, with the usual 30 second step, 6 digits and SHA-1.
+package totp
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/base32"
+	"encoding/binary"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	// Step is how long each code is valid for
+	Step = 30 * time.Second
+
+	// Digits is the length of a code
+	Digits = 6
+
+	// skew is how many steps either side of now are accepted, to allow for
+	// clock drift and slow typing
+	skew = 1
+
+	secretLength = 20
+)
+
+// encoding is the unpadded base32 that authenticator apps expect
+var encoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// NewSecret returns a random base32 secret
+func NewSecret() string {
+	b := make([]byte, secretLength)
+	rand.Read(b)
+	return encoding.EncodeToString(b)
+}
+
+// Counter is the RFC 6238 time step t falls in
+func Counter(t time.Time) int64 {
+	return t.Unix() / int64(Step/time.Second)
+}
+
+// Code returns the code for secret at the given counter
+func Code(secret string, counter int64) (string, error) {
+	key, err := encoding.DecodeString(strings.ToUpper(secret))
+	if err != nil {
+		return "", fmt.Errorf("totp: bad secret: %w", err)
+	}
+	return hotp(key, counter, Digits), nil
+}
+
+// hotp is the RFC 4226 HMAC-based one-time password
+func hotp(key []byte, counter int64, digits int) string {
+	var msg [8]byte
+	binary.BigEndian.PutUint64(msg[:], uint64(counter))
+
+	h := hmac.New(sha1.New, key)
+	h.Write(msg[:])
+	sum := h.Sum(nil)
+
+	offset := sum[len(sum)-1] & 0x0f
+	bin := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+
+	mod := uint32(1)
+	for range digits {
+		mod *= 10
+	}
+	return fmt.Sprintf("%0*d", digits, bin%mod)
+}
+
+// Validate checks code against secret around now and returns the counter it
+// matched. Callers should refuse counters at or below the last one used so a
+// code can't be replayed.
+func Validate(secret, code string, now time.Time) (int64, bool) {
+	code = strings.ReplaceAll(code, " ", "")
+	if len(code) != Digits {
+		return 0, false
+	}
+
+	key, err := encoding.DecodeString(strings.ToUpper(secret))
+	if err != nil {
+		return 0, false
+	}
+
+	current := Counter(now)
+	for c := current - skew; c <= current+skew; c++ {
+		if hmac.Equal([]byte(hotp(key, c, Digits)), []byte(code)) {
+			return c, true
+		}
+	}
+	return 0, false
+}
+
+// URI is the otpauth:// link that authenticator apps read from the QR code
+func URI(issuer, account, secret string) string {
+	v := url.Values{}
+	v.Set("secret", secret)
+	v.Set("issuer", issuer)
+	v.Set("algorithm", "SHA1")
+	v.Set("digits", fmt.Sprint(Digits))
+	v.Set("period", fmt.Sprint(int(Step/time.Second)))
+
+	label := url.PathEscape(issuer) + ":" + url.PathEscape(account)
+	return "otpauth://totp/" + label + "?" + v.Encode()
+}
@@ -0,0 +1,85 @@
+package totp
+
+import (
+	"encoding/base32"
+	"strings"
+	"testing"
+	"time"
+)
+
+// rfcSecret is the SHA-1 seed from RFC 6238 appendix B
+var rfcSecret = base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString([]byte("12345678901234567890"))
+
+func TestRFC6238Vectors(t *testing.T) {
+	// the RFC lists 8 digit codes; ours are the last 6 digits of the same value
+	tests := []struct {
+		unix int64
+		want string
+	}{
+		{59, "94287082"},
+		{1111111109, "07081804"},
+		{1111111111, "14050471"},
+		{1234567890, "89005924"},
+		{2000000000, "69279037"},
+		{20000000000, "65353130"},
+	}
+
+	key := []byte("12345678901234567890")
+	for _, tt := range tests {
+		counter := Counter(time.Unix(tt.unix, 0))
+		if got := hotp(key, counter, 8); got != tt.want {
+			t.Errorf("T=%d: expected %s, got %s", tt.unix, tt.want, got)
+		}
+		if got, _ := Code(rfcSecret, counter); got != tt.want[2:] {
+			t.Errorf("T=%d: expected 6 digit %s, got %s", tt.unix, tt.want[2:], got)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	secret := NewSecret()
+	now := time.Unix(1700000000, 0)
+
+	for _, offset := range []time.Duration{-Step, 0, Step} {
+		code, _ := Code(secret, Counter(now.Add(offset)))
+		counter, ok := Validate(secret, code, now)
+		if !ok || counter != Counter(now.Add(offset)) {
+			t.Errorf("offset %s: expected the code to validate", offset)
+		}
+	}
+
+	stale, _ := Code(secret, Counter(now.Add(-3*Step)))
+	if _, ok := Validate(secret, stale, now); ok {
+		t.Error("expected a code from 90 seconds ago to be refused")
+	}
+	if _, ok := Validate(secret, "12345", now); ok {
+		t.Error("expected a short code to be refused")
+	}
+}
+
+func TestURI(t *testing.T) {
+	uri := URI("GoTH", "ada@example.com", "JBSWY3DPEHPK3PXP")
+	for _, want := range []string{"otpauth://totp/GoTH:ada@example.com?", "secret=JBSWY3DPEHPK3PXP", "issuer=GoTH", "period=30"} {
+		if !strings.Contains(uri, want) {
+			t.Errorf("expected %q in %s", want, uri)
+		}
+	}
+}
+
+func TestRecoveryCodes(t *testing.T) {
+	codes := NewRecoveryCodes(RecoveryCodeCount)
+	seen := map[string]bool{}
+	for _, c := range codes {
+		if len(c) != 11 || c[5] != '-' || c != NormalizeRecoveryCode(c) {
+			t.Errorf("bad code %q", c)
+		}
+		seen[c] = true
+	}
+	if len(seen) != RecoveryCodeCount {
+		t.Errorf("expected %d distinct codes, got %d", RecoveryCodeCount, len(seen))
+	}
+
+	if got := NormalizeRecoveryCode(" ABCDE fghij "); got != "abcde-fghij" {
+		t.Errorf("got %q", got)
+	}
+}
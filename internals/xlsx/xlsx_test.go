@@ -0,0 +1,117 @@
+package xlsx
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"io"
+	"strings"
+	"testing"
+)
+
+// readSheet unzips a workbook and returns the text of every cell by row
+func readSheet(t *testing.T, data []byte) [][]string {
+	t.Helper()
+
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var sheet []byte
+	for _, f := range zr.File {
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, _ := io.ReadAll(rc)
+		rc.Close()
+
+		// every part must at least be well formed
+		if err := xml.Unmarshal(body, new(struct{})); err != nil {
+			t.Fatalf("%s is not valid XML: %v", f.Name, err)
+		}
+		if f.Name == "xl/worksheets/sheet1.xml" {
+			sheet = body
+		}
+	}
+	if sheet == nil {
+		t.Fatal("no worksheet in the workbook")
+	}
+
+	var doc struct {
+		Rows []struct {
+			R     string `xml:"r,attr"`
+			Cells []struct {
+				R    string `xml:"r,attr"`
+				Text string `xml:"is>t"`
+			} `xml:"c"`
+		} `xml:"sheetData>row"`
+	}
+	if err := xml.Unmarshal(sheet, &doc); err != nil {
+		t.Fatal(err)
+	}
+
+	var rows [][]string
+	for _, row := range doc.Rows {
+		var cells []string
+		for _, c := range row.Cells {
+			cells = append(cells, c.Text)
+		}
+		rows = append(rows, cells)
+	}
+	return rows
+}
+
+func TestWriterRoundTrip(t *testing.T) {
+	var buf bytes.Buffer
+	w, err := NewWriter(&buf, "Users")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rows := [][]string{
+		{"name", "bio"},
+		{"Ada <Lovelace>", "likes \"engines\" & maths\nsecond line"},
+		{"=SUM(A1)", "bell\x07 gone"},
+	}
+	for _, row := range rows {
+		if err := w.WriteRow(row); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.WriteRow([]string{"late"}); err == nil {
+		t.Error("expected writing after Close to fail")
+	}
+
+	got := readSheet(t, buf.Bytes())
+	want := [][]string{rows[0], rows[1], {"=SUM(A1)", "bell gone"}}
+	if len(got) != len(want) {
+		t.Fatalf("expected %d rows, got %q", len(want), got)
+	}
+	for i := range want {
+		if strings.Join(got[i], "|") != strings.Join(want[i], "|") {
+			t.Errorf("row %d: expected %q, got %q", i, want[i], got[i])
+		}
+	}
+}
+
+func TestColumnName(t *testing.T) {
+	for i, want := range map[int]string{0: "A", 25: "Z", 26: "AA", 51: "AZ", 52: "BA", 701: "ZZ", 702: "AAA"} {
+		if got := ColumnName(i); got != want {
+			t.Errorf("ColumnName(%d) = %q, want %q", i, got, want)
+		}
+	}
+}
+
+func TestSheetTitle(t *testing.T) {
+	if got := sheetTitle("a/b:c [2024] with a very long name indeed"); got != "a_b_c _2024_ with a very long n" {
+		t.Errorf("unexpected title %q", got)
+	}
+	if got := sheetTitle(""); got != "Sheet1" {
+		t.Errorf("expected a default title, got %q", got)
+	}
+}
@@ -1,8 +1,184 @@
 package config
 
-import "github.com/gorilla/sessions"
+import (
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/stackninja.pro/goth/internals/mail"
+	"github.com/stackninja.pro/goth/internals/sessionstore"
+	"github.com/stackninja.pro/goth/internals/storage"
+)
 
 // AppConfig holds the application configuration
 type AppConfig struct {
-	Session *sessions.CookieStore
+	Session *sessionstore.Store `yaml:"-" toml:"-"`
+	Blob    storage.Blob        `yaml:"-" toml:"-"`
+	Mailer  mail.Sender         `yaml:"-" toml:"-"`
+
+	Env           string           `yaml:"env" toml:"env"`
+	Server        ServerConfig     `yaml:"server" toml:"server"`
+	Database      DatabaseConfig   `yaml:"database" toml:"database"`
+	SessionConfig SessionConfig    `yaml:"session" toml:"session"`
+	Storage       StorageConfig    `yaml:"storage" toml:"storage"`
+	Mail          MailConfig       `yaml:"mail" toml:"mail"`
+	Login         LoginConfig      `yaml:"login" toml:"login"`
+	Accounts      AccountsConfig   `yaml:"accounts" toml:"accounts"`
+	Cloudinary    CloudinaryConfig `yaml:"cloudinary" toml:"cloudinary"`
+}
+
+// ServerConfig holds the HTTP server settings
+type ServerConfig struct {
+	Addr    string `yaml:"addr" toml:"addr"`
+	BaseURL string `yaml:"base_url" toml:"base_url"`
+
+	// TrustProxy takes the client IP from X-Forwarded-For, which is only
+	// safe behind a proxy that sets it
+	TrustProxy bool `yaml:"trust_proxy" toml:"trust_proxy"`
+}
+
+// DatabaseConfig holds the Postgres connection and pool settings
+type DatabaseConfig struct {
+	DSN               string        `yaml:"dsn" toml:"dsn"`
+	MaxConns          int32         `yaml:"max_conns" toml:"max_conns"`
+	MinConns          int32         `yaml:"min_conns" toml:"min_conns"`
+	MaxConnLifetime   time.Duration `yaml:"max_conn_lifetime" toml:"max_conn_lifetime"`
+	HealthCheckPeriod time.Duration `yaml:"health_check_period" toml:"health_check_period"`
+	ConnectRetries    int           `yaml:"connect_retries" toml:"connect_retries"`
+	ConnectBackoff    time.Duration `yaml:"connect_backoff" toml:"connect_backoff"`
+	QueryTimeout      time.Duration `yaml:"query_timeout" toml:"query_timeout"`
+	AutoMigrate       bool          `yaml:"auto_migrate" toml:"auto_migrate"`
+}
+
+// String returns the settings with the DSN password masked so it is safe to log
+func (d DatabaseConfig) String() string {
+	return fmt.Sprintf("{DSN:%s MaxConns:%d MinConns:%d MaxConnLifetime:%s HealthCheckPeriod:%s ConnectRetries:%d QueryTimeout:%s AutoMigrate:%t}",
+		redactDSN(d.DSN), d.MaxConns, d.MinConns, d.MaxConnLifetime, d.HealthCheckPeriod, d.ConnectRetries, d.QueryTimeout, d.AutoMigrate)
+}
+
+// redactDSN masks the password in a postgres:// URL
+func redactDSN(dsn string) string {
+	if dsn == "" {
+		return `""`
+	}
+
+	u, err := url.Parse(dsn)
+	if err != nil || u.User == nil {
+		return "[redacted]"
+	}
+	return u.Redacted()
+}
+
+// SessionConfig holds the session cookie and store settings
+type SessionConfig struct {
+	Secret        string        `yaml:"secret" toml:"secret"`
+	MaxAge        int           `yaml:"max_age" toml:"max_age"`
+	Secure        bool          `yaml:"secure" toml:"secure"`
+	SweepInterval time.Duration `yaml:"sweep_interval" toml:"sweep_interval"`
+}
+
+// String hides the session secret so it is safe to log
+func (s SessionConfig) String() string {
+	return fmt.Sprintf("{Secret:%s MaxAge:%d Secure:%t SweepInterval:%s}", mask(s.Secret), s.MaxAge, s.Secure, s.SweepInterval)
+}
+
+// Storage backends for uploaded files
+const (
+	StorageLocal      = "local"
+	StorageCloudinary = "cloudinary"
+	StorageS3         = "s3"
+)
+
+// StorageConfig picks where uploaded files such as avatars are kept
+type StorageConfig struct {
+	Backend  string   `yaml:"backend" toml:"backend"`
+	LocalDir string   `yaml:"local_dir" toml:"local_dir"`
+	LocalURL string   `yaml:"local_url" toml:"local_url"`
+	S3       S3Config `yaml:"s3" toml:"s3"`
+}
+
+// S3Config holds the settings for an S3 compatible bucket
+type S3Config struct {
+	Endpoint  string `yaml:"endpoint" toml:"endpoint"`
+	Region    string `yaml:"region" toml:"region"`
+	Bucket    string `yaml:"bucket" toml:"bucket"`
+	AccessKey string `yaml:"access_key" toml:"access_key"`
+	SecretKey string `yaml:"secret_key" toml:"secret_key"`
+	PublicURL string `yaml:"public_url" toml:"public_url"`
+	PathStyle bool   `yaml:"path_style" toml:"path_style"`
+}
+
+// String hides the S3 keys so it is safe to log
+func (s S3Config) String() string {
+	return fmt.Sprintf("{Endpoint:%s Region:%s Bucket:%s AccessKey:%s SecretKey:%s PublicURL:%s PathStyle:%t}",
+		s.Endpoint, s.Region, s.Bucket, mask(s.AccessKey), mask(s.SecretKey), s.PublicURL, s.PathStyle)
+}
+
+// Mail drivers
+const (
+	MailSMTP   = "smtp"
+	MailFile   = "file"
+	MailMemory = "memory"
+)
+
+// MailConfig picks how outgoing email is delivered
+type MailConfig struct {
+	Driver       string `yaml:"driver" toml:"driver"`
+	From         string `yaml:"from" toml:"from"`
+	FileDir      string `yaml:"file_dir" toml:"file_dir"`
+	SMTPHost     string `yaml:"smtp_host" toml:"smtp_host"`
+	SMTPPort     int    `yaml:"smtp_port" toml:"smtp_port"`
+	SMTPUsername string `yaml:"smtp_username" toml:"smtp_username"`
+	SMTPPassword string `yaml:"smtp_password" toml:"smtp_password"`
+}
+
+// String hides the SMTP password so it is safe to log
+func (m MailConfig) String() string {
+	return fmt.Sprintf("{Driver:%s From:%s FileDir:%s SMTPHost:%s SMTPPort:%d SMTPUsername:%s SMTPPassword:%s}",
+		m.Driver, m.From, m.FileDir, m.SMTPHost, m.SMTPPort, m.SMTPUsername, mask(m.SMTPPassword))
+}
+
+// LoginConfig holds the brute-force protection settings. Each bucket allows a
+// burst of attempts and then one more every refill period.
+type LoginConfig struct {
+	IPBurst          int           `yaml:"ip_burst" toml:"ip_burst"`
+	IPRefill         time.Duration `yaml:"ip_refill" toml:"ip_refill"`
+	AccountBurst     int           `yaml:"account_burst" toml:"account_burst"`
+	AccountRefill    time.Duration `yaml:"account_refill" toml:"account_refill"`
+	FreeFailures     int           `yaml:"free_failures" toml:"free_failures"`
+	LockoutThreshold int           `yaml:"lockout_threshold" toml:"lockout_threshold"`
+	LockoutDuration  time.Duration `yaml:"lockout_duration" toml:"lockout_duration"`
+}
+
+// AccountsConfig holds the settings for deleted accounts. They can be
+// restored until the retention period is over and are then purged for good.
+type AccountsConfig struct {
+	DeletedRetention time.Duration `yaml:"deleted_retention" toml:"deleted_retention"`
+	PurgeInterval    time.Duration `yaml:"purge_interval" toml:"purge_interval"`
+}
+
+// CloudinaryConfig holds the Cloudinary credentials for the cloudinary storage backend
+type CloudinaryConfig struct {
+	CloudName string `yaml:"cloud_name" toml:"cloud_name"`
+	APIKey    string `yaml:"api_key" toml:"api_key"`
+	APISecret string `yaml:"api_secret" toml:"api_secret"`
+}
+
+// String hides the API key and secret so it is safe to log
+func (c CloudinaryConfig) String() string {
+	return fmt.Sprintf("{CloudName:%s APIKey:%s APISecret:%s}", c.CloudName, mask(c.APIKey), mask(c.APISecret))
+}
+
+// Redacted returns a one-line summary of the configuration without secrets
+func (a *AppConfig) Redacted() string {
+	return fmt.Sprintf("env=%s addr=%s base_url=%s trust_proxy=%t database=%s session=%s storage=%+v mail=%s login=%+v accounts=%+v cloudinary=%s",
+		a.Env, a.Server.Addr, a.Server.BaseURL, a.Server.TrustProxy, a.Database, a.SessionConfig, a.Storage, a.Mail, a.Login, a.Accounts, a.Cloudinary)
+}
+
+// mask replaces a secret with a fixed placeholder, keeping empty values visible
+func mask(s string) string {
+	if s == "" {
+		return `""`
+	}
+	return "[redacted]"
 }
@@ -0,0 +1,417 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net/mail"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// minSecretLength is the shortest session secret we accept
+const minSecretLength = 16
+
+// Default returns the configuration used before any file or environment
+// variable is applied
+func Default() *AppConfig {
+	return &AppConfig{
+		Env: "development",
+		Server: ServerConfig{
+			Addr:    ":8000",
+			BaseURL: "http://localhost:8000",
+		},
+		Database: DatabaseConfig{
+			MaxConns:          10,
+			MinConns:          2,
+			MaxConnLifetime:   time.Hour,
+			HealthCheckPeriod: time.Minute,
+			ConnectRetries:    5,
+			ConnectBackoff:    time.Second,
+			QueryTimeout:      5 * time.Second,
+		},
+		Storage: StorageConfig{
+			Backend:  StorageCloudinary,
+			LocalDir: "uploads",
+			LocalURL: "/uploads",
+			S3: S3Config{
+				Region: "us-east-1",
+			},
+		},
+		Mail: MailConfig{
+			Driver:   MailFile,
+			From:     "GoTH <no-reply@localhost>",
+			FileDir:  "mail",
+			SMTPPort: 587,
+		},
+		Login: LoginConfig{
+			IPBurst:          20,
+			IPRefill:         3 * time.Second,
+			AccountBurst:     5,
+			AccountRefill:    30 * time.Second,
+			FreeFailures:     3,
+			LockoutThreshold: 10,
+			LockoutDuration:  15 * time.Minute,
+		},
+		Accounts: AccountsConfig{
+			DeletedRetention: 30 * 24 * time.Hour,
+			PurgeInterval:    time.Hour,
+		},
+		SessionConfig: SessionConfig{
+			MaxAge:        3600 * 3,
+			Secure:        true,
+			SweepInterval: 15 * time.Minute,
+		},
+	}
+}
+
+// Load builds the configuration from the defaults, the optional YAML or TOML
+// file at path and finally the environment, which always wins. The result is
+// validated before it is returned.
+func Load(path string) (*AppConfig, error) {
+	app, err := Read(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := app.Validate(); err != nil {
+		return nil, err
+	}
+
+	return app, nil
+}
+
+// Read is Load without the validation, for commands such as migrate that only
+// need part of the configuration
+func Read(path string) (*AppConfig, error) {
+	app := Default()
+
+	if path != "" {
+		if err := loadFile(path, app); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := applyEnv(app, os.LookupEnv); err != nil {
+		return nil, err
+	}
+
+	return app, nil
+}
+
+// loadFile decodes a YAML or TOML file into app, picking the format from the extension
+func loadFile(path string, app *AppConfig) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("config: read %s: %w", path, err)
+	}
+
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, app)
+	case ".toml":
+		err = toml.Unmarshal(data, app)
+	default:
+		return fmt.Errorf("config: unsupported file type %q (use .yaml, .yml or .toml)", ext)
+	}
+	if err != nil {
+		return fmt.Errorf("config: parse %s: %w", path, err)
+	}
+
+	return nil
+}
+
+// applyEnv overrides app with any of the supported environment variables that are set
+func applyEnv(app *AppConfig, lookup func(string) (string, bool)) error {
+	strs := map[string]*string{
+		"APP_ENV":               &app.Env,
+		"APP_ADDR":              &app.Server.Addr,
+		"APP_BASE_URL":          &app.Server.BaseURL,
+		"DATABASE_URL":          &app.Database.DSN,
+		"SESSION_SECRET":        &app.SessionConfig.Secret,
+		"CLOUDINARY_CLOUD_NAME": &app.Cloudinary.CloudName,
+		"CLOUDINARY_API_KEY":    &app.Cloudinary.APIKey,
+		"CLOUDINARY_API_SECRET": &app.Cloudinary.APISecret,
+		"STORAGE_BACKEND":       &app.Storage.Backend,
+		"STORAGE_LOCAL_DIR":     &app.Storage.LocalDir,
+		"STORAGE_LOCAL_URL":     &app.Storage.LocalURL,
+		"S3_ENDPOINT":           &app.Storage.S3.Endpoint,
+		"S3_REGION":             &app.Storage.S3.Region,
+		"S3_BUCKET":             &app.Storage.S3.Bucket,
+		"S3_ACCESS_KEY_ID":      &app.Storage.S3.AccessKey,
+		"S3_SECRET_ACCESS_KEY":  &app.Storage.S3.SecretKey,
+		"S3_PUBLIC_URL":         &app.Storage.S3.PublicURL,
+		"MAIL_DRIVER":           &app.Mail.Driver,
+		"MAIL_FROM":             &app.Mail.From,
+		"MAIL_FILE_DIR":         &app.Mail.FileDir,
+		"SMTP_HOST":             &app.Mail.SMTPHost,
+		"SMTP_USERNAME":         &app.Mail.SMTPUsername,
+		"SMTP_PASSWORD":         &app.Mail.SMTPPassword,
+	}
+	for name, dst := range strs {
+		if v, ok := lookup(name); ok {
+			*dst = v
+		}
+	}
+
+	// PORT is what most hosting platforms hand us, so it beats APP_ADDR
+	if v, ok := lookup("PORT"); ok && v != "" {
+		app.Server.Addr = ":" + v
+	}
+
+	ints := map[string]*int{
+		"DB_CONNECT_RETRIES": &app.Database.ConnectRetries,
+		"SMTP_PORT":          &app.Mail.SMTPPort,
+
+		"LOGIN_IP_BURST":          &app.Login.IPBurst,
+		"LOGIN_ACCOUNT_BURST":     &app.Login.AccountBurst,
+		"LOGIN_FREE_FAILURES":     &app.Login.FreeFailures,
+		"LOGIN_LOCKOUT_THRESHOLD": &app.Login.LockoutThreshold,
+	}
+	for name, dst := range ints {
+		if v, ok := lookup(name); ok {
+			n, err := strconv.Atoi(v)
+			if err != nil {
+				return fmt.Errorf("config: %s must be a whole number, got %q", name, v)
+			}
+			*dst = n
+		}
+	}
+
+	conns := map[string]*int32{
+		"DB_MAX_CONNS": &app.Database.MaxConns,
+		"DB_MIN_CONNS": &app.Database.MinConns,
+	}
+	for name, dst := range conns {
+		if v, ok := lookup(name); ok {
+			n, err := strconv.ParseInt(v, 10, 32)
+			if err != nil {
+				return fmt.Errorf("config: %s must be a whole number, got %q", name, v)
+			}
+			*dst = int32(n)
+		}
+	}
+
+	durations := map[string]*time.Duration{
+		"DB_MAX_CONN_LIFETIME":      &app.Database.MaxConnLifetime,
+		"DB_HEALTH_CHECK_PERIOD":    &app.Database.HealthCheckPeriod,
+		"DB_CONNECT_BACKOFF":        &app.Database.ConnectBackoff,
+		"DB_QUERY_TIMEOUT":          &app.Database.QueryTimeout,
+		"SESSION_SWEEP_INTERVAL":    &app.SessionConfig.SweepInterval,
+		"LOGIN_IP_REFILL":           &app.Login.IPRefill,
+		"LOGIN_ACCOUNT_REFILL":      &app.Login.AccountRefill,
+		"LOGIN_LOCKOUT_DURATION":    &app.Login.LockoutDuration,
+		"ACCOUNT_DELETED_RETENTION": &app.Accounts.DeletedRetention,
+		"ACCOUNT_PURGE_INTERVAL":    &app.Accounts.PurgeInterval,
+	}
+	for name, dst := range durations {
+		if v, ok := lookup(name); ok {
+			d, err := time.ParseDuration(v)
+			if err != nil {
+				return fmt.Errorf("config: %s must be a duration such as 30s or 1h, got %q", name, v)
+			}
+			*dst = d
+		}
+	}
+
+	if v, ok := lookup("SESSION_MAX_AGE"); ok {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return fmt.Errorf("config: SESSION_MAX_AGE must be a number of seconds, got %q", v)
+		}
+		app.SessionConfig.MaxAge = n
+	}
+
+	bools := map[string]*bool{
+		"SESSION_SECURE":  &app.SessionConfig.Secure,
+		"DB_AUTO_MIGRATE": &app.Database.AutoMigrate,
+		"S3_PATH_STYLE":   &app.Storage.S3.PathStyle,
+		"APP_TRUST_PROXY": &app.Server.TrustProxy,
+	}
+	for name, dst := range bools {
+		if v, ok := lookup(name); ok {
+			b, err := strconv.ParseBool(v)
+			if err != nil {
+				return fmt.Errorf("config: %s must be true or false, got %q", name, v)
+			}
+			*dst = b
+		}
+	}
+
+	return nil
+}
+
+// Validate reports every missing or invalid setting at once
+func (a *AppConfig) Validate() error {
+	errs := a.databaseErrors()
+
+	if a.Server.Addr == "" {
+		errs = append(errs, errors.New("server address is required (APP_ADDR or PORT)"))
+	}
+	if a.SessionConfig.Secret == "" {
+		errs = append(errs, errors.New("session secret is required (SESSION_SECRET)"))
+	} else if len(a.SessionConfig.Secret) < minSecretLength {
+		errs = append(errs, fmt.Errorf("session secret must be at least %d characters (SESSION_SECRET)", minSecretLength))
+	}
+	if a.SessionConfig.MaxAge <= 0 {
+		errs = append(errs, errors.New("session max age must be positive (SESSION_MAX_AGE)"))
+	}
+	if a.SessionConfig.SweepInterval <= 0 {
+		errs = append(errs, errors.New("session sweep interval must be positive (SESSION_SWEEP_INTERVAL)"))
+	}
+	if u, err := url.Parse(a.Server.BaseURL); err != nil || u.Scheme == "" || u.Host == "" {
+		errs = append(errs, errors.New("base URL must be absolute, such as https://example.com (APP_BASE_URL)"))
+	}
+	errs = append(errs, a.storageErrors()...)
+	errs = append(errs, a.mailErrors()...)
+	errs = append(errs, a.loginErrors()...)
+	if a.Accounts.DeletedRetention <= 0 || a.Accounts.PurgeInterval <= 0 {
+		errs = append(errs, errors.New("deleted account retention and purge interval must be positive (ACCOUNT_DELETED_RETENTION, ACCOUNT_PURGE_INTERVAL)"))
+	}
+
+	return invalid(errs)
+}
+
+// ValidateDatabase checks only the database settings
+func (a *AppConfig) ValidateDatabase() error {
+	return invalid(a.databaseErrors())
+}
+
+// databaseErrors lists the problems with the database settings
+func (a *AppConfig) databaseErrors() []error {
+	var errs []error
+
+	if a.Database.DSN == "" {
+		errs = append(errs, errors.New("database DSN is required (DATABASE_URL)"))
+	}
+	if a.Database.MaxConns < 1 {
+		errs = append(errs, errors.New("database max connections must be at least 1 (DB_MAX_CONNS)"))
+	}
+	if a.Database.MinConns < 0 || a.Database.MinConns > a.Database.MaxConns {
+		errs = append(errs, errors.New("database min connections must be between 0 and max connections (DB_MIN_CONNS)"))
+	}
+	if a.Database.ConnectRetries < 0 {
+		errs = append(errs, errors.New("database connect retries cannot be negative (DB_CONNECT_RETRIES)"))
+	}
+	if a.Database.ConnectBackoff <= 0 {
+		errs = append(errs, errors.New("database connect backoff must be positive (DB_CONNECT_BACKOFF)"))
+	}
+	if a.Database.QueryTimeout < 0 {
+		errs = append(errs, errors.New("database query timeout cannot be negative (DB_QUERY_TIMEOUT)"))
+	}
+
+	return errs
+}
+
+// invalid wraps a list of problems into a single startup error
+func invalid(errs []error) error {
+	if len(errs) > 0 {
+		return fmt.Errorf("invalid configuration:\n  %w", joinLines(errs))
+	}
+	return nil
+}
+
+// joinLines joins errors one per line so startup failures are easy to read
+func joinLines(errs []error) error {
+	msgs := make([]string, len(errs))
+	for i, err := range errs {
+		msgs[i] = err.Error()
+	}
+	return errors.New(strings.Join(msgs, "\n  "))
+}
+
+// storageErrors checks the settings of the chosen storage backend only
+func (a *AppConfig) storageErrors() []error {
+	var errs []error
+
+	switch a.Storage.Backend {
+	case StorageLocal:
+		if a.Storage.LocalDir == "" {
+			errs = append(errs, errors.New("local storage directory is required (STORAGE_LOCAL_DIR)"))
+		}
+		if !strings.HasPrefix(a.Storage.LocalURL, "/") {
+			errs = append(errs, errors.New("local storage URL must be a path such as /uploads (STORAGE_LOCAL_URL)"))
+		}
+	case StorageCloudinary:
+		if a.Cloudinary.CloudName == "" {
+			errs = append(errs, errors.New("cloudinary cloud name is required (CLOUDINARY_CLOUD_NAME)"))
+		}
+		if a.Cloudinary.APIKey == "" {
+			errs = append(errs, errors.New("cloudinary API key is required (CLOUDINARY_API_KEY)"))
+		}
+		if a.Cloudinary.APISecret == "" {
+			errs = append(errs, errors.New("cloudinary API secret is required (CLOUDINARY_API_SECRET)"))
+		}
+	case StorageS3:
+		if a.Storage.S3.Endpoint == "" {
+			errs = append(errs, errors.New("S3 endpoint is required (S3_ENDPOINT)"))
+		}
+		if a.Storage.S3.Bucket == "" {
+			errs = append(errs, errors.New("S3 bucket is required (S3_BUCKET)"))
+		}
+		if a.Storage.S3.AccessKey == "" || a.Storage.S3.SecretKey == "" {
+			errs = append(errs, errors.New("S3 credentials are required (S3_ACCESS_KEY_ID, S3_SECRET_ACCESS_KEY)"))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("storage backend must be local, cloudinary or s3, got %q (STORAGE_BACKEND)", a.Storage.Backend))
+	}
+
+	return errs
+}
+
+// mailErrors checks the settings of the chosen mail driver only
+func (a *AppConfig) mailErrors() []error {
+	var errs []error
+
+	if _, err := mail.ParseAddress(a.Mail.From); err != nil {
+		errs = append(errs, fmt.Errorf("mail from address is invalid: %v (MAIL_FROM)", err))
+	}
+
+	switch a.Mail.Driver {
+	case MailSMTP:
+		if a.Mail.SMTPHost == "" {
+			errs = append(errs, errors.New("SMTP host is required (SMTP_HOST)"))
+		}
+		if a.Mail.SMTPPort <= 0 {
+			errs = append(errs, errors.New("SMTP port must be positive (SMTP_PORT)"))
+		}
+	case MailFile:
+		if a.Mail.FileDir == "" {
+			errs = append(errs, errors.New("mail directory is required (MAIL_FILE_DIR)"))
+		}
+	case MailMemory:
+	default:
+		errs = append(errs, fmt.Errorf("mail driver must be smtp, file or memory, got %q (MAIL_DRIVER)", a.Mail.Driver))
+	}
+
+	return errs
+}
+
+// loginErrors lists the problems with the brute-force protection settings
+func (a *AppConfig) loginErrors() []error {
+	var errs []error
+	l := a.Login
+
+	if l.IPBurst <= 0 || l.IPRefill <= 0 {
+		errs = append(errs, errors.New("login IP burst and refill must be positive (LOGIN_IP_BURST, LOGIN_IP_REFILL)"))
+	}
+	if l.AccountBurst <= 0 || l.AccountRefill <= 0 {
+		errs = append(errs, errors.New("login account burst and refill must be positive (LOGIN_ACCOUNT_BURST, LOGIN_ACCOUNT_REFILL)"))
+	}
+	if l.FreeFailures < 0 {
+		errs = append(errs, errors.New("login free failures can't be negative (LOGIN_FREE_FAILURES)"))
+	}
+	if l.LockoutThreshold <= l.FreeFailures {
+		errs = append(errs, errors.New("login lockout threshold must be above the free failures (LOGIN_LOCKOUT_THRESHOLD)"))
+	}
+	if l.LockoutDuration <= 0 {
+		errs = append(errs, errors.New("login lockout duration must be positive (LOGIN_LOCKOUT_DURATION)"))
+	}
+	return errs
+}
@@ -42,7 +42,20 @@ func ChangeAvatar(td *models.TemplateData) templ.Component {
 		if templ_7745c5c3_Err != nil {
 			return templ_7745c5c3_Err
 		}
-		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 2, "</head><div class=\"flex h-screen bg-gray-900 text-gray-100\"><!-- Main wrapper with sidebar + content side by side --><div class=\"flex flex-1 gap-4 p-4\"><!-- Content (right) --><div class=\"flex-1 flex flex-col\"><header class=\"bg-gray-800 shadow p-4 flex justify-between items-center rounded-lg\"><h1 class=\"text-xl font-bold\">Dashboard</h1><a href=\"/about\" class=\"text-sm text-red-400 hover:text-red-300 transition\">About</a></header><main class=\"flex-1 overflow-y-auto p-6 space-y-6\">")
+		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 2, "</head><div class=\"flex h-screen bg-gray-900 text-gray-100\" hx-headers=\"")
+		if templ_7745c5c3_Err != nil {
+			return templ_7745c5c3_Err
+		}
+		var templ_7745c5c3_Var2 string
+		templ_7745c5c3_Var2, templ_7745c5c3_Err = templ.JoinStringErrs(components.CSRFHeaders(td))
+		if templ_7745c5c3_Err != nil {
+			return templ.Error{Err: templ_7745c5c3_Err, FileName: `web/templates/changeAvatar.templ`, Line: 15, Col: 93}
+		}
+		_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var2))
+		if templ_7745c5c3_Err != nil {
+			return templ_7745c5c3_Err
+		}
+		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 3, "\"><!-- Main wrapper with sidebar + content side by side --><div class=\"flex flex-1 gap-4 p-4\"><!-- Content (right) --><div class=\"flex-1 flex flex-col\"><header class=\"bg-gray-800 shadow p-4 flex justify-between items-center rounded-lg\"><h1 class=\"text-xl font-bold\">Dashboard</h1><a href=\"/about\" class=\"text-sm text-red-400 hover:text-red-300 transition\">About</a></header><main class=\"flex-1 overflow-y-auto p-6 space-y-6\">")
 		if templ_7745c5c3_Err != nil {
 			return templ_7745c5c3_Err
 		}
@@ -50,7 +63,7 @@ func ChangeAvatar(td *models.TemplateData) templ.Component {
 		if templ_7745c5c3_Err != nil {
 			return templ_7745c5c3_Err
 		}
-		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 3, "<!-- Side by side layout --><div class=\"flex flex-col md:flex-row gap-6\"><div class=\"flex-1\">")
+		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 4, "<!-- Side by side layout --><div class=\"flex flex-col md:flex-row gap-6\"><div class=\"flex-1\">")
 		if templ_7745c5c3_Err != nil {
 			return templ_7745c5c3_Err
 		}
@@ -58,7 +71,7 @@ func ChangeAvatar(td *models.TemplateData) templ.Component {
 		if templ_7745c5c3_Err != nil {
 			return templ_7745c5c3_Err
 		}
-		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 4, "</div><div class=\"flex-1 md:w-1/2\">")
+		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 5, "</div><div class=\"flex-1 md:w-1/2\">")
 		if templ_7745c5c3_Err != nil {
 			return templ_7745c5c3_Err
 		}
@@ -66,7 +79,7 @@ func ChangeAvatar(td *models.TemplateData) templ.Component {
 		if templ_7745c5c3_Err != nil {
 			return templ_7745c5c3_Err
 		}
-		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 5, "</div></div></main></div></div></div>")
+		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 6, "</div></div></main></div></div></div>")
 		if templ_7745c5c3_Err != nil {
 			return templ_7745c5c3_Err
 		}
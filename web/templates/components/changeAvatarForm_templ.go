@@ -31,7 +31,30 @@ func ChangeAvatarForm(td *models.TemplateData) templ.Component {
 			templ_7745c5c3_Var1 = templ.NopComponent
 		}
 		ctx = templ.ClearChildren(ctx)
-		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 1, "<div class=\"bg-gray-900 flex items-center justify-center p-6\"><div class=\"bg-gray-800 shadow-lg rounded-xl w-full max-w-md p-6\"><h2 class=\"text-2xl font-semibold text-gray-100 mb-4\">Change Avatar</h2><!-- Avatar Upload Form --><form hx-post=\"/upload-avatar\" hx-encoding=\"multipart/form-data\" hx-target=\"body\" class=\"space-y-4\"><div><label for=\"avatar\" class=\"block text-gray-300 mb-2\">Select a new avatar:</label> <input type=\"file\" name=\"avatar\" id=\"avatar\" accept=\"image/*\" class=\"w-full text-gray-200 bg-gray-700 border border-gray-600 rounded-lg px-3 py-2 focus:outline-none focus:ring-2 focus:ring-purple-500\"></div><button type=\"submit\" class=\"w-full bg-purple-600 hover:bg-purple-500 text-white font-semibold py-2 px-4 rounded-lg shadow-sm transition duration-200\">Update Avatar</button></form></div></div>")
+		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 1, "<div class=\"bg-gray-900 flex items-center justify-center p-6\"><div class=\"bg-gray-800 shadow-lg rounded-xl w-full max-w-md p-6\"><h2 class=\"text-2xl font-semibold text-gray-100 mb-4\">Change Avatar</h2><!-- Error Messages --><div id=\"error-messages\" class=\"mb-4\">")
+		if templ_7745c5c3_Err != nil {
+			return templ_7745c5c3_Err
+		}
+		for _, err := range td.Errors {
+			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 2, "<p class=\"text-red-400 text-sm\">")
+			if templ_7745c5c3_Err != nil {
+				return templ_7745c5c3_Err
+			}
+			var templ_7745c5c3_Var2 string
+			templ_7745c5c3_Var2, templ_7745c5c3_Err = templ.JoinStringErrs(err)
+			if templ_7745c5c3_Err != nil {
+				return templ.Error{Err: templ_7745c5c3_Err, FileName: `web/templates/components/changeAvatarForm.templ`, Line: 15, Col: 57}
+			}
+			_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var2))
+			if templ_7745c5c3_Err != nil {
+				return templ_7745c5c3_Err
+			}
+			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 3, "</p>")
+			if templ_7745c5c3_Err != nil {
+				return templ_7745c5c3_Err
+			}
+		}
+		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 4, "</div><!-- Avatar Upload Form --><form hx-post=\"/upload-avatar\" hx-encoding=\"multipart/form-data\" hx-target=\"body\" class=\"space-y-4\"><div><label for=\"avatar\" class=\"block text-gray-300 mb-2\">Select a new avatar:</label> <input type=\"file\" name=\"avatar\" id=\"avatar\" accept=\"image/*\" class=\"w-full text-gray-200 bg-gray-700 border border-gray-600 rounded-lg px-3 py-2 focus:outline-none focus:ring-2 focus:ring-purple-500\"></div><button type=\"submit\" class=\"w-full bg-purple-600 hover:bg-purple-500 text-white font-semibold py-2 px-4 rounded-lg shadow-sm transition duration-200\">Update Avatar</button></form></div></div>")
 		if templ_7745c5c3_Err != nil {
 			return templ_7745c5c3_Err
 		}
@@ -0,0 +1,137 @@
+package components
+
+import (
+	"encoding/json"
+	"net/url"
+	"strings"
+
+	"github.com/stackninja.pro/goth/internals/models"
+)
+
+// SessionUser returns the logged in user the handler put into the template data
+func SessionUser(td *models.TemplateData) *models.User {
+	if td == nil {
+		return nil
+	}
+	if td.UserData != nil {
+		return td.UserData
+	}
+	user, _ := td.Data["userSession"].(*models.User)
+	return user
+}
+
+// Prefs returns the logged in user's display preferences, or the defaults
+// for visitors
+func Prefs(td *models.TemplateData) models.Preferences {
+	if user := SessionUser(td); user != nil && user.Preferences.DateFormat != "" {
+		return user.Preferences
+	}
+	return models.DefaultPreferences()
+}
+
+// Can reports whether the logged in user may perform p, so templates can show
+// or hide actions per role
+func Can(td *models.TemplateData, p models.Permission) bool {
+	return SessionUser(td).Can(p)
+}
+
+// HasRole reports whether the logged in user has one of the given roles
+func HasRole(td *models.TemplateData, roles ...models.Role) bool {
+	user := SessionUser(td)
+	if user == nil {
+		return false
+	}
+	for _, r := range roles {
+		if user.Role == r {
+			return true
+		}
+	}
+	return false
+}
+
+// CSRFHeaders returns the hx-headers value that makes every HTMX request
+// under the element carry the CSRF token
+func CSRFHeaders(td *models.TemplateData) string {
+	if td == nil || td.CSRFToken == "" {
+		return "{}"
+	}
+	b, _ := json.Marshal(map[string]string{"X-CSRF-Token": td.CSRFToken})
+	return string(b)
+}
+
+// DeviceName turns a user agent into a short "Browser on OS" label for the
+// sessions page
+func DeviceName(ua string) string {
+	browser := "Unknown browser"
+	for _, b := range []struct{ token, name string }{
+		{"Edg/", "Edge"},
+		{"OPR/", "Opera"},
+		{"Firefox/", "Firefox"},
+		{"Chrome/", "Chrome"},
+		{"Safari/", "Safari"},
+		{"curl/", "curl"},
+	} {
+		if strings.Contains(ua, b.token) {
+			browser = b.name
+			break
+		}
+	}
+
+	os := "unknown OS"
+	for _, o := range []struct{ token, name string }{
+		{"Android", "Android"},
+		{"iPhone", "iOS"},
+		{"iPad", "iPadOS"},
+		{"Windows", "Windows"},
+		{"Mac OS X", "macOS"},
+		{"Linux", "Linux"},
+	} {
+		if strings.Contains(ua, o.token) {
+			os = o.name
+			break
+		}
+	}
+
+	return browser + " on " + os
+}
+
+// UsersURL links to the user directory showing q
+func UsersURL(q models.UserQuery) string {
+	return "/users?" + q.Values().Encode()
+}
+
+// ExportFilter is the directory's filters and order without its paging, for
+// exporting every matching user
+func ExportFilter(q models.UserQuery) url.Values {
+	v := q.Values()
+	v.Del("page")
+	v.Del("per_page")
+	return v
+}
+
+// AuditURL links to the audit log showing q
+func AuditURL(q models.AuditQuery) string {
+	return "/users/audit?" + q.Values().Encode()
+}
+
+// AuditExportURL downloads every event matching q as CSV
+func AuditExportURL(q models.AuditQuery) string {
+	q.Page = 1
+	return "/users/audit/export?" + q.Values().Encode()
+}
+
+// SortIndicator is the arrow shown next to the column q is sorted by
+func SortIndicator(q models.UserQuery, column string) string {
+	if q.Sort != column {
+		return ""
+	}
+	if q.Desc {
+		return "▼"
+	}
+	return "▲"
+}
+
+// CoursesURL links to the course list on path showing q
+func CoursesURL(path string, q models.CourseQuery) string {
+	return path + "?" + q.Values().Encode()
+}
@@ -31,7 +31,87 @@ func Navbar(td *models.TemplateData) templ.Component {
 			templ_7745c5c3_Var1 = templ.NopComponent
 		}
 		ctx = templ.ClearChildren(ctx)
-		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 1, "<nav class=\"w-full\"><div class=\"container mx-auto flex items-center justify-between px-4 py-3 md:py-4 lg:px-8\"><!-- Brand --><a href=\"/\" class=\"text-2xl font-bold text-emerald-400 hover:text-emerald-300 transition-colors\">Student<span class=\"text-orange-400\">Mgmt</span></a><!-- Hamburger (Mobile only) --><button class=\"md:hidden text-gray-300 hover:text-white focus:outline-none\" aria-label=\"Toggle Menu\" onclick=\"document.getElementById('mobile-menu').classList.toggle('hidden')\"><svg xmlns=\"http://www.w3.org/2000/svg\" class=\"h-7 w-7\" fill=\"none\" viewBox=\"0 0 24 24\" stroke=\"currentColor\"><path stroke-linecap=\"round\" stroke-linejoin=\"round\" stroke-width=\"2\" d=\"M4 6h16M4 12h16M4 18h16\"></path></svg></button><!-- Desktop Menu --><div class=\"hidden md:flex space-x-8 text-sm font-medium\"><a href=\"/\" class=\"hover:text-emerald-400 transition-colors\">Home</a> <a href=\"/about\" class=\"hover:text-orange-400 transition-colors\">About</a> <a href=\"/logout\" class=\"hover:text-teal-400 transition-colors\">Logout</a></div></div><!-- Mobile Menu --><div id=\"mobile-menu\" class=\"md:hidden hidden bg-gray-900/95 border-t border-gray-800\"><div class=\"flex flex-col space-y-2 px-4 py-4 text-sm font-medium\"><a href=\"/\" class=\"hover:text-emerald-400 transition-colors\">Home</a> <a href=\"/about\" class=\"hover:text-emerald-400 transition-colors\">About</a> <a href=\"/logout\" class=\"hover:text-emerald-400 transition-colors\">Logout</a></div></div></nav>")
+		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 1, "<nav class=\"w-full\"><div class=\"container mx-auto flex items-center justify-between px-4 py-3 md:py-4 lg:px-8\"><!-- Brand --><a href=\"/\" class=\"text-2xl font-bold text-emerald-400 hover:text-emerald-300 transition-colors\">Student<span class=\"text-orange-400\">Mgmt</span></a><!-- Hamburger (Mobile only) --><button class=\"md:hidden text-gray-300 hover:text-white focus:outline-none\" aria-label=\"Toggle Menu\" onclick=\"document.getElementById('mobile-menu').classList.toggle('hidden')\"><svg xmlns=\"http://www.w3.org/2000/svg\" class=\"h-7 w-7\" fill=\"none\" viewBox=\"0 0 24 24\" stroke=\"currentColor\"><path stroke-linecap=\"round\" stroke-linejoin=\"round\" stroke-width=\"2\" d=\"M4 6h16M4 12h16M4 18h16\"></path></svg></button><!-- Desktop Menu --><div class=\"hidden md:flex space-x-8 text-sm font-medium\"><a href=\"/\" class=\"hover:text-emerald-400 transition-colors\">Home</a> <a href=\"/about\" class=\"hover:text-orange-400 transition-colors\">About</a> ")
+		if templ_7745c5c3_Err != nil {
+			return templ_7745c5c3_Err
+		}
+		if Can(td, models.PermBrowseCourses) {
+			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 2, "<a href=\"/courses\" class=\"hover:text-emerald-400 transition-colors\">Courses</a> ")
+			if templ_7745c5c3_Err != nil {
+				return templ_7745c5c3_Err
+			}
+		}
+		if Can(td, models.PermEnrollCourses) {
+			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 3, "<a href=\"/courses/enrolled\" class=\"hover:text-emerald-400 transition-colors\">My courses</a> ")
+			if templ_7745c5c3_Err != nil {
+				return templ_7745c5c3_Err
+			}
+		}
+		if Can(td, models.PermTeachCourses) {
+			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 4, "<a href=\"/courses/manage\" class=\"hover:text-orange-400 transition-colors\">Teaching</a> ")
+			if templ_7745c5c3_Err != nil {
+				return templ_7745c5c3_Err
+			}
+		}
+		if Can(td, models.PermManageTerms) {
+			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 5, "<a href=\"/terms\" class=\"hover:text-emerald-400 transition-colors\">Terms</a> ")
+			if templ_7745c5c3_Err != nil {
+				return templ_7745c5c3_Err
+			}
+		}
+		if Can(td, models.PermManageGrading) {
+			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 6, "<a href=\"/grading\" class=\"hover:text-emerald-400 transition-colors\">Grading</a> ")
+			if templ_7745c5c3_Err != nil {
+				return templ_7745c5c3_Err
+			}
+		}
+		if Can(td, models.PermManageUsers) {
+			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 7, "<a href=\"/users\" class=\"hover:text-orange-400 transition-colors\">Users</a> ")
+			if templ_7745c5c3_Err != nil {
+				return templ_7745c5c3_Err
+			}
+		}
+		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 8, "<a href=\"/settings/sessions\" class=\"hover:text-emerald-400 transition-colors\">Devices</a> <button type=\"button\" hx-post=\"/logout\" class=\"hover:text-teal-400 transition-colors\">Logout</button></div></div><!-- Mobile Menu --><div id=\"mobile-menu\" class=\"md:hidden hidden bg-gray-900/95 border-t border-gray-800\"><div class=\"flex flex-col space-y-2 px-4 py-4 text-sm font-medium\"><a href=\"/\" class=\"hover:text-emerald-400 transition-colors\">Home</a> <a href=\"/about\" class=\"hover:text-emerald-400 transition-colors\">About</a> ")
+		if templ_7745c5c3_Err != nil {
+			return templ_7745c5c3_Err
+		}
+		if Can(td, models.PermBrowseCourses) {
+			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 9, "<a href=\"/courses\" class=\"hover:text-emerald-400 transition-colors\">Courses</a> ")
+			if templ_7745c5c3_Err != nil {
+				return templ_7745c5c3_Err
+			}
+		}
+		if Can(td, models.PermEnrollCourses) {
+			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 10, "<a href=\"/courses/enrolled\" class=\"hover:text-emerald-400 transition-colors\">My courses</a> ")
+			if templ_7745c5c3_Err != nil {
+				return templ_7745c5c3_Err
+			}
+		}
+		if Can(td, models.PermTeachCourses) {
+			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 11, "<a href=\"/courses/manage\" class=\"hover:text-emerald-400 transition-colors\">Teaching</a> ")
+			if templ_7745c5c3_Err != nil {
+				return templ_7745c5c3_Err
+			}
+		}
+		if Can(td, models.PermManageTerms) {
+			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 12, "<a href=\"/terms\" class=\"hover:text-emerald-400 transition-colors\">Terms</a> ")
+			if templ_7745c5c3_Err != nil {
+				return templ_7745c5c3_Err
+			}
+		}
+		if Can(td, models.PermManageGrading) {
+			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 13, "<a href=\"/grading\" class=\"hover:text-emerald-400 transition-colors\">Grading</a> ")
+			if templ_7745c5c3_Err != nil {
+				return templ_7745c5c3_Err
+			}
+		}
+		if Can(td, models.PermManageUsers) {
+			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 14, "<a href=\"/users\" class=\"hover:text-emerald-400 transition-colors\">Users</a> ")
+			if templ_7745c5c3_Err != nil {
+				return templ_7745c5c3_Err
+			}
+		}
+		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 15, "<a href=\"/settings/sessions\" class=\"hover:text-emerald-400 transition-colors\">Devices</a> <button type=\"button\" hx-post=\"/logout\" class=\"hover:text-emerald-400 transition-colors\">Logout</button></div></div></nav>")
 		if templ_7745c5c3_Err != nil {
 			return templ_7745c5c3_Err
 		}
@@ -0,0 +1,97 @@
+package templates
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/stackninja.pro/goth/internals/models"
+)
+
+// dateValue formats t for an <input type="date">, leaving zero times empty
+func dateValue(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format("2006-01-02")
+}
+
+// rosterTerm is the ID of the term a roster is showing, if any
+func rosterTerm(td *models.TemplateData) string {
+	if t, ok := td.Data["term"].(*models.Term); ok {
+		return t.ID
+	}
+	return ""
+}
+
+// sessionTerms is one academic session's terms, for a term picker
+type sessionTerms struct {
+	name  string
+	terms []models.Term
+}
+
+// termsBySession groups terms, kept in order, under their sessions. Terms
+// from before sessions are grouped as having none.
+func termsBySession(terms []models.Term) []sessionTerms {
+	var groups []sessionTerms
+	index := map[string]int{}
+	for _, t := range terms {
+		i, ok := index[t.SessionID]
+		if !ok {
+			name := t.SessionName
+			if t.SessionID == "" {
+				name = "No session"
+			}
+			i = len(groups)
+			index[t.SessionID] = i
+			groups = append(groups, sessionTerms{name: name})
+		}
+		groups[i].terms = append(groups[i].terms, t)
+	}
+	return groups
+}
+
+// scoreValue formats a score, weight or grade point without trailing zeros
+func scoreValue(f float64) string {
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
+
+// weightValue formats an assessment's weight for its input, leaving a new
+// assessment's empty
+func weightValue(w float64) string {
+	if w == 0 {
+		return ""
+	}
+	return scoreValue(w)
+}
+
+// studentScore is the student's score on an assessment for its grid input,
+// empty while ungraded
+func studentScore(td *models.TemplateData, studentID, assessmentID string) string {
+	scores, _ := td.Data["scores"].(map[string]models.Scores)
+	if s, ok := scores[studentID][assessmentID]; ok {
+		return scoreValue(s)
+	}
+	return ""
+}
+
+// studentTotal is the student's weighted total out of 100
+func studentTotal(td *models.TemplateData, studentID string) float64 {
+	assessments, _ := td.Data["assessments"].([]models.Assessment)
+	scores, _ := td.Data["scores"].(map[string]models.Scores)
+	return models.WeightedTotal(assessments, scores[studentID])
+}
+
+// studentGrade is the band the student's total falls in on their scale
+func studentGrade(td *models.TemplateData, studentID string) models.GradeBand {
+	scales, _ := td.Data["scales"].(map[string]models.GradingScale)
+	return scales[studentID].Grade(studentTotal(td, studentID))
+}
+
+// optionalScore formats a score from a score's history, which is a dash
+// when there was none
+func optionalScore(s *float64) string {
+	if s == nil {
+		return "—"
+	}
+	return scoreValue(*s)
+}
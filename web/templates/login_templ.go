@@ -61,15 +61,15 @@ func LoginPage(td *models.TemplateData) templ.Component {
 					}()
 				}
 				ctx = templ.InitializeContext(ctx)
-				for _, err := range td.Errors {
-					templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 2, "<p class=\"text-red-400 text-sm\">")
+				if td.Flash != "" {
+					templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 2, "<p class=\"text-emerald-400 text-sm\">")
 					if templ_7745c5c3_Err != nil {
 						return templ_7745c5c3_Err
 					}
 					var templ_7745c5c3_Var4 string
-					templ_7745c5c3_Var4, templ_7745c5c3_Err = templ.JoinStringErrs(err)
+					templ_7745c5c3_Var4, templ_7745c5c3_Err = templ.JoinStringErrs(td.Flash)
 					if templ_7745c5c3_Err != nil {
-						return templ.Error{Err: templ_7745c5c3_Err, FileName: `web/templates/login.templ`, Line: 23, Col: 45}
+						return templ.Error{Err: templ_7745c5c3_Err, FileName: `web/templates/login.templ`, Line: 23, Col: 54}
 					}
 					_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var4))
 					if templ_7745c5c3_Err != nil {
@@ -80,13 +80,32 @@ func LoginPage(td *models.TemplateData) templ.Component {
 						return templ_7745c5c3_Err
 					}
 				}
+				for _, err := range td.Errors {
+					templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 4, "<p class=\"text-red-400 text-sm\">")
+					if templ_7745c5c3_Err != nil {
+						return templ_7745c5c3_Err
+					}
+					var templ_7745c5c3_Var5 string
+					templ_7745c5c3_Var5, templ_7745c5c3_Err = templ.JoinStringErrs(err)
+					if templ_7745c5c3_Err != nil {
+						return templ.Error{Err: templ_7745c5c3_Err, FileName: `web/templates/login.templ`, Line: 26, Col: 45}
+					}
+					_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var5))
+					if templ_7745c5c3_Err != nil {
+						return templ_7745c5c3_Err
+					}
+					templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 5, "</p>")
+					if templ_7745c5c3_Err != nil {
+						return templ_7745c5c3_Err
+					}
+				}
 				return nil
 			})
 			templ_7745c5c3_Err = templ.Fragment("error-messages").Render(templ.WithChildren(ctx, templ_7745c5c3_Var3), templ_7745c5c3_Buffer)
 			if templ_7745c5c3_Err != nil {
 				return templ_7745c5c3_Err
 			}
-			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 4, "</div><!-- Email --><div class=\"flex flex-col gap-2\"><label for=\"email\" class=\"form-label text-slate-300 text-sm font-medium\">Email address</label> <input type=\"email\" id=\"email\" name=\"email\" placeholder=\"you@example.com\" class=\"form-input bg-slate-800 border-slate-700 text-slate-200 placeholder-slate-500 rounded-lg focus:ring-2 focus:ring-emerald-500\"></div><!-- Password --><div class=\"flex flex-col gap-2\"><label for=\"password\" class=\"form-label text-slate-300 text-sm font-medium\">Password</label> <input type=\"password\" id=\"password\" name=\"password\" placeholder=\"••••••••\" class=\"form-input bg-slate-800 border-slate-700 text-slate-200 placeholder-slate-500 rounded-lg focus:ring-2 focus:ring-emerald-500\"></div><!-- Submit --><button type=\"submit\" hx-target=\"#error-messages\" hx-swap=\"innerHTML\" hx-post=\"/login\" class=\"w-full py-3 rounded-lg font-semibold bg-emerald-600 hover:bg-emerald-500 focus:ring-2 focus:ring-emerald-400 focus:outline-none text-white shadow-md transition-all duration-200\">Login</button></form><!-- Divider --><div class=\"mt-6 text-center text-slate-500 text-sm\">or</div><!-- Links --><div class=\"mt-4 flex flex-col sm:flex-row sm:justify-between text-center gap-3 text-sm\"><a href=\"/register\" class=\"font-medium text-emerald-400 hover:text-emerald-300 transition\">Create an account</a> <a href=\"/forgot-password\" class=\"font-medium text-slate-400 hover:text-slate-300 transition\">Forgot password?</a></div></div></div>")
+			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 6, "</div><!-- Email --><div class=\"flex flex-col gap-2\"><label for=\"email\" class=\"form-label text-slate-300 text-sm font-medium\">Email address</label> <input type=\"email\" id=\"email\" name=\"email\" placeholder=\"you@example.com\" class=\"form-input bg-slate-800 border-slate-700 text-slate-200 placeholder-slate-500 rounded-lg focus:ring-2 focus:ring-emerald-500\"></div><!-- Password --><div class=\"flex flex-col gap-2\"><label for=\"password\" class=\"form-label text-slate-300 text-sm font-medium\">Password</label> <input type=\"password\" id=\"password\" name=\"password\" placeholder=\"••••••••\" class=\"form-input bg-slate-800 border-slate-700 text-slate-200 placeholder-slate-500 rounded-lg focus:ring-2 focus:ring-emerald-500\"></div><!-- Submit --><button type=\"submit\" hx-target=\"#error-messages\" hx-swap=\"innerHTML\" hx-post=\"/login\" class=\"w-full py-3 rounded-lg font-semibold bg-emerald-600 hover:bg-emerald-500 focus:ring-2 focus:ring-emerald-400 focus:outline-none text-white shadow-md transition-all duration-200\">Login</button></form><!-- Divider --><div class=\"mt-6 text-center text-slate-500 text-sm\">or</div><!-- Links --><div class=\"mt-4 flex flex-col sm:flex-row sm:justify-between text-center gap-3 text-sm\"><a href=\"/register\" class=\"font-medium text-emerald-400 hover:text-emerald-300 transition\">Create an account</a> <a href=\"/forgot-password\" class=\"font-medium text-slate-400 hover:text-slate-300 transition\">Forgot password?</a></div></div></div>")
 			if templ_7745c5c3_Err != nil {
 				return templ_7745c5c3_Err
 			}